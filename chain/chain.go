@@ -5,7 +5,6 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
 	"math/big"
 	"strings"
 	"time"
@@ -14,6 +13,7 @@ import (
 
 	"github.com/bittorrent/go-btfs/accounting"
 	"github.com/bittorrent/go-btfs/chain/config"
+	"github.com/bittorrent/go-btfs/ledger"
 	"github.com/bittorrent/go-btfs/settlement"
 	"github.com/bittorrent/go-btfs/settlement/swap"
 	"github.com/bittorrent/go-btfs/settlement/swap/bttc"
@@ -42,6 +42,8 @@ var (
 const (
 	MaxDelay          = 1 * time.Minute
 	CancellationDepth = 6
+	// the pending ledger entries are settled at the interval
+	LedgerSettleInterval = 1 * time.Minute
 )
 
 type ChainInfo struct {
@@ -66,8 +68,8 @@ type SettleInfo struct {
 	FileMetaService vault.FileMeta
 }
 
-// InitChain will initialize the Ethereum backend at the given endpoint and
-// set up the Transaction Service to interact with it using the provided signer.
+// InitChain will initialize the Ethereum backend over the configured endpoints
+// and set up the Transaction Service to interact with it using the provided signer.
 func InitChain(
 	ctx context.Context,
 	stateStore storage.StateStorer,
@@ -78,42 +80,10 @@ func InitChain(
 	chainconfig *config.ChainConfig,
 ) (*ChainInfo, error) {
 	StateStore = stateStore
-	var backend *ethclient.Client
-	var err error
-	if len(chainconfig.MultiEndpoint) > 0 {
-		var backendChan chan *ethclient.Client = make(chan *ethclient.Client, len(chainconfig.MultiEndpoint))
-		for _, endpoint := range chainconfig.MultiEndpoint {
-			go func(e string) {
-				b, err := ethclient.Dial(e)
-				if err != nil {
-					return
-				}
-				_, err = b.BlockNumber(context.Background())
-				if err != nil {
-					return
-				}
-				backendChan <- b
-			}(endpoint)
-		}
-		select {
-		case backend = <-backendChan:
-		case <-time.After(time.Second * 60):
-			return nil, errors.New("could not connect all rpc configuration after 1 min, please try again or check your network")
-		}
-	} else {
-		backend, err = ethclient.Dial(chainconfig.Endpoint)
-		if err != nil {
-			return nil, fmt.Errorf("dial eth client: %w", err)
-		}
-		_, err = backend.BlockNumber(context.Background())
-		if err != nil {
-			errMsg := "Could not connect to blockchain rpc, please check your network connection"
-			if err == io.EOF {
-				return nil, errors.New(errMsg)
-
-			}
-			return nil, fmt.Errorf("%s.%w", errMsg, err)
-		}
+	ledger.Init(stateStore)
+	backend, err := initBackend(chainconfig)
+	if err != nil {
+		return nil, err
 	}
 
 	overlayEthAddress, err := signer.EthereumAddress()
@@ -121,6 +91,9 @@ func InitChain(
 		return nil, fmt.Errorf("eth address: %w", err)
 	}
 
+	// the on-chain entries are recorded once their transactions are mined
+	ledger.StartSettling(ctx, backend.TransactionReceipt, LedgerSettleInterval)
+
 	transactionMonitor := transaction.NewMonitor(backend, overlayEthAddress, pollingInterval, CancellationDepth)
 
 	transactionService, err := transaction.NewService(backend, signer, stateStore, big.NewInt(chainID), transactionMonitor)
@@ -142,6 +115,58 @@ func InitChain(
 	return &ChainObject, nil
 }
 
+// initBackend connects to the configured endpoint and the other endpoints of
+// the chain, and waits for one of them to be healthy
+func initBackend(chainconfig *config.ChainConfig) (*transaction.MultiBackend, error) {
+	urls := make([]string, 0, len(chainconfig.MultiEndpoint)+1)
+	if chainconfig.Endpoint != "" {
+		urls = append(urls, chainconfig.Endpoint)
+	}
+	for _, url := range chainconfig.MultiEndpoint {
+		if url != chainconfig.Endpoint {
+			urls = append(urls, url)
+		}
+	}
+
+	// the endpoints failing to dial are kept, and dialed again by the health
+	// checks until they connect
+	endpoints := make([]transaction.Endpoint, 0, len(urls))
+	for _, url := range urls {
+		endpoint := transaction.Endpoint{URL: url, Dial: dialEndpoint(url)}
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			log.Warnf("dial rpc endpoint %s: %v", url, err)
+		} else {
+			endpoint.Backend = client
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	backend, err := transaction.NewMultiBackend(endpoints, transaction.DefaultMultiBackendOptions())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	err = backend.WaitHealthy(ctx, 5*time.Second)
+	if err != nil {
+		backend.Close()
+		return nil, errors.New("could not connect to blockchain rpc after 1 min, please try again or check your network connection")
+	}
+	backend.Start()
+	return backend, nil
+}
+
+func dialEndpoint(url string) func(ctx context.Context) (transaction.Backend, error) {
+	return func(ctx context.Context) (transaction.Backend, error) {
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+}
+
 func InitSettlement(
 	ctx context.Context,
 	stateStore storage.StateStorer,
@@ -23,6 +23,7 @@ import (
 
 	"github.com/bittorrent/go-btfs/s3"
 	"github.com/bittorrent/go-btfs/s3/api/services/accesskey"
+	"github.com/bittorrent/go-btfs/s3/api/services/object"
 
 	"github.com/bittorrent/go-btfs/chain/tokencfg"
 
@@ -47,6 +48,7 @@ import (
 	corerepo "github.com/bittorrent/go-btfs/core/corerepo"
 	libp2p "github.com/bittorrent/go-btfs/core/node/libp2p"
 	nodeMount "github.com/bittorrent/go-btfs/fuse/node"
+	"github.com/bittorrent/go-btfs/metrics"
 	"github.com/bittorrent/go-btfs/repo"
 	fsrepo "github.com/bittorrent/go-btfs/repo/fsrepo"
 	"github.com/bittorrent/go-btfs/reportstatus"
@@ -706,6 +708,7 @@ If the user need to start multiple nodes on the same machine, the configuration
 
 	// initialize metrics collector
 	prometheus.MustRegister(&corehttp.IpfsNodeCollector{Node: node})
+	metrics.Register()
 
 	// The daemon is *finally* ready.
 	fmt.Printf("Daemon is ready\n")
@@ -719,7 +722,7 @@ If the user need to start multiple nodes on the same machine, the configuration
 	}
 
 	// Init s3 providers
-	err = s3.InitProviders(statestore)
+	err = s3.InitProviders(statestore, node.Repo.Keystore())
 	if err != nil {
 		return err
 	}
@@ -727,6 +730,9 @@ If the user need to start multiple nodes on the same machine, the configuration
 	// Init access-key
 	accesskey.InitService(s3.GetProviders())
 
+	// Init s3 object service for the node commands
+	object.InitService(s3.GetProviders(), object.WithLock(s3.GetLock()))
+
 	// Start s3-compatible-api server
 	s3OptEnable, s3Opt := req.Options[enableS3CompatibleAPIKwd].(bool)
 	if s3OptEnable || (!s3Opt && cfg.S3CompatibleAPI.Enable) {
@@ -760,7 +766,14 @@ If the user need to start multiple nodes on the same machine, the configuration
 		spin.Analytics(api, cctx.ConfigRoot, node, version.CurrentVersionNumber, hValue)
 		spin.Hosts(node, env)
 		spin.Contracts(node, req, env, nodepb.ContractStat_HOST.String())
+		spin.ChallengeAudit(node, req, env)
+		spin.ShardRepair(node, req, env)
+		spin.BatchUploads(node, req, env)
+		spin.VaultGuard(node, req, env)
 		spin.RestartFixChequeCashOut()
+		spin.AutoCashCheques(node)
+		spin.TransactionBumper()
+		spin.StorageMetrics(node)
 
 		// Start auto-renewal service for storage files
 		spin.AutoRenewalService(node, req, env)
@@ -2,9 +2,16 @@ package commands
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
 	cmds "github.com/bittorrent/go-btfs-cmds"
 	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
 	"github.com/bittorrent/go-btfs/s3/api/services/accesskey"
+	"github.com/bittorrent/go-btfs/s3/api/services/sign"
+	"github.com/bittorrent/go-btfs/s3/consts"
 )
 
 var AccessKeyCmd = &cmds.Command{
@@ -20,6 +27,7 @@ var AccessKeyCmd = &cmds.Command{
 		"delete":   accessKeyDeleteCmd,
 		"get":      accessKeyGetCmd,
 		"list":     accessKeyListCmd,
+		"presign":  accessKeyPresignCmd,
 	},
 	NoLocal: true,
 }
@@ -173,3 +181,114 @@ var accessKeyListCmd = &cmds.Command{
 		return
 	},
 }
+
+const (
+	presignAccessKeyOptionName   = "access-key"
+	presignExpiresOptionName     = "expires"
+	presignMethodOptionName      = "method"
+	presignEndpointUrlOptionName = "endpoint-url"
+)
+
+type PresignedURL struct {
+	URL       string    `json:"url"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var accessKeyPresignCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Generate a presigned url of the object.",
+		ShortDescription: `Outputs an url signed in query string by the specified access-key, anyone
+holding the url can get or put the object without the secret until it expires.
+The first enabled access-key is used if no one specified.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("bucket", true, false, "The bucket name"),
+		cmds.StringArg("key", true, false, "The object key"),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(presignAccessKeyOptionName, "a", "The access-key used to sign the url"),
+		cmds.StringOption(presignExpiresOptionName, "e", "Valid duration of the url, at most 168h").WithDefault("1h"),
+		cmds.StringOption(presignMethodOptionName, "m", "The request method allowed by the url, GET or PUT").WithDefault(http.MethodGet),
+		cmds.StringOption(presignEndpointUrlOptionName, "The endpoint url of the s3-compatible-api, default to the configured address"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) (err error) {
+		err = checkDaemon(env)
+		if err != nil {
+			return
+		}
+
+		method := strings.ToUpper(req.Options[presignMethodOptionName].(string))
+		if method != http.MethodGet && method != http.MethodPut {
+			err = fmt.Errorf("unsupported method <%s>", method)
+			return
+		}
+		expires, err := time.ParseDuration(req.Options[presignExpiresOptionName].(string))
+		if err != nil {
+			return
+		}
+		if expires <= 0 || expires > 7*24*time.Hour || expires%time.Second != 0 {
+			err = errors.New("expires should be whole seconds between 1s and 168h")
+			return
+		}
+
+		endpoint, _ := req.Options[presignEndpointUrlOptionName].(string)
+		if endpoint == "" {
+			node, er := cmdenv.GetNode(env)
+			if er != nil {
+				return er
+			}
+			cfg, er := node.Repo.Config()
+			if er != nil {
+				return er
+			}
+			endpoint = "http://" + cfg.S3CompatibleAPI.Address
+		}
+
+		ack, err := getPresignAccessKey(req.Options[presignAccessKeyOptionName])
+		if err != nil {
+			return
+		}
+
+		now := time.Now()
+		path := consts.SlashSeparator + req.Arguments[0] + consts.SlashSeparator + req.Arguments[1]
+		url, err := sign.PresignV4(method, endpoint, path, ack.Key, ack.Secret, consts.DefaultBucketRegion, now, expires)
+		if err != nil {
+			return
+		}
+
+		err = cmds.EmitOnce(res, &PresignedURL{
+			URL:       url,
+			Method:    method,
+			ExpiresAt: now.Add(expires).UTC(),
+		})
+		return
+	},
+	Type: PresignedURL{},
+}
+
+func getPresignAccessKey(opt interface{}) (ack *accesskey.AccessKey, err error) {
+	key, _ := opt.(string)
+	if key != "" {
+		ack, err = accesskey.Get(key)
+		if err != nil {
+			return
+		}
+		if !ack.Enable {
+			err = fmt.Errorf("access-key <%s> is disabled", key)
+		}
+		return
+	}
+	list, err := accesskey.List()
+	if err != nil {
+		return
+	}
+	for _, item := range list {
+		if item.Enable {
+			ack = item
+			return
+		}
+	}
+	err = errors.New("no enabled access-key, please generate one first")
+	return
+}
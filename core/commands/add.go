@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bittorrent/go-btfs/chain"
 	"github.com/bittorrent/go-btfs/chain/abi"
 	chainconfig "github.com/bittorrent/go-btfs/chain/config"
 	oldcmds "github.com/bittorrent/go-btfs/commands"
@@ -389,11 +390,15 @@ only-hash, and progress/status related flags) will change the final hash.
 				}
 				fname := addit.Name()
 				size, _ := addit.Node().Size()
-				cli, err := ethclient.Dial(cfg.ChainInfo.Endpoint)
-				if err != nil {
-					return err
+				cli := chain.ChainObject.Backend
+				if cli == nil {
+					client, err := ethclient.Dial(cfg.ChainInfo.Endpoint)
+					if err != nil {
+						return err
+					}
+					defer client.Close()
+					cli = client
 				}
-				defer cli.Close()
 				currChainCfg, ok := chainconfig.GetChainConfig(cfg.ChainInfo.ChainId)
 				if !ok {
 					return fmt.Errorf("chain %d is not supported yet", cfg.ChainInfo.ChainId)
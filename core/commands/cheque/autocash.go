@@ -0,0 +1,549 @@
+package cheque
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/settlement/swap"
+	"github.com/bittorrent/go-btfs/settlement/swap/priceoracle"
+	"github.com/bittorrent/go-btfs/settlement/swap/vault"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/ethereum/go-ethereum/common"
+	logging "github.com/ipfs/go-log"
+)
+
+const (
+	autoCashConfigKey       = "swap_autocash_config"
+	autoCashStatusKey       = "swap_autocash_status"
+	autoCashGasSpentPrefix  = "swap_autocash_gas_spent_"
+	autoCashHistoryPrefix   = "swap_autocash_history_"
+	autoCashHistoryMaxCount = 1000
+
+	// estimated gas used by a cashout transaction, the gas cost of a cashout
+	// is this times the suggested gas price
+	autoCashEstimatedGas = 200000
+
+	AutoCashActionCashed  = "cashed"
+	AutoCashActionSkipped = "skipped"
+	AutoCashActionFailed  = "failed"
+
+	enableOptionName         = "enable"
+	thresholdOptionName      = "threshold"
+	gasMultipleOptionName    = "gas-multiple"
+	dailyGasBudgetOptionName = "daily-gas-budget"
+	limitOptionName          = "limit"
+)
+
+var (
+	autoCashLog = logging.Logger("autocash")
+
+	// serializes the runs of the scheduler and the config changes
+	autoCashLock sync.Mutex
+)
+
+// AutoCashConfig decides when the uncashed cheques of a peer are cashed out by
+// the scheduler, the cheques of a token are cashed when the uncashed amount
+// reaches the threshold of the token, or the gas multiple times the estimated
+// gas cost of the cashout.
+type AutoCashConfig struct {
+	Enabled bool `json:"enabled"`
+	// uncashed amount by token name
+	Thresholds map[string]*big.Int `json:"thresholds"`
+	// 0 to only cash out by the thresholds
+	GasMultiple uint64 `json:"gas_multiple"`
+	// estimated gas cost in BTT spent on the cashouts a day, nil for no limit
+	DailyGasBudget *big.Int `json:"daily_gas_budget,omitempty"`
+}
+
+// AutoCashDecision is what the scheduler did with the uncashed cheques of a
+// peer
+type AutoCashDecision struct {
+	Time     time.Time `json:"time"`
+	PeerID   string    `json:"peer_id"`
+	Token    string    `json:"token"`
+	Uncashed *big.Int  `json:"uncashed"`
+	GasCost  *big.Int  `json:"gas_cost"`
+	Action   string    `json:"action"`
+	Reason   string    `json:"reason"`
+	TxHash   string    `json:"tx_hash,omitempty"`
+}
+
+// AutoCashStatus is the result of the last run of the scheduler
+type AutoCashStatus struct {
+	LastRunAt time.Time `json:"last_run_at"`
+	Cashed    int       `json:"cashed"`
+	Skipped   int       `json:"skipped"`
+	Failed    int       `json:"failed"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func getAutoCashConfig() (*AutoCashConfig, error) {
+	cfg := &AutoCashConfig{Thresholds: make(map[string]*big.Int)}
+	err := chain.StateStore.Get(autoCashConfigKey, cfg)
+	if err != nil && err != storage.ErrNotFound {
+		return nil, err
+	}
+	if cfg.Thresholds == nil {
+		cfg.Thresholds = make(map[string]*big.Int)
+	}
+	return cfg, nil
+}
+
+func autoCashGasSpentKey() string {
+	return fmt.Sprintf("%s%d", autoCashGasSpentPrefix, utils.TodayUnix())
+}
+
+func getAutoCashGasSpent() (*big.Int, error) {
+	spent := big.NewInt(0)
+	err := chain.StateStore.Get(autoCashGasSpentKey(), &spent)
+	if err != nil && err != storage.ErrNotFound {
+		return nil, err
+	}
+	return spent, nil
+}
+
+func saveAutoCashDecision(d *AutoCashDecision) error {
+	key := fmt.Sprintf("%s%020d_%s_%s", autoCashHistoryPrefix, d.Time.UnixNano(), d.Token, d.PeerID)
+	return chain.StateStore.Put(key, d)
+}
+
+// listAutoCashDecisions lists the decisions from the oldest to the latest
+func listAutoCashDecisions() ([]*AutoCashDecision, error) {
+	out := make([]*AutoCashDecision, 0)
+	err := chain.StateStore.Iterate(autoCashHistoryPrefix, func(key, val []byte) (bool, error) {
+		var d AutoCashDecision
+		if err := json.Unmarshal(val, &d); err != nil {
+			return false, err
+		}
+		out = append(out, &d)
+		return false, nil
+	})
+	return out, err
+}
+
+// pruneAutoCashGasSpent deletes the gas spent of the days before today
+func pruneAutoCashGasSpent() error {
+	today := autoCashGasSpentKey()
+	keys := make([]string, 0)
+	err := chain.StateStore.Iterate(autoCashGasSpentPrefix, func(key, val []byte) (bool, error) {
+		if string(key) != today {
+			keys = append(keys, string(key))
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := chain.StateStore.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneAutoCashHistory deletes the oldest decisions beyond the max count
+func pruneAutoCashHistory() error {
+	keys := make([]string, 0)
+	err := chain.StateStore.Iterate(autoCashHistoryPrefix, func(key, val []byte) (bool, error) {
+		keys = append(keys, string(key))
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(keys)-autoCashHistoryMaxCount; i++ {
+		if err := chain.StateStore.Delete(keys[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// autoCashService is what the scheduler uses of the settlement services
+type autoCashService interface {
+	LastReceivedCheques(token common.Address) (map[string]*vault.SignedCheque, error)
+	CashoutStatus(ctx context.Context, peer string, token common.Address) (*vault.CashoutStatus, error)
+	CashCheque(ctx context.Context, peer string, token common.Address) (common.Hash, error)
+	CurrentRate(token common.Address) (*big.Int, error)
+}
+
+// settleAutoCashService rates the tokens by the price oracle, the rest are
+// done by the swap service
+type settleAutoCashService struct {
+	*swap.Service
+	oracle priceoracle.Service
+}
+
+func (s settleAutoCashService) CurrentRate(token common.Address) (*big.Int, error) {
+	return s.oracle.CurrentRate(token)
+}
+
+type autoCashCandidate struct {
+	peerID   string
+	uncashed *big.Int
+	reason   string
+}
+
+// AutoCashCheques cashes out the uncashed cheques of the peers by the auto
+// cashout config, the peers with the most uncashed amount are cashed first
+// until the daily gas budget is used up. The cheques of a peer are cashed on
+// the vault of the peer, so every peer is cashed out by its own transaction
+// and the gas cost is budgeted per transaction.
+func AutoCashCheques(ctx context.Context) error {
+	autoCashLock.Lock()
+	defer autoCashLock.Unlock()
+
+	cfg, err := getAutoCashConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+	// the cashouts are not allowed until the last ones are fixed on startup
+	if vault.RestartFixCashOutStatusLock {
+		return nil
+	}
+
+	status := &AutoCashStatus{LastRunAt: time.Now()}
+	svc := settleAutoCashService{chain.SettleObject.SwapService, chain.SettleObject.OracleService}
+	err = autoCashCheques(ctx, svc, cfg, status)
+	if err != nil {
+		status.Error = err.Error()
+	}
+	if perr := pruneAutoCashHistory(); perr != nil {
+		autoCashLog.Errorf("Failed to prune auto cashout history: %v", perr)
+	}
+	if perr := pruneAutoCashGasSpent(); perr != nil {
+		autoCashLog.Errorf("Failed to prune auto cashout gas spent: %v", perr)
+	}
+	if serr := chain.StateStore.Put(autoCashStatusKey, status); serr != nil {
+		autoCashLog.Errorf("Failed to save auto cashout status: %v", serr)
+	}
+	return err
+}
+
+func autoCashCheques(ctx context.Context, svc autoCashService, cfg *AutoCashConfig, status *AutoCashStatus) error {
+	gasPrice, err := chain.ChainObject.Backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	gasCost := new(big.Int).Mul(gasPrice, big.NewInt(autoCashEstimatedGas))
+	return cashCandidates(ctx, svc, cfg, gasCost, status)
+}
+
+// cashCandidates cashes out the candidates of every token within the daily
+// gas budget
+func cashCandidates(ctx context.Context, svc autoCashService, cfg *AutoCashConfig, gasCost *big.Int,
+	status *AutoCashStatus) error {
+	spent, err := getAutoCashGasSpent()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(tokencfg.MpTokenAddr))
+	for name := range tokencfg.MpTokenAddr {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		token := tokencfg.MpTokenAddr[name]
+		threshold := cfg.Thresholds[name]
+		if threshold == nil && cfg.GasMultiple == 0 {
+			continue
+		}
+		candidates, err := autoCashCandidates(ctx, svc, cfg, token, threshold, gasCost)
+		if err != nil {
+			return err
+		}
+		for _, c := range candidates {
+			d := &AutoCashDecision{
+				Time:     time.Now(),
+				PeerID:   c.peerID,
+				Token:    name,
+				Uncashed: c.uncashed,
+				GasCost:  gasCost,
+				Reason:   c.reason,
+			}
+			if cfg.DailyGasBudget != nil && new(big.Int).Add(spent, gasCost).Cmp(cfg.DailyGasBudget) > 0 {
+				d.Action = AutoCashActionSkipped
+				d.Reason = "daily gas budget used up"
+				status.Skipped++
+			} else if txHash, err := svc.CashCheque(ctx, c.peerID, token); err != nil {
+				d.Action = AutoCashActionFailed
+				d.Reason = err.Error()
+				status.Failed++
+			} else {
+				d.Action = AutoCashActionCashed
+				d.TxHash = txHash.String()
+				status.Cashed++
+				spent.Add(spent, gasCost)
+				if err := chain.StateStore.Put(autoCashGasSpentKey(), spent); err != nil {
+					return err
+				}
+			}
+			if err := saveAutoCashDecision(d); err != nil {
+				return err
+			}
+			autoCashLog.Infof("Auto cashout of %s from peer %s: %s, %s", name, c.peerID, d.Action, d.Reason)
+		}
+	}
+	return nil
+}
+
+// autoCashCandidates returns the peers whose uncashed cheques of the token
+// should be cashed out, ordered by the uncashed amount descending
+func autoCashCandidates(ctx context.Context, svc autoCashService, cfg *AutoCashConfig, token common.Address,
+	threshold *big.Int, gasCost *big.Int) ([]*autoCashCandidate, error) {
+	cheques, err := svc.LastReceivedCheques(token)
+	if err != nil {
+		return nil, err
+	}
+	// the gas cost is paid in BTT, it is converted to the token by the rate
+	rate, err := svc.CurrentRate(token)
+	if err != nil {
+		return nil, err
+	}
+	minByGas := new(big.Int).Mul(gasCost, rate)
+	minByGas.Mul(minByGas, new(big.Int).SetUint64(cfg.GasMultiple))
+
+	candidates := make([]*autoCashCandidate, 0)
+	for peerID := range cheques {
+		cs, err := svc.CashoutStatus(ctx, peerID, token)
+		if err != nil {
+			autoCashLog.Warnf("Failed to get cashout status of peer %s: %v", peerID, err)
+			continue
+		}
+		// the last cashout is still pending
+		if cs.Last != nil && cs.Last.Result == nil && !cs.Last.Reverted {
+			continue
+		}
+		if cs.UncashedAmount == nil || cs.UncashedAmount.Sign() <= 0 {
+			continue
+		}
+		var reason string
+		if threshold != nil && cs.UncashedAmount.Cmp(threshold) >= 0 {
+			reason = fmt.Sprintf("uncashed amount reaches threshold %s", threshold)
+		} else if cfg.GasMultiple > 0 && cs.UncashedAmount.Cmp(minByGas) >= 0 {
+			reason = fmt.Sprintf("uncashed amount reaches %d times the gas cost", cfg.GasMultiple)
+		} else {
+			continue
+		}
+		candidates = append(candidates, &autoCashCandidate{peerID: peerID, uncashed: cs.UncashedAmount, reason: reason})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].uncashed.Cmp(candidates[j].uncashed) > 0
+	})
+	return candidates, nil
+}
+
+var AutoCashCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Cash out the received cheques automatically.",
+		ShortDescription: `
+The scheduler cashes out the uncashed cheques of a peer when the uncashed
+amount reaches the threshold of the token, or the gas multiple times the
+estimated gas cost of the cashout. The peers with the most uncashed amount are
+cashed first in a run, until the daily gas budget is used up. Every peer is
+cashed out by its own transaction, the cheques of different peers are not
+batched into one.`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"config":  AutoCashConfigCmd,
+		"status":  AutoCashStatusCmd,
+		"history": AutoCashHistoryCmd,
+	},
+}
+
+var AutoCashConfigCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show or set the auto cashout config.",
+		ShortDescription: `
+The threshold is set for the token given, the daily gas budget is in BTT (wei),
+0 for no limit.
+
+Examples:
+    $ btfs cheque autocash config --enable=true --tk=WBTT --threshold=100000000000000000000
+    $ btfs cheque autocash config --gas-multiple=10 --daily-gas-budget=1000000000000000000`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(enableOptionName, "Enable or disable the auto cashout."),
+		cmds.StringOption(tokencfg.TokenTypeName, "tk", "Token of the threshold, default WBTT, other TRX/USDD/USDT.").WithDefault("WBTT"),
+		cmds.StringOption(thresholdOptionName, "Uncashed amount of the token to cash out, 0 to remove the threshold."),
+		cmds.Uint64Option(gasMultipleOptionName, "Cash out when the uncashed amount reaches the multiple of the gas cost, 0 to disable."),
+		cmds.StringOption(dailyGasBudgetOptionName, "Max estimated gas cost of the cashouts a day in BTT (wei), 0 for no limit."),
+	},
+	RunTimeout: 1 * time.Minute,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		autoCashLock.Lock()
+		defer autoCashLock.Unlock()
+		cfg, err := getAutoCashConfig()
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		if enabled, ok := req.Options[enableOptionName].(bool); ok {
+			cfg.Enabled = enabled
+			changed = true
+		}
+		if s, ok := req.Options[thresholdOptionName].(string); ok {
+			tokenStr := req.Options[tokencfg.TokenTypeName].(string)
+			if _, ok := tokencfg.MpTokenAddr[tokenStr]; !ok {
+				return errors.New("your input token is none. ")
+			}
+			threshold, err := parseAmount(s)
+			if err != nil {
+				return err
+			}
+			if threshold.Sign() == 0 {
+				delete(cfg.Thresholds, tokenStr)
+			} else {
+				cfg.Thresholds[tokenStr] = threshold
+			}
+			changed = true
+		}
+		if multiple, ok := req.Options[gasMultipleOptionName].(uint64); ok {
+			cfg.GasMultiple = multiple
+			changed = true
+		}
+		if s, ok := req.Options[dailyGasBudgetOptionName].(string); ok {
+			budget, err := parseAmount(s)
+			if err != nil {
+				return err
+			}
+			if cfg.DailyGasBudget = budget; budget.Sign() == 0 {
+				cfg.DailyGasBudget = nil
+			}
+			changed = true
+		}
+		if changed {
+			if err := chain.StateStore.Put(autoCashConfigKey, cfg); err != nil {
+				return err
+			}
+		}
+		return cmds.EmitOnce(res, cfg)
+	},
+	Type: AutoCashConfig{},
+}
+
+func parseAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount: %s", s)
+	}
+	return amount, nil
+}
+
+type AutoCashStatusRet struct {
+	Config         *AutoCashConfig `json:"config"`
+	LastRun        *AutoCashStatus `json:"last_run,omitempty"`
+	TodayGasSpent  *big.Int        `json:"today_gas_spent"`
+	EstimatedGas   uint64          `json:"estimated_gas"`
+	CurrentGasCost *big.Int        `json:"current_gas_cost,omitempty"`
+}
+
+var AutoCashStatusCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the status of the auto cashout.",
+		ShortDescription: `
+This command shows the auto cashout config, the result of the last run, the
+estimated gas cost spent today and the current gas cost of a cashout.`,
+	},
+	RunTimeout: 1 * time.Minute,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		cfg, err := getAutoCashConfig()
+		if err != nil {
+			return err
+		}
+		out := &AutoCashStatusRet{Config: cfg, EstimatedGas: autoCashEstimatedGas}
+		last := &AutoCashStatus{}
+		err = chain.StateStore.Get(autoCashStatusKey, last)
+		if err == nil {
+			out.LastRun = last
+		} else if err != storage.ErrNotFound {
+			return err
+		}
+		out.TodayGasSpent, err = getAutoCashGasSpent()
+		if err != nil {
+			return err
+		}
+		if gasPrice, err := chain.ChainObject.Backend.SuggestGasPrice(req.Context); err == nil {
+			out.CurrentGasCost = new(big.Int).Mul(gasPrice, big.NewInt(autoCashEstimatedGas))
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Type: AutoCashStatusRet{},
+}
+
+type AutoCashHistoryRet struct {
+	Decisions []*AutoCashDecision `json:"decisions"`
+}
+
+var AutoCashHistoryCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the decisions of the auto cashout.",
+		ShortDescription: `
+This command lists the cashouts done, failed or skipped by the scheduler, the
+latest first.`,
+	},
+	Options: []cmds.Option{
+		cmds.IntOption(limitOptionName, "l", "Max number of the decisions listed.").WithDefault(20),
+	},
+	RunTimeout: 1 * time.Minute,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		limit := req.Options[limitOptionName].(int)
+		if limit <= 0 {
+			return fmt.Errorf("invalid limit: %d", limit)
+		}
+		decisions, err := listAutoCashDecisions()
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(decisions, func(i, j int) bool {
+			return decisions[i].Time.After(decisions[j].Time)
+		})
+		if len(decisions) > limit {
+			decisions = decisions[:limit]
+		}
+		return cmds.EmitOnce(res, &AutoCashHistoryRet{Decisions: decisions})
+	},
+	Type: AutoCashHistoryRet{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *AutoCashHistoryRet) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "TIME\tPEER\tTOKEN\tUNCASHED\tACTION\tREASON\tTX")
+			for _, d := range out.Decisions {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.Time.Format(time.RFC3339), d.PeerID, d.Token,
+					d.Uncashed, d.Action, d.Reason, d.TxHash)
+			}
+			return tw.Flush()
+		}),
+	},
+}
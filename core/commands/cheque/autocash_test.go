@@ -0,0 +1,230 @@
+package cheque
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/settlement/swap/vault"
+	"github.com/bittorrent/go-btfs/statestore/mock"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var testToken = common.HexToAddress("0x107742eb846b86ceaaf7528d5c85cddcad3e409a")
+
+type autoCashServiceMock struct {
+	statuses map[string]*vault.CashoutStatus
+	rate     *big.Int
+	cashed   []string
+}
+
+func (m *autoCashServiceMock) LastReceivedCheques(token common.Address) (map[string]*vault.SignedCheque, error) {
+	cheques := make(map[string]*vault.SignedCheque)
+	for peerID := range m.statuses {
+		cheques[peerID] = &vault.SignedCheque{}
+	}
+	return cheques, nil
+}
+
+func (m *autoCashServiceMock) CashoutStatus(ctx context.Context, peer string, token common.Address) (*vault.CashoutStatus, error) {
+	cs, ok := m.statuses[peer]
+	if !ok {
+		return nil, errors.New("no cheque")
+	}
+	return cs, nil
+}
+
+func (m *autoCashServiceMock) CashCheque(ctx context.Context, peer string, token common.Address) (common.Hash, error) {
+	m.cashed = append(m.cashed, peer)
+	return common.HexToHash("0x1"), nil
+}
+
+func (m *autoCashServiceMock) CurrentRate(token common.Address) (*big.Int, error) {
+	return m.rate, nil
+}
+
+func uncashed(amount int64) *vault.CashoutStatus {
+	return &vault.CashoutStatus{UncashedAmount: big.NewInt(amount)}
+}
+
+func TestAutoCashCandidates(t *testing.T) {
+	pending := uncashed(1000)
+	pending.Last = &vault.LastCashout{}
+	reverted := uncashed(2000)
+	reverted.Last = &vault.LastCashout{Reverted: true}
+	cashed := uncashed(1000)
+	cashed.Last = &vault.LastCashout{Result: &vault.CashChequeResult{}}
+
+	testCases := []struct {
+		name        string
+		threshold   *big.Int
+		gasMultiple uint64
+		statuses    map[string]*vault.CashoutStatus
+		expected    []string
+	}{
+		{
+			name:      "threshold",
+			threshold: big.NewInt(100),
+			statuses: map[string]*vault.CashoutStatus{
+				"a": uncashed(99),
+				"b": uncashed(100),
+				"c": uncashed(0),
+			},
+			expected: []string{"b"},
+		},
+		{
+			// the gas cost is 10 times the rate 2, so a cashout needs 60 for
+			// the multiple 3
+			name:        "gas multiple",
+			gasMultiple: 3,
+			statuses: map[string]*vault.CashoutStatus{
+				"a": uncashed(59),
+				"b": uncashed(60),
+			},
+			expected: []string{"b"},
+		},
+		{
+			name:        "gas multiple below threshold",
+			threshold:   big.NewInt(1000),
+			gasMultiple: 10,
+			statuses: map[string]*vault.CashoutStatus{
+				"a": uncashed(100),
+				"b": uncashed(199),
+				"c": uncashed(200),
+				"d": uncashed(1000),
+			},
+			expected: []string{"d", "c"},
+		},
+		{
+			name:      "pending cashout skipped",
+			threshold: big.NewInt(100),
+			statuses: map[string]*vault.CashoutStatus{
+				"a": pending,
+				"b": reverted,
+				"c": cashed,
+			},
+			expected: []string{"b", "c"},
+		},
+		{
+			name:      "ordered by uncashed amount",
+			threshold: big.NewInt(1),
+			statuses: map[string]*vault.CashoutStatus{
+				"a": uncashed(5),
+				"b": uncashed(50),
+				"c": uncashed(20),
+				"d": uncashed(500),
+			},
+			expected: []string{"d", "b", "c", "a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		svc := &autoCashServiceMock{statuses: tc.statuses, rate: big.NewInt(2)}
+		cfg := &AutoCashConfig{Enabled: true, GasMultiple: tc.gasMultiple}
+		candidates, err := autoCashCandidates(context.Background(), svc, cfg, testToken, tc.threshold, big.NewInt(10))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(candidates) != len(tc.expected) {
+			t.Fatalf("%s: expected %d candidates, got %d", tc.name, len(tc.expected), len(candidates))
+		}
+		for i, c := range candidates {
+			if c.peerID != tc.expected[i] {
+				t.Fatalf("%s: expected candidate %d to be %s, got %s", tc.name, i, tc.expected[i], c.peerID)
+			}
+		}
+	}
+}
+
+func TestAutoCashDailyGasBudget(t *testing.T) {
+	testCases := []struct {
+		name    string
+		budget  *big.Int
+		spent   *big.Int
+		cashed  []string
+		skipped int
+	}{
+		{
+			name:   "no limit",
+			cashed: []string{"c", "b", "a"},
+		},
+		{
+			name:    "budget of two cashouts",
+			budget:  big.NewInt(20),
+			cashed:  []string{"c", "b"},
+			skipped: 1,
+		},
+		{
+			name:    "budget partly spent",
+			budget:  big.NewInt(20),
+			spent:   big.NewInt(15),
+			skipped: 3,
+		},
+	}
+
+	store, tokens := chain.StateStore, tokencfg.MpTokenAddr
+	defer func() {
+		chain.StateStore, tokencfg.MpTokenAddr = store, tokens
+	}()
+	tokencfg.MpTokenAddr = map[string]common.Address{tokencfg.WBTT: testToken}
+
+	for _, tc := range testCases {
+		chain.StateStore = mock.NewStateStore()
+		if tc.spent != nil {
+			if err := chain.StateStore.Put(autoCashGasSpentKey(), tc.spent); err != nil {
+				t.Fatal(err)
+			}
+		}
+		svc := &autoCashServiceMock{
+			statuses: map[string]*vault.CashoutStatus{
+				"a": uncashed(100),
+				"b": uncashed(200),
+				"c": uncashed(300),
+			},
+			rate: big.NewInt(1),
+		}
+		cfg := &AutoCashConfig{
+			Enabled:        true,
+			Thresholds:     map[string]*big.Int{tokencfg.WBTT: big.NewInt(100)},
+			DailyGasBudget: tc.budget,
+		}
+		status := &AutoCashStatus{}
+		if err := cashCandidates(context.Background(), svc, cfg, big.NewInt(10), status); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(svc.cashed) != len(tc.cashed) {
+			t.Fatalf("%s: expected %d cashouts, got %d", tc.name, len(tc.cashed), len(svc.cashed))
+		}
+		for i, peerID := range svc.cashed {
+			if peerID != tc.cashed[i] {
+				t.Fatalf("%s: expected cashout %d of %s, got %s", tc.name, i, tc.cashed[i], peerID)
+			}
+		}
+		if status.Cashed != len(tc.cashed) || status.Skipped != tc.skipped {
+			t.Fatalf("%s: expected %d cashed and %d skipped, got %d and %d", tc.name,
+				len(tc.cashed), tc.skipped, status.Cashed, status.Skipped)
+		}
+
+		decisions, err := listAutoCashDecisions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decisions) != 3 {
+			t.Fatalf("%s: expected 3 decisions, got %d", tc.name, len(decisions))
+		}
+		spent, err := getAutoCashGasSpent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := int64(10 * len(tc.cashed))
+		if tc.spent != nil {
+			expected += tc.spent.Int64()
+		}
+		if spent.Int64() != expected {
+			t.Fatalf("%s: expected gas spent %d, got %s", tc.name, expected, spent)
+		}
+	}
+}
@@ -87,6 +87,7 @@ Vault services include issue cheque to peer, receive cheque and store operations
 		"price":              StorePriceCmd,
 		"price-all":          StorePriceAllCmd,
 		"fix_cheque_cashout": FixChequeCashOutCmd,
+		"autocash":           AutoCashCmd,
 
 		"send":                   SendChequeCmd,
 		"sendlist":               ListSendChequesCmd,
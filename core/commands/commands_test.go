@@ -228,16 +228,32 @@ func TestCommands(t *testing.T) {
 		"/cid/hashes",
 		"/storage",
 		"/storage/path",
+		"/storage/policy",
+		"/storage/policy/show",
+		"/storage/policy/set",
 		"/storage/path/capacity",
 		"/storage/path/status",
 		"/storage/path/migrate",
 		"/storage/path/list",
 		"/storage/path/mkdir",
 		"/storage/path/volumes",
+		"/storage/path/add",
+		"/storage/path/remove",
+		"/storage/path/rebalance",
+		"/storage/path/dirs",
 		"/storage/upload",
 		"/storage/upload/init",
 		"/storage/upload/recvcontract",
 		"/storage/upload/status",
+		"/storage/upload/resume",
+		"/storage/upload/abort",
+		"/storage/upload/quote",
+		"/storage/upload/batch",
+		"/storage/upload/batch/add",
+		"/storage/upload/batch/list",
+		"/storage/upload/batch/pause",
+		"/storage/upload/batch/resume",
+		"/storage/upload/batch/cancel",
 		"/storage/upload/repair",
 		"/storage/upload/getcontractbatch",
 		"/storage/upload/signcontractbatch",
@@ -257,9 +273,10 @@ func TestCommands(t *testing.T) {
 		"/storage/hosts",
 		"/storage/hosts/sync",
 		"/storage/hosts/info",
-		// "/storage/challenge",
-		// "/storage/challenge/request",
-		// "/storage/challenge/response",
+		"/storage/challenge",
+		"/storage/challenge/history",
+		"/storage/challenge/request",
+		"/storage/challenge/response",
 		"/storage/dcrepair",
 		"/storage/dcrepair/request",
 		"/storage/dcrepair/response",
@@ -267,6 +284,7 @@ func TestCommands(t *testing.T) {
 		"/storage/stats/info",
 		"/storage/stats/sync",
 		"/storage/stats/list",
+		"/storage/stats/rejections",
 		"/storage/contracts",
 		"/storage/contracts/list",
 		"/storage/contracts/stat",
@@ -319,6 +337,8 @@ func TestCommands(t *testing.T) {
 		"/storage/upload/renew/service/stop",
 		"/storage/upload/renew/service/restart",
 		"/storage/upload/renew/service/status",
+		"/storage/upload/renew/budget",
+		"/storage/upload/renew/notify",
 		"/test",
 		"/test/cheque",
 		"/test/hosts",
@@ -331,6 +351,9 @@ func TestCommands(t *testing.T) {
 		"/vault/wbttbalance",
 		"/vault/withdraw",
 		"/vault/upgrade",
+		"/vault/guard",
+		"/vault/guard/config",
+		"/vault/guard/status",
 		"/network",
 		"/bttc",
 		"/bttc/btt2wbtt",
@@ -370,7 +393,18 @@ func TestCommands(t *testing.T) {
 		"/accesskey/delete",
 		"/accesskey/get",
 		"/accesskey/list",
+		"/accesskey/presign",
+		"/s3",
+		"/s3/storage",
+		"/s3/storage/set",
+		"/s3/storage/get",
+		"/s3/storage/delete",
+		"/s3/status",
 		"/cheque/fix_cheque_cashout",
+		"/cheque/autocash",
+		"/cheque/autocash/config",
+		"/cheque/autocash/status",
+		"/cheque/autocash/history",
 		"/encrypt",
 		"/decrypt",
 		"/dashboard",
@@ -391,6 +425,15 @@ func TestCommands(t *testing.T) {
 		"/stake/unlock",
 		"/stake/withdraw",
 		"/stake/query",
+		"/ledger",
+		"/ledger/list",
+		"/ledger/balance",
+		"/ledger/export",
+		"/tx",
+		"/tx/pending",
+		"/tx/bump",
+		"/tx/cancel",
+		"/tx/gas",
 	}
 
 	cmdSet := make(map[string]struct{})
@@ -0,0 +1,247 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bittorrent/go-btfs/ledger"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+)
+
+const (
+	fromOptionName    = "from"
+	toOptionName      = "to"
+	typeOptionName    = "type"
+	tokenOptionName   = "token"
+	accountOptionName = "account"
+	formatOptionName  = "format"
+
+	formatCSV  = "csv"
+	formatJSON = "json"
+
+	dateLayout = "2006-01-02"
+)
+
+var timeOptions = []cmds.Option{
+	cmds.StringOption(fromOptionName, "f", "Include the entries from the time, in YYYY-MM-DD, RFC3339 or unix seconds."),
+	cmds.StringOption(toOptionName, "t", "Include the entries before the time, in YYYY-MM-DD, RFC3339 or unix seconds."),
+}
+
+var LedgerCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the ledger of all the payments of the node.",
+		ShortDescription: `
+The ledger records every movement of funds in double entry, each entry debits
+an account and credits another one by the amount of a token:
+
+    wallet              the tokens at the bttc address of the node
+    vault               the tokens in the vault of the node
+    cheques-receivable  the received cheques not cashed out yet
+    stake               the staked BTT
+    stake-unlocking     the unstaked BTT not withdrawn yet
+    exchange            the clearing account of the BTT/WBTT swaps
+    proxy-users         the payments of the proxy users not spent yet
+    gas-fees            the gas paid for the transactions of the node
+    external            the other parties
+
+Cheques, cashouts, vault deposits and withdrawals, swaps, transfers, stake
+operations and proxy payments are recorded. The on-chain movements are recorded
+along with their gas fees once their transactions are mined.`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"list":    LedgerListCmd,
+		"balance": LedgerBalanceCmd,
+		"export":  LedgerExportCmd,
+	},
+}
+
+type LedgerListRet struct {
+	Entries []*ledger.Entry `json:"entries"`
+}
+
+var LedgerListCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the ledger entries.",
+	},
+	Options: append([]cmds.Option{
+		cmds.StringOption(typeOptionName, "Only list the entries of the type, e.g. cheque-sent."),
+		cmds.StringOption(tokenOptionName, "tk", "Only list the entries of the token."),
+		cmds.StringOption(accountOptionName, "a", "Only list the entries debiting or crediting the account."),
+	}, timeOptions...),
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		entries, err := listEntries(req)
+		if err != nil {
+			return err
+		}
+		typ, _ := req.Options[typeOptionName].(string)
+		token, _ := req.Options[tokenOptionName].(string)
+		account, _ := req.Options[accountOptionName].(string)
+		out := make([]*ledger.Entry, 0, len(entries))
+		for _, e := range entries {
+			if typ != "" && e.Type != typ {
+				continue
+			}
+			if token != "" && e.Token != token {
+				continue
+			}
+			if account != "" && e.Debit != account && e.Credit != account {
+				continue
+			}
+			out = append(out, e)
+		}
+		return cmds.EmitOnce(res, &LedgerListRet{Entries: out})
+	},
+	Type: &LedgerListRet{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *LedgerListRet) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "TIME\tTYPE\tDEBIT\tCREDIT\tTOKEN\tAMOUNT\tCOUNTERPARTY\tREFERENCE\tTX")
+			for _, e := range out.Entries {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					time.Unix(e.Time, 0).Format(time.RFC3339), e.Type, e.Debit, e.Credit, e.Token, e.Amount,
+					e.Counterparty, e.Reference, e.TxHash)
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+type LedgerBalanceRet struct {
+	Balances []*ledger.Balance `json:"balances"`
+}
+
+var LedgerBalanceCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the balances of the ledger accounts by token.",
+		ShortDescription: `
+The balance of an account is its debits minus its credits, the balances of a
+token always sum to zero.`,
+	},
+	Options: timeOptions,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		entries, err := listEntries(req)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &LedgerBalanceRet{Balances: ledger.Balances(entries)})
+	},
+	Type: &LedgerBalanceRet{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *LedgerBalanceRet) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "TOKEN\tACCOUNT\tDEBIT\tCREDIT\tBALANCE")
+			for _, b := range out.Balances {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", b.Token, b.Account, b.Debit, b.Credit, b.Balance)
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+var LedgerExportCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Export the ledger entries in CSV or JSON.",
+		ShortDescription: `
+Examples:
+    # Export the entries of September 2026 in CSV
+    $ btfs ledger export --format=csv --from=2026-09-01 --to=2026-10-01 > ledger.csv
+`,
+	},
+	Options: append([]cmds.Option{
+		cmds.StringOption(formatOptionName, "Export format, csv or json.").WithDefault(formatCSV),
+	}, timeOptions...),
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		format := req.Options[formatOptionName].(string)
+		if format != formatCSV && format != formatJSON {
+			return fmt.Errorf("invalid format: %s", format)
+		}
+		entries, err := listEntries(req)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &LedgerListRet{Entries: entries})
+	},
+	Type: &LedgerListRet{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *LedgerListRet) error {
+			if req.Options[formatOptionName].(string) == formatJSON {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(out.Entries)
+			}
+			return writeCSV(w, out.Entries)
+		}),
+	},
+}
+
+func writeCSV(w io.Writer, entries []*ledger.Entry) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"id", "time", "type", "debit", "credit", "token", "amount", "counterparty",
+		"reference", "tx_hash"})
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		err = cw.Write([]string{e.ID, time.Unix(e.Time, 0).UTC().Format(time.RFC3339), e.Type, e.Debit, e.Credit,
+			e.Token, e.Amount.String(), e.Counterparty, e.Reference, e.TxHash})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func listEntries(req *cmds.Request) ([]*ledger.Entry, error) {
+	from, err := parseTimeOption(req, fromOptionName)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTimeOption(req, toOptionName)
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+	return ledger.List(from, to)
+}
+
+// parseTimeOption parses the time in YYYY-MM-DD (UTC), RFC3339 or unix
+// seconds, the zero time is returned if the option is not set
+func parseTimeOption(req *cmds.Request, name string) (time.Time, error) {
+	s, ok := req.Options[name].(string)
+	if !ok || s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(dateLayout, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s time: %s", name, s)
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"github.com/bittorrent/go-btfs/utils"
 	"io"
+	"text/tabwriter"
 	"time"
 
 	cmds "github.com/bittorrent/go-btfs-cmds"
 	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/transaction"
 )
 
 const (
@@ -21,6 +23,9 @@ type NetworkRet struct {
 	ErrBttc    string `json:"err_bttc"`
 	CodeStatus int    `json:"code_status"`
 	ErrStatus  string `json:"err_status"`
+	// the rpc endpoint the transactions are sent to
+	ActiveEndpoint string                       `json:"active_endpoint"`
+	Endpoints      []transaction.EndpointStatus `json:"endpoints"`
 }
 
 var NetworkCmd = &cmds.Command{
@@ -51,6 +56,10 @@ var NetworkCmd = &cmds.Command{
 			CodeStatus: chain.CodeStatus,
 			ErrStatus:  switchErrToString(chain.ErrStatus),
 		}
+		if backend, ok := chain.ChainObject.Backend.(*transaction.MultiBackend); ok {
+			ret.ActiveEndpoint = backend.ActiveEndpoint()
+			ret.Endpoints = backend.Status()
+		}
 		return cmds.EmitOnce(res, &ret)
 	},
 	Type: NetworkRet{},
@@ -58,7 +67,17 @@ var NetworkCmd = &cmds.Command{
 		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *NetworkRet) error {
 			_, err := fmt.Fprintf(w, "code bttc:\t%d\nerr bttc:\t%s\ncode status:\t%d\nerr status:\t%s\n",
 				out.CodeBttc, out.ErrBttc, out.CodeStatus, out.ErrStatus)
-			return err
+			if err != nil || len(out.Endpoints) == 0 {
+				return err
+			}
+			fmt.Fprintf(w, "active endpoint:\t%s\n\n", out.ActiveEndpoint)
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ENDPOINT\tACTIVE\tHEALTHY\tBLOCK\tLAG\tLATENCY\tERROR RATE\tLAST ERROR")
+			for _, e := range out.Endpoints {
+				fmt.Fprintf(tw, "%s\t%t\t%t\t%d\t%d\t%dms\t%.0f%%\t%s\n", e.URL, e.Active, e.Healthy, e.Block,
+					e.BlockLag, e.LatencyMs, e.ErrorRate*100, e.LastError)
+			}
+			return tw.Flush()
 		}),
 	},
 }
@@ -7,10 +7,12 @@ import (
 	"github.com/bittorrent/go-btfs/core/commands/cheque"
 	cmdenv "github.com/bittorrent/go-btfs/core/commands/cmdenv"
 	dag "github.com/bittorrent/go-btfs/core/commands/dag"
+	"github.com/bittorrent/go-btfs/core/commands/ledger"
 	name "github.com/bittorrent/go-btfs/core/commands/name"
 	ocmd "github.com/bittorrent/go-btfs/core/commands/object"
 	settlement "github.com/bittorrent/go-btfs/core/commands/settlements"
 	"github.com/bittorrent/go-btfs/core/commands/storage"
+	"github.com/bittorrent/go-btfs/core/commands/storage/challenge"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/proxy"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/upload"
 	"github.com/bittorrent/go-btfs/core/commands/vault"
@@ -179,11 +181,14 @@ var rootSubcommands = map[string]*cmds.Command{
 	"backup":         BackupCmd,
 	"recovery":       RecoveryCmd,
 	"accesskey":      AccessKeyCmd,
+	"s3":             S3Cmd,
 	"encrypt":        encryptCmd,
 	"decrypt":        decryptCmd,
 	"dashboard":      dashboardCmd,
 	"cidstore":       CidStoreCmd,
 	"stake":          StakeCmd,
+	"ledger":         ledger.LedgerCmd,
+	"tx":             TxCmd,
 }
 
 // RootRO is the readonly version of Root
@@ -238,11 +243,11 @@ var RootRemote = &cmds.Command{}
 var rootRemoteSubcommands = map[string]*cmds.Command{
 	"storage": {
 		Subcommands: map[string]*cmds.Command{
-			// "challenge": {
-			// 	Subcommands: map[string]*cmds.Command{
-			// 		"response": challenge.StorageChallengeResponseCmd,
-			// 	},
-			// },
+			"challenge": {
+				Subcommands: map[string]*cmds.Command{
+					"response": challenge.StorageChallengeResponseCmd,
+				},
+			},
 			"upload": {
 				Subcommands: map[string]*cmds.Command{
 					"init":          upload.StorageUploadInitCmd,
@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"errors"
+	"time"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/s3/api/services/object"
+)
+
+var S3Cmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline:          "Manage S3-Compatible-API buckets stored in this node.",
+		ShortDescription: "Commands for the bucket storage policies and the storage status of objects.",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"storage": s3StorageCmd,
+		"status":  s3StatusCmd,
+	},
+	NoLocal: true,
+}
+
+var s3StorageCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the storage policy of buckets.",
+		ShortDescription: `
+The new objects of a bucket with storage policy are pushed into the paid
+decentralized storage by the storage upload sessions of this node, the
+storage is paid by this node.`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"set":    s3StorageSetCmd,
+		"get":    s3StorageGetCmd,
+		"delete": s3StorageDeleteCmd,
+	},
+}
+
+const (
+	s3ReplicationOptionName   = "replication-factor"
+	s3StorageLengthOptionName = "storage-length"
+	s3MaxPriceOptionName      = "price"
+	s3AutoRenewOptionName     = "autorenew"
+	s3VersionIdOptionName     = "version-id"
+
+	defaultS3Replication   = 3
+	defaultS3StorageLength = 30
+)
+
+var s3StorageSetCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline:          "Set the storage policy of the bucket.",
+		ShortDescription: "Outputs empty if the storage policy has been set.",
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("bucket", true, false, "The bucket name"),
+	},
+	Options: []cmds.Option{
+		cmds.IntOption(s3ReplicationOptionName, "r", "Copies of the object stored on hosts.").WithDefault(defaultS3Replication),
+		cmds.IntOption(s3StorageLengthOptionName, "len", "Object storage period on hosts in days.").WithDefault(defaultS3StorageLength),
+		cmds.StringOption(tokencfg.TokenTypeName, "tk", "Storage with token type, default WBTT, other TRX/USDD/USDT.").WithDefault("WBTT"),
+		cmds.Int64Option(s3MaxPriceOptionName, "p", "Max price per GiB per day of storage in µBTT (=0.000001BTT)."),
+		cmds.BoolOption(s3AutoRenewOptionName, "Enable automatic renewal before expiration.").WithDefault(false),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) (err error) {
+		err = checkDaemon(env)
+		if err != nil {
+			return
+		}
+		storage := &object.StoragePolicy{
+			Replication:   req.Options[s3ReplicationOptionName].(int),
+			StorageLength: req.Options[s3StorageLengthOptionName].(int),
+			Token:         req.Options[tokencfg.TokenTypeName].(string),
+			AutoRenew:     req.Options[s3AutoRenewOptionName].(bool),
+		}
+		storage.MaxPrice, _ = req.Options[s3MaxPriceOptionName].(int64)
+		if storage.Replication < 1 {
+			err = errors.New("replication factor must be at least 1")
+			return
+		}
+		if storage.StorageLength < 1 {
+			err = errors.New("storage length must be at least 1 day")
+			return
+		}
+		if _, ok := tokencfg.MpTokenAddr[storage.Token]; !ok {
+			err = errors.New("your input token is none")
+			return
+		}
+		bucket := req.Arguments[0]
+		err = object.PutBucketStorage(bucket, storage)
+		return
+	},
+}
+
+var s3StorageGetCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline:          "Get the storage policy of the bucket.",
+		ShortDescription: "Outputs the storage policy of the bucket.",
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("bucket", true, false, "The bucket name"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) (err error) {
+		err = checkDaemon(env)
+		if err != nil {
+			return
+		}
+		bucket := req.Arguments[0]
+		storage, err := object.GetBucketStorage(bucket)
+		if err != nil {
+			return
+		}
+		err = cmds.EmitOnce(res, storage)
+		return
+	},
+	Type: object.StoragePolicy{},
+}
+
+var s3StorageDeleteCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline:          "Delete the storage policy of the bucket.",
+		ShortDescription: "Outputs empty if the storage policy has been deleted, the queued objects will not be pushed any more.",
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("bucket", true, false, "The bucket name"),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) (err error) {
+		err = checkDaemon(env)
+		if err != nil {
+			return
+		}
+		bucket := req.Arguments[0]
+		err = object.DeleteBucketStorage(bucket)
+		return
+	},
+}
+
+type S3ObjectStatus struct {
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	VersionID string    `json:"version_id,omitempty"`
+	Cid       string    `json:"cid"`
+	Status    string    `json:"status"`
+	SessionID string    `json:"session_id,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Updated   time.Time `json:"updated,omitempty"`
+}
+
+var s3StatusCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Get the storage status of the object.",
+		ShortDescription: `
+Outputs the cid of the object and the state of pushing it into the
+decentralized storage, the session can be checked by:
+    $ btfs storage upload status <session-id>`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("bucket", true, false, "The bucket name"),
+		cmds.StringArg("key", true, false, "The object key"),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(s3VersionIdOptionName, "v", "The object version id, default the current version."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) (err error) {
+		err = checkDaemon(env)
+		if err != nil {
+			return
+		}
+		bucket, key := req.Arguments[0], req.Arguments[1]
+		verid, _ := req.Options[s3VersionIdOptionName].(string)
+		obj, err := object.GetObjectStorage(bucket, key, verid)
+		if err != nil {
+			return
+		}
+		status := &S3ObjectStatus{
+			Bucket:    obj.Bucket,
+			Key:       obj.Name,
+			VersionID: obj.VersionID,
+			Cid:       obj.CID,
+		}
+		if obj.Storage != nil {
+			status.Status = obj.Storage.Status
+			status.SessionID = obj.Storage.SessionID
+			status.Message = obj.Storage.Message
+			status.Updated = obj.Storage.Updated
+		}
+		err = cmds.EmitOnce(res, status)
+		return
+	},
+	Type: S3ObjectStatus{},
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/bittorrent/go-btfs/chain/abi"
 	chainconfig "github.com/bittorrent/go-btfs/chain/config"
 	oldcmds "github.com/bittorrent/go-btfs/commands"
+	"github.com/bittorrent/go-btfs/ledger"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	ethCrypto "github.com/ethereum/go-ethereum/crypto"
@@ -106,6 +107,14 @@ var StakeCmd = &cmds.Command{
 		if err != nil {
 			return err
 		}
+		ledger.RecordPending(tx.Hash(), &ledger.Entry{
+			Type:   ledger.TypeStake,
+			Debit:  ledger.AccountStake,
+			Credit: ledger.AccountWallet,
+			Token:  ledger.BTT,
+			Amount: lockAmount,
+			TxHash: tx.Hash().Hex(),
+		})
 
 		return res.Emit(map[string]string{
 			"txHash": tx.Hash().Hex(),
@@ -170,6 +179,14 @@ Example: btfs stake unlock <amount>
 		if err != nil {
 			return err
 		}
+		ledger.RecordPending(tx.Hash(), &ledger.Entry{
+			Type:   ledger.TypeUnstake,
+			Debit:  ledger.AccountUnlocking,
+			Credit: ledger.AccountStake,
+			Token:  ledger.BTT,
+			Amount: unlockAmount,
+			TxHash: tx.Hash().Hex(),
+		})
 
 		return res.Emit(map[string]string{
 			"status": "success",
@@ -222,10 +239,24 @@ Example: btfs stake withdraw
 			return err
 		}
 
+		// the unlocked amount is withdrawn, it is not recorded if unknown
+		var unlocked *big.Int
+		if stake, err := sc.GetUserStake(nil, opts.From); err == nil {
+			unlocked = stake.UnlockedAmount
+		}
+
 		tx, err := sc.Withdraw(opts)
 		if err != nil {
 			return err
 		}
+		ledger.RecordPending(tx.Hash(), &ledger.Entry{
+			Type:   ledger.TypeStakeWithdraw,
+			Debit:  ledger.AccountWallet,
+			Credit: ledger.AccountUnlocking,
+			Token:  ledger.BTT,
+			Amount: unlocked,
+			TxHash: tx.Hash().Hex(),
+		})
 
 		return res.Emit(map[string]string{
 			"status": "success",
@@ -0,0 +1,273 @@
+package challenge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/core/corehttp/remote"
+	"github.com/bittorrent/go-btfs/protos/metadata"
+
+	cidlib "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// challengeHistoryPrefix is followed by file hash, contract id and challenge time
+	challengeHistoryPrefix = "/btfs/%s/renter/challenges/"
+	challengeHistoryKey    = challengeHistoryPrefix + "%s/%s/%d"
+
+	challengeTimeout = 1 * time.Minute
+
+	// challengeHistoryMaxCount is the max count of the records kept per contract
+	challengeHistoryMaxCount = 100
+)
+
+var auditLog = logging.Logger("challenge-audit")
+
+// ChallengeRecord is the result of one challenge sent to the host of a shard
+type ChallengeRecord struct {
+	ContractID string    `json:"contract_id"`
+	FileHash   string    `json:"file_hash"`
+	ShardHash  string    `json:"shard_hash"`
+	ShardIndex uint64    `json:"shard_index"`
+	Host       string    `json:"host"`
+	ChunkIndex int       `json:"chunk_index"`
+	Nonce      string    `json:"nonce"`
+	Passed     bool      `json:"passed"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// AuditSessions challenges one random shard of every completed upload session,
+// the shards whose contracts have been expired or closed are not challenged.
+// The result of every challenge is recorded to the challenge history.
+func AuditSessions(ctxParams *uh.ContextParams) error {
+	cursor, err := sessions.GetRenterSessionsCursor(ctxParams)
+	if err != nil {
+		return err
+	}
+	for {
+		if ctxParams.Ctx.Err() != nil {
+			return ctxParams.Ctx.Err()
+		}
+		session, err := cursor.NextSession(sessions.RssCompleteStatus)
+		if err != nil || session == nil {
+			break
+		}
+		record, err := auditSession(ctxParams, session)
+		if err != nil {
+			auditLog.Debugf("skip auditing session %s: %v", session.SsId, err)
+			continue
+		}
+		err = SaveChallengeRecord(ctxParams.Ctx, ctxParams.N.Repo.Datastore(), ctxParams.N.Identity.String(), record)
+		if err != nil {
+			auditLog.Errorf("failed to save challenge record of contract %s: %v", record.ContractID, err)
+		}
+	}
+	return nil
+}
+
+// auditSession samples a shard of the session and challenges its host
+func auditSession(ctxParams *uh.ContextParams, session *sessions.RenterSession) (*ChallengeRecord, error) {
+	if len(session.ShardHashes) == 0 {
+		return nil, errors.New("no shards in session")
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(session.ShardHashes))))
+	if err != nil {
+		return nil, err
+	}
+	index := int(n.Int64())
+	shard, err := sessions.GetUserShard(ctxParams, session.SsId, session.ShardHashes[index], index)
+	if err != nil {
+		return nil, err
+	}
+	contract, err := shard.Contracts()
+	if err != nil {
+		return nil, err
+	}
+	err = checkAuditContract(contract, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctxParams.Ctx, challengeTimeout)
+	defer cancel()
+	return ChallengeContract(ctx, ctxParams, session.Hash, contract.Meta)
+}
+
+// checkAuditContract checks the contract of the shard is still to be challenged
+func checkAuditContract(contract *metadata.Contract, now time.Time) error {
+	if contract.Meta == nil || contract.Meta.ContractId == "" {
+		return errors.New("no contract of shard")
+	}
+	if contract.Status == metadata.Contract_CLOSED {
+		return errors.New("contract closed")
+	}
+	if end := contract.Meta.StorageEnd; end > 0 && uint64(now.Unix()) > end {
+		return errors.New("contract expired")
+	}
+	return nil
+}
+
+// ChallengeContract generates a new challenge of the contract shard and checks
+// the answer of the host, any failure of the host is recorded as not passed.
+// No record is returned if the challenge cannot be generated locally.
+func ChallengeContract(ctx context.Context, ctxParams *uh.ContextParams, fileHash string,
+	meta *metadata.ContractMeta) (*ChallengeRecord, error) {
+	record := &ChallengeRecord{
+		ContractID: meta.ContractId,
+		FileHash:   fileHash,
+		ShardHash:  meta.ShardHash,
+		ShardIndex: meta.ShardIndex,
+		Host:       meta.SpId,
+		Time:       time.Now(),
+	}
+	sc, err := newContractChallenge(ctx, ctxParams, record)
+	if err != nil {
+		return nil, err
+	}
+	err = askHost(ctx, ctxParams, record, sc)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record, nil
+}
+
+// newContractChallenge generates the challenge from the local copy of the file
+func newContractChallenge(ctx context.Context, ctxParams *uh.ContextParams, record *ChallengeRecord) (*StorageChallenge, error) {
+	rootHash, err := cidlib.Parse(record.FileHash)
+	if err != nil {
+		return nil, err
+	}
+	shardHash, err := cidlib.Parse(record.ShardHash)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := NewStorageChallenge(ctx, ctxParams.N, ctxParams.Api, rootHash, shardHash)
+	if err != nil {
+		return nil, err
+	}
+	err = sc.GenChallenge()
+	if err != nil {
+		return nil, err
+	}
+	record.ChunkIndex = sc.CIndex
+	record.Nonce = sc.Nonce
+	return sc, nil
+}
+
+// askHost asks the host to solve the challenge and checks the answer
+func askHost(ctx context.Context, ctxParams *uh.ContextParams, record *ChallengeRecord, sc *StorageChallenge) error {
+	hostPid, err := peer.Decode(record.Host)
+	if err != nil {
+		return err
+	}
+	resp, err := remote.P2PCallStrings(ctx, ctxParams.N, ctxParams.Api, hostPid, "/storage/challenge/response",
+		record.ContractID, record.FileHash, record.ShardHash, strconv.Itoa(sc.CIndex), sc.Nonce)
+	if err != nil {
+		return err
+	}
+	var scr StorageChallengeRes
+	err = json.Unmarshal(resp, &scr)
+	if err != nil {
+		return err
+	}
+	if scr.Answer != sc.Hash {
+		return errors.New("challenge answer mismatch")
+	}
+	record.Passed = true
+	return nil
+}
+
+// SaveChallengeRecord appends the record to the challenge history of its
+// contract, the oldest records beyond the max count are deleted
+func SaveChallengeRecord(ctx context.Context, d datastore.Datastore, peerId string, record *ChallengeRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(challengeHistoryKey, peerId, record.FileHash, record.ContractID, record.Time.UnixNano())
+	if err := d.Put(ctx, datastore.NewKey(key), data); err != nil {
+		return err
+	}
+	return pruneChallengeRecords(ctx, d, peerId, record.FileHash, record.ContractID)
+}
+
+// pruneChallengeRecords deletes the oldest records of the contract beyond the
+// max count
+func pruneChallengeRecords(ctx context.Context, d datastore.Datastore, peerId, fileHash, contractId string) error {
+	prefix := fmt.Sprintf(challengeHistoryPrefix, peerId) + fileHash + "/" + contractId + "/"
+	results, err := d.Query(ctx, query.Query{
+		Prefix:   prefix,
+		KeysOnly: true,
+	})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+	if len(entries) <= challengeHistoryMaxCount {
+		return nil
+	}
+	times := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		t, err := strconv.ParseInt(strings.TrimPrefix(e.Key, prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	for i := 0; i < len(times)-challengeHistoryMaxCount; i++ {
+		key := fmt.Sprintf(challengeHistoryKey, peerId, fileHash, contractId, times[i])
+		if err := d.Delete(ctx, datastore.NewKey(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListChallengeRecords lists the challenge history of all contracts of the file
+func ListChallengeRecords(ctx context.Context, d datastore.Datastore, peerId string, fileHash string) ([]*ChallengeRecord, error) {
+	prefix := fmt.Sprintf(challengeHistoryPrefix, peerId) + fileHash + "/"
+	results, err := d.Query(ctx, query.Query{
+		Prefix: prefix,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	records := make([]*ChallengeRecord, 0)
+	for entry := range results.Next() {
+		if entry.Error != nil {
+			return nil, entry.Error
+		}
+		record := new(ChallengeRecord)
+		if err := json.Unmarshal(entry.Value, record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].ContractID != records[j].ContractID {
+			return records[i].ContractID < records[j].ContractID
+		}
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
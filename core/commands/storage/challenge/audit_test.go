@@ -0,0 +1,161 @@
+package challenge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bittorrent/go-btfs/protos/metadata"
+
+	cidlib "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/multiformats/go-multihash"
+)
+
+func testCid(t *testing.T, data string) cidlib.Cid {
+	h, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cidlib.NewCidV0(h)
+}
+
+func TestCheckAuditContract(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		contract *metadata.Contract
+		ok       bool
+	}{
+		{"no meta", &metadata.Contract{}, false},
+		{"no contract id", &metadata.Contract{Meta: &metadata.ContractMeta{}}, false},
+		{"closed", &metadata.Contract{
+			Meta:   &metadata.ContractMeta{ContractId: "c1"},
+			Status: metadata.Contract_CLOSED,
+		}, false},
+		{"expired", &metadata.Contract{
+			Meta: &metadata.ContractMeta{ContractId: "c1", StorageEnd: uint64(now.Add(-time.Hour).Unix())},
+		}, false},
+		{"active", &metadata.Contract{
+			Meta: &metadata.ContractMeta{ContractId: "c1", StorageEnd: uint64(now.Add(time.Hour).Unix())},
+		}, true},
+		{"no end", &metadata.Contract{
+			Meta: &metadata.ContractMeta{ContractId: "c1"},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAuditContract(tt.contract, now)
+			if (err == nil) != tt.ok {
+				t.Errorf("checkAuditContract() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheckHostContract(t *testing.T) {
+	shard := testCid(t, "shard")
+	other := testCid(t, "other")
+	contract := func(spId string, shardHash cidlib.Cid, status metadata.Contract_ContractStatus) *metadata.Contract {
+		return &metadata.Contract{
+			Meta: &metadata.ContractMeta{
+				ContractId: "c1",
+				SpId:       spId,
+				ShardHash:  shardHash.String(),
+			},
+			Status: status,
+		}
+	}
+	tests := []struct {
+		name     string
+		contract *metadata.Contract
+		ok       bool
+	}{
+		{"valid", contract("host", shard, metadata.Contract_COMPLETED), true},
+		{"no meta", &metadata.Contract{}, false},
+		{"other host", contract("other", shard, metadata.Contract_COMPLETED), false},
+		{"other shard", contract("host", other, metadata.Contract_COMPLETED), false},
+		{"closed", contract("host", shard, metadata.Contract_CLOSED), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHostContract(tt.contract, "host", shard)
+			if (err == nil) != tt.ok {
+				t.Errorf("checkHostContract() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestChallengeHistory(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	start := time.Now()
+	records := []*ChallengeRecord{
+		{ContractID: "c2", FileHash: "file", Time: start.Add(time.Second), Passed: true},
+		{ContractID: "c1", FileHash: "file", Time: start.Add(2 * time.Second), Error: "challenge answer mismatch"},
+		{ContractID: "c1", FileHash: "file", Time: start, Passed: true},
+		{ContractID: "c3", FileHash: "other", Time: start, Passed: true},
+	}
+	for _, r := range records {
+		if err := SaveChallengeRecord(ctx, ds, "peer", r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// malformed records are skipped
+	err := ds.Put(ctx, datastore.NewKey("/btfs/peer/renter/challenges/file/c4/1"), []byte("{"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ListChallengeRecords(ctx, ds, "peer", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*ChallengeRecord{records[2], records[1], records[0]}
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ContractID != want[i].ContractID || !got[i].Time.Equal(want[i].Time) ||
+			got[i].Passed != want[i].Passed || got[i].Error != want[i].Error {
+			t.Errorf("record %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	got, err = ListChallengeRecords(ctx, ds, "other-peer", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %d records of other peer, want 0", len(got))
+	}
+}
+
+func TestChallengeHistoryPruned(t *testing.T) {
+	ctx := context.Background()
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	start := time.Now()
+	for i := 0; i < challengeHistoryMaxCount+5; i++ {
+		r := &ChallengeRecord{ContractID: "c1", FileHash: "file", Time: start.Add(time.Duration(i) * time.Second)}
+		if err := SaveChallengeRecord(ctx, ds, "peer", r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SaveChallengeRecord(ctx, ds, "peer", &ChallengeRecord{ContractID: "c2", FileHash: "file", Time: start}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ListChallengeRecords(ctx, ds, "peer", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != challengeHistoryMaxCount+1 {
+		t.Fatalf("got %d records, want %d", len(got), challengeHistoryMaxCount+1)
+	}
+	// the oldest records of the contract are deleted
+	if !got[0].Time.Equal(start.Add(5 * time.Second)) {
+		t.Errorf("got oldest record at %v, want %v", got[0].Time, start.Add(5*time.Second))
+	}
+}
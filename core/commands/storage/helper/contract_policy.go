@@ -0,0 +1,161 @@
+package helper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/core"
+
+	"github.com/dustin/go-humanize"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	ContractPolicyPrefix     = "/btfs/contract_policy/"
+	ContractRejectionsPrefix = "/btfs/contract_rejections/"
+)
+
+// ContractPolicy is the declarative policy of a host accepting the contracts
+// from renters, a zero value field means no limit.
+type ContractPolicy struct {
+	// Min price per GiB per day in µBTT by token, e.g. {"WBTT": 1000}
+	MinPrice map[string]uint64 `json:"min_price,omitempty"`
+	// Max size of a shard, e.g. "1GB"
+	MaxShardSize string `json:"max_shard_size,omitempty"`
+	// Max total size of the shards stored for a renter, e.g. "100GB"
+	MaxRenterBytes string `json:"max_renter_bytes,omitempty"`
+	// Only accept the contracts of these renters if not empty
+	RenterAllowList []string `json:"renter_allow_list,omitempty"`
+	// Never accept the contracts of these renters
+	RenterDenyList []string `json:"renter_deny_list,omitempty"`
+	// Min number of days for storage
+	MinStorageLength uint64 `json:"min_storage_length,omitempty"`
+	// Free space of the storage path kept after storing the shard, e.g. "20GB"
+	FreeSpaceReserve string `json:"free_space_reserve,omitempty"`
+}
+
+// Validate checks the tokens, the sizes and the peer ids of the policy
+func (p *ContractPolicy) Validate() error {
+	for token := range p.MinPrice {
+		if _, ok := tokencfg.MpTokenAddr[token]; !ok {
+			return fmt.Errorf("invalid token in min_price: %s", token)
+		}
+	}
+	for name, size := range map[string]string{
+		"max_shard_size":     p.MaxShardSize,
+		"max_renter_bytes":   p.MaxRenterBytes,
+		"free_space_reserve": p.FreeSpaceReserve,
+	} {
+		if _, err := parsePolicySize(size); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+	for _, id := range append(append([]string{}, p.RenterAllowList...), p.RenterDenyList...) {
+		if _, err := peer.Decode(id); err != nil {
+			return fmt.Errorf("invalid renter id %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// Sizes returns the max shard size, the max renter bytes and the free space
+// reserve of the policy in bytes
+func (p *ContractPolicy) Sizes() (maxShardSize, maxRenterBytes, freeSpaceReserve uint64, err error) {
+	if maxShardSize, err = parsePolicySize(p.MaxShardSize); err != nil {
+		return
+	}
+	if maxRenterBytes, err = parsePolicySize(p.MaxRenterBytes); err != nil {
+		return
+	}
+	freeSpaceReserve, err = parsePolicySize(p.FreeSpaceReserve)
+	return
+}
+
+func parsePolicySize(s string) (uint64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	return humanize.ParseBytes(s)
+}
+
+// PutContractPolicy saves the contract acceptance policy of this host.
+func PutContractPolicy(ctx context.Context, node *core.IpfsNode, p *ContractPolicy) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("cannot put contract policy: %s", err.Error())
+	}
+	return node.Repo.Datastore().Put(ctx, NewKeyHelper(ContractPolicyPrefix, node.Identity.String()), b)
+}
+
+// GetContractPolicy returns the contract acceptance policy of this host, an
+// empty policy is returned if it has never been set.
+func GetContractPolicy(ctx context.Context, node *core.IpfsNode) (*ContractPolicy, error) {
+	b, err := node.Repo.Datastore().Get(ctx, NewKeyHelper(ContractPolicyPrefix, node.Identity.String()))
+	if errors.Is(err, ds.ErrNotFound) {
+		return &ContractPolicy{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := new(ContractPolicy)
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+var contractRejectionsLock sync.Mutex
+
+// AddContractRejection counts a contract rejected by this host for the reason.
+func AddContractRejection(ctx context.Context, node *core.IpfsNode, reason string) error {
+	contractRejectionsLock.Lock()
+	defer contractRejectionsLock.Unlock()
+	rds := node.Repo.Datastore()
+	key := NewKeyHelper(ContractRejectionsPrefix, node.Identity.String(), "/", reason)
+	var count uint64
+	b, err := rds.Get(ctx, key)
+	if err == nil {
+		err = json.Unmarshal(b, &count)
+	}
+	if err != nil && !errors.Is(err, ds.ErrNotFound) {
+		return err
+	}
+	b, err = json.Marshal(count + 1)
+	if err != nil {
+		return err
+	}
+	return rds.Put(ctx, key, b)
+}
+
+// GetContractRejections returns the number of the contracts rejected by this
+// host by reason.
+func GetContractRejections(ctx context.Context, node *core.IpfsNode) (map[string]uint64, error) {
+	prefix := ContractRejectionsPrefix + node.Identity.String() + "/"
+	results, err := node.Repo.Datastore().Query(ctx, query.Query{
+		Prefix: prefix,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	rejections := make(map[string]uint64)
+	for entry := range results.Next() {
+		if entry.Error != nil {
+			return nil, entry.Error
+		}
+		var count uint64
+		if err := json.Unmarshal(entry.Value, &count); err != nil {
+			continue
+		}
+		rejections[ds.NewKey(entry.Key).BaseNamespace()] = count
+	}
+	return rejections, nil
+}
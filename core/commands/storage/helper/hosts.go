@@ -8,6 +8,7 @@ import (
 	"github.com/bittorrent/go-btfs/core"
 	"github.com/bittorrent/go-btfs/core/hub"
 	"github.com/bittorrent/go-btfs/repo"
+	"github.com/bittorrent/go-btfs/repo/datadirs"
 
 	hubpb "github.com/bittorrent/go-btfs-common/protos/hub"
 	nodepb "github.com/bittorrent/go-btfs-common/protos/node"
@@ -203,6 +204,15 @@ func CheckAndValidateHostStorageMax(ctx context.Context, cfgRoot string, r repo.
 		return 0, err
 	}
 	totalAvailable := su + du.Free
+	// the data directories added to the repo contribute their space within
+	// their capacities
+	if dirs, err := datadirs.Current(); err == nil {
+		free, err := dirs.ExtraFree(ctx)
+		if err != nil {
+			return 0, err
+		}
+		totalAvailable += free
+	}
 
 	// Setting a new max storage, check if it exceeds available space
 	if newMax != nil {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/bittorrent/go-btfs/core"
+	"github.com/bittorrent/go-btfs/ledger"
 	ds "github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/query"
 )
@@ -74,7 +75,22 @@ func PutProxyStoragePayment(ctx context.Context, node *core.IpfsNode, ns *ProxyS
 		return fmt.Errorf("cannot put current proxy storage settings: %s", err.Error())
 	}
 	// /proxy_payment/address/txHash
-	return rds.Put(ctx, GetProxyStoragePaymentKey(node.Identity.String()+"/"+strings.ToLower(ns.From)+"/"+ns.Hash), b)
+	err = rds.Put(ctx, GetProxyStoragePaymentKey(node.Identity.String()+"/"+strings.ToLower(ns.From)+"/"+ns.Hash), b)
+	if err != nil {
+		return err
+	}
+	// the payments are kept for the users until their files are uploaded
+	ledger.Record(&ledger.Entry{
+		Time:         ns.PayTime,
+		Type:         ledger.TypeProxyPayment,
+		Debit:        ledger.AccountWallet,
+		Credit:       ledger.AccountProxyUsers,
+		Token:        ledger.BTT,
+		Amount:       ns.Value,
+		Counterparty: ns.From,
+		TxHash:       ns.Hash,
+	})
+	return nil
 }
 
 func GetProxyStoragePayment(ctx context.Context, node *core.IpfsNode) ([]*ProxyStoragePaymentInfo, error) {
@@ -280,7 +296,20 @@ func PutProxyUploadedFileInfo(ctx context.Context, node *core.IpfsNode, uploaded
 	if err != nil {
 		return fmt.Errorf("cannot put current proxy storage settings: %s", err.Error())
 	}
-	return rds.Put(ctx, GetProxyUploadedFileInfoKey(node.Identity.String()+"/"+uploadedCidInfo.CID), b)
+	err = rds.Put(ctx, GetProxyUploadedFileInfoKey(node.Identity.String()+"/"+uploadedCidInfo.CID), b)
+	if err != nil {
+		return err
+	}
+	ledger.Record(&ledger.Entry{
+		Type:         ledger.TypeProxyUpload,
+		Debit:        ledger.AccountProxyUsers,
+		Credit:       ledger.AccountExternal,
+		Token:        ledger.BTT,
+		Amount:       uploadedCidInfo.TotalPay,
+		Counterparty: uploadedCidInfo.From,
+		Reference:    uploadedCidInfo.CID,
+	})
+	return nil
 }
 
 func ListProxyUploadedFileInfo(ctx context.Context, node *core.IpfsNode) ([]*ProxyUploadFileInfo, error) {
@@ -0,0 +1,237 @@
+package path
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
+	"github.com/bittorrent/go-btfs/repo/datadirs"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+
+	"github.com/dustin/go-humanize"
+	"github.com/mitchellh/go-homedir"
+)
+
+const placementPolicyOptionName = "policy"
+
+var PathAddCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Add a data directory storing the blocks.",
+		ShortDescription: `
+The blocks are stored across the repo and the data directories added, the new
+blocks are placed by the placement policy. The blocks stored in the directory
+are limited to the capacity if it is given, the capacity counts towards the
+max storage size of the host.
+
+Examples:
+    $ btfs storage path add /mnt/disk2/btfs 2TB
+    $ btfs storage path add /mnt/disk3/btfs --policy=most-free`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path-name", true, false, "Data directory, should be absolute path."),
+		cmds.StringArg("capacity", false, false, "Max size of the blocks stored in the directory, e.g. 500GB."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(placementPolicyOptionName, "Placement policy of the new blocks: fill-first, round-robin or most-free."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		d, err := dataDirsOf(env)
+		if err != nil {
+			return err
+		}
+		path, err := expandPath(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		var capacity uint64
+		if len(req.Arguments) > 1 {
+			capacity, err = humanize.ParseBytes(req.Arguments[1])
+			if err != nil {
+				return err
+			}
+		}
+		err = d.Add(req.Context, path, capacity)
+		if err != nil {
+			return err
+		}
+		if policy, ok := req.Options[placementPolicyOptionName].(string); ok {
+			err = d.SetPolicy(policy)
+			if err != nil {
+				return err
+			}
+		}
+		return emitDataDirs(req, res, d)
+	},
+	Type: DataDirsRes{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(encodeDataDirs),
+	},
+}
+
+var PathRemoveCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove a data directory.",
+		ShortDescription: `
+The blocks of the directory are moved to the other directories in the
+background, no new blocks are placed on it meanwhile. The directory is removed
+from the repo once it is empty, the files left in it are not deleted. Use
+'btfs storage path dirs' to check the progress.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path-name", true, false, "Data directory, should be absolute path."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		d, err := dataDirsOf(env)
+		if err != nil {
+			return err
+		}
+		path, err := expandPath(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		err = d.Remove(path)
+		if err != nil {
+			return err
+		}
+		return emitDataDirs(req, res, d)
+	},
+	Type: DataDirsRes{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(encodeDataDirs),
+	},
+}
+
+var PathRebalanceCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Rebalance the blocks across the data directories.",
+		ShortDescription: `
+The blocks are moved from the directories used most to the ones used least in
+the background, until every directory is used about the same ratio of its
+capacity. Use 'btfs storage path dirs' to check the progress.`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		d, err := dataDirsOf(env)
+		if err != nil {
+			return err
+		}
+		err = d.Rebalance()
+		if err != nil {
+			return err
+		}
+		return emitDataDirs(req, res, d)
+	},
+	Type: DataDirsRes{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(encodeDataDirs),
+	},
+}
+
+var PathDirsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the data directories storing the blocks.",
+		ShortDescription: `
+This command lists the data directories with their usage, the placement policy
+and the progress of the last migration. The placement policy is set if it is
+given.
+
+    $ btfs storage path dirs --policy=round-robin`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(placementPolicyOptionName, "Placement policy of the new blocks: fill-first, round-robin or most-free."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		d, err := dataDirsOf(env)
+		if err != nil {
+			return err
+		}
+		if policy, ok := req.Options[placementPolicyOptionName].(string); ok {
+			err = d.SetPolicy(policy)
+			if err != nil {
+				return err
+			}
+		}
+		return emitDataDirs(req, res, d)
+	},
+	Type: DataDirsRes{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(encodeDataDirs),
+	},
+}
+
+type DataDirsRes struct {
+	Policy    string
+	Dirs      []*datadirs.DirStatus
+	Migration *datadirs.Migration `json:",omitempty"`
+}
+
+func dataDirsOf(env cmds.Environment) (*datadirs.Datastore, error) {
+	// the data directories are opened along with the repo
+	if _, err := cmdenv.GetNode(env); err != nil {
+		return nil, err
+	}
+	return datadirs.Current()
+}
+
+func expandPath(path string) (string, error) {
+	path = strings.Trim(path, " ")
+	if path == "" {
+		return "", fmt.Errorf("path is not defined")
+	}
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
+
+func emitDataDirs(req *cmds.Request, res cmds.ResponseEmitter, d *datadirs.Datastore) error {
+	policy, dirs, m, err := d.Status(req.Context)
+	if err != nil {
+		return err
+	}
+	return cmds.EmitOnce(res, &DataDirsRes{
+		Policy:    policy,
+		Dirs:      dirs,
+		Migration: m,
+	})
+}
+
+func encodeDataDirs(req *cmds.Request, w io.Writer, out *DataDirsRes) error {
+	fmt.Fprintf(w, "Placement policy: %s\n\n", out.Policy)
+	tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tUSED\tCAPACITY\tFREE\tSTATUS")
+	for _, dir := range out.Dirs {
+		capacity := "-"
+		if dir.Capacity > 0 {
+			capacity = humanize.Bytes(dir.Capacity)
+		}
+		status := "active"
+		if dir.Primary {
+			status = "repo"
+		}
+		if dir.Draining {
+			status = "removing"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", dir.Path, humanize.Bytes(dir.Used), capacity,
+			humanize.Bytes(dir.Free), status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if m := out.Migration; m != nil {
+		state := "running"
+		if m.EndedAt != nil {
+			state = "done"
+		}
+		if m.Error != "" {
+			state = "failed: " + m.Error
+		}
+		fmt.Fprintf(w, "\nLast migration: %s %s, %d blocks (%s) moved, %s\n", m.Type, m.Path, m.Moved,
+			humanize.Bytes(m.Bytes), state)
+	}
+	return nil
+}
@@ -100,15 +100,22 @@ var PathCmd = &cmds.Command{
 The default local repository path is located at ~/.btfs folder, in order to
 improve the hard disk space usage, provide the function to change the original 
 storage location, a specified path as a parameter need to be passed.
+
+To store the blocks across several disks without moving the repo, add data
+directories with 'btfs storage path add'.
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"status":   PathStatusCmd,
-		"capacity": PathCapacityCmd,
-		"migrate":  PathMigrateCmd,
-		"list":     PathListCmd,
-		"mkdir":    PathMkdirCmd,
-		"volumes":  PathVolumesCmd,
+		"status":    PathStatusCmd,
+		"capacity":  PathCapacityCmd,
+		"migrate":   PathMigrateCmd,
+		"list":      PathListCmd,
+		"mkdir":     PathMkdirCmd,
+		"volumes":   PathVolumesCmd,
+		"add":       PathAddCmd,
+		"remove":    PathRemoveCmd,
+		"rebalance": PathRebalanceCmd,
+		"dirs":      PathDirsCmd,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("path-name", true, false,
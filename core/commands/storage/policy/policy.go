@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
+	"github.com/bittorrent/go-btfs/core/commands/storage/helper"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+)
+
+var StoragePolicyCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the contract acceptance policy of the host.",
+		ShortDescription: `
+The host checks every contract offered by renters against the policy, and
+rejects the contract with the reason if any rule is not met. The rejections are
+counted in 'btfs storage stats rejections'.
+
+The policy is a JSON document, all rules are optional:
+
+    {
+      "min_price": {"WBTT": 1000},          min price per GiB per day in µBTT by token
+      "max_shard_size": "1GB",              max size of a shard
+      "max_renter_bytes": "100GB",          max total size of the shards of a renter
+      "renter_allow_list": ["<peer-id>"],   only accept these renters
+      "renter_deny_list": ["<peer-id>"],    never accept these renters
+      "min_storage_length": 30,             min number of days for storage
+      "free_space_reserve": "20GB"          free space of the storage path kept
+    }
+
+    $ btfs storage policy set '{"max_shard_size": "1GB", "min_storage_length": 60}'
+    $ btfs storage policy show`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"show": storagePolicyShowCmd,
+		"set":  storagePolicySetCmd,
+	},
+}
+
+var storagePolicyShowCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the contract acceptance policy of the host.",
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		p, err := helper.GetContractPolicy(req.Context, n)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, p)
+	},
+	Type: helper.ContractPolicy{},
+}
+
+var storagePolicySetCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Set the contract acceptance policy of the host.",
+		ShortDescription: `
+This command replaces the whole policy with the given JSON document, use '{}'
+to accept contracts without any rule.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("policy", true, false, "Policy in JSON.").EnableStdin(),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		cfg, err := cmdenv.GetConfig(env)
+		if err != nil {
+			return err
+		}
+		if !cfg.Experimental.StorageHostEnabled {
+			return fmt.Errorf("storage host api not enabled")
+		}
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		p := new(helper.ContractPolicy)
+		if err := json.Unmarshal([]byte(req.Arguments[0]), p); err != nil {
+			return fmt.Errorf("invalid policy: %v", err)
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if err := helper.PutContractPolicy(req.Context, n, p); err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, p)
+	},
+	Type: helper.ContractPolicy{},
+}
@@ -33,7 +33,7 @@ const (
 
 // Storage Stats
 //
-// Includes sub-commands: info, sync
+// Includes sub-commands: info, sync, list, rejections
 var StorageStatsCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Get node storage stats.",
@@ -41,9 +41,10 @@ var StorageStatsCmd = &cmds.Command{
 This command get node storage stats in the network.`,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"sync": storageStatsSyncCmd,
-		"info": storageStatsInfoCmd,
-		"list": storageStatsListCmd,
+		"sync":       storageStatsSyncCmd,
+		"info":       storageStatsInfoCmd,
+		"list":       storageStatsListCmd,
+		"rejections": storageStatsRejectionsCmd,
 	},
 }
 
@@ -205,6 +206,43 @@ This command get node stats in the network from the local node data store.`,
 	Type: nodepb.StorageStat{},
 }
 
+// sub-commands: btfs storage stats rejections
+var storageStatsRejectionsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Get the numbers of the contracts rejected by the host.",
+		ShortDescription: `
+This command counts the contracts rejected by the host by reason, such as the
+rules of the contract acceptance policy set by 'btfs storage policy'.`,
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+
+		n, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		rejections, err := helper.GetContractRejections(req.Context, n)
+		if err != nil {
+			return err
+		}
+		out := &RejectionsRes{Rejections: rejections}
+		for _, c := range rejections {
+			out.Total += c
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Type: RejectionsRes{},
+}
+
+type RejectionsRes struct {
+	Total      uint64
+	Rejections map[string]uint64
+}
+
 // sub-commands: btfs storage stats list
 var storageStatsListCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
@@ -2,10 +2,12 @@ package storage
 
 import (
 	"github.com/bittorrent/go-btfs/core/commands/storage/announce"
+	"github.com/bittorrent/go-btfs/core/commands/storage/challenge"
 	"github.com/bittorrent/go-btfs/core/commands/storage/contracts"
 	"github.com/bittorrent/go-btfs/core/commands/storage/hosts"
 	"github.com/bittorrent/go-btfs/core/commands/storage/info"
 	"github.com/bittorrent/go-btfs/core/commands/storage/path"
+	"github.com/bittorrent/go-btfs/core/commands/storage/policy"
 	"github.com/bittorrent/go-btfs/core/commands/storage/stats"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/upload"
 
@@ -20,14 +22,15 @@ Storage services include client upload operations, host storage operations,
 host information sync/display operations, and BTT payment-related routines.`,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"upload":   upload.StorageUploadCmd,
-		"hosts":    hosts.StorageHostsCmd,
-		"info":     info.StorageInfoCmd,
-		"announce": announce.StorageAnnounceCmd,
-		// "challenge": challenge.StorageChallengeCmd,
+		"upload":    upload.StorageUploadCmd,
+		"hosts":     hosts.StorageHostsCmd,
+		"info":      info.StorageInfoCmd,
+		"announce":  announce.StorageAnnounceCmd,
+		"challenge": challenge.StorageChallengeCmd,
 		"stats":     stats.StorageStatsCmd,
 		"contracts": contracts.StorageContractsCmd,
 		"path":      path.PathCmd,
+		"policy":    policy.StoragePolicyCmd,
 		"dcrepair":  upload.StorageDcRepairRouterCmd,
 	},
 }
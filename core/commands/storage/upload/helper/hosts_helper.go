@@ -28,6 +28,9 @@ const (
 
 type IHostsProvider interface {
 	NextValidHost() (string, error)
+	// ReleaseHost releases the host returned by NextValidHost if it does not
+	// store the shard
+	ReleaseHost(host string)
 }
 
 type CustomizedHostsProvider struct {
@@ -66,6 +69,8 @@ func (p *CustomizedHostsProvider) NextValidHost() (string, error) {
 	return "", errors.New(failMsg)
 }
 
+func (p *CustomizedHostsProvider) ReleaseHost(host string) {}
+
 func GetCustomizedSPProvider(cp *ContextParams, hosts []string) IHostsProvider {
 	return &CustomizedHostsProvider{
 		cp:      cp,
@@ -97,9 +102,18 @@ type HostsProvider struct {
 	cancel          context.CancelFunc
 	times           int
 	needHigherPrice bool
+	selection       *HostSelection
+	selected        map[string]*hubpb.Host
 }
 
 func GetSPsProvider(cp *ContextParams, blacklist []string) IHostsProvider {
+	return GetSPsProviderWithSelection(cp, blacklist, nil)
+}
+
+// GetSPsProviderWithSelection returns the provider of the hosts passing the
+// constraints of the selection, the hosts are tried in the order of the
+// selection strategy, or from a random one if no strategy
+func GetSPsProviderWithSelection(cp *ContextParams, blacklist []string, selection *HostSelection) IHostsProvider {
 	ctx, cancel := context.WithTimeout(cp.Ctx, 10*time.Minute)
 	p := &HostsProvider{
 		cp:              cp,
@@ -109,11 +123,17 @@ func GetSPsProvider(cp *ContextParams, blacklist []string) IHostsProvider {
 		ctx:             ctx,
 		cancel:          cancel,
 		needHigherPrice: false,
+		selection:       selection,
+		selected:        make(map[string]*hubpb.Host),
 	}
 	p.init()
 	if len(p.hosts) == 0 {
 		return p
 	}
+	if selection != nil && selection.Strategy != nil {
+		p.hosts = selection.Strategy.Order(cp, p.hosts)
+		return p
+	}
 	p.current = rand.Intn(len(p.hosts))
 	return p
 }
@@ -231,6 +251,9 @@ func (p *HostsProvider) PickFromBackupHosts() (string, error) {
 		if !b {
 			continue
 		}
+		if !p.accept(p.backupHost(host, ns)) {
+			continue
+		}
 		return host, nil
 	}
 	return "", errors.New("shouldn't reach here")
@@ -261,6 +284,12 @@ LOOP:
 					continue LOOP
 				}
 			}
+			if p.selection != nil && !p.selection.allow(p.cp, host) {
+				p.Lock()
+				p.times++
+				p.Unlock()
+				continue
+			}
 			id, err := peer.Decode(host.NodeId)
 			// if err != nil || int64(host.StoragePriceAsk) > price {
 			//	p.needHigherPrice = true
@@ -282,6 +311,12 @@ LOOP:
 				p.Unlock()
 				continue
 			}
+			if !p.accept(host) {
+				p.Lock()
+				p.times++
+				p.Unlock()
+				continue
+			}
 			return host.NodeId, nil
 		} else if !endOfBackup {
 			if h, err := p.PickFromBackupHosts(); err == nil {
@@ -298,6 +333,46 @@ LOOP:
 	return "", errors.New(p.getMsg())
 }
 
+// backupHost returns the hub record of the backup host, or the record made of
+// its storage settings, the region of such a host is unknown
+func (p *HostsProvider) backupHost(host string, ns *nodepb.Node_Settings) *hubpb.Host {
+	p.Lock()
+	defer p.Unlock()
+	for _, h := range p.hosts {
+		if h.NodeId == host {
+			return h
+		}
+	}
+	return &hubpb.Host{
+		NodeId:          host,
+		StoragePriceAsk: ns.StoragePriceAsk,
+	}
+}
+
+// accept selects the host, the selection is kept until the host is released
+func (p *HostsProvider) accept(host *hubpb.Host) bool {
+	if p.selection == nil {
+		return true
+	}
+	if !p.selection.accept(p.cp, host) {
+		return false
+	}
+	p.Lock()
+	p.selected[host.NodeId] = host
+	p.Unlock()
+	return true
+}
+
+func (p *HostsProvider) ReleaseHost(host string) {
+	p.Lock()
+	h, ok := p.selected[host]
+	delete(p.selected, host)
+	p.Unlock()
+	if ok {
+		p.selection.release(p.cp, h)
+	}
+}
+
 func (p *HostsProvider) getMsg() string {
 	msg := failMsg
 	if p.needHigherPrice {
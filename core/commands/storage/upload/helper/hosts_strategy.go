@@ -0,0 +1,430 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+
+	hubpb "github.com/bittorrent/go-btfs-common/protos/hub"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/libp2p/go-libp2p/core/peer"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// Built-in host select strategies
+const (
+	HostSelectStrategyScore    = "score"
+	HostSelectStrategyCheapest = "cheapest"
+	HostSelectStrategyLatency  = "lowest-latency"
+	HostSelectStrategyDiverse  = "diverse"
+)
+
+// Host constraint names, used as <name>=<value> in the constraint list
+const (
+	HostConstraintMaxPrice       = "max-price"
+	HostConstraintMaxLatency     = "max-latency"
+	HostConstraintRegion         = "region"
+	HostConstraintMinSuccessRate = "min-success-rate"
+	HostConstraintOnePerSubnet   = "one-per-subnet"
+	HostConstraintOnePerOperator = "one-per-operator"
+)
+
+var (
+	ErrUnknownHostSelectStrategy = errors.New("unknown host select strategy")
+	ErrInvalidHostConstraint     = errors.New("invalid host constraint")
+)
+
+// HostSelectStrategy orders the candidate hosts, the hosts in front are tried
+// first when uploading shards
+type HostSelectStrategy interface {
+	Order(cp *ContextParams, hosts []*hubpb.Host) []*hubpb.Host
+}
+
+// HostConstraint filters the candidate hosts, a constraint may track the hosts
+// already selected to limit the shards stored by the similar hosts
+type HostConstraint interface {
+	// Allow reports whether the host can be selected
+	Allow(cp *ContextParams, host *hubpb.Host) bool
+	// Select records the host as selected
+	Select(cp *ContextParams, host *hubpb.Host)
+	// Release forgets the selected host, as the shard was not stored by it
+	Release(cp *ContextParams, host *hubpb.Host)
+}
+
+// HostSelection is the strategy and constraints of selecting the hosts of an
+// upload, nil strategy keeps the hosts in random order
+type HostSelection struct {
+	Strategy    HostSelectStrategy
+	Constraints []HostConstraint
+
+	lock sync.Mutex
+}
+
+// allow reports whether the host passes all constraints
+func (s *HostSelection) allow(cp *ContextParams, host *hubpb.Host) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.allowLocked(cp, host)
+}
+
+// accept checks and selects the host in one step, the selection is kept while
+// the shard is uploaded, so the concurrent shards do not select similar hosts,
+// and must be released if the host does not store the shard
+func (s *HostSelection) accept(cp *ContextParams, host *hubpb.Host) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.allowLocked(cp, host) {
+		return false
+	}
+	for _, c := range s.Constraints {
+		c.Select(cp, host)
+	}
+	return true
+}
+
+// release releases the host accepted before
+func (s *HostSelection) release(cp *ContextParams, host *hubpb.Host) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, c := range s.Constraints {
+		c.Release(cp, host)
+	}
+}
+
+func (s *HostSelection) allowLocked(cp *ContextParams, host *hubpb.Host) bool {
+	for _, c := range s.Constraints {
+		if !c.Allow(cp, host) {
+			return false
+		}
+	}
+	return true
+}
+
+var hostSelectStrategies = map[string]HostSelectStrategy{
+	HostSelectStrategyScore:    scoreStrategy{},
+	HostSelectStrategyCheapest: cheapestStrategy{},
+	HostSelectStrategyLatency:  latencyStrategy{},
+	HostSelectStrategyDiverse:  diverseStrategy{},
+}
+
+// RegisterHostSelectStrategy adds or replaces the strategy of the name
+func RegisterHostSelectStrategy(name string, strategy HostSelectStrategy) {
+	hostSelectStrategies[name] = strategy
+}
+
+// GetHostSelectStrategy returns the strategy registered with the name
+func GetHostSelectStrategy(name string) (HostSelectStrategy, error) {
+	strategy, ok := hostSelectStrategies[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHostSelectStrategy, name)
+	}
+	return strategy, nil
+}
+
+// scoreStrategy orders hosts by the hub score, the highest first
+type scoreStrategy struct{}
+
+func (scoreStrategy) Order(cp *ContextParams, hosts []*hubpb.Host) []*hubpb.Host {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return hosts[i].Score > hosts[j].Score
+	})
+	return hosts
+}
+
+// cheapestStrategy orders hosts by the storage price asked, the lowest first
+type cheapestStrategy struct{}
+
+func (cheapestStrategy) Order(cp *ContextParams, hosts []*hubpb.Host) []*hubpb.Host {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return hosts[i].StoragePriceAsk < hosts[j].StoragePriceAsk
+	})
+	return hosts
+}
+
+// latencyStrategy orders hosts by the latency measured by this node, the hosts
+// with unknown latency are the last
+type latencyStrategy struct{}
+
+func (latencyStrategy) Order(cp *ContextParams, hosts []*hubpb.Host) []*hubpb.Host {
+	latencies := make(map[string]time.Duration, len(hosts))
+	for _, h := range hosts {
+		latencies[h.NodeId] = hostLatency(cp, h.NodeId)
+	}
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return latencyKey(latencies[hosts[i].NodeId]) < latencyKey(latencies[hosts[j].NodeId])
+	})
+	return hosts
+}
+
+func latencyKey(d time.Duration) time.Duration {
+	if d <= 0 {
+		return math.MaxInt64
+	}
+	return d
+}
+
+// diverseStrategy takes hosts from the regions in turn, the hosts of a region
+// are ordered by score
+type diverseStrategy struct{}
+
+func (diverseStrategy) Order(cp *ContextParams, hosts []*hubpb.Host) []*hubpb.Host {
+	hosts = scoreStrategy{}.Order(cp, hosts)
+	regions := make([]string, 0)
+	groups := make(map[string][]*hubpb.Host)
+	for _, h := range hosts {
+		r := hostRegion(h)
+		if _, ok := groups[r]; !ok {
+			regions = append(regions, r)
+		}
+		groups[r] = append(groups[r], h)
+	}
+	ordered := make([]*hubpb.Host, 0, len(hosts))
+	for len(ordered) < len(hosts) {
+		for _, r := range regions {
+			if len(groups[r]) == 0 {
+				continue
+			}
+			ordered = append(ordered, groups[r][0])
+			groups[r] = groups[r][1:]
+		}
+	}
+	return ordered
+}
+
+func hostRegion(h *hubpb.Host) string {
+	if h.Region != "" {
+		return strings.ToLower(h.Region)
+	}
+	return strings.ToLower(h.CountryShort)
+}
+
+func hostLatency(cp *ContextParams, nodeId string) time.Duration {
+	id, err := peer.Decode(nodeId)
+	if err != nil {
+		return 0
+	}
+	return cp.N.Peerstore.LatencyEWMA(id)
+}
+
+// HostSuccessRates loads the rates of the contracts completed by the hosts
+type HostSuccessRates func() (map[string]float64, error)
+
+// ParseHostConstraints parses the constraints separated by ',', such as
+// "max-price=100,region=us|eu,one-per-subnet"
+func ParseHostConstraints(s string, rates HostSuccessRates) ([]HostConstraint, error) {
+	constraints := make([]HostConstraint, 0)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(item, "=")
+		c, err := parseHostConstraint(strings.TrimSpace(name), strings.TrimSpace(value), rates)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+func parseHostConstraint(name, value string, rates HostSuccessRates) (HostConstraint, error) {
+	invalid := fmt.Errorf("%w: %s=%s", ErrInvalidHostConstraint, name, value)
+	switch name {
+	case HostConstraintMaxPrice:
+		price, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return &maxPriceConstraint{price: price}, nil
+	case HostConstraintMaxLatency:
+		latency, err := time.ParseDuration(value)
+		if err != nil || latency <= 0 {
+			return nil, invalid
+		}
+		return &maxLatencyConstraint{latency: latency}, nil
+	case HostConstraintRegion:
+		if value == "" {
+			return nil, invalid
+		}
+		regions := make(map[string]bool)
+		for _, r := range strings.Split(value, "|") {
+			if r = strings.ToLower(strings.TrimSpace(r)); r != "" {
+				regions[r] = true
+			}
+		}
+		if len(regions) == 0 {
+			return nil, invalid
+		}
+		return &regionConstraint{regions: regions}, nil
+	case HostConstraintMinSuccessRate:
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 || rates == nil {
+			return nil, invalid
+		}
+		return &successRateConstraint{min: rate, load: rates}, nil
+	case HostConstraintOnePerSubnet:
+		return &onePerSubnetConstraint{subnets: make(map[string]bool)}, nil
+	case HostConstraintOnePerOperator:
+		return &onePerOperatorConstraint{operators: make(map[string]bool)}, nil
+	}
+	return nil, invalid
+}
+
+// maxPriceConstraint allows the hosts asking no more than the price
+type maxPriceConstraint struct {
+	price uint64
+}
+
+func (c *maxPriceConstraint) Allow(cp *ContextParams, host *hubpb.Host) bool {
+	return host.StoragePriceAsk <= c.price
+}
+
+func (c *maxPriceConstraint) Select(cp *ContextParams, host *hubpb.Host) {}
+
+func (c *maxPriceConstraint) Release(cp *ContextParams, host *hubpb.Host) {}
+
+// maxLatencyConstraint allows the hosts with known latency under the limit
+type maxLatencyConstraint struct {
+	latency time.Duration
+}
+
+func (c *maxLatencyConstraint) Allow(cp *ContextParams, host *hubpb.Host) bool {
+	latency := hostLatency(cp, host.NodeId)
+	return latency > 0 && latency <= c.latency
+}
+
+func (c *maxLatencyConstraint) Select(cp *ContextParams, host *hubpb.Host) {}
+
+func (c *maxLatencyConstraint) Release(cp *ContextParams, host *hubpb.Host) {}
+
+// regionConstraint allows the hosts in the regions or countries
+type regionConstraint struct {
+	regions map[string]bool
+}
+
+func (c *regionConstraint) Allow(cp *ContextParams, host *hubpb.Host) bool {
+	return c.regions[strings.ToLower(host.Region)] || c.regions[strings.ToLower(host.CountryShort)]
+}
+
+func (c *regionConstraint) Select(cp *ContextParams, host *hubpb.Host) {}
+
+func (c *regionConstraint) Release(cp *ContextParams, host *hubpb.Host) {}
+
+// successRateConstraint allows the hosts with enough completed contracts in the
+// past, the hosts without past contracts are allowed
+type successRateConstraint struct {
+	min   float64
+	load  HostSuccessRates
+	rates map[string]float64
+}
+
+func (c *successRateConstraint) Allow(cp *ContextParams, host *hubpb.Host) bool {
+	if c.rates == nil {
+		rates, err := c.load()
+		if err != nil {
+			log.Debugf("load host success rates error: %v", err)
+			rates = make(map[string]float64)
+		}
+		c.rates = rates
+	}
+	rate, ok := c.rates[host.NodeId]
+	return !ok || rate >= c.min
+}
+
+func (c *successRateConstraint) Select(cp *ContextParams, host *hubpb.Host) {}
+
+func (c *successRateConstraint) Release(cp *ContextParams, host *hubpb.Host) {}
+
+// onePerSubnetConstraint allows one host per IPv4 /24 or IPv6 /48 subnet
+type onePerSubnetConstraint struct {
+	subnets map[string]bool
+}
+
+func (c *onePerSubnetConstraint) Allow(cp *ContextParams, host *hubpb.Host) bool {
+	for _, s := range hostSubnets(cp, host.NodeId) {
+		if c.subnets[s] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *onePerSubnetConstraint) Select(cp *ContextParams, host *hubpb.Host) {
+	for _, s := range hostSubnets(cp, host.NodeId) {
+		c.subnets[s] = true
+	}
+}
+
+func (c *onePerSubnetConstraint) Release(cp *ContextParams, host *hubpb.Host) {
+	for _, s := range hostSubnets(cp, host.NodeId) {
+		delete(c.subnets, s)
+	}
+}
+
+func hostSubnets(cp *ContextParams, nodeId string) []string {
+	id, err := peer.Decode(nodeId)
+	if err != nil {
+		return nil
+	}
+	subnets := make([]string, 0)
+	for _, addr := range cp.N.Peerstore.Addrs(id) {
+		if !manet.IsPublicAddr(addr) {
+			continue
+		}
+		ip, err := manet.ToIP(addr)
+		if err != nil {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			subnets = append(subnets, ip4.Mask(net.CIDRMask(24, 32)).String()+"/24")
+		} else {
+			subnets = append(subnets, ip.Mask(net.CIDRMask(48, 128)).String()+"/48")
+		}
+	}
+	return subnets
+}
+
+// onePerOperatorConstraint allows one host per operator, the hosts paid to the
+// same vault are regarded as the same operator
+type onePerOperatorConstraint struct {
+	operators map[string]bool
+}
+
+func (c *onePerOperatorConstraint) Allow(cp *ContextParams, host *hubpb.Host) bool {
+	operator := hostOperator(cp, host.NodeId)
+	return operator == "" || !c.operators[operator]
+}
+
+func (c *onePerOperatorConstraint) Select(cp *ContextParams, host *hubpb.Host) {
+	if operator := hostOperator(cp, host.NodeId); operator != "" {
+		c.operators[operator] = true
+	}
+}
+
+func (c *onePerOperatorConstraint) Release(cp *ContextParams, host *hubpb.Host) {
+	if operator := hostOperator(cp, host.NodeId); operator != "" {
+		delete(c.operators, operator)
+	}
+}
+
+func hostOperator(cp *ContextParams, nodeId string) string {
+	id, err := peer.Decode(nodeId)
+	if err != nil {
+		return ""
+	}
+	vault, err := chain.SettleObject.Factory.GetPeerVaultWithCache(cp.Ctx, id)
+	if err != nil || vault == (common.Address{}) {
+		return ""
+	}
+	return vault.Hex()
+}
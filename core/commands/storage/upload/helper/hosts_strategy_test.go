@@ -0,0 +1,175 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bittorrent/go-btfs/core"
+
+	hubpb "github.com/bittorrent/go-btfs-common/protos/hub"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/core/test"
+	"github.com/libp2p/go-libp2p/p2p/host/peerstore/pstoremem"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+)
+
+func testContextParams(t *testing.T) *ContextParams {
+	ps, err := pstoremem.NewPeerstore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ps.Close() })
+	return &ContextParams{N: &core.IpfsNode{Peerstore: ps}}
+}
+
+func testPeers(t *testing.T, n int) []peer.ID {
+	ids := make([]peer.ID, n)
+	for i := range ids {
+		ids[i] = test.RandPeerIDFatal(t)
+	}
+	return ids
+}
+
+func TestHostSelectStrategyOrder(t *testing.T) {
+	hosts := func() []*hubpb.Host {
+		return []*hubpb.Host{
+			{NodeId: "a", Score: 1, StoragePriceAsk: 300, Region: "us"},
+			{NodeId: "b", Score: 3, StoragePriceAsk: 100, Region: "us"},
+			{NodeId: "c", Score: 2, StoragePriceAsk: 200, Region: "eu"},
+			{NodeId: "d", Score: 4, StoragePriceAsk: 400, CountryShort: "JP"},
+		}
+	}
+	ids := func(hosts []*hubpb.Host) []string {
+		res := make([]string, 0, len(hosts))
+		for _, h := range hosts {
+			res = append(res, h.NodeId)
+		}
+		return res
+	}
+
+	cases := map[string][]string{
+		HostSelectStrategyScore:    {"d", "b", "c", "a"},
+		HostSelectStrategyCheapest: {"b", "c", "a", "d"},
+		HostSelectStrategyDiverse:  {"d", "b", "c", "a"},
+	}
+	for name, expected := range cases {
+		strategy, err := GetHostSelectStrategy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ids(strategy.Order(nil, hosts())), name)
+	}
+
+	_, err := GetHostSelectStrategy("fastest")
+	assert.True(t, errors.Is(err, ErrUnknownHostSelectStrategy))
+}
+
+func TestParseHostConstraints(t *testing.T) {
+	rates := func() (map[string]float64, error) {
+		return map[string]float64{"a": 0.5, "b": 1}, nil
+	}
+	cs, err := ParseHostConstraints("max-price=200, region=US|jp ,min-success-rate=0.8", rates)
+	assert.NoError(t, err)
+	assert.Len(t, cs, 3)
+
+	selection := &HostSelection{Constraints: cs}
+	assert.False(t, selection.allow(nil, &hubpb.Host{NodeId: "a", StoragePriceAsk: 100, Region: "us"}))
+	assert.True(t, selection.allow(nil, &hubpb.Host{NodeId: "b", StoragePriceAsk: 200, Region: "us"}))
+	assert.True(t, selection.allow(nil, &hubpb.Host{NodeId: "c", StoragePriceAsk: 100, CountryShort: "JP"}))
+	assert.False(t, selection.allow(nil, &hubpb.Host{NodeId: "c", StoragePriceAsk: 300, Region: "us"}))
+	assert.False(t, selection.allow(nil, &hubpb.Host{NodeId: "c", StoragePriceAsk: 100, Region: "eu"}))
+
+	for _, s := range []string{"max-price=cheap", "region=", "min-success-rate=2", "max-latency=-1s", "nearest"} {
+		_, err = ParseHostConstraints(s, rates)
+		assert.True(t, errors.Is(err, ErrInvalidHostConstraint), s)
+	}
+}
+
+func TestLatencyStrategyOrder(t *testing.T) {
+	cp := testContextParams(t)
+	ids := testPeers(t, 4)
+	cp.N.Peerstore.RecordLatency(ids[0], 300*time.Millisecond)
+	cp.N.Peerstore.RecordLatency(ids[2], 100*time.Millisecond)
+	cp.N.Peerstore.RecordLatency(ids[3], 200*time.Millisecond)
+
+	hosts := []*hubpb.Host{
+		{NodeId: ids[0].String()},
+		{NodeId: ids[1].String()},
+		{NodeId: ids[2].String()},
+		{NodeId: ids[3].String()},
+		{NodeId: "invalid"},
+	}
+	strategy, err := GetHostSelectStrategy(HostSelectStrategyLatency)
+	assert.NoError(t, err)
+	ordered := strategy.Order(cp, hosts)
+
+	// the hosts with unknown latency keep their order at the end
+	expected := []string{ids[2].String(), ids[3].String(), ids[0].String(), ids[1].String(), "invalid"}
+	got := make([]string, 0, len(ordered))
+	for _, h := range ordered {
+		got = append(got, h.NodeId)
+	}
+	assert.Equal(t, expected, got)
+}
+
+func TestParseHostConstraintsLatency(t *testing.T) {
+	cp := testContextParams(t)
+	ids := testPeers(t, 3)
+	cp.N.Peerstore.RecordLatency(ids[0], 50*time.Millisecond)
+	cp.N.Peerstore.RecordLatency(ids[1], 500*time.Millisecond)
+
+	cs, err := ParseHostConstraints("max-latency=100ms", nil)
+	assert.NoError(t, err)
+	selection := &HostSelection{Constraints: cs}
+	assert.True(t, selection.allow(cp, &hubpb.Host{NodeId: ids[0].String()}))
+	assert.False(t, selection.allow(cp, &hubpb.Host{NodeId: ids[1].String()}))
+	// unknown latency
+	assert.False(t, selection.allow(cp, &hubpb.Host{NodeId: ids[2].String()}))
+}
+
+func TestParseHostConstraintsNames(t *testing.T) {
+	cases := map[string]int{
+		"":                                0,
+		" , ":                             0,
+		"one-per-subnet":                  1,
+		"one-per-subnet,one-per-operator": 2,
+		"region=us|,max-latency=1s":       2,
+	}
+	for s, n := range cases {
+		cs, err := ParseHostConstraints(s, nil)
+		assert.NoError(t, err, s)
+		assert.Len(t, cs, n, s)
+	}
+
+	// the success rate needs the rates of the hosts
+	_, err := ParseHostConstraints("min-success-rate=0.5", nil)
+	assert.True(t, errors.Is(err, ErrInvalidHostConstraint))
+	for _, s := range []string{"region=|", "max-latency=0s", "min-success-rate=-0.1", "max-price=-1"} {
+		_, err = ParseHostConstraints(s, func() (map[string]float64, error) { return nil, nil })
+		assert.True(t, errors.Is(err, ErrInvalidHostConstraint), s)
+	}
+}
+
+func TestHostSelectionRelease(t *testing.T) {
+	cp := testContextParams(t)
+	ids := testPeers(t, 3)
+	addrs := []string{"/ip4/8.8.8.1/tcp/4001", "/ip4/8.8.8.2/tcp/4001", "/ip4/8.8.9.1/tcp/4001"}
+	for i, a := range addrs {
+		cp.N.Peerstore.AddAddr(ids[i], ma.StringCast(a), peerstore.PermanentAddrTTL)
+	}
+	cs, err := ParseHostConstraints("one-per-subnet", nil)
+	assert.NoError(t, err)
+	selection := &HostSelection{Constraints: cs}
+	hosts := []*hubpb.Host{{NodeId: ids[0].String()}, {NodeId: ids[1].String()}, {NodeId: ids[2].String()}}
+
+	assert.True(t, selection.accept(cp, hosts[0]))
+	// same /24 subnet
+	assert.False(t, selection.accept(cp, hosts[1]))
+	assert.True(t, selection.accept(cp, hosts[2]))
+
+	// the subnet is free again once the host failed to store the shard
+	selection.release(cp, hosts[0])
+	assert.True(t, selection.accept(cp, hosts[1]))
+}
@@ -177,6 +177,20 @@ func GetPriceAndMinStorageLength(params *ContextParams) (price int64, storageLen
 	return
 }
 
+// CheckStorageLength checks the storage length against the minimum storage time
+// in the host storage config
+func CheckStorageLength(params *ContextParams, storageLength int) error {
+	ns, err := helper.GetHostStorageConfig(params.Ctx, params.N)
+	if err != nil {
+		return err
+	}
+	if uint64(storageLength) < ns.StorageTimeMin {
+		return fmt.Errorf("invalid storage len. want: >= %d, got: %d",
+			ns.StorageTimeMin, storageLength)
+	}
+	return nil
+}
+
 func TotalPay(shardSize int64, price int64, storageLength int, rate *big.Int) (int64, error) {
 	if rate.Cmp(big.NewInt(1)) > 0 {
 		return TotalPayReal(shardSize, price, storageLength, rate)
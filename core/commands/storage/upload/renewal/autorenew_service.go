@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
 	"github.com/bittorrent/go-btfs/chain"
 	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/metrics"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/query"
 	logging "github.com/ipfs/go-log/v2"
@@ -17,6 +19,9 @@ import (
 
 const (
 	userFileShard = "/btfs/%s/shards/file/%s"
+
+	// Renew 24 hours before expiration
+	autoRenewalThreshold = 24 * time.Hour
 )
 
 var (
@@ -113,10 +118,16 @@ func (ars *AutoRenewalService) run() {
 	}
 }
 
-// checkAndRenewFiles checks for files that need renewal and processes them
+// checkAndRenewFiles checks for files that need renewal and processes them,
+// and notifies the files about to expire without auto-renewal
 func (ars *AutoRenewalService) checkAndRenewFiles() {
 	autoRenewLog.Debug("Checking for files that need renewal")
 
+	notifyCfg, err := getRenewalNotifyConfig(ars.ctxParams)
+	if err != nil {
+		autoRenewLog.Errorf("Failed to get renewal notify config: %v", err)
+	}
+
 	configs, err := ars.getAutoRenewalConfigs()
 	if err != nil {
 		autoRenewLog.Errorf("Failed to get auto-renewal configs: %v", err)
@@ -124,7 +135,6 @@ func (ars *AutoRenewalService) checkAndRenewFiles() {
 	}
 
 	now := time.Now()
-	renewalThreshold := 24 * time.Hour // Renew 24 hours before expiration
 
 	for _, config := range configs {
 		if !config.Enabled {
@@ -132,27 +142,104 @@ func (ars *AutoRenewalService) checkAndRenewFiles() {
 		}
 
 		// Check if renewal is needed (within threshold of expiration)
-		if now.Add(renewalThreshold).After(config.NextRenewalAt) {
+		if now.Add(autoRenewalThreshold).After(config.NextRenewalAt) {
 			autoRenewLog.Infof("Processing auto-renewal for file: %s", config.CID)
 
-			err = ars.processAutoRenewal(config)
+			err = ars.renewWithinBudget(config)
+			metrics.Renewals.WithLabelValues(RenewTypeAuto, renewalResult(err)).Inc()
 			if err != nil {
-				autoRenewLog.Errorf("Failed to auto-renew file %s: %v", config.CID, err)
+				notifyRenewalEvent(ars.ctx, notifyCfg, &RenewalEvent{
+					Type:      RenewalEventFailed,
+					CID:       config.CID,
+					ExpiresAt: config.NextRenewalAt,
+					Error:     err.Error(),
+				})
 			} else {
 				autoRenewLog.Infof("Successfully auto-renewed file: %s", config.CID)
 			}
 		}
 	}
+
+	if notifyCfg == nil || notifyCfg.ExpiryWarningDays <= 0 {
+		return
+	}
+	files, err := listFileContracts(ars.ctxParams)
+	if err != nil {
+		autoRenewLog.Errorf("Failed to list file contracts: %v", err)
+		return
+	}
+	expiring, err := expiringFiles(ars.ctxParams, files, time.Duration(notifyCfg.ExpiryWarningDays)*24*time.Hour)
+	if err != nil {
+		autoRenewLog.Errorf("Failed to check expiring files: %v", err)
+		return
+	}
+	for _, f := range expiring {
+		// a file is notified once until its contracts are extended
+		notified, err := expiryNotified(ars.ctxParams, f.contracts)
+		if err != nil {
+			autoRenewLog.Errorf("Failed to check expiry notification of file %s: %v", f.CID, err)
+			continue
+		}
+		if notified {
+			continue
+		}
+		notifyRenewalEvent(ars.ctx, notifyCfg, &RenewalEvent{
+			Type:      RenewalEventExpiring,
+			CID:       f.CID,
+			ExpiresAt: f.ExpiresAt,
+		})
+		if err := markExpiryNotified(ars.ctxParams, f.contracts); err != nil {
+			autoRenewLog.Errorf("Failed to mark expiry notification of file %s: %v", f.CID, err)
+		}
+	}
+	if err := pruneExpiryNotified(ars.ctxParams, files); err != nil {
+		autoRenewLog.Errorf("Failed to prune expiry notifications: %v", err)
+	}
+}
+
+// renewWithinBudget renews the file if the cost is within the monthly budget
+// of the token, the cost is reserved from the budget before paying and the
+// reservation is corrected by the amount actually paid
+func (ars *AutoRenewalService) renewWithinBudget(config *RenewalInfo) error {
+	cost, err := autoRenewalCost(config)
+	if err != nil {
+		return err
+	}
+	month, err := reserveRenewalSpend(ars.ctxParams, config.Token, cost)
+	if err != nil {
+		return err
+	}
+	paid, err := ars.processAutoRenewal(config)
+	if aerr := adjustRenewalSpend(ars.ctxParams, month, config.Token, new(big.Int).Sub(paid, cost)); aerr != nil {
+		autoRenewLog.Errorf("Failed to record renewal spend of file %s: %v", config.CID, aerr)
+	}
+	return err
+}
+
+// renewalResult returns the result label of the renewal metrics
+func renewalResult(err error) string {
+	if errors.Is(err, ErrRenewalBudgetExceeded) {
+		return "over-budget"
+	}
+	return metrics.Result(err)
 }
 
 // getAutoRenewalConfigs retrieves all auto-renewal configurations
 func (ars *AutoRenewalService) getAutoRenewalConfigs() ([]*RenewalInfo, error) {
-	prefix := fmt.Sprintf(autoRenewKey, ars.ctxParams.N.Identity.String())
+	return listRenewalInfos(ars.ctxParams, RenewTypeAuto)
+}
+
+// listRenewalInfos retrieves all renewal information of the type
+func listRenewalInfos(ctxParams *uh.ContextParams, renewType string) ([]*RenewalInfo, error) {
+	prefix := fmt.Sprintf(autoRenewKey, ctxParams.N.Identity.String())
+	if renewType == RenewTypeManual {
+		prefix = fmt.Sprintf(manualRenewKey, ctxParams.N.Identity.String())
+	}
 	q := query.Query{
 		Prefix: prefix,
 	}
 
-	results, err := ars.ctxParams.N.Repo.Datastore().Query(ars.ctx, q)
+	results, err := ctxParams.N.Repo.Datastore().Query(ctxParams.Ctx, q)
 	if err != nil {
 		return nil, err
 	}
@@ -178,8 +265,10 @@ func (ars *AutoRenewalService) getAutoRenewalConfigs() ([]*RenewalInfo, error) {
 	return configs, nil
 }
 
-// processAutoRenewal processes the automatic renewal for a specific file
-func (ars *AutoRenewalService) processAutoRenewal(config *RenewalInfo) error {
+// processAutoRenewal processes the automatic renewal for a specific file, and
+// returns the amount paid even if it fails in the middle
+func (ars *AutoRenewalService) processAutoRenewal(config *RenewalInfo) (paid *big.Int, err error) {
+	paid = new(big.Int)
 
 	shardInfos := make(map[string][]*RenewalShardInfo, 0)
 	renewInfos := make(map[string]*RenewalInfo, 0)
@@ -202,7 +291,10 @@ func (ars *AutoRenewalService) processAutoRenewal(config *RenewalInfo) error {
 		// Execute renewal
 		resp, err := executeRenewal(ars.ctxParams, renewReq)
 		if err != nil {
-			return fmt.Errorf("renewal execution failed: %v", err)
+			return paid, fmt.Errorf("renewal execution failed: %v", err)
+		}
+		if resp.TotalCost != nil {
+			paid.Add(paid, resp.TotalCost)
 		}
 
 		renewShardInfo := &RenewalShardInfo{
@@ -238,7 +330,7 @@ func (ars *AutoRenewalService) processAutoRenewal(config *RenewalInfo) error {
 
 	}
 
-	return nil
+	return paid, nil
 }
 
 // updateAutoRenewalConfig updates an auto-renewal configuration
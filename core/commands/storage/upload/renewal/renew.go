@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"time"
 
 	nodepb "github.com/bittorrent/go-btfs-common/protos/node"
@@ -14,6 +15,8 @@ import (
 	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
 	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/metrics"
+	"github.com/bittorrent/go-btfs/protos/metadata"
 	"github.com/bittorrent/go-btfs/settlement/swap/vault"
 	"github.com/bittorrent/go-btfs/utils"
 
@@ -27,9 +30,11 @@ import (
 var log = logging.Logger("renew")
 
 const (
-	renewDurationOptionName = "duration"
-	renewTokenOptionName    = "renew-token"
-	renewPriceOptionName    = "renew-price"
+	renewDurationOptionName  = "duration"
+	renewTokenOptionName     = "renew-token"
+	renewPriceOptionName     = "renew-price"
+	expiringWithinOptionName = "expiring-within"
+	dryRunOptionName         = "dry-run"
 )
 
 // RenewRequest represents a file renewal request
@@ -48,12 +53,25 @@ type RenewRequest struct {
 	TotalCost   int64          `json:"total_cost"`
 }
 
-// RenewResponse represents the response of a renewal operation
+// RenewResponse represents the response of a renewal operation, CID and
+// NewExpiration are only set when a single file is renewed
 type RenewResponse struct {
-	Success       bool      `json:"success"`
+	Success       bool               `json:"success"`
+	CID           string             `json:"cid"`
+	NewExpiration time.Time          `json:"new_expiration"`
+	TotalCost     *big.Int           `json:"total_cost"`
+	DryRun        bool               `json:"dry_run,omitempty"`
+	Files         []*RenewFileResult `json:"files,omitempty"`
+}
+
+// RenewFileResult is the renewal result of a file, the cost is only projected
+// on dry run
+type RenewFileResult struct {
 	CID           string    `json:"cid"`
+	Success       bool      `json:"success"`
 	NewExpiration time.Time `json:"new_expiration"`
 	TotalCost     *big.Int  `json:"total_cost"`
+	Error         string    `json:"error,omitempty"`
 }
 
 // StorageRenewCmd implements the storage renew command
@@ -71,6 +89,12 @@ Examples:
 
     # Renew with specific token and price
     $ btfs storage upload renew <file-hash> --duration 60 --token WBTT --price 1000
+
+    # Renew many files, and all files expiring within 7 days without auto-renewal
+    $ btfs storage upload renew <file-hash1> <file-hash2> --expiring-within 7
+
+    # Show the projected spend without paying
+    $ btfs storage upload renew --expiring-within 7 --dry-run
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
@@ -79,14 +103,18 @@ Examples:
 		"info":    StorageRenewInfoCmd,
 		"list":    StorageRenewListCmd,
 		"service": StorageRenewServiceCmd,
+		"budget":  StorageRenewBudgetCmd,
+		"notify":  StorageRenewNotifyCmd,
 	},
 	Arguments: []cmds.Argument{
-		cmds.StringArg("cid", true, false, "cid of the file to renew."),
+		cmds.StringArg("cid", false, true, "cids of the files to renew."),
 	},
 	Options: []cmds.Option{
 		cmds.IntOption(renewDurationOptionName, "d", "Renewal duration in days.").WithDefault(30),
 		cmds.StringOption(renewTokenOptionName, "rt", "Token type for payment (WBTT/TRX/USDD/USDT).").WithDefault("WBTT"),
 		cmds.Int64Option(renewPriceOptionName, "rp", "Max price per GiB per day in µBTT."),
+		cmds.IntOption(expiringWithinOptionName, "ew", "Also renew the files expiring within the days without auto-renewal."),
+		cmds.BoolOption(dryRunOptionName, "Show the projected renewal spend without paying.").WithDefault(false),
 	},
 	RunTimeout: 10 * time.Minute,
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
@@ -105,10 +133,11 @@ Examples:
 		}
 
 		// Extract parameters
-		cid := req.Arguments[0]
 		duration, _ := req.Options[renewDurationOptionName].(int)
 		tokenStr, _ := req.Options[renewTokenOptionName].(string)
 		priceOpt, hasPriceOpt := req.Options[renewPriceOptionName].(int64)
+		within, _ := req.Options[expiringWithinOptionName].(int)
+		dryRun, _ := req.Options[dryRunOptionName].(bool)
 
 		// Validate parameters
 		if duration <= 0 {
@@ -121,14 +150,6 @@ Examples:
 			return err
 		}
 
-		// check if the cid enabled autorenew
-		info, err := getRenewalInfo(ctxParams, cid, RenewTypeAuto)
-		if err != nil {
-			return err
-		}
-		if info != nil && info.Enabled {
-			return fmt.Errorf("file %s is already auto-renewed and cannot be renewed manually", cid)
-		}
 		// Get token address
 		token, exists := tokencfg.MpTokenAddr[tokenStr]
 		if !exists {
@@ -147,26 +168,89 @@ Examples:
 			price = priceObj.Int64()
 		}
 
-		contracts, err := sessions.ListShardsContracts(ctxParams.N.Repo.Datastore(), ctxParams.N.Identity.String(), nodepb.ContractStat_RENTER.String())
+		files, err := listFileContracts(ctxParams)
 		if err != nil {
 			return fmt.Errorf("failed to get shard contract, you can sync first, then try it again")
 		}
 
+		cids := req.Arguments
+		if within > 0 {
+			expiring, err := expiringFiles(ctxParams, files, time.Duration(within)*24*time.Hour)
+			if err != nil {
+				return err
+			}
+			for _, f := range expiring {
+				if !containsString(cids, f.CID) {
+					cids = append(cids, f.CID)
+				}
+			}
+		}
+		if len(cids) == 0 {
+			if within > 0 {
+				return fmt.Errorf("no files expiring within %d days", within)
+			}
+			return errors.New("no files to renew, give the cids or --" + expiringWithinOptionName)
+		}
+
+		out := &RenewResponse{Success: true, DryRun: dryRun, TotalCost: big.NewInt(0)}
+		for _, cid := range cids {
+			r := renewFile(ctxParams, cid, files[cid], token, price, duration, dryRun)
+			out.Files = append(out.Files, r)
+			if r.Error != "" {
+				out.Success = false
+				continue
+			}
+			out.TotalCost.Add(out.TotalCost, r.TotalCost)
+		}
+		if len(cids) == 1 {
+			if !out.Success {
+				return errors.New(out.Files[0].Error)
+			}
+			out.CID = cids[0]
+			out.NewExpiration = out.Files[0].NewExpiration
+		}
+		return res.Emit(out)
+	},
+	Type: RenewResponse{},
+}
+
+// renewFile pays the hosts of all shards of the file for the duration, and only
+// calculates the cost on dry run
+func renewFile(ctxParams *uh.ContextParams, cid string, contracts []*metadata.Contract, token common.Address,
+	price int64, duration int, dryRun bool) *RenewFileResult {
+	result := &RenewFileResult{CID: cid}
+	err := func() error {
+		// check if the cid enabled autorenew
+		info, err := getRenewalInfo(ctxParams, cid, RenewTypeAuto)
+		if err != nil {
+			return err
+		}
+		if info != nil && info.Enabled {
+			return fmt.Errorf("file %s is already auto-renewed and cannot be renewed manually", cid)
+		}
+		if len(contracts) == 0 {
+			return fmt.Errorf("no shard contracts found for file %s", cid)
+		}
+
+		if dryRun {
+			sizes := make([]int64, 0, len(contracts))
+			for _, c := range contracts {
+				sizes = append(sizes, int64(c.Meta.ShardSize))
+				end := time.Unix(int64(c.Meta.StorageEnd), 0).Add(time.Duration(duration) * 24 * time.Hour)
+				if end.After(result.NewExpiration) {
+					result.NewExpiration = end
+				}
+			}
+			result.TotalCost, err = renewalCost(token, price, duration, sizes)
+			return err
+		}
+
 		var totalCost *big.Int
 
 		now := time.Now()
 		nextRenewAt := now.Add(time.Duration(duration) * 24 * time.Hour)
 		renewShardInfo := make([]*RenewalShardInfo, 0)
 		for _, c := range contracts {
-			fileHash, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(
-				fmt.Sprintf(userFileShard, ctxParams.N.Identity, c.Meta.ContractId)))
-			if err != nil {
-				return fmt.Errorf("failed to get file hash for contract %s: %v", c.Meta.ContractId, err)
-			}
-			if cid != string(fileHash) {
-				continue
-			}
-
 			renewReq := &RenewRequest{
 				CID:         cid,
 				Token:       token,
@@ -220,14 +304,92 @@ Examples:
 
 		_ = StoreRenewalInfo(ctxParams, newInfo, RenewTypeManual)
 
-		return res.Emit(RenewResponse{
-			Success:       true,
-			CID:           cid,
-			NewExpiration: newInfo.NextRenewalAt,
-			TotalCost:     totalCost,
-		})
-	},
-	Type: RenewResponse{},
+		result.NewExpiration = newInfo.NextRenewalAt
+		result.TotalCost = totalCost
+		return nil
+	}()
+	if !dryRun {
+		metrics.Renewals.WithLabelValues(RenewTypeManual, renewalResult(err)).Inc()
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// listFileContracts lists the renter contracts of the shards by file hash
+func listFileContracts(ctxParams *uh.ContextParams) (map[string][]*metadata.Contract, error) {
+	contracts, err := sessions.ListShardsContracts(ctxParams.N.Repo.Datastore(), ctxParams.N.Identity.String(), nodepb.ContractStat_RENTER.String())
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]*metadata.Contract)
+	for _, c := range contracts {
+		if c.Meta == nil {
+			continue
+		}
+		fileHash, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(
+			fmt.Sprintf(userFileShard, ctxParams.N.Identity, c.Meta.ContractId)))
+		if err != nil {
+			log.Debugf("failed to get file hash for contract %s: %v", c.Meta.ContractId, err)
+			continue
+		}
+		files[string(fileHash)] = append(files[string(fileHash)], c)
+	}
+	return files, nil
+}
+
+// FileExpiration is the time the first shard contract of a file expires
+type FileExpiration struct {
+	CID       string
+	ExpiresAt time.Time
+
+	contracts []*metadata.Contract
+}
+
+// expiringFiles returns the files without auto-renewal which are not expired
+// yet but expire within the period, the earliest first
+func expiringFiles(ctxParams *uh.ContextParams, files map[string][]*metadata.Contract, within time.Duration) ([]*FileExpiration, error) {
+	now := time.Now()
+	expiring := make([]*FileExpiration, 0)
+	for cid, contracts := range files {
+		var end time.Time
+		for _, c := range contracts {
+			if c.Status == metadata.Contract_CLOSED {
+				continue
+			}
+			e := time.Unix(int64(c.Meta.StorageEnd), 0)
+			if end.IsZero() || e.Before(end) {
+				end = e
+			}
+		}
+		if end.IsZero() || end.Before(now) || end.After(now.Add(within)) {
+			continue
+		}
+		info, err := getRenewalInfo(ctxParams, cid, RenewTypeAuto)
+		if err != nil {
+			return nil, err
+		}
+		if info != nil && info.Enabled {
+			continue
+		}
+		expiring = append(expiring, &FileExpiration{CID: cid, ExpiresAt: end, contracts: contracts})
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].ExpiresAt.Before(expiring[j].ExpiresAt)
+	})
+	return expiring, nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 // executeRenewal performs the actual renewal operation
@@ -0,0 +1,341 @@
+package renewal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
+	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-datastore"
+)
+
+var (
+	// keys are kept out of renewKeyPrefix, which is listed as the renewals
+	renewalBudgetKey = "/btfs/%s/renewal-budget"
+	renewalSpendKey  = "/btfs/%s/renewal-spend/%s"
+
+	ErrRenewalBudgetExceeded = errors.New("monthly renewal budget exceeded")
+
+	renewalSpendLock sync.Mutex
+)
+
+// RenewalBudget is the max amount spent on the auto-renewals per month by token
+type RenewalBudget map[string]*big.Int
+
+// RenewalBudgetStatus is the budget and the spend of the auto-renewals of a token
+// in the current month
+type RenewalBudgetStatus struct {
+	Token     string   `json:"token"`
+	Budget    *big.Int `json:"budget,omitempty"`
+	Spent     *big.Int `json:"spent"`
+	Remaining *big.Int `json:"remaining,omitempty"`
+	// spend of the auto-renewals due in the rest of the month
+	Projected *big.Int `json:"projected"`
+}
+
+type RenewalBudgetResponse struct {
+	Month   string                 `json:"month"`
+	Budgets []*RenewalBudgetStatus `json:"budgets"`
+}
+
+// StorageRenewBudgetCmd shows and sets the monthly budgets of auto-renewals
+var StorageRenewBudgetCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show or set the monthly budget of auto-renewals by token.",
+		ShortDescription: `
+The auto-renewal service skips the renewals which would make the spend of the
+month exceed the budget of the token, and notifies the failures. The amount is
+in the same unit as the total cost of the renewals, 0 removes the budget.
+
+Examples:
+    # Show the budgets, the spend and the projected spend of this month
+    $ btfs storage upload renew budget
+
+    # Spend no more than the amount on the auto-renewals paid in WBTT per month
+    $ btfs storage upload renew budget WBTT 1000000000000000000000
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("token", false, false, "Token type of the budget (WBTT/TRX/USDD/USDT)."),
+		cmds.StringArg("amount", false, false, "Max amount spent per month, 0 to remove the budget."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		_, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		err = utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		ctxParams, err := uh.ExtractContextParams(req, env)
+		if err != nil {
+			return err
+		}
+
+		if len(req.Arguments) == 1 {
+			return errors.New("both token and amount are required to set the budget")
+		}
+		if len(req.Arguments) == 2 {
+			if _, ok := tokencfg.MpTokenAddr[req.Arguments[0]]; !ok {
+				return fmt.Errorf("invalid token type: %s", req.Arguments[0])
+			}
+			amount, ok := new(big.Int).SetString(req.Arguments[1], 10)
+			if !ok || amount.Sign() < 0 {
+				return fmt.Errorf("invalid amount: %s", req.Arguments[1])
+			}
+			err = setRenewalBudget(ctxParams, req.Arguments[0], amount)
+			if err != nil {
+				return err
+			}
+		}
+
+		out, err := getRenewalBudgetStatus(ctxParams)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Type: RenewalBudgetResponse{},
+}
+
+func getRenewalBudget(ctxParams *uh.ContextParams) (RenewalBudget, error) {
+	budget := make(RenewalBudget)
+	key := fmt.Sprintf(renewalBudgetKey, ctxParams.N.Identity.String())
+	data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return budget, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &budget)
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func setRenewalBudget(ctxParams *uh.ContextParams, token string, amount *big.Int) error {
+	budget, err := getRenewalBudget(ctxParams)
+	if err != nil {
+		return err
+	}
+	if amount.Sign() == 0 {
+		delete(budget, token)
+	} else {
+		budget[token] = amount
+	}
+	data, err := json.Marshal(budget)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(renewalBudgetKey, ctxParams.N.Identity.String())
+	return ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, datastore.NewKey(key), data)
+}
+
+func renewalMonth(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// getRenewalSpend returns the amount spent on the auto-renewals in the month by token
+func getRenewalSpend(ctxParams *uh.ContextParams, month string) (map[string]*big.Int, error) {
+	spend := make(map[string]*big.Int)
+	key := fmt.Sprintf(renewalSpendKey, ctxParams.N.Identity.String(), month)
+	data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return spend, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &spend)
+	if err != nil {
+		return nil, err
+	}
+	return spend, nil
+}
+
+func putRenewalSpend(ctxParams *uh.ContextParams, month string, spend map[string]*big.Int) error {
+	data, err := json.Marshal(spend)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(renewalSpendKey, ctxParams.N.Identity.String(), month)
+	return ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, datastore.NewKey(key), data)
+}
+
+// reserveRenewalSpend checks the cost against the budget of the token and adds
+// it to the spend of this month in one step, so the concurrent renewals cannot
+// exceed the budget together. It returns ErrRenewalBudgetExceeded if paying the
+// cost makes the spend exceed the budget, and the month the cost is added to.
+func reserveRenewalSpend(ctxParams *uh.ContextParams, token common.Address, cost *big.Int) (string, error) {
+	renewalSpendLock.Lock()
+	defer renewalSpendLock.Unlock()
+	budget, err := getRenewalBudget(ctxParams)
+	if err != nil {
+		return "", err
+	}
+	month := renewalMonth(time.Now())
+	spend, err := getRenewalSpend(ctxParams, month)
+	if err != nil {
+		return "", err
+	}
+	name := tokencfg.MpTokenStr[token]
+	total := new(big.Int).Set(cost)
+	if spent := spend[name]; spent != nil {
+		total.Add(total, spent)
+	}
+	if limit, ok := budget[name]; ok && total.Cmp(limit) > 0 {
+		return "", fmt.Errorf("%w: %s needs %s, budget %s", ErrRenewalBudgetExceeded, name, total, limit)
+	}
+	spend[name] = total
+	return month, putRenewalSpend(ctxParams, month, spend)
+}
+
+// adjustRenewalSpend adds the difference between the amount paid and the cost
+// reserved to the spend of the month, the spend never drops below zero
+func adjustRenewalSpend(ctxParams *uh.ContextParams, month string, token common.Address, delta *big.Int) error {
+	if delta.Sign() == 0 {
+		return nil
+	}
+	renewalSpendLock.Lock()
+	defer renewalSpendLock.Unlock()
+	spend, err := getRenewalSpend(ctxParams, month)
+	if err != nil {
+		return err
+	}
+	name := tokencfg.MpTokenStr[token]
+	if spend[name] == nil {
+		spend[name] = new(big.Int)
+	}
+	spend[name].Add(spend[name], delta)
+	if spend[name].Sign() < 0 {
+		spend[name].SetInt64(0)
+	}
+	return putRenewalSpend(ctxParams, month, spend)
+}
+
+// renewalCost calculates the cost of renewing the shards for the duration
+func renewalCost(token common.Address, price int64, duration int, shardSizes []int64) (*big.Int, error) {
+	rate, err := chain.SettleObject.OracleService.CurrentRate(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token rate: %v", err)
+	}
+	total := new(big.Int)
+	for _, size := range shardSizes {
+		pay, err := uh.TotalPay(size, price, duration, rate)
+		if err != nil {
+			return nil, err
+		}
+		total.Add(total, new(big.Int).Mul(big.NewInt(pay), rate))
+	}
+	return total, nil
+}
+
+func autoRenewalCost(info *RenewalInfo) (*big.Int, error) {
+	sizes := make([]int64, 0, len(info.ShardsInfo))
+	for _, s := range info.ShardsInfo {
+		sizes = append(sizes, int64(s.ShardSize))
+	}
+	return renewalCost(info.Token, info.Price, info.RenewalDuration, sizes)
+}
+
+// AutoRenewalCommitments returns the cost of the enabled auto-renewals due
+// within the horizon by token
+func AutoRenewalCommitments(ctxParams *uh.ContextParams, horizon time.Duration) (map[common.Address]*big.Int, error) {
+	infos, err := listRenewalInfos(ctxParams, RenewTypeAuto)
+	if err != nil {
+		return nil, err
+	}
+	until := time.Now().Add(horizon)
+	commitments := make(map[common.Address]*big.Int)
+	for _, info := range infos {
+		if !info.Enabled || !info.NextRenewalAt.Add(-autoRenewalThreshold).Before(until) {
+			continue
+		}
+		cost, err := autoRenewalCost(info)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := commitments[info.Token]; !ok {
+			commitments[info.Token] = new(big.Int)
+		}
+		commitments[info.Token].Add(commitments[info.Token], cost)
+	}
+	return commitments, nil
+}
+
+func getRenewalBudgetStatus(ctxParams *uh.ContextParams) (*RenewalBudgetResponse, error) {
+	now := time.Now()
+	month := renewalMonth(now)
+	budget, err := getRenewalBudget(ctxParams)
+	if err != nil {
+		return nil, err
+	}
+	spend, err := getRenewalSpend(ctxParams, month)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]*RenewalBudgetStatus)
+	status := func(token string) *RenewalBudgetStatus {
+		if s, ok := statuses[token]; ok {
+			return s
+		}
+		s := &RenewalBudgetStatus{Token: token, Spent: new(big.Int), Projected: new(big.Int)}
+		statuses[token] = s
+		return s
+	}
+	for token, amount := range budget {
+		status(token).Budget = amount
+	}
+	for token, amount := range spend {
+		status(token).Spent = amount
+	}
+
+	// project the auto-renewals due before the end of the month
+	utc := now.UTC()
+	monthEnd := time.Date(utc.Year(), utc.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	infos, err := listRenewalInfos(ctxParams, RenewTypeAuto)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if !info.Enabled || !info.NextRenewalAt.Add(-autoRenewalThreshold).Before(monthEnd) {
+			continue
+		}
+		cost, err := autoRenewalCost(info)
+		if err != nil {
+			return nil, err
+		}
+		s := status(tokencfg.MpTokenStr[info.Token])
+		s.Projected.Add(s.Projected, cost)
+	}
+
+	out := &RenewalBudgetResponse{Month: month, Budgets: make([]*RenewalBudgetStatus, 0, len(statuses))}
+	for _, s := range statuses {
+		if s.Budget != nil {
+			s.Remaining = new(big.Int).Sub(s.Budget, s.Spent)
+			if s.Remaining.Sign() < 0 {
+				s.Remaining.SetInt64(0)
+			}
+		}
+		out.Budgets = append(out.Budgets, s)
+	}
+	sort.Slice(out.Budgets, func(i, j int) bool {
+		return out.Budgets[i].Token < out.Budgets[j].Token
+	})
+	return out, nil
+}
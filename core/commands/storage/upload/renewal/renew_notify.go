@@ -0,0 +1,254 @@
+package renewal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
+	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/protos/metadata"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/ipfs/go-datastore"
+)
+
+const (
+	webhookOptionName    = "webhook"
+	expiryDaysOptionName = "expiry-days"
+
+	defaultExpiryWarningDays = 7
+	webhookTimeout           = 10 * time.Second
+
+	RenewalEventExpiring = "expiring"
+	RenewalEventFailed   = "renewal-failed"
+)
+
+var (
+	renewalNotifyKey = "/btfs/%s/renewal-notify"
+	// storage end of the contract the expiry has been notified of
+	renewalNotifiedKey    = "/btfs/%s/renewal-notified/%s"
+	renewalNotifiedPrefix = "/btfs/%s/renewal-notified/"
+)
+
+// RenewalNotifyConfig is where and when the renewal notifications are sent
+type RenewalNotifyConfig struct {
+	// Notifications are posted to the webhook in JSON if it is set, they are
+	// always logged.
+	Webhook string `json:"webhook,omitempty"`
+	// Files are notified when they expire within the days.
+	ExpiryWarningDays int `json:"expiry_warning_days"`
+}
+
+// RenewalEvent is the notification of a file about to expire or failed to renew
+type RenewalEvent struct {
+	Type      string    `json:"type"`
+	CID       string    `json:"cid"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// StorageRenewNotifyCmd shows and sets the renewal notifications
+var StorageRenewNotifyCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show or set the notifications of expiring files and failed renewals.",
+		ShortDescription: `
+The auto-renewal service logs a notification when a file expires within the
+expiry days without auto-renewal, or an auto-renewal fails. The notification is
+also posted to the webhook in JSON if it is set.
+
+Examples:
+    $ btfs storage upload renew notify --webhook=https://example.com/hook --expiry-days=7
+
+    # Remove the webhook
+    $ btfs storage upload renew notify --webhook=""
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(webhookOptionName, "w", "URL the notifications are posted to, empty to remove it."),
+		cmds.IntOption(expiryDaysOptionName, "e", "Notify the files expiring within the days."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		_, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		err = utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		ctxParams, err := uh.ExtractContextParams(req, env)
+		if err != nil {
+			return err
+		}
+		cfg, err := getRenewalNotifyConfig(ctxParams)
+		if err != nil {
+			return err
+		}
+
+		webhook, setWebhook := req.Options[webhookOptionName].(string)
+		days, setDays := req.Options[expiryDaysOptionName].(int)
+		if setWebhook {
+			if webhook != "" {
+				if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+					return fmt.Errorf("invalid webhook: %s", webhook)
+				}
+			}
+			cfg.Webhook = webhook
+		}
+		if setDays {
+			if days < 0 {
+				return errors.New("expiry days must not be negative")
+			}
+			cfg.ExpiryWarningDays = days
+		}
+		if setWebhook || setDays {
+			data, err := json.Marshal(cfg)
+			if err != nil {
+				return err
+			}
+			key := fmt.Sprintf(renewalNotifyKey, ctxParams.N.Identity.String())
+			err = ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, datastore.NewKey(key), data)
+			if err != nil {
+				return err
+			}
+		}
+		return cmds.EmitOnce(res, cfg)
+	},
+	Type: RenewalNotifyConfig{},
+}
+
+func getRenewalNotifyConfig(ctxParams *uh.ContextParams) (*RenewalNotifyConfig, error) {
+	cfg := &RenewalNotifyConfig{ExpiryWarningDays: defaultExpiryWarningDays}
+	key := fmt.Sprintf(renewalNotifyKey, ctxParams.N.Identity.String())
+	data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// notifyRenewalEvent logs the event and posts it to the webhook
+func notifyRenewalEvent(ctx context.Context, cfg *RenewalNotifyConfig, event *RenewalEvent) {
+	event.Time = time.Now()
+	switch event.Type {
+	case RenewalEventExpiring:
+		autoRenewLog.Warnf("File %s expires at %s without auto-renewal", event.CID, event.ExpiresAt.Format(time.RFC3339))
+	case RenewalEventFailed:
+		autoRenewLog.Errorf("Failed to auto-renew file %s: %s", event.CID, event.Error)
+	}
+	if cfg == nil || cfg.Webhook == "" {
+		return
+	}
+	if err := postRenewalEvent(ctx, cfg.Webhook, event); err != nil {
+		autoRenewLog.Errorf("Failed to post renewal notification to webhook: %v", err)
+	}
+}
+
+func postRenewalEvent(ctx context.Context, webhook string, event *RenewalEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// expiryNotified returns whether the expiry of all the open contracts of a
+// file has been notified, a contract extended since is notified again
+func expiryNotified(ctxParams *uh.ContextParams, contracts []*metadata.Contract) (bool, error) {
+	for _, c := range contracts {
+		if c.Status == metadata.Contract_CLOSED {
+			continue
+		}
+		key := fmt.Sprintf(renewalNotifiedKey, ctxParams.N.Identity.String(), c.Meta.ContractId)
+		data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+		if errors.Is(err, datastore.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if string(data) != strconv.FormatUint(c.Meta.StorageEnd, 10) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// markExpiryNotified persists that the expiry of the open contracts has been
+// notified
+func markExpiryNotified(ctxParams *uh.ContextParams, contracts []*metadata.Contract) error {
+	for _, c := range contracts {
+		if c.Status == metadata.Contract_CLOSED {
+			continue
+		}
+		key := fmt.Sprintf(renewalNotifiedKey, ctxParams.N.Identity.String(), c.Meta.ContractId)
+		err := ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, datastore.NewKey(key),
+			[]byte(strconv.FormatUint(c.Meta.StorageEnd, 10)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneExpiryNotified deletes the notified marks of the contracts which are
+// closed, expired or gone
+func pruneExpiryNotified(ctxParams *uh.ContextParams, files map[string][]*metadata.Contract) error {
+	now := uint64(time.Now().Unix())
+	open := make(map[string]bool)
+	for _, contracts := range files {
+		for _, c := range contracts {
+			if c.Status != metadata.Contract_CLOSED && c.Meta.StorageEnd > now {
+				open[c.Meta.ContractId] = true
+			}
+		}
+	}
+	prefix := fmt.Sprintf(renewalNotifiedPrefix, ctxParams.N.Identity.String())
+	keys, err := sessions.ListKeys(ctxParams.N.Repo.Datastore(), prefix)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if open[path.Base(k)] {
+			continue
+		}
+		err = ctxParams.N.Repo.Datastore().Delete(ctxParams.Ctx, datastore.NewKey(k))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
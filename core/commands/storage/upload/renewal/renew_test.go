@@ -2,10 +2,19 @@ package renewal
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	coremock "github.com/bittorrent/go-btfs/core/mock"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -179,6 +188,130 @@ func TestCalculateRenewalCost(t *testing.T) {
 	}
 }
 
+func TestPostRenewalEvent(t *testing.T) {
+	received := make(chan *RenewalEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := new(RenewalEvent)
+		if err := json.NewDecoder(r.Body).Decode(event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer srv.Close()
+
+	err := postRenewalEvent(context.Background(), srv.URL, &RenewalEvent{
+		Type:  RenewalEventFailed,
+		CID:   "QmTestHash",
+		Error: ErrRenewalBudgetExceeded.Error(),
+	})
+	require.NoError(t, err)
+	event := <-received
+	assert.Equal(t, RenewalEventFailed, event.Type)
+	assert.Equal(t, "QmTestHash", event.CID)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	err = postRenewalEvent(context.Background(), failing.URL, &RenewalEvent{Type: RenewalEventExpiring})
+	assert.Error(t, err)
+}
+
+func TestRenewalMonth(t *testing.T) {
+	at := time.Date(2024, time.December, 31, 23, 0, 0, 0, time.FixedZone("UTC-2", -2*60*60))
+	assert.Equal(t, "2025-01", renewalMonth(at))
+}
+
+func newBudgetParams(t *testing.T) *uh.ContextParams {
+	node, err := coremock.NewMockNode()
+	require.NoError(t, err)
+	if _, ok := tokencfg.MpTokenAddr[tokencfg.WBTT]; !ok {
+		tokencfg.InitToken(0)
+	}
+	return &uh.ContextParams{Ctx: context.Background(), N: node}
+}
+
+func TestReserveRenewalSpend(t *testing.T) {
+	ctxParams := newBudgetParams(t)
+	wbtt := tokencfg.MpTokenAddr[tokencfg.WBTT]
+	usdt := tokencfg.MpTokenAddr[tokencfg.USDT]
+	require.NoError(t, setRenewalBudget(ctxParams, tokencfg.WBTT, big.NewInt(100)))
+
+	month, err := reserveRenewalSpend(ctxParams, wbtt, big.NewInt(60))
+	require.NoError(t, err)
+	assert.Equal(t, renewalMonth(time.Now()), month)
+	_, err = reserveRenewalSpend(ctxParams, wbtt, big.NewInt(40))
+	require.NoError(t, err)
+
+	// the cost past the cap is refused and not added to the spend
+	_, err = reserveRenewalSpend(ctxParams, wbtt, big.NewInt(1))
+	assert.True(t, errors.Is(err, ErrRenewalBudgetExceeded))
+	spend, err := getRenewalSpend(ctxParams, month)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), spend[tokencfg.WBTT])
+
+	// the tokens without budget are not limited
+	_, err = reserveRenewalSpend(ctxParams, usdt, big.NewInt(1000))
+	require.NoError(t, err)
+	spend, err = getRenewalSpend(ctxParams, month)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1000), spend[tokencfg.USDT])
+}
+
+func TestAdjustRenewalSpend(t *testing.T) {
+	ctxParams := newBudgetParams(t)
+	wbtt := tokencfg.MpTokenAddr[tokencfg.WBTT]
+	require.NoError(t, setRenewalBudget(ctxParams, tokencfg.WBTT, big.NewInt(100)))
+
+	month, err := reserveRenewalSpend(ctxParams, wbtt, big.NewInt(80))
+	require.NoError(t, err)
+
+	// paid less than reserved, the rest of the budget is available again
+	require.NoError(t, adjustRenewalSpend(ctxParams, month, wbtt, big.NewInt(-30)))
+	spend, err := getRenewalSpend(ctxParams, month)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(50), spend[tokencfg.WBTT])
+	_, err = reserveRenewalSpend(ctxParams, wbtt, big.NewInt(50))
+	require.NoError(t, err)
+
+	// paid more than reserved
+	require.NoError(t, adjustRenewalSpend(ctxParams, month, wbtt, big.NewInt(10)))
+	spend, err = getRenewalSpend(ctxParams, month)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(110), spend[tokencfg.WBTT])
+
+	// the spend never drops below zero
+	require.NoError(t, adjustRenewalSpend(ctxParams, month, wbtt, big.NewInt(-1000)))
+	spend, err = getRenewalSpend(ctxParams, month)
+	require.NoError(t, err)
+	assert.Equal(t, 0, spend[tokencfg.WBTT].Sign())
+}
+
+func TestRenewalSpendMonthRollover(t *testing.T) {
+	ctxParams := newBudgetParams(t)
+	wbtt := tokencfg.MpTokenAddr[tokencfg.WBTT]
+	require.NoError(t, setRenewalBudget(ctxParams, tokencfg.WBTT, big.NewInt(100)))
+
+	// the budget used up last month doesn't limit this month
+	utc := time.Now().UTC()
+	lastMonth := renewalMonth(time.Date(utc.Year(), utc.Month(), 1, 0, 0, 0, 0, time.UTC).Add(-time.Hour))
+	require.NoError(t, putRenewalSpend(ctxParams, lastMonth, map[string]*big.Int{tokencfg.WBTT: big.NewInt(100)}))
+	month, err := reserveRenewalSpend(ctxParams, wbtt, big.NewInt(100))
+	require.NoError(t, err)
+	assert.NotEqual(t, lastMonth, month)
+
+	// the renewal reserved last month and paid this month is adjusted on the
+	// spend of last month
+	require.NoError(t, adjustRenewalSpend(ctxParams, lastMonth, wbtt, big.NewInt(-40)))
+	spend, err := getRenewalSpend(ctxParams, lastMonth)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(60), spend[tokencfg.WBTT])
+	spend, err = getRenewalSpend(ctxParams, month)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), spend[tokencfg.WBTT])
+}
+
 // Helper functions for testing
 
 func validateRenewRequest(req *RenewRequest) error {
@@ -166,6 +166,16 @@ func (hs *SPShard) Complete() error {
 	return hs.fsm.Event(hshToCompleteEvent)
 }
 
+// GetSPShardContract returns the contract of the shard stored at the host
+func GetSPShardContract(ds datastore.Datastore, peerId string, contractId string) (*metadata.Contract, error) {
+	contract := &metadata.Contract{}
+	err := Get(ds, fmt.Sprintf(hostShardContractsKey, peerId, contractId), contract)
+	if err != nil {
+		return nil, err
+	}
+	return contract, nil
+}
+
 func (hs *SPShard) UpdateContractStatus() error {
 	meta := &metadata.Contract{}
 	err := Get(hs.ds, fmt.Sprintf(hostShardContractsKey, hs.peerId, hs.contractId), meta)
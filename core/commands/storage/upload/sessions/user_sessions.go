@@ -12,6 +12,7 @@ import (
 
 	"github.com/bittorrent/go-btfs/core/commands/storage/helper"
 	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/metrics"
 	renterpb "github.com/bittorrent/go-btfs/protos/renter"
 	sessionpb "github.com/bittorrent/go-btfs/protos/session"
 
@@ -51,6 +52,9 @@ const (
 	RenterSessionAdditionalInfoKey = RenterSessionKey + "additional-info"
 	RenterSessionOfflineMetaKey    = RenterSessionKey + "offline-meta"
 	RenterSessionOfflineSigningKey = RenterSessionKey + "offline-signing"
+	RenterSessionUploadParamsKey   = RenterSessionKey + "upload-params"
+	RenterSessionCreatedAtKey      = RenterSessionKey + "created-at"
+	RenterSessionRepairOfKey       = RenterSessionKey + "repair-of"
 )
 
 var (
@@ -87,6 +91,11 @@ type RenterSession struct {
 	Ctx         context.Context
 	Cancel      context.CancelFunc
 	Token       common.Address
+	// when the session is created and when it entered the current status, for
+	// the metrics of the session durations, the creation time is zero for the
+	// sessions persisted before it was recorded
+	createdAt time.Time
+	enteredAt time.Time
 }
 
 func GetRenterSession(ctxParams *uh.ContextParams, ssId string, hash string, shardHashes []string) (*RenterSession,
@@ -107,11 +116,19 @@ func GetRenterSession(ctxParams *uh.ContextParams, ssId string, hash string, sha
 			Ctx:         ctx,
 			Cancel:      cancel,
 			CtxParams:   ctxParams,
+			enteredAt:   time.Now(),
 		}
 		status, err := rs.GetRenterSessionStatus()
 		if err != nil {
 			return nil, err
 		}
+		// the sessions are only looked up here, the creation time is persisted
+		// by GetUserSessionWithToken creating them
+		rs.createdAt, err = initSessionCreatedAt(ctxParams.N.Repo.Datastore(), rs.PeerId, ssId,
+			false, rs.enteredAt)
+		if err != nil {
+			return nil, err
+		}
 		if rs.Hash = hash; hash == "" {
 			rs.Hash = status.Hash
 		}
@@ -146,12 +163,18 @@ func GetUserSessionWithToken(ctxParams *uh.ContextParams, ssId string, hash stri
 			Ctx:         ctx,
 			Cancel:      cancel,
 			CtxParams:   ctxParams,
+			enteredAt:   time.Now(),
 			Token:       token,
 		}
 		status, err := rs.GetRenterSessionStatus()
 		if err != nil {
 			return nil, err
 		}
+		rs.createdAt, err = initSessionCreatedAt(ctxParams.N.Repo.Datastore(), rs.PeerId, ssId,
+			status.LastUpdated.IsZero(), rs.enteredAt)
+		if err != nil {
+			return nil, err
+		}
 		if rs.Hash = hash; hash == "" {
 			rs.Hash = status.Hash
 		}
@@ -185,12 +208,17 @@ func (rs *RenterSession) enterState(e *fsm.Event) {
 		msg = ""
 	}
 
+	now := time.Now()
+	metrics.SessionStateDuration.WithLabelValues(e.Src).Observe(now.Sub(rs.enteredAt).Seconds())
+	rs.enteredAt = now
 	switch e.Dst {
 	case RssErrorStatus:
 		msg = e.Args[0].(error).Error()
 		rs.Cancel()
+		rs.observeDuration(e.Dst, now)
 	case RssCompleteStatus:
 		rs.Cancel()
+		rs.observeDuration(e.Dst, now)
 	}
 	fmt.Printf("[%s] session: %s entered state: %s, msg: %s\n", time.Now().Format(time.RFC3339), rs.SsId, e.Dst, msg)
 
@@ -217,6 +245,36 @@ func (rs *RenterSession) enterState(e *fsm.Event) {
 	}()
 }
 
+// observeDuration observes the time the session takes from its creation to
+// the final status, the sessions without creation time are not observed
+func (rs *RenterSession) observeDuration(status string, now time.Time) {
+	if rs.createdAt.IsZero() {
+		return
+	}
+	metrics.SessionDuration.WithLabelValues(status).Observe(now.Sub(rs.createdAt).Seconds())
+}
+
+// initSessionCreatedAt returns the persisted creation time of the session, a
+// new session persists the time given as its creation time
+func initSessionCreatedAt(d datastore.Datastore, peerId, ssId string, isNew bool, now time.Time) (time.Time, error) {
+	key := datastore.NewKey(fmt.Sprintf(RenterSessionCreatedAtKey, peerId, ssId))
+	data, err := d.Get(context.TODO(), key)
+	if err == nil {
+		return time.Parse(time.RFC3339Nano, string(data))
+	}
+	if err != datastore.ErrNotFound {
+		return time.Time{}, err
+	}
+	if !isNew {
+		return time.Time{}, nil
+	}
+	err = d.Put(context.TODO(), key, []byte(now.UTC().Format(time.RFC3339Nano)))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now, nil
+}
+
 func (rs *RenterSession) UpdateAdditionalInfo(info string) error {
 	return Save(rs.CtxParams.N.Repo.Datastore(), fmt.Sprintf(RenterSessionAdditionalInfoKey, rs.PeerId, rs.SsId),
 		&renterpb.RenterSessionAdditionalInfo{
@@ -274,6 +332,37 @@ func (rs *RenterSession) To(event string, args ...interface{}) error {
 	return rs.fsm.Event(event, args...)
 }
 
+// Status returns the current status of the session fsm
+func (rs *RenterSession) Status() string {
+	if rs.fsm == nil {
+		return RssCompleteStatus
+	}
+	return rs.fsm.Current()
+}
+
+// HasRenterSession checks if the session has been created by this renter
+func HasRenterSession(ctxParams *uh.ContextParams, ssId string) (bool, error) {
+	k := fmt.Sprintf(RenterSessionStatusKey, ctxParams.N.Identity.String(), ssId)
+	return ctxParams.N.Repo.Datastore().Has(ctxParams.Ctx, datastore.NewKey(k))
+}
+
+// CountRenterSessions counts the sessions of the renter by status
+func CountRenterSessions(d datastore.Datastore, peerId string) (map[string]int, error) {
+	vs, err := List(d, fmt.Sprintf(RenterSessionPrefix, peerId), "/status")
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, v := range vs {
+		s := &renterpb.RenterSessionStatus{}
+		if err := proto.Unmarshal(v, s); err != nil {
+			continue
+		}
+		counts[s.Status]++
+	}
+	return counts, nil
+}
+
 func (rs *RenterSession) SaveOfflineMeta(meta *renterpb.OfflineMeta) error {
 	return Save(rs.CtxParams.N.Repo.Datastore(), fmt.Sprintf(RenterSessionOfflineMetaKey, rs.PeerId, rs.SsId), meta)
 }
@@ -327,13 +416,16 @@ func (r *RenterSessionsCursor) nextKey() string {
 	return result
 }
 
-func (r *RenterSessionsCursor) NextSession(status string) (*RenterSession, error) {
+// NextSession returns the next session in any of the statuses
+func (r *RenterSessionsCursor) NextSession(statuses ...string) (*RenterSession, error) {
 	key := r.nextKey()
 	for ; key != ""; key = r.nextKey() {
 		s := &sessionpb.Status{}
 		if err := Get(r.ctxParam.N.Repo.Datastore(), key, s); err == nil {
-			if s.Status == status {
-				return GetRenterSession(r.ctxParam, getSessionId(key), "", make([]string, 0))
+			for _, status := range statuses {
+				if s.Status == status {
+					return GetRenterSession(r.ctxParam, getSessionId(key), "", make([]string, 0))
+				}
 			}
 		}
 	}
@@ -2,7 +2,13 @@ package sessions
 
 import (
 	"testing"
+	"time"
 
+	"github.com/bittorrent/go-btfs/metrics"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -11,3 +17,44 @@ func TestGetractSessionId(t *testing.T) {
 	id := getSessionId(key)
 	assert.Equal(t, "0fb2f98b-3ff2-42ca-b297-7e5e13d0fe5a", id)
 }
+
+func TestInitSessionCreatedAt(t *testing.T) {
+	d := datastore.NewMapDatastore()
+	peerId := "16Uiu2HAkyUxz9mhH9yRg3GnLPn9DMaY1A8Ce23jAGyN6LX2XgRmz"
+	createdAt := time.Date(2024, time.May, 1, 10, 0, 0, 123, time.UTC)
+
+	// a new session persists its creation time
+	got, err := initSessionCreatedAt(d, peerId, "new", true, createdAt)
+	assert.NoError(t, err)
+	assert.True(t, createdAt.Equal(got))
+
+	// the session loaded again keeps the persisted creation time
+	got, err = initSessionCreatedAt(d, peerId, "new", false, createdAt.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, createdAt.Equal(got))
+	got, err = initSessionCreatedAt(d, peerId, "new", true, createdAt.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, createdAt.Equal(got))
+
+	// the session persisted without creation time has none
+	got, err = initSessionCreatedAt(d, peerId, "legacy", false, createdAt)
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero())
+}
+
+func TestObserveSessionDuration(t *testing.T) {
+	status := "test-observe-duration"
+	histogram := func() uint64 {
+		m := &dto.Metric{}
+		err := metrics.SessionDuration.WithLabelValues(status).(prometheus.Histogram).Write(m)
+		assert.NoError(t, err)
+		return m.GetHistogram().GetSampleCount()
+	}
+	now := time.Now()
+
+	(&RenterSession{}).observeDuration(status, now)
+	assert.Equal(t, uint64(0), histogram())
+
+	(&RenterSession{createdAt: now.Add(-time.Minute)}).observeDuration(status, now)
+	assert.Equal(t, uint64(1), histogram())
+}
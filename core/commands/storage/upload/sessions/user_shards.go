@@ -2,8 +2,10 @@ package sessions
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/bittorrent/go-btfs/core/commands/storage/helper"
@@ -14,6 +16,7 @@ import (
 
 	nodepb "github.com/bittorrent/go-btfs-common/protos/node"
 	"github.com/bittorrent/protobuf/proto"
+	"github.com/ethereum/go-ethereum/common"
 
 	"github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log"
@@ -28,6 +31,8 @@ const (
 	renterShardStatusKey         = renterShardKey + "status"
 	renterShardContractsKey      = renterShardKey + "contracts"
 	renterShardAdditionalInfoKey = renterShardKey + "additional-info"
+	renterShardPaidKey           = renterShardKey + "paid"
+	renterShardPayingKey         = renterShardKey + "paying"
 
 	creatorShardContractKey = "/btfs/%s/creator/shard-contracts/%s"
 	userFileShard           = "/btfs/%s/shards/file/%s"
@@ -144,6 +149,92 @@ func (rs *UserShard) UpdateContractsStatus() error {
 	return Save(rs.ds, fmt.Sprintf(renterShardContractsKey, rs.peerId, GetShardId(rs.ssId, rs.hash, rs.index)), contract)
 }
 
+// Contracted checks if the host has signed the contract of the shard
+func (rs *UserShard) Contracted() (bool, error) {
+	status, err := rs.GetShardStatus()
+	if err != nil {
+		return false, err
+	}
+	return status.Status == rshContractStatus, nil
+}
+
+// Paid checks if the host has been paid for the contract of the shard
+func (rs *UserShard) Paid() (bool, error) {
+	k := fmt.Sprintf(renterShardPaidKey, rs.peerId, GetShardId(rs.ssId, rs.hash, rs.index))
+	return rs.ds.Has(context.Background(), datastore.NewKey(k))
+}
+
+// SetPaid marks the contract of the shard paid, so the host is paid only once
+// when the session is resumed
+func (rs *UserShard) SetPaid(contractId string) error {
+	k := fmt.Sprintf(renterShardPaidKey, rs.peerId, GetShardId(rs.ssId, rs.hash, rs.index))
+	err := rs.ds.Put(context.Background(), datastore.NewKey(k), []byte(contractId))
+	if err != nil {
+		return err
+	}
+	k = fmt.Sprintf(renterShardPayingKey, rs.peerId, GetShardId(rs.ssId, rs.hash, rs.index))
+	err = rs.ds.Delete(context.Background(), datastore.NewKey(k))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ShardPayment is the payment of the host of the shard, it is saved before the
+// cheque is sent to find out if the cheque was sent when the payment is
+// interrupted
+type ShardPayment struct {
+	ContractId string         `json:"contract_id"`
+	Host       string         `json:"host"`
+	Amount     *big.Int       `json:"amount"`
+	Token      common.Address `json:"token"`
+	StartedAt  int64          `json:"started_at"`
+}
+
+// SetPaying saves the payment of the shard about to be sent
+func (rs *UserShard) SetPaying(payment *ShardPayment) error {
+	data, err := json.Marshal(payment)
+	if err != nil {
+		return err
+	}
+	k := fmt.Sprintf(renterShardPayingKey, rs.peerId, GetShardId(rs.ssId, rs.hash, rs.index))
+	return rs.ds.Put(context.Background(), datastore.NewKey(k), data)
+}
+
+// Paying returns the payment of the shard started but not marked paid, it is
+// nil if there is none
+func (rs *UserShard) Paying() (*ShardPayment, error) {
+	k := fmt.Sprintf(renterShardPayingKey, rs.peerId, GetShardId(rs.ssId, rs.hash, rs.index))
+	data, err := rs.ds.Get(context.Background(), datastore.NewKey(k))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	payment := new(ShardPayment)
+	if err := json.Unmarshal(data, payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// CloseContract marks the contract of the shard closed, nothing is done if the
+// shard has not been contracted
+func (rs *UserShard) CloseContract() error {
+	k := fmt.Sprintf(renterShardContractsKey, rs.peerId, GetShardId(rs.ssId, rs.hash, rs.index))
+	contract := &metadata.Contract{}
+	err := Get(rs.ds, k, contract)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	contract.Status = metadata.Contract_CLOSED
+	return Save(rs.ds, k, contract)
+}
+
 func (rs *UserShard) saveUserShard(contractId string) {
 	err := rs.ds.Put(context.Background(), datastore.NewKey(fmt.Sprintf(userFileShard, rs.peerId, contractId)), []byte(rs.hash))
 	if err != nil {
@@ -155,6 +246,17 @@ func (rs *UserShard) UpdateShardToContractStatus(signedContract *metadata.Contra
 	return rs.fsm.Event(rshToContractEvent, signedContract)
 }
 
+// ReplaceContract replaces the contract of the shard with the contract of its
+// repaired copy, the shard is kept in contract status
+func (rs *UserShard) ReplaceContract(contract *metadata.Contract) error {
+	err := rs.saveShardStatusAndContract(contract)
+	if err != nil {
+		return err
+	}
+	rs.saveUserShard(contract.Meta.ContractId)
+	return nil
+}
+
 func (rs *UserShard) Contracts() (*metadata.Contract, error) {
 	contract := &metadata.Contract{}
 	err := Get(rs.ds, fmt.Sprintf(renterShardContractsKey, rs.peerId, GetShardId(rs.ssId, rs.hash, rs.index)), contract)
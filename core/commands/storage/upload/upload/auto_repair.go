@@ -0,0 +1,547 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/core/commands/storage/challenge"
+	storagehelper "github.com/bittorrent/go-btfs/core/commands/storage/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/core/corehttp/remote"
+	"github.com/bittorrent/go-btfs/protos/metadata"
+
+	files "github.com/bittorrent/go-btfs-files"
+	"github.com/bittorrent/interface-go-btfs-core/options"
+	"github.com/bittorrent/interface-go-btfs-core/path"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	cidlib "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+const (
+	// a shard is lost after its host failed these consecutive challenges
+	repairFailedChallenges = 3
+	repairFindPeerTimeout  = 30 * time.Second
+	// hosts looked for at the same time when checking the shards of a file
+	repairFindPeerConcurrency = 10
+	repairRebuildTimeout      = 30 * time.Minute
+	repairConfirmTimeout      = 24 * time.Hour
+)
+
+// repairingFiles holds the file hashes being repaired, a file is repaired by
+// one session at a time
+var repairingFiles = cmap.New()
+
+// lostShard is a shard of the upload session which has to be stored again
+type lostShard struct {
+	index    int
+	contract *metadata.Contract
+	reason   string
+}
+
+// RepairSessions checks the shards of all completed upload sessions, the lost
+// shards are rebuilt and uploaded to new hosts in background on the repair
+// context. A shard is lost if its contract has been expired before the file,
+// its host is unreachable, or its host has failed the recent challenges.
+func RepairSessions(ctxParams *helper.ContextParams, repairCtx context.Context) error {
+	cursor, err := sessions.GetRenterSessionsCursor(ctxParams)
+	if err != nil {
+		return err
+	}
+	for {
+		if ctxParams.Ctx.Err() != nil {
+			return ctxParams.Ctx.Err()
+		}
+		rss, err := cursor.NextSession(sessions.RssCompleteStatus)
+		if err != nil || rss == nil {
+			break
+		}
+		if repairingFiles.Has(rss.Hash) {
+			continue
+		}
+		lost, kept, err := findLostShards(ctxParams, rss)
+		if err != nil {
+			log.Debugf("skip checking shards of session %s: %v", rss.SsId, err)
+			continue
+		}
+		if len(lost) == 0 {
+			continue
+		}
+		for _, l := range lost {
+			log.Infof("shard %s of file %s is lost: %s", l.contract.Meta.ShardHash, rss.Hash, l.reason)
+		}
+
+		// The repair may last much longer than the check round
+		params := *ctxParams
+		params.Ctx = repairCtx
+		repairingFiles.Set(rss.Hash, rss.SsId)
+		go func(rss *sessions.RenterSession, lost []*lostShard, kept []*metadata.Contract) {
+			defer repairingFiles.Remove(rss.Hash)
+			err := repairShards(&params, rss, lost, kept)
+			if err != nil {
+				log.Errorf("repair shards of file %s error: %v", rss.Hash, err)
+			}
+		}(rss, lost, kept)
+	}
+	return nil
+}
+
+// findLostShards splits the shard contracts of the session into the lost ones
+// and the kept ones, nothing is lost if the whole file has been expired
+func findLostShards(ctxParams *helper.ContextParams, rss *sessions.RenterSession) (lost []*lostShard,
+	kept []*metadata.Contract, err error) {
+	contracts := make([]*metadata.Contract, len(rss.ShardHashes))
+	for i, h := range rss.ShardHashes {
+		shard, err := sessions.GetUserShard(ctxParams, rss.SsId, h, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		c, err := shard.Contracts()
+		if err != nil {
+			return nil, nil, err
+		}
+		if c.Meta == nil || c.Meta.ContractId == "" {
+			return nil, nil, fmt.Errorf("no contract of shard %s", h)
+		}
+		contracts[i] = c
+	}
+	now := uint64(time.Now().Unix())
+	if fileStorageEnd(contracts) <= now {
+		return nil, nil, nil
+	}
+
+	records, err := challenge.ListChallengeRecords(ctxParams.Ctx, ctxParams.N.Repo.Datastore(),
+		ctxParams.N.Identity.String(), rss.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	failures := failedChallenges(records)
+	unreachable := unreachableHosts(ctxParams, contracts, failures, now)
+	lost, kept = splitShards(contracts, failures, unreachable, now)
+	return lost, kept, nil
+}
+
+// splitShards splits the shard contracts into the lost ones and the kept ones
+func splitShards(contracts []*metadata.Contract, failures map[string]int, unreachable map[string]bool,
+	now uint64) (lost []*lostShard, kept []*metadata.Contract) {
+	for i, c := range contracts {
+		reason := checkShard(c, failures[c.Meta.ContractId], unreachable[c.Meta.SpId], now)
+		if reason == "" {
+			kept = append(kept, c)
+			continue
+		}
+		lost = append(lost, &lostShard{
+			index:    i,
+			contract: c,
+			reason:   reason,
+		})
+	}
+	return lost, kept
+}
+
+// checkShard returns the reason why the shard is lost, or empty if it is kept
+func checkShard(c *metadata.Contract, failures int, unreachable bool, now uint64) string {
+	if c.Meta.StorageEnd <= now || c.Status == metadata.Contract_CLOSED {
+		return "contract expired"
+	}
+	if failures >= repairFailedChallenges {
+		return fmt.Sprintf("host failed %d challenges", failures)
+	}
+	if unreachable {
+		return "host unreachable"
+	}
+	return ""
+}
+
+// unreachableHosts looks for the hosts of the shards not lost otherwise, the
+// hosts are looked for concurrently within one deadline
+func unreachableHosts(ctxParams *helper.ContextParams, contracts []*metadata.Contract, failures map[string]int,
+	now uint64) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, c := range contracts {
+		if checkShard(c, failures[c.Meta.ContractId], false, now) == "" {
+			hosts[c.Meta.SpId] = true
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctxParams.Ctx, repairFindPeerTimeout)
+	defer cancel()
+	var (
+		wg          sync.WaitGroup
+		lock        sync.Mutex
+		sem         = make(chan struct{}, repairFindPeerConcurrency)
+		unreachable = make(map[string]bool)
+	)
+	for host := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if _, err := remote.FindPeer(ctx, ctxParams.N, host); err != nil {
+				lock.Lock()
+				unreachable[host] = true
+				lock.Unlock()
+			}
+		}(host)
+	}
+	wg.Wait()
+	return unreachable
+}
+
+// failedChallenges counts the latest consecutive failed challenges of every
+// contract, the records are ordered by contract and challenge time
+func failedChallenges(records []*challenge.ChallengeRecord) map[string]int {
+	failures := make(map[string]int)
+	for _, r := range records {
+		if r.Passed {
+			failures[r.ContractID] = 0
+		} else {
+			failures[r.ContractID]++
+		}
+	}
+	return failures
+}
+
+// repairShards rebuilds the lost shards and uploads them to new hosts for the
+// rest of the file storage period, then the file meta on chain and the shard
+// records of the session are updated with the new contracts
+func repairShards(ctxParams *helper.ContextParams, rss *sessions.RenterSession, lost []*lostShard,
+	kept []*metadata.Contract) error {
+	fileMeta, err := chain.SettleObject.FileMetaService.GetFileMetaByCID(rss.Hash)
+	if err != nil {
+		return err
+	}
+
+	// Rebuild lost shards
+	lostHashes := make([]string, 0, len(lost))
+	shardIndexes := make([]int, 0, len(lost))
+	for _, l := range lost {
+		lostHashes = append(lostHashes, l.contract.Meta.ShardHash)
+		shardIndexes = append(shardIndexes, int(l.contract.Meta.ShardIndex))
+	}
+	err = rebuildShards(ctxParams, rss.Hash, lostHashes)
+	if err != nil {
+		return err
+	}
+
+	// Storage period left
+	storageLength, err := repairStorageLength(lost, kept, time.Now())
+	if err != nil {
+		return err
+	}
+	blacklist := make([]string, 0, len(lost)+len(kept))
+	for _, c := range kept {
+		blacklist = append(blacklist, c.Meta.SpId)
+	}
+	for _, l := range lost {
+		blacklist = append(blacklist, l.contract.Meta.SpId)
+	}
+
+	// Upload to new hosts
+	meta := lost[0].contract.Meta
+	token := common.HexToAddress(meta.Token)
+	repairRss, err := sessions.GetUserSessionWithToken(ctxParams, uuid.New().String(), rss.Hash, lostHashes, token)
+	if err != nil {
+		return err
+	}
+	// the repair session is failed on startup if the daemon stops before it
+	// completes, the lost shards are repaired again by the next round
+	err = saveRepairSession(ctxParams, repairRss.SsId, &repairSession{SsId: rss.SsId, ShardIndexes: shardIndexes})
+	if err != nil {
+		return err
+	}
+	startSession(repairRss.SsId)
+	defer endSession(repairRss.SsId)
+	if !ctxParams.Cfg.Experimental.HostsSyncEnabled {
+		_ = SyncSPs(ctxParams)
+	}
+	uctx := &ShardUploadContext{
+		Rss:           repairRss,
+		HostsProvider: helper.GetSPsProvider(ctxParams, blacklist),
+		Price:         int64(meta.Price),
+		Token:         token,
+		ShardSize:     int64(meta.ShardSize),
+		StorageLength: storageLength,
+		RenterId:      ctxParams.N.Identity,
+		FileSize:      int64(fileMeta.FileSize),
+		ShardIndexes:  shardIndexes,
+		AutoRenewal:   meta.AutoRenewal,
+	}
+	expectOnePay, err := checkAndPreparePayment(uctx)
+	if err != nil {
+		_ = repairRss.To(sessions.RssToErrorEvent, err)
+		return err
+	}
+	for i, h := range repairRss.ShardHashes {
+		go sendShardContractToHost(uctx, shardIndexes[i], h, expectOnePay)
+	}
+	repaired, err := waitForRepairContracts(uctx)
+	if err != nil {
+		_ = repairRss.To(sessions.RssToErrorEvent, err)
+		return err
+	}
+	err = submitRepairToChain(uctx, repaired, kept)
+	if err != nil {
+		_ = repairRss.To(sessions.RssToErrorEvent, err)
+		return err
+	}
+
+	// Replace the shard records of the session
+	for i, l := range lost {
+		shard, err := sessions.GetUserShard(ctxParams, rss.SsId, rss.ShardHashes[l.index], l.index)
+		if err != nil {
+			return err
+		}
+		err = shard.ReplaceContract(repaired[i])
+		if err != nil {
+			return err
+		}
+	}
+	log.Infof("repaired %d shards of file %s in session %s", len(repaired), rss.Hash, repairRss.SsId)
+	return nil
+}
+
+// fileStorageEnd returns the end of the file storage, the latest end of the
+// shard contracts
+func fileStorageEnd(contracts []*metadata.Contract) uint64 {
+	var end uint64
+	for _, c := range contracts {
+		if c.Meta.StorageEnd > end {
+			end = c.Meta.StorageEnd
+		}
+	}
+	return end
+}
+
+// repairStorageLength returns the days left of the file storage, the new
+// contracts of the lost shards last until the end of the file storage
+func repairStorageLength(lost []*lostShard, kept []*metadata.Contract, now time.Time) (int, error) {
+	contracts := append(make([]*metadata.Contract, 0, len(lost)+len(kept)), kept...)
+	for _, l := range lost {
+		contracts = append(contracts, l.contract)
+	}
+	left := time.Unix(int64(fileStorageEnd(contracts)), 0).Sub(now)
+	storageLength := int(math.Ceil(left.Hours() / 24))
+	if storageLength < 1 {
+		return 0, errors.New("file storage has been expired")
+	}
+	return storageLength, nil
+}
+
+// rebuildShards reconstructs the lost shards of the reed-solomon encoded file
+// from the surviving ones, the copies of a replicated file are not rebuilt
+func rebuildShards(ctxParams *helper.ContextParams, fileHash string, lostHashes []string) error {
+	repairs := make([]cidlib.Cid, 0, len(lostHashes))
+	for _, h := range lostHashes {
+		if h == fileHash {
+			continue
+		}
+		c, err := cidlib.Parse(h)
+		if err != nil {
+			return err
+		}
+		repairs = append(repairs, c)
+	}
+	if len(repairs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctxParams.Ctx, repairRebuildTimeout)
+	defer cancel()
+	rootHash, err := cidlib.Parse(fileHash)
+	if err != nil {
+		return err
+	}
+	hashes, _, err := storagehelper.CheckAndGetReedSolomonShardHashes(ctx, ctxParams.N, ctxParams.Api, rootHash)
+	if err != nil {
+		return err
+	}
+	if len(repairs) >= len(hashes) {
+		return errors.New("no surviving shards to rebuild from")
+	}
+
+	// Reading through the whole file rebuilds the lost shards
+	rootPath := path.IpfsPath(rootHash)
+	node, err := ctxParams.Api.Unixfs().Get(ctx, rootPath, options.Unixfs.Repairs(repairs))
+	if err != nil {
+		return err
+	}
+	defer node.Close()
+	f := files.ToFile(node)
+	if f == nil {
+		return errors.New("file is not regular")
+	}
+	_, err = io.Copy(io.Discard, f)
+	if err != nil {
+		return err
+	}
+	return ctxParams.Api.Pin().Add(ctx, rootPath, options.Pin.Recursive(true))
+}
+
+// waitForRepairContracts waits for the new contracts signed by hosts, the shard
+// records of the repair session are indexed by the shard indexes of the file
+func waitForRepairContracts(ctx *ShardUploadContext) ([]*metadata.Contract, error) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			status, err := ctx.Rss.GetRenterSessionStatus()
+			if err == nil && status.Status == sessions.RssErrorStatus {
+				return nil, errors.New(status.Message)
+			}
+			contracts := make([]*metadata.Contract, 0, len(ctx.Rss.ShardHashes))
+			for i, h := range ctx.Rss.ShardHashes {
+				shard, err := sessions.GetUserShard(ctx.Rss.CtxParams, ctx.Rss.SsId, h, ctx.ShardIndexes[i])
+				if err != nil {
+					break
+				}
+				c, err := shard.Contracts()
+				if err != nil || c.Meta == nil || c.Meta.ContractId == "" {
+					break
+				}
+				contracts = append(contracts, c)
+			}
+			if len(contracts) == len(ctx.Rss.ShardHashes) {
+				return contracts, nil
+			}
+		case <-ctx.Rss.Ctx.Done():
+			return nil, errors.New("session context done")
+		}
+	}
+}
+
+// submitRepairToChain updates the file meta with the kept and the repaired
+// contracts, then pays the hosts of the repaired shards once they are stored
+func submitRepairToChain(ctx *ShardUploadContext, repaired []*metadata.Contract, kept []*metadata.Contract) error {
+	rss := ctx.Rss
+	for _, event := range []string{
+		sessions.RssToSubmitEvent,
+		sessions.RssToContractEvent,
+		sessions.RssToContractFileMetaSignedEvent,
+	} {
+		if err := rss.To(event); err != nil {
+			return err
+		}
+	}
+
+	// Update file meta
+	contracts := append(append(make([]*metadata.Contract, 0, len(kept)+len(repaired)), kept...), repaired...)
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].Meta.ShardIndex < contracts[j].Meta.ShardIndex
+	})
+	meta, err := NewFileStatus(contracts, rss.CtxParams.Cfg, repaired[0].Meta.UserId, rss.Hash, ctx.FileSize)
+	if err != nil {
+		return err
+	}
+	err = chain.SettleObject.FileMetaService.AddFileMeta(rss.Hash, meta)
+	if err != nil {
+		return err
+	}
+	if err := rss.To(sessions.RssToContractFileMetaAddedEvent); err != nil {
+		return err
+	}
+
+	// Wait for the repaired shards stored by hosts
+	if err := rss.To(sessions.RssToWaitUploadEvent); err != nil {
+		return err
+	}
+	contractIds := make([]string, 0, len(repaired))
+	for _, c := range repaired {
+		contractIds = append(contractIds, c.Meta.ContractId)
+	}
+	err = backoff.Retry(func() error {
+		m, err := chain.SettleObject.FileMetaService.GetFileMeta(rss.Hash, contractIds)
+		if err != nil {
+			return err
+		}
+		num := 0
+		for _, c := range m.Contracts {
+			for _, id := range contractIds {
+				if c.Meta.ContractId == id && c.Status == metadata.Contract_COMPLETED {
+					num++
+				}
+			}
+		}
+		if num < len(contractIds) {
+			return errors.New("uploading")
+		}
+		return nil
+	}, backoff.WithContext(helper.WaitUploadBo(repairConfirmTimeout), rss.Ctx))
+	if err != nil {
+		return err
+	}
+	if err := rss.To(sessions.RssToWaitUploadReqSignedEvent); err != nil {
+		return err
+	}
+
+	// Pay in cheque
+	if err := rss.To(sessions.RssToPayEvent); err != nil {
+		return err
+	}
+	for _, c := range repaired {
+		if err := rss.Ctx.Err(); err != nil {
+			return err
+		}
+		realAmount, err := getRealAmount(int64(c.Meta.Amount), rss.Token)
+		if err != nil {
+			return err
+		}
+		err = chain.SettleObject.SwapService.Settle(c.Meta.SpId, realAmount, c.Meta.ContractId, rss.Token)
+		if err != nil {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return rss.To(sessions.RssToCompleteEvent)
+}
+
+// repairSession is the session whose shards are repaired by a repair session,
+// the shards of the repair session are indexed by the shard indexes of the file
+type repairSession struct {
+	SsId         string `json:"ss_id"`
+	ShardIndexes []int  `json:"shard_indexes"`
+}
+
+func saveRepairSession(ctxParams *helper.ContextParams, ssId string, repaired *repairSession) error {
+	data, err := json.Marshal(repaired)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(sessions.RenterSessionRepairOfKey, ctxParams.N.Identity.String(), ssId)
+	return ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, datastore.NewKey(key), data)
+}
+
+// getRepairSession returns the session repaired by the session, nil if it is
+// not a repair session
+func getRepairSession(ctxParams *helper.ContextParams, ssId string) (*repairSession, error) {
+	key := fmt.Sprintf(sessions.RenterSessionRepairOfKey, ctxParams.N.Identity.String(), ssId)
+	data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	repaired := new(repairSession)
+	if err := json.Unmarshal(data, repaired); err != nil {
+		return nil, err
+	}
+	return repaired, nil
+}
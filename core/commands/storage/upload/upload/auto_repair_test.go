@@ -0,0 +1,110 @@
+package upload
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bittorrent/go-btfs/core/commands/storage/challenge"
+	"github.com/bittorrent/go-btfs/protos/metadata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func repairContract(id, host string, end time.Time, status metadata.Contract_ContractStatus) *metadata.Contract {
+	return &metadata.Contract{
+		Meta: &metadata.ContractMeta{
+			ContractId: id,
+			SpId:       host,
+			StorageEnd: uint64(end.Unix()),
+		},
+		Status: status,
+	}
+}
+
+func TestFailedChallenges(t *testing.T) {
+	records := []*challenge.ChallengeRecord{
+		{ContractID: "c1", Passed: false},
+		{ContractID: "c1", Passed: false},
+		{ContractID: "c1", Passed: true},
+		{ContractID: "c1", Passed: false},
+		{ContractID: "c2", Passed: false},
+		{ContractID: "c2", Passed: false},
+		{ContractID: "c2", Passed: false},
+		{ContractID: "c3", Passed: true},
+	}
+	assert.Equal(t, map[string]int{"c1": 1, "c2": 3, "c3": 0}, failedChallenges(records))
+}
+
+func TestSplitShards(t *testing.T) {
+	now := time.Now()
+	later := now.Add(30 * 24 * time.Hour)
+	contracts := []*metadata.Contract{
+		repairContract("kept", "h1", later, metadata.Contract_COMPLETED),
+		repairContract("expired", "h2", now.Add(-time.Hour), metadata.Contract_COMPLETED),
+		repairContract("closed", "h3", later, metadata.Contract_CLOSED),
+		repairContract("failed", "h4", later, metadata.Contract_COMPLETED),
+		repairContract("unreachable", "h5", later, metadata.Contract_COMPLETED),
+		repairContract("recovered", "h6", later, metadata.Contract_COMPLETED),
+	}
+	failures := map[string]int{
+		"failed":    repairFailedChallenges,
+		"recovered": repairFailedChallenges - 1,
+	}
+	unreachable := map[string]bool{"h5": true}
+
+	lost, kept := splitShards(contracts, failures, unreachable, uint64(now.Unix()))
+
+	keptIds := make([]string, 0, len(kept))
+	for _, c := range kept {
+		keptIds = append(keptIds, c.Meta.ContractId)
+	}
+	assert.Equal(t, []string{"kept", "recovered"}, keptIds)
+
+	expected := map[string]struct {
+		index  int
+		reason string
+	}{
+		"expired":     {1, "contract expired"},
+		"closed":      {2, "contract expired"},
+		"failed":      {3, "host failed 3 challenges"},
+		"unreachable": {4, "host unreachable"},
+	}
+	assert.Len(t, lost, len(expected))
+	for _, l := range lost {
+		e, ok := expected[l.contract.Meta.ContractId]
+		assert.True(t, ok, l.contract.Meta.ContractId)
+		assert.Equal(t, e.index, l.index, l.contract.Meta.ContractId)
+		assert.Equal(t, e.reason, l.reason, l.contract.Meta.ContractId)
+	}
+}
+
+func TestRepairStorageLength(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+
+	// the lost shard with the latest end defines the end of the file storage
+	lost := []*lostShard{
+		{contract: repairContract("lost", "h1", now.Add(30*day), metadata.Contract_COMPLETED)},
+	}
+	kept := []*metadata.Contract{
+		repairContract("kept", "h2", now.Add(10*day), metadata.Contract_COMPLETED),
+	}
+	length, err := repairStorageLength(lost, kept, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, length)
+
+	// part of a day left is counted as one day
+	kept = []*metadata.Contract{
+		repairContract("kept", "h2", now.Add(40*day+time.Hour), metadata.Contract_COMPLETED),
+	}
+	length, err = repairStorageLength(lost, kept, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 41, length)
+
+	// all contracts expired
+	lost = []*lostShard{
+		{contract: repairContract("lost", "h1", now.Add(-day), metadata.Contract_COMPLETED)},
+	}
+	_, err = repairStorageLength(lost, nil, now)
+	assert.Error(t, err)
+}
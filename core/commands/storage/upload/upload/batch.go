@@ -0,0 +1,291 @@
+package upload
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+)
+
+const (
+	batchDirectoryOptionName   = "directory"
+	batchConcurrencyOptionName = "concurrency"
+	batchMaxAttemptsOptionName = "max-attempts"
+)
+
+var StorageUploadBatchCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Upload many files through a persistent job queue.",
+		ShortDescription: `
+This command queues many files, or all files under directories, into a batch
+upload job. The daemon uploads the files of a job with a bounded number of
+upload sessions at the same time, retries the failed uploads with backoff and
+continues the jobs after restarting.
+
+    $ btfs storage upload batch add <file-hash1> <file-hash2> ...
+    $ btfs storage upload batch add -d <dir-hash>
+    $ btfs storage upload batch list
+    $ btfs storage upload batch pause <job-id>`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add":    storageUploadBatchAddCmd,
+		"list":   storageUploadBatchListCmd,
+		"pause":  storageUploadBatchPauseCmd,
+		"resume": storageUploadBatchResumeCmd,
+		"cancel": storageUploadBatchCancelCmd,
+	},
+}
+
+var storageUploadBatchAddCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Add a batch upload job.",
+		ShortDescription: `
+This command adds a job uploading the given files with the same options.
+With -d, every hash is a directory and all files under it are uploaded.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("file-hash", true, true, "Hashes of the files or directories to upload."),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption(batchDirectoryOptionName, "d", "Upload all files under the directories.").WithDefault(false),
+		cmds.IntOption(batchConcurrencyOptionName, "c", "Max number of upload sessions running at the same time.").WithDefault(defaultBatchConcurrency),
+		cmds.IntOption(batchMaxAttemptsOptionName, "Max number of attempts of uploading each file.").WithDefault(defaultBatchMaxAttempts),
+		cmds.StringOption(hostSelectStrategyOptionName, "st", "Order of trying hosts: score, cheapest, lowest-latency or diverse. Default: random."),
+		cmds.StringOption(hostConstraintsOptionName, "hc", "Constraints of selecting hosts. Use ',' as delimiter."),
+		cmds.IntOption(storageLengthOptionName, "len", "File storage period on hosts in days.").WithDefault(defaultStorageLength),
+		cmds.IntOption(copyName, "copy num of file hash.").WithDefault(0),
+		cmds.StringOption(tokencfg.TokenTypeName, "tk", "file storage with token type,default WBTT, other TRX/USDD/USDT.").WithDefault("WBTT"),
+		cmds.BoolOption(autoRenewOptionName, "Enable automatic renewal before expiration.").WithDefault(false),
+	},
+	RunTimeout: 5 * time.Minute,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		q, err := batchQueueOf(env)
+		if err != nil {
+			return err
+		}
+		opts := uploadOptionsFromRequest(req)
+		if _, ok := tokencfg.MpTokenAddr[opts.Token]; !ok {
+			return fmt.Errorf("invalid token: %s", opts.Token)
+		}
+		ctxParams, err := helper.ExtractContextParams(req, env)
+		if err != nil {
+			return err
+		}
+		// check the options before any file is queued
+		if _, err := getHostSelection(ctxParams, opts); err != nil {
+			return err
+		}
+		if err := helper.CheckStorageLength(ctxParams, opts.StorageLength); err != nil {
+			return err
+		}
+
+		dir, _ := req.Options[batchDirectoryOptionName].(bool)
+		concurrency, _ := req.Options[batchConcurrencyOptionName].(int)
+		maxAttempts, _ := req.Options[batchMaxAttemptsOptionName].(int)
+		job, err := q.AddJob(req.Context, req.Arguments, dir, opts, concurrency, maxAttempts)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, newBatchJobRes(job, false))
+	},
+	Type: BatchJobRes{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *BatchJobRes) error {
+			_, err := fmt.Fprintf(w, "Added batch upload job %s with %d files\n", out.ID, out.Progress.Total)
+			return err
+		}),
+	},
+}
+
+var storageUploadBatchListCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List batch upload jobs and their progress.",
+		ShortDescription: `
+This command lists all batch upload jobs with the number of files in each
+status. The files of the job are listed if the job id is given.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("job-id", false, false, "ID of the batch upload job."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		q, err := batchQueueOf(env)
+		if err != nil {
+			return err
+		}
+		out := &BatchListRes{Jobs: make([]*BatchJobRes, 0)}
+		if len(req.Arguments) > 0 {
+			job, err := q.Job(req.Arguments[0])
+			if err != nil {
+				return err
+			}
+			out.Jobs = append(out.Jobs, newBatchJobRes(job, true))
+		} else {
+			for _, job := range q.Jobs() {
+				out.Jobs = append(out.Jobs, newBatchJobRes(job, false))
+			}
+		}
+		for _, job := range out.Jobs {
+			out.Total.add(job.Progress)
+		}
+		return cmds.EmitOnce(res, out)
+	},
+	Type: BatchListRes{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *BatchListRes) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "JOB\tSTATUS\tTOTAL\tQUEUED\tUPLOADING\tCOMPLETE\tFAILED\tCANCELED")
+			for _, job := range out.Jobs {
+				p := job.Progress
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n", job.ID, job.Status,
+					p.Total, p.Queued, p.Uploading, p.Complete, p.Failed, p.Canceled)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			t := out.Total
+			fmt.Fprintf(w, "\nTotal: %d/%d files complete, %d uploading, %d queued, %d failed, %d canceled\n",
+				t.Complete, t.Total, t.Uploading, t.Queued, t.Failed, t.Canceled)
+			for _, job := range out.Jobs {
+				if len(job.Items) == 0 {
+					continue
+				}
+				fmt.Fprintln(w)
+				tw = tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "FILE\tPATH\tSTATUS\tSESSION\tATTEMPTS\tERROR")
+				for _, item := range job.Items {
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", item.FileHash, item.Path, item.Status,
+						item.SessionID, item.Attempts, item.Error)
+				}
+				if err := tw.Flush(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var storageUploadBatchPauseCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Pause a batch upload job.",
+		ShortDescription: `
+No more upload sessions of the job are started until it is resumed, the
+running ones go on.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("job-id", true, false, "ID of the batch upload job."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		q, err := batchQueueOf(env)
+		if err != nil {
+			return err
+		}
+		job, err := q.Pause(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, newBatchJobRes(job, false))
+	},
+	Type: BatchJobRes{},
+}
+
+var storageUploadBatchResumeCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Resume a paused batch upload job.",
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("job-id", true, false, "ID of the batch upload job."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		q, err := batchQueueOf(env)
+		if err != nil {
+			return err
+		}
+		job, err := q.Resume(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, newBatchJobRes(job, false))
+	},
+	Type: BatchJobRes{},
+}
+
+var storageUploadBatchCancelCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Cancel a batch upload job.",
+		ShortDescription: `
+The queued files of the job are canceled and its running upload sessions are
+stopped. The files uploaded already are not affected.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("job-id", true, false, "ID of the batch upload job."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		q, err := batchQueueOf(env)
+		if err != nil {
+			return err
+		}
+		job, err := q.Cancel(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, newBatchJobRes(job, false))
+	},
+	Type: BatchJobRes{},
+}
+
+func batchQueueOf(env cmds.Environment) (*BatchQueue, error) {
+	if _, err := cmdenv.GetNode(env); err != nil {
+		return nil, err
+	}
+	if err := utils.CheckSimpleMode(env); err != nil {
+		return nil, err
+	}
+	return getBatchQueue()
+}
+
+type BatchJobRes struct {
+	ID          string
+	Status      string
+	Concurrency int
+	Progress    BatchProgress
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Items       []*BatchItem `json:",omitempty"`
+}
+
+func newBatchJobRes(job *BatchJob, items bool) *BatchJobRes {
+	r := &BatchJobRes{
+		ID:          job.ID,
+		Status:      job.Status,
+		Concurrency: job.Concurrency,
+		Progress:    job.Progress(),
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+	}
+	if items {
+		r.Items = job.Items
+	}
+	return r
+}
+
+type BatchListRes struct {
+	Jobs  []*BatchJobRes
+	Total BatchProgress
+}
+
+func (p *BatchProgress) add(o BatchProgress) {
+	p.Total += o.Total
+	p.Queued += o.Queued
+	p.Uploading += o.Uploading
+	p.Complete += o.Complete
+	p.Failed += o.Failed
+	p.Canceled += o.Canceled
+}
@@ -0,0 +1,573 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+
+	iface "github.com/bittorrent/interface-go-btfs-core"
+	"github.com/bittorrent/interface-go-btfs-core/options"
+	"github.com/bittorrent/interface-go-btfs-core/path"
+	"github.com/google/uuid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+const (
+	batchJobPrefix = "/btfs/%s/renter/batch/"
+	batchJobKey    = batchJobPrefix + "%s"
+	batchItemsKey  = batchJobKey + "/%d"
+
+	BatchJobRunning  = "running"
+	BatchJobPaused   = "paused"
+	BatchJobCanceled = "canceled"
+	BatchJobComplete = "complete"
+	BatchJobFailed   = "failed"
+
+	BatchItemQueued    = "queued"
+	BatchItemUploading = "uploading"
+	BatchItemComplete  = "complete"
+	BatchItemFailed    = "failed"
+	BatchItemCanceled  = "canceled"
+
+	defaultBatchConcurrency = 3
+	defaultBatchMaxAttempts = 5
+
+	batchRetryBaseDelay = 1 * time.Minute
+	batchRetryMaxDelay  = 1 * time.Hour
+)
+
+var (
+	ErrBatchQueueNotStarted = errors.New("batch upload queue is not started, storage client is not enabled")
+	ErrBatchJobNotFound     = errors.New("batch upload job not found")
+	ErrBatchJobFinished     = errors.New("batch upload job has been finished")
+
+	errBatchJobCanceled   = errors.New("batch upload job canceled")
+	errBatchUploadStopped = errors.New("upload interrupted by daemon restart")
+)
+
+// BatchJob is a queue of files uploaded with the same options, at most
+// Concurrency upload sessions of the job are running at the same time.
+// The items are saved under their own keys, so that an item transition
+// doesn't rewrite the whole job.
+type BatchJob struct {
+	ID          string         `json:"id"`
+	Status      string         `json:"status"`
+	Concurrency int            `json:"concurrency"`
+	MaxAttempts int            `json:"max_attempts"`
+	Options     *UploadOptions `json:"options"`
+	Items       []*BatchItem   `json:"-"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// BatchItem is a file of the batch job, a new upload session is started for
+// every attempt of uploading the file.
+type BatchItem struct {
+	FileHash  string    `json:"file_hash"`
+	Path      string    `json:"path,omitempty"`
+	Status    string    `json:"status"`
+	SessionID string    `json:"session_id,omitempty"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error,omitempty"`
+	NextRetry time.Time `json:"next_retry,omitempty"`
+}
+
+// BatchProgress is the number of the items of a batch job in each status
+type BatchProgress struct {
+	Total     int
+	Queued    int
+	Uploading int
+	Complete  int
+	Failed    int
+	Canceled  int
+}
+
+// Progress counts the items of the job by status
+func (j *BatchJob) Progress() BatchProgress {
+	p := BatchProgress{Total: len(j.Items)}
+	for _, item := range j.Items {
+		switch item.Status {
+		case BatchItemQueued:
+			p.Queued++
+		case BatchItemUploading:
+			p.Uploading++
+		case BatchItemComplete:
+			p.Complete++
+		case BatchItemFailed:
+			p.Failed++
+		case BatchItemCanceled:
+			p.Canceled++
+		}
+	}
+	return p
+}
+
+func (j *BatchJob) finished() bool {
+	return j.Status == BatchJobComplete || j.Status == BatchJobFailed || j.Status == BatchJobCanceled
+}
+
+// BatchQueue runs the batch upload jobs of this renter, the jobs are kept in
+// the datastore so that they are continued after the daemon restarts.
+type BatchQueue struct {
+	ctxParams *helper.ContextParams
+	lock      sync.Mutex
+	jobs      map[string]*BatchJob
+	// items waiting for their sessions to be started
+	starting map[string]bool
+}
+
+var batchQueue *BatchQueue
+
+// StartBatchQueue loads the batch jobs saved in the datastore, the jobs are
+// processed by the calls of Process afterwards.
+func StartBatchQueue(ctxParams *helper.ContextParams) (*BatchQueue, error) {
+	q := &BatchQueue{
+		ctxParams: ctxParams,
+		jobs:      make(map[string]*BatchJob),
+		starting:  make(map[string]bool),
+	}
+	prefix := fmt.Sprintf(batchJobPrefix, ctxParams.N.Identity.String())
+	results, err := ctxParams.N.Repo.Datastore().Query(ctxParams.Ctx, query.Query{
+		Prefix: prefix,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	items := make(map[string]map[int]*BatchItem)
+	for entry := range results.Next() {
+		if entry.Error != nil {
+			return nil, entry.Error
+		}
+		parts := strings.Split(strings.TrimPrefix(entry.Key, prefix), "/")
+		if len(parts) == 2 {
+			index, err := strconv.Atoi(parts[1])
+			if err != nil {
+				continue
+			}
+			item := new(BatchItem)
+			if err := json.Unmarshal(entry.Value, item); err != nil {
+				log.Errorf("failed to load batch upload item %s: %v", entry.Key, err)
+				continue
+			}
+			if items[parts[0]] == nil {
+				items[parts[0]] = make(map[int]*BatchItem)
+			}
+			items[parts[0]][index] = item
+			continue
+		}
+		job := new(BatchJob)
+		if err := json.Unmarshal(entry.Value, job); err != nil {
+			log.Errorf("failed to load batch upload job %s: %v", entry.Key, err)
+			continue
+		}
+		q.jobs[job.ID] = job
+	}
+	for id, job := range q.jobs {
+		job.Items = make([]*BatchItem, 0, len(items[id]))
+		for i := 0; i < len(items[id]); i++ {
+			item, ok := items[id][i]
+			if !ok {
+				break
+			}
+			job.Items = append(job.Items, item)
+		}
+		if len(job.Items) == 0 {
+			log.Errorf("no items of batch upload job %s found", id)
+			delete(q.jobs, id)
+		}
+	}
+	batchQueue = q
+	return q, nil
+}
+
+func getBatchQueue() (*BatchQueue, error) {
+	if batchQueue == nil {
+		return nil, ErrBatchQueueNotStarted
+	}
+	return batchQueue, nil
+}
+
+// AddJob queues the files for uploading, every hash is expanded to all the
+// files under it if dir is true.
+func (q *BatchQueue) AddJob(ctx context.Context, hashes []string, dir bool, opts *UploadOptions,
+	concurrency int, maxAttempts int) (*BatchJob, error) {
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultBatchMaxAttempts
+	}
+	items := make([]*BatchItem, 0, len(hashes))
+	for _, h := range hashes {
+		if !dir {
+			items = append(items, &BatchItem{FileHash: h, Status: BatchItemQueued})
+			continue
+		}
+		files, err := q.listFiles(ctx, h, "")
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, files...)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("no files to upload")
+	}
+	now := time.Now()
+	job := &BatchJob{
+		ID:          uuid.New().String(),
+		Status:      BatchJobRunning,
+		Concurrency: concurrency,
+		MaxAttempts: maxAttempts,
+		Options:     opts,
+		Items:       items,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if err := q.saveNewJob(job); err != nil {
+		return nil, err
+	}
+	q.jobs[job.ID] = job
+	return copyBatchJob(job), nil
+}
+
+// listFiles lists the files under the directory recursively
+func (q *BatchQueue) listFiles(ctx context.Context, root string, prefix string) ([]*BatchItem, error) {
+	entries, err := q.ctxParams.Api.Unixfs().Ls(ctx, path.New(root), options.Unixfs.ResolveChildren(true))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*BatchItem, 0)
+	for entry := range entries {
+		if entry.Err != nil {
+			return nil, entry.Err
+		}
+		name := prefix + entry.Name
+		switch entry.Type {
+		case iface.TDirectory:
+			files, err := q.listFiles(ctx, entry.Cid.String(), name+"/")
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, files...)
+		case iface.TFile:
+			items = append(items, &BatchItem{FileHash: entry.Cid.String(), Path: name, Status: BatchItemQueued})
+		}
+	}
+	return items, nil
+}
+
+// Jobs returns the copies of all batch jobs, the latest one first
+func (q *BatchQueue) Jobs() []*BatchJob {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	jobs := make([]*BatchJob, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, copyBatchJob(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Job returns the copy of the batch job
+func (q *BatchQueue) Job(id string) (*BatchJob, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrBatchJobNotFound
+	}
+	return copyBatchJob(job), nil
+}
+
+// Pause stops starting new sessions of the job, the running ones go on.
+func (q *BatchQueue) Pause(id string) (*BatchJob, error) {
+	return q.setStatus(id, BatchJobPaused)
+}
+
+// Resume continues starting the sessions of the paused job
+func (q *BatchQueue) Resume(id string) (*BatchJob, error) {
+	return q.setStatus(id, BatchJobRunning)
+}
+
+// Cancel cancels the queued items and the running sessions of the job
+func (q *BatchQueue) Cancel(id string) (*BatchJob, error) {
+	return q.setStatus(id, BatchJobCanceled)
+}
+
+func (q *BatchQueue) setStatus(id string, status string) (*BatchJob, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrBatchJobNotFound
+	}
+	if job.finished() {
+		return nil, ErrBatchJobFinished
+	}
+	job.Status = status
+	changed := make([]int, 0)
+	if status == BatchJobCanceled {
+		for i, item := range job.Items {
+			switch item.Status {
+			case BatchItemQueued:
+				item.Status = BatchItemCanceled
+			case BatchItemUploading:
+				item.Status = BatchItemCanceled
+				item.Error = errBatchJobCanceled.Error()
+				q.cancelSession(item.SessionID)
+			default:
+				continue
+			}
+			changed = append(changed, i)
+		}
+	}
+	job.UpdatedAt = time.Now()
+	if err := q.saveJob(job, changed...); err != nil {
+		return nil, err
+	}
+	return copyBatchJob(job), nil
+}
+
+// Process checks the sessions of the running items, retries the failed ones
+// and starts the queued items of the running jobs.
+func (q *BatchQueue) Process(ctx context.Context) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for _, job := range q.jobs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if job.finished() {
+			continue
+		}
+		changed := q.checkItems(job)
+		if job.Status == BatchJobRunning {
+			changed = append(changed, q.startItems(job)...)
+		}
+		finished := q.finishJob(job)
+		if len(changed) == 0 && !finished {
+			continue
+		}
+		job.UpdatedAt = time.Now()
+		if err := q.saveJob(job, changed...); err != nil {
+			log.Errorf("failed to save batch upload job %s: %v", job.ID, err)
+		}
+	}
+	return nil
+}
+
+// checkItems updates the uploading items by the status of their sessions,
+// the indexes of the changed items are returned
+func (q *BatchQueue) checkItems(job *BatchJob) []int {
+	changed := make([]int, 0)
+	for i, item := range job.Items {
+		if item.Status != BatchItemUploading || q.starting[batchItemKey(job.ID, i)] {
+			continue
+		}
+		if item.SessionID == "" {
+			q.failItem(job, item, errBatchUploadStopped)
+			changed = append(changed, i)
+			continue
+		}
+		rss, err := sessions.GetRenterSession(q.ctxParams, item.SessionID, "", make([]string, 0))
+		if err != nil {
+			log.Debugf("failed to get session %s of batch upload job %s: %v", item.SessionID, job.ID, err)
+			continue
+		}
+		status, err := rss.GetRenterSessionStatus()
+		if err != nil {
+			continue
+		}
+		switch {
+		case status.Status == sessions.RssCompleteStatus:
+			item.Status = BatchItemComplete
+			item.Error = ""
+		case status.Status == sessions.RssErrorStatus:
+			q.failItem(job, item, errors.New(status.Message))
+		case !q.resumable(item.SessionID):
+			q.failItem(job, item, errBatchUploadStopped)
+		default:
+			continue
+		}
+		changed = append(changed, i)
+	}
+	return changed
+}
+
+// startItems starts the sessions of the queued items within the concurrency,
+// the indexes of the started items are returned
+func (q *BatchQueue) startItems(job *BatchJob) []int {
+	active := job.Progress().Uploading
+	now := time.Now()
+	changed := make([]int, 0)
+	for i, item := range job.Items {
+		if active >= job.Concurrency {
+			break
+		}
+		if item.Status != BatchItemQueued || now.Before(item.NextRetry) {
+			continue
+		}
+		item.Status = BatchItemUploading
+		item.SessionID = ""
+		item.Attempts++
+		q.starting[batchItemKey(job.ID, i)] = true
+		go q.startItem(job.ID, i, item.FileHash, job.Options)
+		active++
+		changed = append(changed, i)
+	}
+	return changed
+}
+
+func (q *BatchQueue) startItem(jobID string, index int, fileHash string, opts *UploadOptions) {
+	ssId, err := StartUpload(q.ctxParams, fileHash, opts)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.starting, batchItemKey(jobID, index))
+	job, ok := q.jobs[jobID]
+	if !ok {
+		return
+	}
+	item := job.Items[index]
+	if err == nil {
+		item.SessionID = ssId
+	}
+	switch {
+	case item.Status == BatchItemCanceled:
+		q.cancelSession(item.SessionID)
+	case err != nil:
+		log.Errorf("failed to upload %s of batch upload job %s: %v", fileHash, jobID, err)
+		q.failItem(job, item, err)
+	}
+	job.UpdatedAt = time.Now()
+	if err := q.saveJob(job, index); err != nil {
+		log.Errorf("failed to save batch upload job %s: %v", job.ID, err)
+	}
+}
+
+// failItem queues the item again with backoff, or fails it if the attempts
+// have been used up
+func (q *BatchQueue) failItem(job *BatchJob, item *BatchItem, err error) {
+	item.Error = err.Error()
+	if item.Attempts >= job.MaxAttempts {
+		item.Status = BatchItemFailed
+		return
+	}
+	delay := batchRetryBaseDelay << uint(item.Attempts-1)
+	if delay <= 0 || delay > batchRetryMaxDelay {
+		delay = batchRetryMaxDelay
+	}
+	item.Status = BatchItemQueued
+	item.NextRetry = time.Now().Add(delay)
+}
+
+// finishJob marks the job complete or failed once all of its items are done
+func (q *BatchQueue) finishJob(job *BatchJob) bool {
+	p := job.Progress()
+	if p.Queued > 0 || p.Uploading > 0 {
+		return false
+	}
+	if p.Failed > 0 {
+		job.Status = BatchJobFailed
+	} else {
+		job.Status = BatchJobComplete
+	}
+	return true
+}
+
+// resumable checks if the unfinished session is being uploaded or resumed
+// after the daemon restarts
+func (q *BatchQueue) resumable(ssId string) bool {
+	if activeSessions.Has(ssId) {
+		return true
+	}
+	ok, err := hasSessionParams(q.ctxParams, ssId)
+	return err != nil || ok
+}
+
+func (q *BatchQueue) cancelSession(ssId string) {
+	if ssId == "" {
+		return
+	}
+	go func() {
+		if err := AbortSession(q.ctxParams, ssId, errBatchJobCanceled); err != nil {
+			log.Debugf("failed to abort session %s: %v", ssId, err)
+		}
+	}()
+}
+
+// saveNewJob saves the job with all of its items in one batch
+func (q *BatchQueue) saveNewJob(job *BatchJob) error {
+	batch, err := q.ctxParams.N.Repo.Datastore().Batch(q.ctxParams.Ctx)
+	if err != nil {
+		return err
+	}
+	for i := range job.Items {
+		if err := q.putItem(batch, job, i); err != nil {
+			return err
+		}
+	}
+	if err := q.putJob(batch, job); err != nil {
+		return err
+	}
+	return batch.Commit(q.ctxParams.Ctx)
+}
+
+// saveJob saves the job header and the items of the indexes only
+func (q *BatchQueue) saveJob(job *BatchJob, indexes ...int) error {
+	d := q.ctxParams.N.Repo.Datastore()
+	for _, i := range indexes {
+		if err := q.putItem(d, job, i); err != nil {
+			return err
+		}
+	}
+	return q.putJob(d, job)
+}
+
+func (q *BatchQueue) putJob(w datastore.Write, job *BatchJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(batchJobKey, q.ctxParams.N.Identity.String(), job.ID)
+	return w.Put(q.ctxParams.Ctx, datastore.NewKey(key), data)
+}
+
+func (q *BatchQueue) putItem(w datastore.Write, job *BatchJob, index int) error {
+	data, err := json.Marshal(job.Items[index])
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(batchItemsKey, q.ctxParams.N.Identity.String(), job.ID, index)
+	return w.Put(q.ctxParams.Ctx, datastore.NewKey(key), data)
+}
+
+func batchItemKey(jobID string, index int) string {
+	return fmt.Sprintf("%s/%d", jobID, index)
+}
+
+func copyBatchJob(job *BatchJob) *BatchJob {
+	c := *job
+	c.Items = make([]*BatchItem, 0, len(job.Items))
+	for _, item := range job.Items {
+		i := *item
+		c.Items = append(c.Items, &i)
+	}
+	return &c
+}
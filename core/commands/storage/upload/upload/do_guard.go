@@ -17,20 +17,13 @@ func addFileMetaToBttcChainAndPay(rss *sessions.RenterSession, fileSize int64, o
 	if err := rss.To(sessions.RssToContractEvent); err != nil {
 		return err
 	}
+	return signAndAddFileMeta(rss, fileSize, offlineSigning)
+}
 
-	as := make([]*metadata.Contract, 0)
-	for i, h := range rss.ShardHashes {
-		shard, err := sessions.GetUserShard(rss.CtxParams, rss.SsId, h, i)
-		if err != nil {
-			return err
-		}
-		contract, err := shard.Contracts()
-		if err != nil {
-			return err
-		}
-		as = append(as, contract)
-	}
-	meta, err := NewFileStatus(as, rss.CtxParams.Cfg, as[0].Meta.UserId, rss.Hash, fileSize)
+// signAndAddFileMeta signs the file meta of the contracted shards and adds it
+// to the chain, the session goes on from contract status
+func signAndAddFileMeta(rss *sessions.RenterSession, fileSize int64, offlineSigning bool) error {
+	meta, err := sessionFileMeta(rss, fileSize)
 	if err != nil {
 		return err
 	}
@@ -67,22 +60,61 @@ func addFileMetaToBttcChainAndPay(rss *sessions.RenterSession, fileSize int64, o
 			}
 		}()
 	}
-	<-cb
+	select {
+	case <-cb:
+	case <-rss.Ctx.Done():
+		uh.FileMetaChanMaps.Remove(rss.SsId)
+		return rss.Ctx.Err()
+	}
 	uh.FileMetaChanMaps.Remove(rss.SsId)
 	if err := rss.To(sessions.RssToContractFileMetaSignedEvent); err != nil {
 		return err
 	}
-	// fsStatus.RenterSignature = signBytes
-	err = chain.SettleObject.FileMetaService.AddFileMeta(rss.Hash, meta)
-	if err != nil {
-		return err
+	return addFileMeta(rss, meta, offlineSigning)
+}
+
+// addFileMeta adds the file meta to the chain unless it has been added before
+// the session is resumed, the session goes on from file-meta-signed status
+func addFileMeta(rss *sessions.RenterSession, meta *metadata.FileMetaInfo, offlineSigning bool) error {
+	if !fileMetaAdded(rss, meta) {
+		// fsStatus.RenterSignature = signBytes
+		err := chain.SettleObject.FileMetaService.AddFileMeta(rss.Hash, meta)
+		if err != nil {
+			return err
+		}
 	}
-	err = rss.To(sessions.RssToContractFileMetaAddedEvent)
+	err := rss.To(sessions.RssToContractFileMetaAddedEvent)
 	if err != nil {
 		return err
 	}
 
-	return waitSPSaveFileSuccAndToPay(rss, offlineSigning, meta, false)
+	return waitSPSaveFileSuccAndToPay(rss, offlineSigning, meta)
+}
+
+// sessionFileMeta builds the file meta from the contracts of the shards
+func sessionFileMeta(rss *sessions.RenterSession, fileSize int64) (*metadata.FileMetaInfo, error) {
+	as := make([]*metadata.Contract, 0)
+	for i, h := range rss.ShardHashes {
+		shard, err := sessions.GetUserShard(rss.CtxParams, rss.SsId, h, i)
+		if err != nil {
+			return nil, err
+		}
+		contract, err := shard.Contracts()
+		if err != nil {
+			return nil, err
+		}
+		as = append(as, contract)
+	}
+	return NewFileStatus(as, rss.CtxParams.Cfg, as[0].Meta.UserId, rss.Hash, fileSize)
+}
+
+func fileMetaAdded(rss *sessions.RenterSession, meta *metadata.FileMetaInfo) bool {
+	var contracts []string
+	for _, c := range meta.Contracts {
+		contracts = append(contracts, c.Meta.ContractId)
+	}
+	added, err := chain.SettleObject.FileMetaService.GetFileMeta(rss.Hash, contracts)
+	return err == nil && len(added.Contracts) > 0
 }
 
 func NewFileStatus(contracts []*metadata.Contract, configuration *config.Config,
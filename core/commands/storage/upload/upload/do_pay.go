@@ -1,6 +1,7 @@
 package upload
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -9,14 +10,34 @@ import (
 
 	"github.com/bittorrent/go-btfs/chain"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/settlement/swap/vault"
 )
 
+// payInCheque pays the hosts of the shards, the ones paid before the session is
+// resumed are skipped and the interrupted payments whose cheques were sent are
+// marked paid instead of being sent again
 func payInCheque(rss *sessions.RenterSession) error {
 	for i, hash := range rss.ShardHashes {
+		if err := rss.Ctx.Err(); err != nil {
+			return err
+		}
 		shard, err := sessions.GetUserShard(rss.CtxParams, rss.SsId, hash, i)
 		if err != nil {
 			return err
 		}
+		paid, err := shard.Paid()
+		if err != nil {
+			return err
+		}
+		if !paid {
+			paid, err = reconcilePayment(shard)
+			if err != nil {
+				return err
+			}
+		}
+		if paid {
+			continue
+		}
 		c, err := shard.Contracts()
 		if err != nil {
 			return err
@@ -33,16 +54,62 @@ func payInCheque(rss *sessions.RenterSession) error {
 		contractId := c.Meta.ContractId
 		fmt.Printf("send cheque: paying...  host:%v, amount:%v, contractId:%v, token:%v. \n", host, realAmount.String(), contractId, rss.Token.String())
 
+		err = shard.SetPaying(&sessions.ShardPayment{
+			ContractId: contractId,
+			Host:       host,
+			Amount:     realAmount,
+			Token:      rss.Token,
+			StartedAt:  time.Now().Unix(),
+		})
+		if err != nil {
+			return err
+		}
 		err = chain.SettleObject.SwapService.Settle(host, realAmount, contractId, rss.Token)
 		if err != nil {
 			return err
 		}
+		err = shard.SetPaid(contractId)
+		if err != nil {
+			return err
+		}
 		time.Sleep(500 * time.Millisecond)
 	}
 
 	return nil
 }
 
+// reconcilePayment marks the shard paid if the cheque of its interrupted
+// payment was sent, so the host is not paid twice
+func reconcilePayment(shard *sessions.UserShard) (bool, error) {
+	payment, err := shard.Paying()
+	if err != nil || payment == nil {
+		return false, err
+	}
+	records, err := chain.SettleObject.SwapService.SendChequeRecordsByPeer(payment.Host)
+	if errors.Is(err, vault.ErrNoCheque) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !chequeSent(records, payment) {
+		return false, nil
+	}
+	return true, shard.SetPaid(payment.ContractId)
+}
+
+// chequeSent checks if a cheque of the payment is in the sent cheque records,
+// the records are not kept per contract so it is matched by amount and time
+func chequeSent(records []vault.ChequeRecord, payment *sessions.ShardPayment) bool {
+	for _, r := range records {
+		if r.Token == payment.Token && r.Amount != nil && r.Amount.Cmp(payment.Amount) == 0 &&
+			r.ReceiveTime >= payment.StartedAt {
+			return true
+		}
+	}
+	return false
+}
+
 func getRealAmount(amount int64, token common.Address) (*big.Int, error) {
 	// this is price's rate [Compatible with older versions]
 	rateObj, err := chain.SettleObject.OracleService.CurrentRate(token)
@@ -0,0 +1,33 @@
+package upload
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/settlement/swap/vault"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChequeSent(t *testing.T) {
+	token := common.HexToAddress("0x1")
+	payment := &sessions.ShardPayment{
+		ContractId: "c1",
+		Host:       "host",
+		Amount:     big.NewInt(100),
+		Token:      token,
+		StartedAt:  1000,
+	}
+	record := func(token common.Address, amount int64, at int64) vault.ChequeRecord {
+		return vault.ChequeRecord{Token: token, Amount: big.NewInt(amount), ReceiveTime: at}
+	}
+
+	assert.False(t, chequeSent(nil, payment))
+	assert.True(t, chequeSent([]vault.ChequeRecord{record(token, 100, 1000)}, payment))
+	// sent before the payment started
+	assert.False(t, chequeSent([]vault.ChequeRecord{record(token, 100, 999)}, payment))
+	assert.False(t, chequeSent([]vault.ChequeRecord{record(token, 50, 1001)}, payment))
+	assert.False(t, chequeSent([]vault.ChequeRecord{record(common.HexToAddress("0x2"), 100, 1001)}, payment))
+}
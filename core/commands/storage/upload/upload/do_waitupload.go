@@ -24,19 +24,11 @@ func getSuccessThreshold(totalShards int) int {
 	return int(math.Min(float64(totalShards), thresholdContractsNums))
 }
 
-func ResumeWaitUploadOnSigning(rss *sessions.RenterSession) error {
-	// return waitUpload(rss, false, &guardpb.FileStoreStatus{
-	// FileStoreMeta: guardpb.FileStoreMeta{
-	// RenterPid: rss.CtxParams.N.Identity.String(),
-	// FileSize:  math.MaxInt64,
-	// },
-	// }, true)
-	return nil
-}
-
-func waitSPSaveFileSuccAndToPay(rss *sessions.RenterSession, offlineSigning bool, fsStatus *metadata.FileMetaInfo, resume bool) error {
+// waitSPSaveFileSuccAndToPay waits for the hosts storing the shards and pays
+// them, the session goes on from file-meta-added or any wait-upload status
+func waitSPSaveFileSuccAndToPay(rss *sessions.RenterSession, offlineSigning bool, fsStatus *metadata.FileMetaInfo) error {
 	threshold := getSuccessThreshold(len(rss.ShardHashes))
-	if !resume {
+	if rss.Status() == sessions.RssFileMetaAddedStatus {
 		if err := rss.To(sessions.RssToWaitUploadEvent); err != nil {
 			return err
 		}
@@ -76,7 +68,7 @@ func waitSPSaveFileSuccAndToPay(rss *sessions.RenterSession, offlineSigning bool
 	// }
 	// sign := <-cb
 	helper.WaitUploadChanMap.Remove(rss.SsId)
-	if !resume {
+	if rss.Status() == sessions.RssWaitUploadStatus {
 		if err := rss.To(sessions.RssToWaitUploadReqSignedEvent); err != nil {
 			return err
 		}
@@ -129,15 +121,22 @@ func waitSPSaveFileSuccAndToPay(rss *sessions.RenterSession, offlineSigning bool
 			return nil
 		}
 		return errors.New("uploading")
-	}, helper.WaitUploadBo(highRetry))
+	}, backoff.WithContext(helper.WaitUploadBo(highRetry), rss.Ctx))
 	if err != nil {
 		return err
 	}
+	return payAndComplete(rss)
+}
 
-	// pay in cheque
-	if err := rss.To(sessions.RssToPayEvent); err != nil {
-		return err
+// payAndComplete pays the hosts in cheque and completes the session, the
+// session goes on from wait-upload:req-signed or pay status
+func payAndComplete(rss *sessions.RenterSession) error {
+	if rss.Status() == sessions.RssWaitUploadReqSignedStatus {
+		if err := rss.To(sessions.RssToPayEvent); err != nil {
+			return err
+		}
 	}
+	var err error
 	var errC = make(chan error)
 	go func() {
 		err = func() error {
@@ -151,7 +150,7 @@ func waitSPSaveFileSuccAndToPay(rss *sessions.RenterSession, offlineSigning bool
 	}()
 	err = <-errC
 	if err != nil {
-		if fsmErr := rss.To(sessions.RssToErrorEvent); fsmErr != nil {
+		if fsmErr := rss.To(sessions.RssToErrorEvent, err); fsmErr != nil {
 			log.Errorf("fsm transfer error:%v", fsmErr)
 		}
 		log.Errorf("payInCheque error:%v", err)
@@ -1,7 +1,15 @@
 package upload
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
 	"github.com/bittorrent/go-btfs/core/commands/storage/contracts"
+	"github.com/bittorrent/go-btfs/core/commands/storage/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/protos/metadata"
 
 	guardpb "github.com/bittorrent/go-btfs-common/protos/guard"
 	"github.com/bittorrent/go-btfs-common/protos/node"
@@ -60,3 +68,124 @@ func (h *Count) Count(ds datastore.Datastore, peerId string, status guardpb.Cont
 	}
 	return c, nil
 }
+
+const (
+	RejectContractLimit    = "contract-limit"
+	RejectMinPrice         = "min-price"
+	RejectMaxShardSize     = "max-shard-size"
+	RejectMaxRenterBytes   = "max-renter-bytes"
+	RejectRenterNotAllowed = "renter-not-allowed"
+	RejectRenterDenied     = "renter-denied"
+	RejectMinStorageLength = "min-storage-length"
+	RejectFreeSpace        = "free-space-reserve"
+)
+
+var contractRejectionRegexp = regexp.MustCompile(`contract rejected \[([a-z-]+)\]`)
+
+// ContractRejection is the reason of a host rejecting the contract, it is
+// returned to the renter as the error of the upload init call.
+type ContractRejection struct {
+	Reason  string
+	Message string
+}
+
+func (r *ContractRejection) Error() string {
+	return fmt.Sprintf("contract rejected [%s]: %s", r.Reason, r.Message)
+}
+
+// ParseContractRejection gets the rejection from the error returned by the host
+func ParseContractRejection(err error) (*ContractRejection, bool) {
+	if err == nil {
+		return nil, false
+	}
+	var r *ContractRejection
+	if errors.As(err, &r) {
+		return r, true
+	}
+	m := contractRejectionRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil, false
+	}
+	return &ContractRejection{Reason: m[1], Message: err.Error()}, true
+}
+
+// ContractRequest is the contract offered by the renter to this host
+type ContractRequest struct {
+	Renter        string
+	Token         string
+	Price         uint64
+	ShardSize     uint64
+	StorageLength uint64
+}
+
+// HostUsage is the storage of this host used by the policy checks
+type HostUsage struct {
+	// total size of the shards of the renter still being stored
+	RenterBytes uint64
+	// free space of the storage path
+	DiskFree uint64
+}
+
+// CheckContractPolicy checks the contract against the acceptance policy, a
+// rejection is returned if any rule of the policy is not met.
+func CheckContractPolicy(p *helper.ContractPolicy, req *ContractRequest, usage *HostUsage) error {
+	for _, id := range p.RenterDenyList {
+		if id == req.Renter {
+			return &ContractRejection{RejectRenterDenied, "renter is denied by host"}
+		}
+	}
+	if len(p.RenterAllowList) > 0 {
+		allowed := false
+		for _, id := range p.RenterAllowList {
+			allowed = allowed || id == req.Renter
+		}
+		if !allowed {
+			return &ContractRejection{RejectRenterNotAllowed, "renter is not in the allow list of host"}
+		}
+	}
+	if min, ok := p.MinPrice[req.Token]; ok && req.Price < min {
+		return &ContractRejection{RejectMinPrice,
+			fmt.Sprintf("price %d is less than min price %d of token %s", req.Price, min, req.Token)}
+	}
+	if p.MinStorageLength > 0 && req.StorageLength < p.MinStorageLength {
+		return &ContractRejection{RejectMinStorageLength,
+			fmt.Sprintf("storage length %d is less than %d days", req.StorageLength, p.MinStorageLength)}
+	}
+	maxShardSize, maxRenterBytes, freeSpaceReserve, err := p.Sizes()
+	if err != nil {
+		return err
+	}
+	if maxShardSize > 0 && req.ShardSize > maxShardSize {
+		return &ContractRejection{RejectMaxShardSize,
+			fmt.Sprintf("shard size %d is more than %d bytes", req.ShardSize, maxShardSize)}
+	}
+	if maxRenterBytes > 0 && usage.RenterBytes+req.ShardSize > maxRenterBytes {
+		return &ContractRejection{RejectMaxRenterBytes,
+			fmt.Sprintf("renter has stored %d bytes, no more than %d bytes is accepted", usage.RenterBytes, maxRenterBytes)}
+	}
+	if freeSpaceReserve > 0 && usage.DiskFree < req.ShardSize+freeSpaceReserve {
+		return &ContractRejection{RejectFreeSpace, "not enough free space on host"}
+	}
+	return nil
+}
+
+// RenterBytes sums the size of the shards of the renter stored by this host,
+// the closed and expired contracts are not counted.
+func RenterBytes(ds datastore.Datastore, hostId string, renterId string) (uint64, error) {
+	cs, err := sessions.ListShardsContracts(ds, hostId, node.ContractStat_HOST.String())
+	if err != nil {
+		return 0, err
+	}
+	now := uint64(time.Now().Unix())
+	var total uint64
+	for _, c := range cs {
+		if c.Meta == nil || c.Meta.UserId != renterId || c.Status == metadata.Contract_CLOSED {
+			continue
+		}
+		if c.Meta.StorageEnd > 0 && c.Meta.StorageEnd < now {
+			continue
+		}
+		total += c.Meta.ShardSize
+	}
+	return total, nil
+}
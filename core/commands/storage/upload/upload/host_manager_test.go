@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/bittorrent/go-btfs/core/commands/storage/helper"
 	coremock "github.com/bittorrent/go-btfs/core/mock"
 
 	guardpb "github.com/bittorrent/go-btfs-common/protos/guard"
@@ -68,3 +69,65 @@ func TestAcceptContract(t *testing.T) {
 		assert.Equal(t, tc.want, accept)
 	}
 }
+
+func TestCheckContractPolicy(t *testing.T) {
+	policy := &helper.ContractPolicy{
+		MinPrice:         map[string]uint64{"WBTT": 1000},
+		MaxShardSize:     "1MB",
+		MaxRenterBytes:   "3MB",
+		RenterDenyList:   []string{"bad"},
+		MinStorageLength: 30,
+		FreeSpaceReserve: "10MB",
+	}
+	ok := func() *ContractRequest {
+		return &ContractRequest{Renter: "good", Token: "WBTT", Price: 1000, ShardSize: 1000000, StorageLength: 30}
+	}
+	tests := []struct {
+		name   string
+		req    func(r *ContractRequest)
+		usage  HostUsage
+		reason string
+	}{
+		{name: "accept", usage: HostUsage{RenterBytes: 1000000, DiskFree: 20000000}},
+		{name: "denied", req: func(r *ContractRequest) { r.Renter = "bad" }, usage: HostUsage{DiskFree: 20000000}, reason: RejectRenterDenied},
+		{name: "price", req: func(r *ContractRequest) { r.Price = 999 }, usage: HostUsage{DiskFree: 20000000}, reason: RejectMinPrice},
+		{name: "other token", req: func(r *ContractRequest) { r.Token, r.Price = "TRX", 1 }, usage: HostUsage{DiskFree: 20000000}},
+		{name: "length", req: func(r *ContractRequest) { r.StorageLength = 29 }, usage: HostUsage{DiskFree: 20000000}, reason: RejectMinStorageLength},
+		{name: "shard size", req: func(r *ContractRequest) { r.ShardSize = 1000001 }, usage: HostUsage{DiskFree: 20000000}, reason: RejectMaxShardSize},
+		{name: "renter bytes", usage: HostUsage{RenterBytes: 2500000, DiskFree: 20000000}, reason: RejectMaxRenterBytes},
+		{name: "free space", usage: HostUsage{DiskFree: 10500000}, reason: RejectFreeSpace},
+	}
+	for _, tc := range tests {
+		req := ok()
+		if tc.req != nil {
+			tc.req(req)
+		}
+		err := CheckContractPolicy(policy, req, &tc.usage)
+		if tc.reason == "" {
+			assert.NoError(t, err, tc.name)
+			continue
+		}
+		r, isRejection := ParseContractRejection(err)
+		if assert.True(t, isRejection, tc.name) {
+			assert.Equal(t, tc.reason, r.Reason, tc.name)
+		}
+	}
+
+	allow := &helper.ContractPolicy{RenterAllowList: []string{"good"}}
+	assert.NoError(t, CheckContractPolicy(allow, ok(), &HostUsage{}))
+	req := ok()
+	req.Renter = "other"
+	r, _ := ParseContractRejection(CheckContractPolicy(allow, req, &HostUsage{}))
+	assert.Equal(t, RejectRenterNotAllowed, r.Reason)
+}
+
+func TestParseContractRejection(t *testing.T) {
+	err := &ContractRejection{Reason: RejectMinPrice, Message: "price too low"}
+	r, ok := ParseContractRejection(errors.New("remote call failed: " + err.Error()))
+	assert.True(t, ok)
+	assert.Equal(t, RejectMinPrice, r.Reason)
+	_, ok = ParseContractRejection(errors.New("host timeout"))
+	assert.False(t, ok)
+	_, ok = ParseContractRejection(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,67 @@
+package upload
+
+import (
+	"time"
+
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/protos/metadata"
+
+	nodepb "github.com/bittorrent/go-btfs-common/protos/node"
+)
+
+// contracts started within this period are still being stored, they are not
+// counted into the success rates of hosts
+const hostSuccessRateSettle = 24 * time.Hour
+
+// getHostSelection parses the host select strategy and constraints of the
+// upload options, nil is returned if neither is given
+func getHostSelection(ctxParams *helper.ContextParams, opts *UploadOptions) (*helper.HostSelection, error) {
+	name, constraints := opts.HostSelectStrategy, opts.HostConstraints
+	if name == "" && constraints == "" {
+		return nil, nil
+	}
+	selection := &helper.HostSelection{}
+	if name != "" {
+		strategy, err := helper.GetHostSelectStrategy(name)
+		if err != nil {
+			return nil, err
+		}
+		selection.Strategy = strategy
+	}
+	cs, err := helper.ParseHostConstraints(constraints, hostSuccessRates(ctxParams))
+	if err != nil {
+		return nil, err
+	}
+	selection.Constraints = cs
+	return selection, nil
+}
+
+// hostSuccessRates loads the rates of the past contracts of this renter which
+// have been completed by the hosts
+func hostSuccessRates(ctxParams *helper.ContextParams) helper.HostSuccessRates {
+	return func() (map[string]float64, error) {
+		contracts, err := sessions.ListShardsContracts(ctxParams.N.Repo.Datastore(), ctxParams.N.Identity.String(),
+			nodepb.ContractStat_RENTER.String())
+		if err != nil {
+			return nil, err
+		}
+		settled := uint64(time.Now().Add(-hostSuccessRateSettle).Unix())
+		total := make(map[string]int)
+		completed := make(map[string]int)
+		for _, c := range contracts {
+			if c.Meta == nil || c.Meta.StorageStart > settled {
+				continue
+			}
+			total[c.Meta.SpId]++
+			if c.Status == metadata.Contract_COMPLETED {
+				completed[c.Meta.SpId]++
+			}
+		}
+		rates := make(map[string]float64, len(total))
+		for host, n := range total {
+			rates[host] = float64(completed[host]) / float64(n)
+		}
+		return rates, nil
+	}
+}
@@ -0,0 +1,191 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	coreiface "github.com/bittorrent/interface-go-btfs-core"
+)
+
+var StorageUploadQuoteCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Quote the cost of storing a file without uploading it.",
+		ShortDescription: `
+This command resolves the shards of the file, picks the hosts the same way as
+'btfs storage upload', and prints the cost of every shard and the total cost in
+the chosen token, with whether the vault balance is sufficient.
+No contract is sent to the hosts and no fund is moved.
+
+    $ btfs storage upload quote <file-hash> -len=60 -tk=WBTT`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("file-hash", true, false, "Hash of file to quote."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(hostSelectModeOptionName, "m", "Based on this mode to select hosts. Default: mode set in config option Experimental.HostsSyncMode."),
+		cmds.StringOption(hostSelectionOptionName, "s", "Use only these selected hosts in order on 'custom' mode. Use ',' as delimiter."),
+		cmds.StringOption(hostSelectStrategyOptionName, "st", "Order of trying hosts: score, cheapest, lowest-latency or diverse. Default: random."),
+		cmds.StringOption(hostConstraintsOptionName, "hc", "Constraints of selecting hosts. Use ',' as delimiter."),
+		cmds.IntOption(storageLengthOptionName, "len", "File storage period on hosts in days.").WithDefault(defaultStorageLength),
+		cmds.IntOption(copyName, "copy num of file hash.").WithDefault(0),
+		cmds.StringOption(tokencfg.TokenTypeName, "tk", "file storage with token type,default WBTT, other TRX/USDD/USDT.").WithDefault("WBTT"),
+	},
+	RunTimeout: 5 * time.Minute,
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		if !nd.IsOnline {
+			return coreiface.ErrOffline
+		}
+		err = utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		ctxParams, err := helper.ExtractContextParams(req, env)
+		if err != nil {
+			return err
+		}
+
+		opts := uploadOptionsFromRequest(req)
+		tokenStr := opts.Token
+		token, ok := tokencfg.MpTokenAddr[tokenStr]
+		if !ok {
+			return errors.New("your input token is none. ")
+		}
+
+		fileHash := req.Arguments[0]
+		shardHashes, fileSize, shardSize, err := getShardHashes(ctxParams, fileHash, opts.Copy)
+		if err != nil {
+			return err
+		}
+		storageLength := opts.StorageLength
+		err = helper.CheckStorageLength(ctxParams, storageLength)
+		if err != nil {
+			return err
+		}
+
+		// Price and rate of the token
+		priceObj, err := chain.SettleObject.OracleService.CurrentPrice(token)
+		if err != nil {
+			return err
+		}
+		price := priceObj.Int64()
+		rate, err := chain.SettleObject.OracleService.CurrentRate(token)
+		if err != nil {
+			return err
+		}
+		onePay, err := helper.TotalPay(shardSize, price, storageLength, rate)
+		if err != nil {
+			return err
+		}
+		shardCost := new(big.Int).Mul(big.NewInt(onePay), rate)
+
+		// Candidate hosts
+		selection, err := getHostSelection(ctxParams, opts)
+		if err != nil {
+			return err
+		}
+		if !ctxParams.Cfg.Experimental.HostsSyncEnabled {
+			_ = SyncSPs(ctxParams)
+		}
+		sp, err := getHostsProvider(ctxParams, opts, shardHashes, selection)
+		if err != nil {
+			return err
+		}
+
+		quote := &QuoteRes{
+			FileHash:      fileHash,
+			FileSize:      fileSize,
+			ShardSize:     shardSize,
+			Token:         tokenStr,
+			Price:         price,
+			Rate:          rate.String(),
+			StorageLength: storageLength,
+			Shards:        make([]*QuoteShard, 0, len(shardHashes)),
+		}
+		total := new(big.Int)
+		for i, h := range shardHashes {
+			shard := &QuoteShard{
+				Index:     i,
+				ShardHash: h,
+				Cost:      shardCost.String(),
+			}
+			host, err := sp.NextValidHost()
+			if err != nil {
+				shard.Error = err.Error()
+			} else {
+				shard.Host = host
+			}
+			quote.Shards = append(quote.Shards, shard)
+			total.Add(total, shardCost)
+		}
+		quote.TotalCost = total.String()
+
+		// Vault balance
+		balance, err := chain.SettleObject.VaultService.AvailableBalance(req.Context, token)
+		if err != nil {
+			return err
+		}
+		quote.VaultBalance = balance.String()
+		quote.Sufficient = balance.Cmp(total) >= 0
+
+		return cmds.EmitOnce(res, quote)
+	},
+	Type: QuoteRes{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *QuoteRes) error {
+			fmt.Fprintf(w, "File: %s (%d bytes), %d shards of %d bytes\n", out.FileHash, out.FileSize, len(out.Shards), out.ShardSize)
+			fmt.Fprintf(w, "Token: %s, price: %d per GiB per day, rate: %s, storage length: %d days\n\n",
+				out.Token, out.Price, out.Rate, out.StorageLength)
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "INDEX\tSHARD\tHOST\tCOST")
+			for _, s := range out.Shards {
+				host := s.Host
+				if host == "" {
+					host = "<none: " + s.Error + ">"
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Index, s.ShardHash, host, s.Cost)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "\nTotal cost: %s\nVault balance: %s\nSufficient: %t\n", out.TotalCost, out.VaultBalance, out.Sufficient)
+			return nil
+		}),
+	},
+}
+
+type QuoteRes struct {
+	FileHash      string
+	FileSize      int64
+	ShardSize     int64
+	Token         string
+	Price         int64
+	Rate          string
+	StorageLength int
+	Shards        []*QuoteShard
+	TotalCost     string
+	VaultBalance  string
+	Sufficient    bool
+}
+
+type QuoteShard struct {
+	Index     int
+	ShardHash string
+	Host      string
+	Error     string `json:",omitempty"`
+	Cost      string
+}
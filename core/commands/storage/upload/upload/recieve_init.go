@@ -18,12 +18,14 @@ import (
 	"github.com/libp2p/go-libp2p/core/peer"
 
 	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
 	"github.com/bittorrent/go-btfs/core/commands/rm"
 	"github.com/bittorrent/go-btfs/core/commands/storage/challenge"
 	"github.com/bittorrent/go-btfs/core/commands/storage/helper"
 	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
 	"github.com/bittorrent/go-btfs/core/corehttp/remote"
+	"github.com/bittorrent/go-btfs/repo/datadirs"
 
 	cmds "github.com/bittorrent/go-btfs-cmds"
 	"github.com/bittorrent/go-btfs-common/crypto"
@@ -33,6 +35,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	cidlib "github.com/ipfs/go-cid"
 	ic "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/shirou/gopsutil/v3/disk"
 )
 
 var StorageUploadInitCmd = &cmds.Command{
@@ -100,7 +103,7 @@ the shard and replies back to client for the next challenge step.`,
 			return err
 		}
 		if !accept {
-			return errors.New("too many initialized contracts")
+			return rejectContract(ctxParams, &ContractRejection{RejectContractLimit, "too many initialized contracts"})
 		}
 		_, err = strconv.ParseInt(req.Arguments[3], 10, 64)
 		if err != nil {
@@ -165,6 +168,10 @@ the shard and replies back to client for the next challenge step.`,
 		if !ok || err != nil {
 			return fmt.Errorf("can't verify guard contract: %v", err)
 		}
+		err = checkContractPolicy(ctxParams, env, contractMeta, uint64(storeLen))
+		if err != nil {
+			return err
+		}
 
 		signedContract, err := signContract(contractMeta, halfSignedContract, ctxParams.N.PrivateKey)
 		if err != nil {
@@ -441,3 +448,58 @@ func getInputPriceAmountRate(ctxParams *uh.ContextParams, contractId string) (in
 
 	return shard.GetInputPrice(), shard.GetInputAmount(), shard.GetInputRate(), nil
 }
+
+// checkContractPolicy checks the contract offered by the renter against the
+// acceptance policy of this host
+func checkContractPolicy(ctxParams *uh.ContextParams, env cmds.Environment, meta *metadata.ContractMeta,
+	storageLength uint64) error {
+	policy, err := helper.GetContractPolicy(ctxParams.Ctx, ctxParams.N)
+	if err != nil {
+		return err
+	}
+	usage := &HostUsage{}
+	if policy.MaxRenterBytes != "" {
+		usage.RenterBytes, err = RenterBytes(ctxParams.N.Repo.Datastore(), ctxParams.N.Identity.String(), meta.UserId)
+		if err != nil {
+			return err
+		}
+	}
+	if policy.FreeSpaceReserve != "" {
+		cfgRoot, err := cmdenv.GetConfigRoot(env)
+		if err != nil {
+			return err
+		}
+		du, err := disk.UsageWithContext(ctxParams.Ctx, cfgRoot)
+		if err != nil {
+			return err
+		}
+		usage.DiskFree = du.Free
+		// the new blocks may be placed in the data directories added to the repo
+		if dirs, err := datadirs.Current(); err == nil {
+			free, err := dirs.ExtraFree(ctxParams.Ctx)
+			if err != nil {
+				return err
+			}
+			usage.DiskFree += free
+		}
+	}
+	err = CheckContractPolicy(policy, &ContractRequest{
+		Renter:        meta.UserId,
+		Token:         tokencfg.MpTokenStr[common.HexToAddress(meta.Token)],
+		Price:         meta.Price,
+		ShardSize:     meta.ShardSize,
+		StorageLength: storageLength,
+	}, usage)
+	if r, ok := err.(*ContractRejection); ok {
+		return rejectContract(ctxParams, r)
+	}
+	return err
+}
+
+// rejectContract counts the rejection into the storage stats of this host
+func rejectContract(ctxParams *uh.ContextParams, r *ContractRejection) error {
+	if err := helper.AddContractRejection(ctxParams.Ctx, ctxParams.N, r.Reason); err != nil {
+		log.Errorf("failed to count contract rejection %s: %v", r.Reason, err)
+	}
+	return r
+}
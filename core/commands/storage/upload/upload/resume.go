@@ -0,0 +1,343 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p/core/peer"
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+var (
+	ErrSessionRunning      = errors.New("upload session is running")
+	ErrSessionNotResumable = errors.New("upload session cannot be resumed, its upload params are not found")
+	ErrRepairInterrupted   = errors.New("repair session cannot be resumed, the shards are repaired again by the next round")
+	errSessionAborted      = errors.New("upload session aborted")
+
+	// sessions being uploaded by this daemon
+	activeSessions = cmap.New()
+)
+
+// sessionParams are the params of an upload session, they are saved along with
+// the session to resume it after the daemon restarts
+type sessionParams struct {
+	Options        *UploadOptions `json:"options"`
+	RenterId       string         `json:"renter_id"`
+	OfflineSigning bool           `json:"offline_signing"`
+	Price          int64          `json:"price"`
+	ShardSize      int64          `json:"shard_size"`
+	FileSize       int64          `json:"file_size"`
+	ShardIndexes   []int          `json:"shard_indexes"`
+	TotalPay       *big.Int       `json:"total_pay"`
+}
+
+var StorageUploadResumeCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Resume an interrupted upload session.",
+		ShortDescription: `
+This command continues the upload session from its current status. The shards
+contracted and the hosts paid already are not sent or paid again. The daemon
+resumes all unfinished sessions on startup, this command is for resuming the
+session manually.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("session-id", true, false, "ID for the entire storage upload session."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		ctxParams, err := helper.ExtractContextParams(req, env)
+		if err != nil {
+			return err
+		}
+		if !ctxParams.Cfg.Experimental.StorageClientEnabled {
+			return fmt.Errorf("storage client api not enabled")
+		}
+		err = ResumeSession(ctxParams, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		return res.Emit(&Res{ID: req.Arguments[0]})
+	},
+	Type: Res{},
+}
+
+var StorageUploadAbortCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Abort an upload session.",
+		ShortDescription: `
+This command stops the upload session, closes the contracts of the shards whose
+hosts have not been paid and keeps the session from being resumed. The hosts
+paid already are not affected.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("session-id", true, false, "ID for the entire storage upload session."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		ctxParams, err := helper.ExtractContextParams(req, env)
+		if err != nil {
+			return err
+		}
+		if !ctxParams.Cfg.Experimental.StorageClientEnabled {
+			return fmt.Errorf("storage client api not enabled")
+		}
+		err = AbortSession(ctxParams, req.Arguments[0], errSessionAborted)
+		if err != nil {
+			return err
+		}
+		return res.Emit(&Res{ID: req.Arguments[0]})
+	},
+	Type: Res{},
+}
+
+// ResumeSession continues the upload session from its current status in the
+// background
+func ResumeSession(ctxParams *helper.ContextParams, ssId string) error {
+	rss, err := getSession(ctxParams, ssId)
+	if err != nil {
+		return err
+	}
+	switch rss.Status() {
+	case sessions.RssCompleteStatus, sessions.RssErrorStatus:
+		return fmt.Errorf("upload session %s is %s", ssId, rss.Status())
+	}
+	repaired, err := getRepairSession(ctxParams, ssId)
+	if err != nil {
+		return err
+	}
+	if repaired != nil {
+		if activeSessions.Has(ssId) {
+			return ErrSessionRunning
+		}
+		return ErrRepairInterrupted
+	}
+	params, err := getSessionParams(ctxParams, ssId)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return ErrSessionNotResumable
+	}
+	if err != nil {
+		return err
+	}
+	token, ok := tokencfg.MpTokenAddr[params.Options.Token]
+	if !ok {
+		return fmt.Errorf("invalid token: %s", params.Options.Token)
+	}
+	rss.Token = token
+	ctx, err := newShardUploadContext(rss, params)
+	if err != nil {
+		return err
+	}
+
+	if rss.Status() == sessions.RssInitStatus {
+		selection, err := getHostSelection(ctxParams, params.Options)
+		if err != nil {
+			return err
+		}
+		ctx.HostsProvider, err = getHostsProvider(ctxParams, params.Options, rss.ShardHashes, selection)
+		if err != nil {
+			return err
+		}
+		return UploadShard(ctx)
+	}
+	if !startSession(ssId) {
+		return ErrSessionRunning
+	}
+	go func() {
+		defer endSession(ssId)
+		completeUpload(ctx)
+	}()
+	return nil
+}
+
+// AbortSession stops the upload session, closes the contracts of the shards
+// not paid yet and removes the params of resuming the session
+func AbortSession(ctxParams *helper.ContextParams, ssId string, reason error) error {
+	rss, err := getSession(ctxParams, ssId)
+	if err != nil {
+		return err
+	}
+	switch rss.Status() {
+	case sessions.RssCompleteStatus:
+		return fmt.Errorf("upload session %s is complete", ssId)
+	case sessions.RssErrorStatus:
+		// stopped already, release what is left below
+	default:
+		if err := rss.To(sessions.RssToErrorEvent, reason); err != nil {
+			return err
+		}
+	}
+	helper.FileMetaChanMaps.Remove(ssId)
+	helper.WaitUploadChanMap.Remove(ssId)
+	// a payment in flight is finished before the unpaid shards are found out
+	if err := waitSession(ctxParams.Ctx, ssId); err != nil {
+		return err
+	}
+	repaired, err := getRepairSession(ctxParams, ssId)
+	if err != nil {
+		return err
+	}
+	for i, h := range rss.ShardHashes {
+		index := i
+		if repaired != nil && i < len(repaired.ShardIndexes) {
+			index = repaired.ShardIndexes[i]
+		}
+		shard, err := sessions.GetUserShard(ctxParams, ssId, h, index)
+		if err != nil {
+			return err
+		}
+		paid, err := shard.Paid()
+		if err != nil {
+			return err
+		}
+		if !paid {
+			paid, err = reconcilePayment(shard)
+			if err != nil {
+				return err
+			}
+		}
+		if paid {
+			continue
+		}
+		if err := shard.CloseContract(); err != nil {
+			return err
+		}
+	}
+	return deleteSessionParams(ctxParams, ssId)
+}
+
+// continueSession goes on with the contracted session from its current status
+// until it is complete
+func continueSession(rss *sessions.RenterSession, fileSize int64, offlineSigning bool) error {
+	switch status := rss.Status(); status {
+	case sessions.RssSubmitStatus:
+		return SubmitToChain(rss, fileSize, offlineSigning)
+	case sessions.RssContractStatus:
+		return signAndAddFileMeta(rss, fileSize, offlineSigning)
+	case sessions.RssGuardFileMetaSignedStatus:
+		meta, err := sessionFileMeta(rss, fileSize)
+		if err != nil {
+			return err
+		}
+		return addFileMeta(rss, meta, offlineSigning)
+	case sessions.RssFileMetaAddedStatus, sessions.RssWaitUploadStatus, sessions.RssWaitUploadReqSignedStatus:
+		meta, err := sessionFileMeta(rss, fileSize)
+		if err != nil {
+			return err
+		}
+		return waitSPSaveFileSuccAndToPay(rss, offlineSigning, meta)
+	case sessions.RssPayStatus:
+		return payAndComplete(rss)
+	case sessions.RssCompleteStatus:
+		return nil
+	default:
+		return fmt.Errorf("upload session in %s status cannot be continued", status)
+	}
+}
+
+func getSession(ctxParams *helper.ContextParams, ssId string) (*sessions.RenterSession, error) {
+	ok, err := sessions.HasRenterSession(ctxParams, ssId)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("upload session %s not found", ssId)
+	}
+	return sessions.GetRenterSession(ctxParams, ssId, "", make([]string, 0))
+}
+
+func newShardUploadContext(rss *sessions.RenterSession, params *sessionParams) (*ShardUploadContext, error) {
+	renterId, err := peer.Decode(params.RenterId)
+	if err != nil {
+		return nil, err
+	}
+	return &ShardUploadContext{
+		Rss:            rss,
+		Price:          params.Price,
+		Token:          rss.Token,
+		ShardSize:      params.ShardSize,
+		StorageLength:  params.Options.StorageLength,
+		OfflineSigning: params.OfflineSigning,
+		RenterId:       renterId,
+		FileSize:       params.FileSize,
+		ShardIndexes:   params.ShardIndexes,
+		RepairParams:   nil,
+		AutoRenewal:    params.Options.AutoRenew,
+		TotalPay:       params.TotalPay,
+	}, nil
+}
+
+// startSession marks the session being uploaded, it returns false if the
+// session is being uploaded already
+func startSession(ssId string) bool {
+	return activeSessions.SetIfAbsent(ssId, make(chan struct{}))
+}
+
+func endSession(ssId string) {
+	if done, ok := activeSessions.Pop(ssId); ok {
+		close(done.(chan struct{}))
+	}
+}
+
+// waitSession waits until the session being uploaded by this daemon stops
+func waitSession(ctx context.Context, ssId string) error {
+	done, ok := activeSessions.Get(ssId)
+	if !ok {
+		return nil
+	}
+	select {
+	case <-done.(chan struct{}):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func saveSessionParams(ctxParams *helper.ContextParams, ssId string, params *sessionParams) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(sessions.RenterSessionUploadParamsKey, ctxParams.N.Identity.String(), ssId)
+	return ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, datastore.NewKey(key), data)
+}
+
+func getSessionParams(ctxParams *helper.ContextParams, ssId string) (*sessionParams, error) {
+	key := fmt.Sprintf(sessions.RenterSessionUploadParamsKey, ctxParams.N.Identity.String(), ssId)
+	data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+	if err != nil {
+		return nil, err
+	}
+	params := new(sessionParams)
+	err = json.Unmarshal(data, params)
+	if err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+func hasSessionParams(ctxParams *helper.ContextParams, ssId string) (bool, error) {
+	key := fmt.Sprintf(sessions.RenterSessionUploadParamsKey, ctxParams.N.Identity.String(), ssId)
+	return ctxParams.N.Repo.Datastore().Has(ctxParams.Ctx, datastore.NewKey(key))
+}
+
+func deleteSessionParams(ctxParams *helper.ContextParams, ssId string) error {
+	key := fmt.Sprintf(sessions.RenterSessionUploadParamsKey, ctxParams.N.Identity.String(), ssId)
+	return ctxParams.N.Repo.Datastore().Delete(ctxParams.Ctx, datastore.NewKey(key))
+}
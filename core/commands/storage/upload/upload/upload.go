@@ -40,6 +40,8 @@ const (
 	replicationFactorOptionName      = "replication-factor"
 	hostSelectModeOptionName         = "host-select-mode"
 	hostSelectionOptionName          = "host-selection"
+	hostSelectStrategyOptionName     = "host-select-strategy"
+	hostConstraintsOptionName        = "host-constraints"
 	testOnlyOptionName               = "host-search-local"
 	customizedPayoutOptionName       = "customize-payout"
 	customizedPayoutPeriodOptionName = "customize-payout-period"
@@ -88,8 +90,25 @@ To custom upload and storage a file on specific hosts:
     # Total # of hosts (N) must match # of shards given
     $ btfs storage upload <shard-hash1> <shard-hash2> ... <shard-hashN> -l -m=custom -s=<host1-peer-id>,<host2-peer-id>,...,<hostN-peer-id>
 
+To steer the hosts selected automatically:
+    Use --host-select-strategy with one of 'score', 'cheapest', 'lowest-latency' or 'diverse',
+    and --host-constraints with the constraints separated by ',':
+        max-price=<µBTT>             ask price per GiB per day no more than the price
+        max-latency=<duration>       latency to this node no more than the duration, e.g. 200ms
+        region=<region>|<region>     hosts in the regions or countries
+        min-success-rate=<0~1>       rate of the past contracts completed by the host
+        one-per-subnet               no more than one shard per /24 (IPv4) or /48 (IPv6)
+        one-per-operator             no more than one shard per host vault
+
+    $ btfs storage upload <file-hash> --host-select-strategy=diverse --host-constraints=max-price=500,one-per-subnet
+
 Use status command to check for completion:
-    $ btfs storage upload status <session-id> | jq`,
+    $ btfs storage upload status <session-id> | jq
+
+The unfinished sessions are resumed after the daemon restarts, use resume or abort
+command to resume or abort a session manually:
+    $ btfs storage upload resume <session-id>
+    $ btfs storage upload abort <session-id>`,
 	},
 	Subcommands: map[string]*cmds.Command{
 		"init":              StorageUploadInitCmd,
@@ -97,6 +116,10 @@ Use status command to check for completion:
 		"cheque":            StorageUploadChequeCmd,
 		"recvcontract":      StorageUploadRecvContractCmd,
 		"status":            StorageUploadStatusCmd,
+		"resume":            StorageUploadResumeCmd,
+		"abort":             StorageUploadAbortCmd,
+		"quote":             StorageUploadQuoteCmd,
+		"batch":             StorageUploadBatchCmd,
 		"repair":            StorageUploadRepairCmd,
 		"renew":             renewal.StorageRenewCmd,
 		"getcontractbatch":  offline.StorageUploadGetContractBatchCmd,
@@ -116,6 +139,8 @@ Use status command to check for completion:
 		cmds.IntOption(replicationFactorOptionName, "r", "Replication factor for the file with erasure coding built-in.").WithDefault(defaultRepFactor),
 		cmds.StringOption(hostSelectModeOptionName, "m", "Based on this mode to select hosts and upload automatically. Default: mode set in config option Experimental.HostsSyncMode."),
 		cmds.StringOption(hostSelectionOptionName, "s", "Use only these selected hosts in order on 'custom' mode. Use ',' as delimiter."),
+		cmds.StringOption(hostSelectStrategyOptionName, "st", "Order of trying hosts: score, cheapest, lowest-latency or diverse. Default: random."),
+		cmds.StringOption(hostConstraintsOptionName, "hc", "Constraints of selecting hosts. Use ',' as delimiter."),
 		cmds.BoolOption(testOnlyOptionName, "t", "Enable host search under all domains 0.0.0.0 (useful for local test)."),
 		cmds.IntOption(storageLengthOptionName, "len", "File storage period on hosts in days.").WithDefault(defaultStorageLength),
 		cmds.BoolOption(customizedPayoutOptionName, "Enable file storage customized payout schedule.").WithDefault(false),
@@ -145,7 +170,6 @@ Use status command to check for completion:
 		swapprotocol.Req = req
 		swapprotocol.Env = env
 
-		ssId := uuid.New().String()
 		ctxParams, err := helper.ExtractContextParams(req, env)
 		if err != nil {
 			return err
@@ -197,119 +221,23 @@ Use status command to check for completion:
 			return nil
 		}, helper.WaitingForPeersBo)
 
-		var shardHashes []string
-		var fileSize int64
-		var shardSize int64
-
-		// token: parse token argument
-		tokenStr := req.Options[tokencfg.TokenTypeName].(string)
-		token, bl := tokencfg.MpTokenAddr[tokenStr]
-		if !bl {
-			return errors.New("your input token is none. ")
-		}
-		fmt.Println("token =", token, tokenStr)
-
-		fileHash := req.Arguments[0]
-		shardHashes, fileSize, shardSize, err = helper.GetShardHashes(ctxParams, fileHash)
-
-		if len(shardHashes) == 0 && fileSize == -1 && shardSize == -1 &&
-			strings.HasPrefix(err.Error(), "invalid hash: file must be reed-solomon encoded") {
-			if copyNum, ok := req.Options[copyName].(int); ok {
-				shardHashes, fileSize, shardSize, err = helper.GetShardHashesCopy(ctxParams, fileHash, copyNum)
-				fmt.Printf("copy get, shardHashes:%v fileSize:%v, shardSize:%v, copy:%v err:%v \n",
-					shardHashes, fileSize, shardSize, copyNum, err)
-			}
-		}
-		if err != nil {
-			return err
-		}
-		_, storageLength, err := helper.GetPriceAndMinStorageLength(ctxParams)
-		if err != nil {
-			return err
-		}
-
-		// token: get new price
-		priceObj, err := chain.SettleObject.OracleService.CurrentPrice(token)
-		if err != nil {
-			return err
-		}
-		price := priceObj.Int64()
-		// token: get new rate
-		rate, err := chain.SettleObject.OracleService.CurrentRate(token)
-		if err != nil {
-			return err
-		}
-		totalPay, err := helper.TotalPay(shardSize, price, storageLength, rate)
-		if err != nil {
-			fmt.Println(err.Error())
-			return err
-		}
-
-		// sync sps from hub.
-		if !ctxParams.Cfg.Experimental.HostsSyncEnabled {
-			_ = SyncSPs(ctxParams)
-		}
-
-		sp := helper.GetSPsProvider(ctxParams, make([]string, 0))
-
-		if mode, ok := req.Options[hostSelectModeOptionName].(string); ok {
-			var hostIDs []string
-			if mode == "custom" {
-				if hosts, ok := req.Options[hostSelectionOptionName].(string); ok {
-					hostIDs = strings.Split(hosts, ",")
-				}
-				if len(hostIDs) != len(shardHashes) {
-					return fmt.Errorf("custom mode hosts length must match shard hashes length")
-				}
-				sp = helper.GetCustomizedSPProvider(ctxParams, hostIDs)
-			}
-		}
-
-		rss, err := sessions.GetUserSessionWithToken(ctxParams, ssId, fileHash, shardHashes, token)
-		if err != nil {
-			return err
-		}
-
 		// v4.0 TODO offlineSignature
+		var offlineMeta *renterpb.OfflineMeta
 		if offlineSigning {
 			offNonceTimestamp, err := strconv.ParseUint(req.Arguments[2], 10, 64)
 			if err != nil {
 				return err
 			}
-			err = rss.SaveOfflineMeta(&renterpb.OfflineMeta{
+			offlineMeta = &renterpb.OfflineMeta{
 				OfflinePeerId:    req.Arguments[1],
 				OfflineNonceTs:   offNonceTimestamp,
 				OfflineSignature: req.Arguments[3],
-			})
-			if err != nil {
-				return err
 			}
 		}
 
-		shardIndexes := make([]int, 0)
-		for i := range rss.ShardHashes {
-			shardIndexes = append(shardIndexes, i)
-		}
-
-		// Check for auto-renewal option
-		autoRenew, _ := req.Options[autoRenewOptionName].(bool)
-		// autoRenewDuration, _ := req.Options["autorenew-duration"].(int)
-
-		err = UploadShard(&ShardUploadContext{
-			Rss:            rss,
-			HostsProvider:  sp,
-			Price:          price,
-			Token:          token,
-			ShardSize:      shardSize,
-			StorageLength:  storageLength,
-			OfflineSigning: offlineSigning,
-			RenterId:       renterId,
-			FileSize:       fileSize,
-			ShardIndexes:   shardIndexes,
-			RepairParams:   nil,
-			AutoRenewal:    autoRenew,
-			TotalPay:       new(big.Int).Mul(big.NewInt(totalPay), rate),
-		})
+		opts := uploadOptionsFromRequest(req)
+		fmt.Println("token =", tokencfg.MpTokenAddr[opts.Token], opts.Token)
+		ssId, err := startUpload(ctxParams, req.Arguments[0], opts, renterId, offlineMeta)
 		if err != nil {
 			return err
 		}
@@ -322,6 +250,172 @@ Use status command to check for completion:
 	Type: Res{},
 }
 
+// UploadOptions are the options of starting an upload session, they are kept
+// along with the queued batch uploads to start the sessions later
+type UploadOptions struct {
+	Token              string `json:"token"`
+	StorageLength      int    `json:"storage_length"`
+	Copy               int    `json:"copy"`
+	AutoRenew          bool   `json:"auto_renew"`
+	HostSelectMode     string `json:"host_select_mode,omitempty"`
+	HostSelection      string `json:"host_selection,omitempty"`
+	HostSelectStrategy string `json:"host_select_strategy,omitempty"`
+	HostConstraints    string `json:"host_constraints,omitempty"`
+}
+
+func uploadOptionsFromRequest(req *cmds.Request) *UploadOptions {
+	opts := &UploadOptions{}
+	opts.Token, _ = req.Options[tokencfg.TokenTypeName].(string)
+	opts.StorageLength, _ = req.Options[storageLengthOptionName].(int)
+	opts.Copy, _ = req.Options[copyName].(int)
+	opts.AutoRenew, _ = req.Options[autoRenewOptionName].(bool)
+	opts.HostSelectMode, _ = req.Options[hostSelectModeOptionName].(string)
+	opts.HostSelection, _ = req.Options[hostSelectionOptionName].(string)
+	opts.HostSelectStrategy, _ = req.Options[hostSelectStrategyOptionName].(string)
+	opts.HostConstraints, _ = req.Options[hostConstraintsOptionName].(string)
+	return opts
+}
+
+// StartUpload starts a new upload session of the file with this node as the
+// renter, and returns the session id once the shards are being sent to hosts.
+func StartUpload(ctxParams *helper.ContextParams, fileHash string, opts *UploadOptions) (string, error) {
+	return startUpload(ctxParams, fileHash, opts, ctxParams.N.Identity, nil)
+}
+
+func startUpload(ctxParams *helper.ContextParams, fileHash string, opts *UploadOptions,
+	renterId peer.ID, offlineMeta *renterpb.OfflineMeta) (string, error) {
+	// token: parse token argument
+	token, ok := tokencfg.MpTokenAddr[opts.Token]
+	if !ok {
+		return "", errors.New("your input token is none. ")
+	}
+
+	shardHashes, fileSize, shardSize, err := getShardHashes(ctxParams, fileHash, opts.Copy)
+	if err != nil {
+		return "", err
+	}
+	err = helper.CheckStorageLength(ctxParams, opts.StorageLength)
+	if err != nil {
+		return "", err
+	}
+
+	// token: get new price
+	priceObj, err := chain.SettleObject.OracleService.CurrentPrice(token)
+	if err != nil {
+		return "", err
+	}
+	price := priceObj.Int64()
+	// token: get new rate
+	rate, err := chain.SettleObject.OracleService.CurrentRate(token)
+	if err != nil {
+		return "", err
+	}
+	totalPay, err := helper.TotalPay(shardSize, price, opts.StorageLength, rate)
+	if err != nil {
+		fmt.Println(err.Error())
+		return "", err
+	}
+	cost := new(big.Int).Mul(big.NewInt(totalPay), rate)
+	cost.Mul(cost, big.NewInt(int64(len(shardHashes))))
+	err = checkVaultBalance(ctxParams, token, cost)
+	if err != nil {
+		return "", err
+	}
+
+	selection, err := getHostSelection(ctxParams, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// sync sps from hub.
+	if !ctxParams.Cfg.Experimental.HostsSyncEnabled {
+		_ = SyncSPs(ctxParams)
+	}
+
+	sp, err := getHostsProvider(ctxParams, opts, shardHashes, selection)
+	if err != nil {
+		return "", err
+	}
+
+	ssId := uuid.New().String()
+	rss, err := sessions.GetUserSessionWithToken(ctxParams, ssId, fileHash, shardHashes, token)
+	if err != nil {
+		return "", err
+	}
+
+	if offlineMeta != nil {
+		err = rss.SaveOfflineMeta(offlineMeta)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	shardIndexes := make([]int, 0)
+	for i := range rss.ShardHashes {
+		shardIndexes = append(shardIndexes, i)
+	}
+
+	params := &sessionParams{
+		Options:        opts,
+		RenterId:       renterId.String(),
+		OfflineSigning: offlineMeta != nil,
+		Price:          price,
+		ShardSize:      shardSize,
+		FileSize:       fileSize,
+		ShardIndexes:   shardIndexes,
+		TotalPay:       new(big.Int).Mul(big.NewInt(totalPay), rate),
+	}
+	err = saveSessionParams(ctxParams, ssId, params)
+	if err != nil {
+		return "", err
+	}
+	ctx, err := newShardUploadContext(rss, params)
+	if err != nil {
+		return "", err
+	}
+	ctx.HostsProvider = sp
+	err = UploadShard(ctx)
+	if err != nil {
+		// never resume the session failed to start
+		_ = rss.To(sessions.RssToErrorEvent, err)
+		_ = deleteSessionParams(ctxParams, ssId)
+		return "", err
+	}
+	return ssId, nil
+}
+
+// getShardHashes resolves the shards of the reed-solomon encoded file, or the
+// copies of the file if it is not encoded
+func getShardHashes(ctxParams *helper.ContextParams, fileHash string, copyNum int) (shardHashes []string,
+	fileSize int64, shardSize int64, err error) {
+	shardHashes, fileSize, shardSize, err = helper.GetShardHashes(ctxParams, fileHash)
+
+	if len(shardHashes) == 0 && fileSize == -1 && shardSize == -1 &&
+		strings.HasPrefix(err.Error(), "invalid hash: file must be reed-solomon encoded") {
+		shardHashes, fileSize, shardSize, err = helper.GetShardHashesCopy(ctxParams, fileHash, copyNum)
+		fmt.Printf("copy get, shardHashes:%v fileSize:%v, shardSize:%v, copy:%v err:%v \n",
+			shardHashes, fileSize, shardSize, copyNum, err)
+	}
+	return
+}
+
+// getHostsProvider returns the provider of the hosts given on 'custom' mode, or
+// the hosts selected automatically
+func getHostsProvider(ctxParams *helper.ContextParams, opts *UploadOptions, shardHashes []string,
+	selection *helper.HostSelection) (helper.IHostsProvider, error) {
+	if opts.HostSelectMode == "custom" {
+		var hostIDs []string
+		if opts.HostSelection != "" {
+			hostIDs = strings.Split(opts.HostSelection, ",")
+		}
+		if len(hostIDs) != len(shardHashes) {
+			return nil, fmt.Errorf("custom mode hosts length must match shard hashes length")
+		}
+		return helper.GetCustomizedSPProvider(ctxParams, hostIDs), nil
+	}
+	return helper.GetSPsProviderWithSelection(ctxParams, make([]string, 0), selection), nil
+}
+
 func SyncSPs(ctxParams *helper.ContextParams) error {
 	cfg, err := ctxParams.N.Repo.Config()
 	if err != nil {
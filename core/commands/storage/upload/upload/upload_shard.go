@@ -10,6 +10,7 @@ import (
 
 	"github.com/bittorrent/go-btfs/chain"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/renewal"
+	"github.com/bittorrent/go-btfs/metrics"
 	"github.com/bittorrent/go-btfs/protos/metadata"
 	"github.com/ethereum/go-ethereum/common"
 
@@ -21,6 +22,22 @@ import (
 	"github.com/libp2p/go-libp2p/core/peer"
 )
 
+// reasons of the failed shard uploads besides the contract rejections
+const (
+	shardFailNoHost           = "no-host"
+	shardFailHostUnreachable  = "host-unreachable"
+	shardFailTokenUnsupported = "token-unsupported"
+	shardFailHostTimeout      = "host-timeout"
+	shardFailSign             = "sign-contract"
+	shardFailOther            = "other"
+)
+
+var (
+	errTokenUnsupported = errors.New("host does not support token")
+	errHostTimeout      = errors.New("host timeout")
+	errSignContract     = errors.New("failed to sign contract")
+)
+
 type ShardUploadContext struct {
 	Rss            *sessions.RenterSession
 	HostsProvider  helper.IHostsProvider
@@ -38,17 +55,26 @@ type ShardUploadContext struct {
 }
 
 func UploadShard(ctx *ShardUploadContext) error {
+	if !startSession(ctx.Rss.SsId) {
+		return ErrSessionRunning
+	}
 	expectOnePay, err := checkAndPreparePayment(ctx)
 	if err != nil {
+		endSession(ctx.Rss.SsId)
 		return err
 	}
 	for i, shardHash := range ctx.Rss.ShardHashes {
 		h := shardHash
 		index := i
+		// the shards contracted before the session is resumed are kept
+		if contracted, _ := shardContracted(ctx.Rss, h, ctx.ShardIndexes[index]); contracted {
+			continue
+		}
 		go sendShardContractToHost(ctx, ctx.ShardIndexes[index], h, expectOnePay)
 	}
 
 	go func() {
+		defer endSession(ctx.Rss.SsId)
 		isComplete, err := waitForAllShardsComplete(ctx)
 		if err != nil {
 			log.Errorf("wait for all shards complete error: %s", err.Error())
@@ -61,55 +87,72 @@ func UploadShard(ctx *ShardUploadContext) error {
 				log.Errorf("set rss status from init to submit error: %s", err.Error())
 				return
 			}
-			err := SubmitToChain(ctx.Rss, ctx.FileSize, ctx.OfflineSigning)
-			if err != nil {
-				_ = ctx.Rss.To(sessions.RssToErrorEvent, err)
-				return
-			}
-
-			// save auto-renewal info
-			shardsInfo := make([]*renewal.RenewalShardInfo, 0)
-			for i, shard := range ctx.Rss.ShardHashes {
-				shards, err := sessions.GetUserShard(ctx.Rss.CtxParams, ctx.Rss.SsId, shard, i)
-				if err != nil {
-					log.Errorf("get user shard error: %s", err.Error())
-					continue
-				}
-				contracts, err := shards.Contracts()
-				if err != nil {
-					log.Errorf("get contracts error: %s", err.Error())
-					continue
-				}
-				si := &renewal.RenewalShardInfo{
-					SPId:       contracts.Meta.SpId,
-					ShardId:    contracts.Meta.ShardHash,
-					ShardSize:  int(contracts.Meta.ShardSize),
-					ContractID: contracts.Meta.ContractId,
-				}
-				shardsInfo = append(shardsInfo, si)
-			}
-			info := &renewal.RenewalInfo{
-				CID:             ctx.Rss.Hash,
-				RenewalDuration: ctx.StorageLength,
-				Token:           ctx.Token,
-				Price:           ctx.Price,
-				Enabled:         ctx.AutoRenewal,
-				CreatedAt:       time.Now(),
-				LastRenewalAt:   nil,
-				NextRenewalAt:   time.Now().Add(time.Duration(ctx.StorageLength) * 24 * time.Hour),
-				ShardsInfo:      shardsInfo,
-				TotalPay:        ctx.TotalPay,
-			}
-			err = renewal.StoreRenewalInfo(ctx.Rss.CtxParams, info, renewal.RenewTypeAuto)
-			if err != nil {
-				log.Errorf("Failed to store auto-renewal config: %v", err)
-				return
-			}
+			completeUpload(ctx)
 		}
 	}()
 	return nil
 }
 
+// completeUpload submits the contracted shards to the chain, pays the hosts
+// and saves the auto-renewal info, the session goes on from its current status
+func completeUpload(ctx *ShardUploadContext) {
+	err := continueSession(ctx.Rss, ctx.FileSize, ctx.OfflineSigning)
+	if err != nil {
+		_ = ctx.Rss.To(sessions.RssToErrorEvent, err)
+		return
+	}
+	if err := deleteSessionParams(ctx.Rss.CtxParams, ctx.Rss.SsId); err != nil {
+		log.Errorf("Failed to delete params of session %s: %v", ctx.Rss.SsId, err)
+	}
+
+	// save auto-renewal info
+	shardsInfo := make([]*renewal.RenewalShardInfo, 0)
+	for i, shard := range ctx.Rss.ShardHashes {
+		shards, err := sessions.GetUserShard(ctx.Rss.CtxParams, ctx.Rss.SsId, shard, i)
+		if err != nil {
+			log.Errorf("get user shard error: %s", err.Error())
+			continue
+		}
+		contracts, err := shards.Contracts()
+		if err != nil {
+			log.Errorf("get contracts error: %s", err.Error())
+			continue
+		}
+		si := &renewal.RenewalShardInfo{
+			SPId:       contracts.Meta.SpId,
+			ShardId:    contracts.Meta.ShardHash,
+			ShardSize:  int(contracts.Meta.ShardSize),
+			ContractID: contracts.Meta.ContractId,
+		}
+		shardsInfo = append(shardsInfo, si)
+	}
+	info := &renewal.RenewalInfo{
+		CID:             ctx.Rss.Hash,
+		RenewalDuration: ctx.StorageLength,
+		Token:           ctx.Token,
+		Price:           ctx.Price,
+		Enabled:         ctx.AutoRenewal,
+		CreatedAt:       time.Now(),
+		LastRenewalAt:   nil,
+		NextRenewalAt:   time.Now().Add(time.Duration(ctx.StorageLength) * 24 * time.Hour),
+		ShardsInfo:      shardsInfo,
+		TotalPay:        ctx.TotalPay,
+	}
+	err = renewal.StoreRenewalInfo(ctx.Rss.CtxParams, info, renewal.RenewTypeAuto)
+	if err != nil {
+		log.Errorf("Failed to store auto-renewal config: %v", err)
+		return
+	}
+}
+
+func shardContracted(rss *sessions.RenterSession, shardHash string, index int) (bool, error) {
+	shard, err := sessions.GetUserShard(rss.CtxParams, rss.SsId, shardHash, index)
+	if err != nil {
+		return false, err
+	}
+	return shard.Contracted()
+}
+
 func checkAndPreparePayment(ctx *ShardUploadContext) (int64, error) {
 	rate, err := chain.SettleObject.OracleService.CurrentRate(ctx.Token)
 	if err != nil {
@@ -146,6 +189,7 @@ func sendShardContract(ctx *ShardUploadContext, shardIndex int, shardHash string
 	}
 	host, err := ctx.HostsProvider.NextValidHost()
 	if err != nil {
+		metrics.ShardUploadFailures.WithLabelValues(shardFailNoHost).Inc()
 		terr := ctx.Rss.To(sessions.RssToErrorEvent, err)
 		if terr != nil {
 			log.Debugf("original err: %s, transition err: %s", err.Error(), terr.Error())
@@ -155,12 +199,43 @@ func sendShardContract(ctx *ShardUploadContext, shardIndex int, shardHash string
 	hostPid, err := peer.Decode(host)
 	if err != nil {
 		log.Errorf("shard %s decodes host_pid error: %s", shardHash, err.Error())
+		ctx.HostsProvider.ReleaseHost(host)
 		return err
 	}
 	if err := checkHostTokenSupport(ctx, hostPid); err != nil {
+		ctx.HostsProvider.ReleaseHost(host)
+		reason := shardFailHostUnreachable
+		if errors.Is(err, errTokenUnsupported) {
+			reason = shardFailTokenUnsupported
+		}
+		metrics.ShardUploadFailures.WithLabelValues(reason).Inc()
 		return err
 	}
-	return signShardContractAndSendToSP(ctx, host, hostPid, shardIndex, shardHash, amount)
+	err = signShardContractAndSendToSP(ctx, host, hostPid, shardIndex, shardHash, amount)
+	if err != nil {
+		ctx.HostsProvider.ReleaseHost(host)
+		metrics.ShardUploadFailures.WithLabelValues(shardFailureReason(err)).Inc()
+	}
+	if r, ok := ParseContractRejection(err); ok {
+		log.Infof("shard %s is rejected by host %s for %s", shardHash, host, r.Reason)
+	}
+	return err
+}
+
+// shardFailureReason returns the reason of the failed contract with the host,
+// it is the reason of the rejection if the host rejected the contract
+func shardFailureReason(err error) string {
+	if r, ok := ParseContractRejection(err); ok {
+		return r.Reason
+	}
+	switch {
+	case errors.Is(err, errHostTimeout):
+		return shardFailHostTimeout
+	case errors.Is(err, errSignContract):
+		return shardFailSign
+	default:
+		return shardFailOther
+	}
 }
 
 func checkHostTokenSupport(ctx *ShardUploadContext, hostPid peer.ID) error {
@@ -181,7 +256,7 @@ func checkHostTokenSupport(ctx *ShardUploadContext, hostPid peer.ID) error {
 			return nil
 		}
 	}
-	return errors.New("host does not support token")
+	return errTokenUnsupported
 }
 
 func signShardContractAndSendToSP(ctx *ShardUploadContext, host string, hostPid peer.ID, shardIndex int, shardHash string, amount int64) error {
@@ -215,7 +290,7 @@ func signShardContractAndSendToSP(ctx *ShardUploadContext, host string, hostPid
 			)
 			if err != nil {
 				log.Errorf("shard %s signs guard_contract error: %s", shardHash, err.Error())
-				return err
+				return fmt.Errorf("%w: %v", errSignContract, err)
 			}
 			return nil
 		}()
@@ -249,7 +324,7 @@ func signShardContractAndSendToSP(ctx *ShardUploadContext, host string, hostPid
 		ShardErrChanMap.Remove(contractID)
 		return err
 	case <-ticker.C:
-		return errors.New("host timeout")
+		return errHostTimeout
 	}
 }
 
@@ -0,0 +1,612 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/core/commands/cmdenv"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/renewal"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/utils"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-datastore"
+)
+
+const (
+	warnAtOptionName         = "warn-at"
+	renewalHorizonOptionName = "renewal-horizon"
+	autoTopUpOptionName      = "auto-top-up"
+	topUpTargetOptionName    = "top-up-target"
+	dailyTopUpCapOptionName  = "daily-cap"
+
+	defaultRenewalHorizonDays = 30
+	defaultTopUpTarget        = 200
+
+	// the same warning of a token is repeated after the cooldown
+	vaultGuardWarningCooldown = 24 * time.Hour
+
+	vaultGuardWarningCoverage = "coverage"
+	vaultGuardWarningTopUp    = "top-up"
+)
+
+var (
+	vaultGuardConfigKey = "/btfs/%s/vault-guard"
+	vaultGuardTopUpKey  = "/btfs/%s/vault-guard-topup/%d/%s"
+	// prefix of the top-up keys of all the days
+	vaultGuardTopUpPrefix = "/btfs/%s/vault-guard-topup/"
+	vaultGuardWarningKey  = "/btfs/%s/vault-guard-warning/%s/%s"
+
+	defaultWarnAt = []int{200, 100}
+
+	ErrVaultBalanceLow = errors.New("vault balance is too low")
+
+	vaultGuardLock sync.Mutex
+)
+
+// VaultGuardConfig is when the vault balance is warned and topped up, the
+// coverage is the percent of the commitments the available balance covers
+type VaultGuardConfig struct {
+	// warn when the coverage drops below any of the percents
+	WarnAt []int `json:"warn_at"`
+	// auto-renewals due within the days are committed
+	RenewalHorizonDays int  `json:"renewal_horizon_days"`
+	AutoTopUp          bool `json:"auto_top_up"`
+	// deposit from the wallet up to the coverage percent
+	TopUpTarget int `json:"top_up_target"`
+	// max amount deposited per day by token, tokens without cap are never
+	// topped up
+	DailyTopUpCaps map[string]*big.Int `json:"daily_top_up_caps,omitempty"`
+}
+
+// VaultBalanceStatus is the available balance of a token against its
+// commitments
+type VaultBalanceStatus struct {
+	Token           string   `json:"token"`
+	Available       *big.Int `json:"available"`
+	PendingSessions *big.Int `json:"pending_sessions"`
+	AutoRenewals    *big.Int `json:"auto_renewals"`
+	Commitments     *big.Int `json:"commitments"`
+	// percent, absent without commitments
+	Coverage      *int64   `json:"coverage,omitempty"`
+	Warning       string   `json:"warning,omitempty"`
+	ToppedUpToday *big.Int `json:"topped_up_today"`
+
+	// the coverage percent warned below
+	warnBelow int
+}
+
+// vaultGuardWarning is the last warning sent of a token, the warnings of the
+// same reason are not repeated within the cooldown
+type vaultGuardWarning struct {
+	Reason   string    `json:"reason"`
+	WarnedAt time.Time `json:"warned_at"`
+}
+
+type VaultGuardStatusResponse struct {
+	Balances []*VaultBalanceStatus `json:"balances"`
+}
+
+// VaultGuardCmd shows and configures the vault balance guard
+var VaultGuardCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Guard the vault balance against the storage commitments.",
+		ShortDescription: `
+The vault guard compares the available vault balance of each token with its
+commitments, which are the unpaid shards of the pending upload sessions and the
+auto-renewals due soon. It warns when the coverage drops below the thresholds,
+and deposits from the wallet when the automatic top-up is enabled.
+
+New uploads are refused before any contract is signed if the available balance
+cannot cover them along with the pending sessions.`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"config": VaultGuardConfigCmd,
+		"status": VaultGuardStatusCmd,
+	},
+}
+
+var VaultGuardConfigCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show or set the thresholds and the automatic top-up of the vault guard.",
+		ShortDescription: `
+Coverage is the percent of the commitments covered by the available balance.
+
+Examples:
+    # Warn below 300% and 100% of the commitments
+    $ btfs vault guard config --warn-at=300,100
+
+    # Deposit WBTT up to 200% of the commitments, at most 1000 WBTT per day
+    $ btfs vault guard config --auto-top-up --top-up-target=200 --tk=WBTT --daily-cap=1000000000000000000000
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(warnAtOptionName, "w", "Coverage percents to warn below, separated by ','."),
+		cmds.IntOption(renewalHorizonOptionName, "r", "Commit the auto-renewals due within the days."),
+		cmds.BoolOption(autoTopUpOptionName, "a", "Deposit from the wallet when the coverage is below the target."),
+		cmds.IntOption(topUpTargetOptionName, "t", "Coverage percent the automatic top-up deposits up to."),
+		cmds.StringOption(tokencfg.TokenTypeName, "tk", "token type of the daily cap, default WBTT, other TRX/USDD/USDT.").WithDefault("WBTT"),
+		cmds.StringOption(dailyTopUpCapOptionName, "c", "Max amount of the token deposited per day, 0 disables the top-up of the token."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		_, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		err = utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		ctxParams, err := helper.ExtractContextParams(req, env)
+		if err != nil {
+			return err
+		}
+		cfg, err := getVaultGuardConfig(ctxParams)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		if s, ok := req.Options[warnAtOptionName].(string); ok {
+			warnAt, err := parseWarnAt(s)
+			if err != nil {
+				return err
+			}
+			cfg.WarnAt = warnAt
+			changed = true
+		}
+		if days, ok := req.Options[renewalHorizonOptionName].(int); ok {
+			if days < 0 {
+				return errors.New("renewal horizon must not be negative")
+			}
+			cfg.RenewalHorizonDays = days
+			changed = true
+		}
+		if enabled, ok := req.Options[autoTopUpOptionName].(bool); ok {
+			cfg.AutoTopUp = enabled
+			changed = true
+		}
+		if target, ok := req.Options[topUpTargetOptionName].(int); ok {
+			if target <= 0 {
+				return errors.New("top-up target must be positive")
+			}
+			cfg.TopUpTarget = target
+			changed = true
+		}
+		if s, ok := req.Options[dailyTopUpCapOptionName].(string); ok {
+			tokenStr := req.Options[tokencfg.TokenTypeName].(string)
+			if _, ok := tokencfg.MpTokenAddr[tokenStr]; !ok {
+				return errors.New("your input token is none. ")
+			}
+			amount, ok := new(big.Int).SetString(utils.RemoveSpaceAndComma(s), 10)
+			if !ok || amount.Sign() < 0 {
+				return fmt.Errorf("invalid daily cap: %s", s)
+			}
+			if amount.Sign() == 0 {
+				delete(cfg.DailyTopUpCaps, tokenStr)
+			} else {
+				cfg.DailyTopUpCaps[tokenStr] = amount
+			}
+			changed = true
+		}
+		if changed {
+			err = saveVaultGuardConfig(ctxParams, cfg)
+			if err != nil {
+				return err
+			}
+		}
+		return cmds.EmitOnce(res, cfg)
+	},
+	Type: VaultGuardConfig{},
+}
+
+var VaultGuardStatusCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the available vault balances against the commitments.",
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		_, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+		err = utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		ctxParams, err := helper.ExtractContextParams(req, env)
+		if err != nil {
+			return err
+		}
+		cfg, err := getVaultGuardConfig(ctxParams)
+		if err != nil {
+			return err
+		}
+		balances, err := getVaultBalanceStatuses(ctxParams, cfg)
+		if err != nil {
+			return err
+		}
+		return cmds.EmitOnce(res, &VaultGuardStatusResponse{Balances: balances})
+	},
+	Type: VaultGuardStatusResponse{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *VaultGuardStatusResponse) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "TOKEN\tAVAILABLE\tCOMMITMENTS\tCOVERAGE\tTOPPED UP TODAY\tWARNING")
+			for _, b := range out.Balances {
+				coverage := "-"
+				if b.Coverage != nil {
+					coverage = fmt.Sprintf("%d%%", *b.Coverage)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", b.Token, b.Available, b.Commitments,
+					coverage, b.ToppedUpToday, b.Warning)
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+func parseWarnAt(s string) ([]int, error) {
+	warnAt := make([]int, 0)
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		percent, err := strconv.Atoi(p)
+		if err != nil || percent <= 0 {
+			return nil, fmt.Errorf("invalid coverage percent: %s", p)
+		}
+		warnAt = append(warnAt, percent)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(warnAt)))
+	return warnAt, nil
+}
+
+func getVaultGuardConfig(ctxParams *helper.ContextParams) (*VaultGuardConfig, error) {
+	cfg := &VaultGuardConfig{
+		WarnAt:             defaultWarnAt,
+		RenewalHorizonDays: defaultRenewalHorizonDays,
+		TopUpTarget:        defaultTopUpTarget,
+	}
+	key := fmt.Sprintf(vaultGuardConfigKey, ctxParams.N.Identity.String())
+	data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+	if err != nil && !errors.Is(err, datastore.ErrNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		err = json.Unmarshal(data, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.DailyTopUpCaps == nil {
+		cfg.DailyTopUpCaps = make(map[string]*big.Int)
+	}
+	return cfg, nil
+}
+
+func saveVaultGuardConfig(ctxParams *helper.ContextParams, cfg *VaultGuardConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(vaultGuardConfigKey, ctxParams.N.Identity.String())
+	return ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, datastore.NewKey(key), data)
+}
+
+func getToppedUp(ctxParams *helper.ContextParams, tokenStr string) (*big.Int, error) {
+	key := fmt.Sprintf(vaultGuardTopUpKey, ctxParams.N.Identity.String(), utils.TodayUnix(), tokenStr)
+	data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return new(big.Int), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	amount, ok := new(big.Int).SetString(string(data), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid top-up amount: %s", data)
+	}
+	return amount, nil
+}
+
+// pruneToppedUp deletes the top-up amounts of the days before today
+func pruneToppedUp(ctxParams *helper.ContextParams) error {
+	prefix := fmt.Sprintf(vaultGuardTopUpPrefix, ctxParams.N.Identity.String())
+	keys, err := sessions.ListKeys(ctxParams.N.Repo.Datastore(), prefix)
+	if err != nil {
+		return err
+	}
+	today := fmt.Sprintf("%s%d/", prefix, utils.TodayUnix())
+	for _, k := range keys {
+		if strings.HasPrefix(k, today) {
+			continue
+		}
+		err = ctxParams.N.Repo.Datastore().Delete(ctxParams.Ctx, datastore.NewKey(k))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getVaultGuardWarning(ctxParams *helper.ContextParams, tokenStr, kind string) (*vaultGuardWarning, error) {
+	key := fmt.Sprintf(vaultGuardWarningKey, ctxParams.N.Identity.String(), tokenStr, kind)
+	data, err := ctxParams.N.Repo.Datastore().Get(ctxParams.Ctx, datastore.NewKey(key))
+	if errors.Is(err, datastore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	warning := new(vaultGuardWarning)
+	err = json.Unmarshal(data, warning)
+	if err != nil {
+		return nil, err
+	}
+	return warning, nil
+}
+
+// shouldWarn returns whether the warning of the reason is sent, it is not if
+// the last warning has the same reason and is within the cooldown
+func shouldWarn(last *vaultGuardWarning, reason string, now time.Time) bool {
+	return last == nil || last.Reason != reason || now.Sub(last.WarnedAt) >= vaultGuardWarningCooldown
+}
+
+// warnVault logs the warning of the token unless the same one has been sent
+// within the cooldown, the empty reason clears the last warning of the kind
+func warnVault(ctxParams *helper.ContextParams, tokenStr, kind, reason, msg string) error {
+	key := datastore.NewKey(fmt.Sprintf(vaultGuardWarningKey, ctxParams.N.Identity.String(), tokenStr, kind))
+	if reason == "" {
+		err := ctxParams.N.Repo.Datastore().Delete(ctxParams.Ctx, key)
+		if errors.Is(err, datastore.ErrNotFound) {
+			err = nil
+		}
+		return err
+	}
+	last, err := getVaultGuardWarning(ctxParams, tokenStr, kind)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !shouldWarn(last, reason, now) {
+		return nil
+	}
+	log.Warnf("vault %s: %s", tokenStr, msg)
+	data, err := json.Marshal(&vaultGuardWarning{Reason: reason, WarnedAt: now})
+	if err != nil {
+		return err
+	}
+	return ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, key, data)
+}
+
+func addToppedUp(ctxParams *helper.ContextParams, tokenStr string, amount *big.Int) error {
+	toppedUp, err := getToppedUp(ctxParams, tokenStr)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(vaultGuardTopUpKey, ctxParams.N.Identity.String(), utils.TodayUnix(), tokenStr)
+	return ctxParams.N.Repo.Datastore().Put(ctxParams.Ctx, datastore.NewKey(key),
+		[]byte(toppedUp.Add(toppedUp, amount).String()))
+}
+
+// pendingSessionCommitments returns the amount of the unpaid shards of the
+// unfinished upload sessions by token
+func pendingSessionCommitments(ctxParams *helper.ContextParams) (map[common.Address]*big.Int, error) {
+	prefix := fmt.Sprintf(sessions.RenterSessionPrefix, ctxParams.N.Identity.String())
+	keys, err := sessions.ListKeys(ctxParams.N.Repo.Datastore(), prefix, "/upload-params")
+	if err != nil {
+		return nil, err
+	}
+	commitments := make(map[common.Address]*big.Int)
+	for _, k := range keys {
+		ssId := path.Base(path.Dir(k))
+		params, err := getSessionParams(ctxParams, ssId)
+		if err != nil {
+			log.Warnf("skip the malformed upload params of session %s: %v", ssId, err)
+			continue
+		}
+		token, ok := tokencfg.MpTokenAddr[params.Options.Token]
+		if !ok || params.TotalPay == nil {
+			continue
+		}
+		rss, err := getSession(ctxParams, ssId)
+		if err != nil {
+			continue
+		}
+		if status := rss.Status(); status == sessions.RssCompleteStatus || status == sessions.RssErrorStatus {
+			continue
+		}
+		unpaid := int64(0)
+		for i, h := range rss.ShardHashes {
+			shard, err := sessions.GetUserShard(ctxParams, ssId, h, i)
+			if err != nil {
+				return nil, err
+			}
+			paid, err := shard.Paid()
+			if err != nil {
+				return nil, err
+			}
+			if !paid {
+				unpaid++
+			}
+		}
+		if _, ok := commitments[token]; !ok {
+			commitments[token] = new(big.Int)
+		}
+		commitments[token].Add(commitments[token], new(big.Int).Mul(params.TotalPay, big.NewInt(unpaid)))
+	}
+	return commitments, nil
+}
+
+func getVaultBalanceStatuses(ctxParams *helper.ContextParams, cfg *VaultGuardConfig) ([]*VaultBalanceStatus, error) {
+	pending, err := pendingSessionCommitments(ctxParams)
+	if err != nil {
+		return nil, err
+	}
+	horizon := time.Duration(cfg.RenewalHorizonDays) * 24 * time.Hour
+	renewals, err := renewal.AutoRenewalCommitments(ctxParams, horizon)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]*VaultBalanceStatus, 0, len(tokencfg.MpTokenAddr))
+	for tokenStr, token := range tokencfg.MpTokenAddr {
+		available, err := chain.SettleObject.VaultService.AvailableBalance(ctxParams.Ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		toppedUp, err := getToppedUp(ctxParams, tokenStr)
+		if err != nil {
+			return nil, err
+		}
+		b := &VaultBalanceStatus{
+			Token:           tokenStr,
+			Available:       available,
+			PendingSessions: new(big.Int),
+			AutoRenewals:    new(big.Int),
+			ToppedUpToday:   toppedUp,
+		}
+		if amount, ok := pending[token]; ok {
+			b.PendingSessions = amount
+		}
+		if amount, ok := renewals[token]; ok {
+			b.AutoRenewals = amount
+		}
+		b.Commitments = new(big.Int).Add(b.PendingSessions, b.AutoRenewals)
+		if b.Commitments.Sign() > 0 {
+			coverage := new(big.Int).Div(new(big.Int).Mul(available, big.NewInt(100)), b.Commitments).Int64()
+			b.Coverage = &coverage
+			// the lowest threshold crossed is warned
+			for _, percent := range cfg.WarnAt {
+				if coverage < int64(percent) {
+					b.Warning = fmt.Sprintf("available balance covers %d%% of the commitments, below %d%%",
+						coverage, percent)
+					b.warnBelow = percent
+				}
+			}
+		}
+		balances = append(balances, b)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Token < balances[j].Token
+	})
+	return balances, nil
+}
+
+// checkVaultBalance refuses the upload of the cost if the available balance
+// cannot cover it along with the pending sessions
+func checkVaultBalance(ctxParams *helper.ContextParams, token common.Address, cost *big.Int) error {
+	available, err := chain.SettleObject.VaultService.AvailableBalance(ctxParams.Ctx, token)
+	if err != nil {
+		return err
+	}
+	pending, err := pendingSessionCommitments(ctxParams)
+	if err != nil {
+		return err
+	}
+	required := new(big.Int).Set(cost)
+	if amount, ok := pending[token]; ok {
+		required.Add(required, amount)
+	}
+	if available.Cmp(required) < 0 {
+		return fmt.Errorf("%w: available %s %s cannot cover the upload cost %s and the pending sessions %s, "+
+			"deposit with 'btfs vault deposit' first", ErrVaultBalanceLow, available, tokencfg.MpTokenStr[token],
+			cost, new(big.Int).Sub(required, cost))
+	}
+	return nil
+}
+
+// GuardVaultBalance warns the tokens whose available balance is low against
+// the commitments, and tops them up from the wallet if it is enabled
+func GuardVaultBalance(ctxParams *helper.ContextParams) error {
+	vaultGuardLock.Lock()
+	defer vaultGuardLock.Unlock()
+
+	cfg, err := getVaultGuardConfig(ctxParams)
+	if err != nil {
+		return err
+	}
+	balances, err := getVaultBalanceStatuses(ctxParams, cfg)
+	if err != nil {
+		return err
+	}
+	if err := pruneToppedUp(ctxParams); err != nil {
+		log.Errorf("failed to prune the vault top-up amounts: %v", err)
+	}
+	for _, b := range balances {
+		reason := ""
+		if b.Warning != "" {
+			reason = fmt.Sprintf("below %d%%", b.warnBelow)
+		}
+		if err := warnVault(ctxParams, b.Token, vaultGuardWarningCoverage, reason, b.Warning); err != nil {
+			log.Errorf("failed to warn vault %s: %v", b.Token, err)
+		}
+		if !cfg.AutoTopUp || b.Commitments.Sign() == 0 {
+			continue
+		}
+		err = topUpVault(ctxParams, cfg, b)
+		if err != nil {
+			log.Errorf("failed to top up vault %s: %v", b.Token, err)
+		}
+	}
+	return nil
+}
+
+// topUpVault deposits the token up to the target coverage, within the daily
+// cap and the wallet balance
+func topUpVault(ctxParams *helper.ContextParams, cfg *VaultGuardConfig, b *VaultBalanceStatus) error {
+	capAmount, ok := cfg.DailyTopUpCaps[b.Token]
+	if !ok {
+		return nil
+	}
+	target := new(big.Int).Div(new(big.Int).Mul(b.Commitments, big.NewInt(int64(cfg.TopUpTarget))), big.NewInt(100))
+	amount := new(big.Int).Sub(target, b.Available)
+	if amount.Sign() <= 0 {
+		return nil
+	}
+	remaining := new(big.Int).Sub(capAmount, b.ToppedUpToday)
+	if remaining.Sign() <= 0 {
+		return warnVault(ctxParams, b.Token, vaultGuardWarningTopUp, "cap reached",
+			fmt.Sprintf("daily top-up cap %s reached", capAmount))
+	}
+	if amount.Cmp(remaining) > 0 {
+		amount = remaining
+	}
+	wallet, err := chain.SettleObject.VaultService.TokenBalanceOf(ctxParams.Ctx, chain.ChainObject.OverlayAddress, b.Token)
+	if err != nil {
+		return err
+	}
+	if amount.Cmp(wallet) > 0 {
+		amount = wallet
+	}
+	if amount.Sign() <= 0 {
+		return warnVault(ctxParams, b.Token, vaultGuardWarningTopUp, "no wallet balance",
+			"no wallet balance to top up")
+	}
+	err = warnVault(ctxParams, b.Token, vaultGuardWarningTopUp, "", "")
+	if err != nil {
+		return err
+	}
+
+	hash, err := chain.SettleObject.VaultService.Deposit(ctxParams.Ctx, amount, tokencfg.MpTokenAddr[b.Token])
+	if err != nil {
+		return err
+	}
+	log.Infof("vault %s: topped up %s, transaction %s", b.Token, amount, hash)
+	return addToppedUp(ctxParams, b.Token, amount)
+}
@@ -0,0 +1,261 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/renewal"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	coremock "github.com/bittorrent/go-btfs/core/mock"
+	renterpb "github.com/bittorrent/go-btfs/protos/renter"
+	"github.com/bittorrent/go-btfs/settlement/swap/vault"
+	vaultmock "github.com/bittorrent/go-btfs/settlement/swap/vault/mock"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldWarn(t *testing.T) {
+	now := time.Now()
+	last := &vaultGuardWarning{Reason: "below 100%", WarnedAt: now.Add(-time.Hour)}
+
+	assert.True(t, shouldWarn(nil, "below 100%", now))
+	assert.False(t, shouldWarn(last, "below 100%", now))
+	assert.True(t, shouldWarn(last, "below 50%", now))
+	assert.True(t, shouldWarn(last, "below 100%", now.Add(vaultGuardWarningCooldown)))
+}
+
+type rateOracle struct {
+	rate *big.Int
+}
+
+func (o *rateOracle) CurrentPrice(token common.Address) (*big.Int, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (o *rateOracle) CurrentRate(token common.Address) (*big.Int, error) {
+	return o.rate, nil
+}
+
+func (o *rateOracle) CurrentTotalPrice(token common.Address) (*big.Int, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (o *rateOracle) CheckNewPrice(token common.Address) (*big.Int, error) {
+	return nil, errors.New("not implemented")
+}
+
+// walletVault is the vault mock with the wallet balance
+type walletVault struct {
+	vault.Service
+	wallet    *big.Int
+	deposited []*big.Int
+}
+
+func (v *walletVault) TokenBalanceOf(ctx context.Context, addr common.Address, tokenStr string) (*big.Int, error) {
+	return v.wallet, nil
+}
+
+func (v *walletVault) Deposit(ctx context.Context, amount *big.Int, token common.Address) (common.Hash, error) {
+	v.deposited = append(v.deposited, amount)
+	v.wallet = new(big.Int).Sub(v.wallet, amount)
+	return common.Hash{}, nil
+}
+
+// newVaultGuardParams sets up the node, the vault with the available balance
+// of WBTT, and the token rate of 1
+func newVaultGuardParams(t *testing.T, available *big.Int) *helper.ContextParams {
+	node, err := coremock.NewMockNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tokencfg.MpTokenAddr[tokencfg.WBTT]; !ok {
+		tokencfg.InitToken(0)
+	}
+	settle := chain.SettleObject
+	t.Cleanup(func() {
+		chain.SettleObject = settle
+	})
+	wbtt := tokencfg.MpTokenAddr[tokencfg.WBTT]
+	chain.SettleObject.VaultService = vaultmock.NewVault(
+		vaultmock.WithVaultAvailableBalanceFunc(func(ctx context.Context, token common.Address) (*big.Int, error) {
+			if token == wbtt {
+				return available, nil
+			}
+			return new(big.Int), nil
+		}),
+	)
+	chain.SettleObject.OracleService = &rateOracle{rate: big.NewInt(1)}
+	return &helper.ContextParams{Ctx: context.Background(), N: node}
+}
+
+// addPendingSession saves the session of the shards with the status, the first
+// paid shards of them are marked paid
+func addPendingSession(t *testing.T, ctxParams *helper.ContextParams, ssId string, status string,
+	totalPay int64, shards int, paid int) {
+	hashes := make([]string, 0, shards)
+	for i := 0; i < shards; i++ {
+		hashes = append(hashes, fmt.Sprintf("%s-shard-%d", ssId, i))
+	}
+	err := saveSessionParams(ctxParams, ssId, &sessionParams{
+		Options:  &UploadOptions{Token: tokencfg.WBTT},
+		TotalPay: big.NewInt(totalPay),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sessions.Save(ctxParams.N.Repo.Datastore(),
+		fmt.Sprintf(sessions.RenterSessionStatusKey, ctxParams.N.Identity.String(), ssId),
+		&renterpb.RenterSessionStatus{Status: status, ShardHashes: hashes, LastUpdated: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < paid; i++ {
+		shard, err := sessions.GetUserShard(ctxParams, ssId, hashes[i], i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := shard.SetPaid(fmt.Sprintf("%s-contract-%d", ssId, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func addAutoRenewal(t *testing.T, ctxParams *helper.ContextParams, cid string, enabled bool, due time.Time) {
+	err := renewal.StoreRenewalInfo(ctxParams, &renewal.RenewalInfo{
+		CID: cid,
+		ShardsInfo: []*renewal.RenewalShardInfo{
+			{ShardId: cid + "-0", ShardSize: 1 << 30},
+			{ShardId: cid + "-1", ShardSize: 1 << 30},
+		},
+		RenewalDuration: 30,
+		Token:           tokencfg.MpTokenAddr[tokencfg.WBTT],
+		Price:           10,
+		Enabled:         enabled,
+		NextRenewalAt:   due,
+	}, renewal.RenewTypeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultBalanceCommitments(t *testing.T) {
+	ctxParams := newVaultGuardParams(t, big.NewInt(900))
+	addPendingSession(t, ctxParams, "guard-pending", sessions.RssPayStatus, 100, 3, 1)
+	addPendingSession(t, ctxParams, "guard-complete", sessions.RssCompleteStatus, 100, 3, 0)
+	addPendingSession(t, ctxParams, "guard-error", sessions.RssErrorStatus, 100, 3, 0)
+	now := time.Now()
+	addAutoRenewal(t, ctxParams, "guard-due", true, now.Add(10*24*time.Hour))
+	addAutoRenewal(t, ctxParams, "guard-later", true, now.Add(90*24*time.Hour))
+	addAutoRenewal(t, ctxParams, "guard-disabled", false, now.Add(10*24*time.Hour))
+
+	// 1 GiB for 30 days at the price 10 of each shard
+	renewalCost, err := helper.TotalPay(1<<30, 10, 30, big.NewInt(1))
+	assert.NoError(t, err)
+	renewals := big.NewInt(2 * renewalCost)
+
+	cfg := &VaultGuardConfig{WarnAt: []int{200, 100}, RenewalHorizonDays: 30}
+	balances, err := getVaultBalanceStatuses(ctxParams, cfg)
+	assert.NoError(t, err)
+	var b *VaultBalanceStatus
+	for _, s := range balances {
+		if s.Token == tokencfg.WBTT {
+			b = s
+		}
+	}
+	if b == nil {
+		t.Fatal("no balance of WBTT")
+	}
+
+	// the two unpaid shards of the pending session and the renewal due
+	// within the horizon are committed
+	assert.Equal(t, big.NewInt(200), b.PendingSessions)
+	assert.Equal(t, renewals, b.AutoRenewals)
+	commitments := new(big.Int).Add(big.NewInt(200), renewals)
+	assert.Equal(t, commitments, b.Commitments)
+	coverage := 900 * 100 / commitments.Int64()
+	assert.Equal(t, coverage, *b.Coverage)
+	switch {
+	case coverage < 100:
+		assert.Equal(t, 100, b.warnBelow)
+	case coverage < 200:
+		assert.Equal(t, 200, b.warnBelow)
+	default:
+		assert.Empty(t, b.Warning)
+	}
+
+	// the renewals due later are committed within the longer horizon
+	cfg.RenewalHorizonDays = 120
+	balances, err = getVaultBalanceStatuses(ctxParams, cfg)
+	assert.NoError(t, err)
+	for _, s := range balances {
+		if s.Token == tokencfg.WBTT {
+			assert.Equal(t, new(big.Int).Mul(renewals, big.NewInt(2)), s.AutoRenewals)
+		}
+	}
+}
+
+func TestTopUpVaultDailyCap(t *testing.T) {
+	ctxParams := newVaultGuardParams(t, big.NewInt(100))
+	v := &walletVault{Service: chain.SettleObject.VaultService, wallet: big.NewInt(1000)}
+	chain.SettleObject.VaultService = v
+	cfg := &VaultGuardConfig{
+		TopUpTarget:    200,
+		DailyTopUpCaps: map[string]*big.Int{tokencfg.WBTT: big.NewInt(60)},
+	}
+	status := func() *VaultBalanceStatus {
+		toppedUp, err := getToppedUp(ctxParams, tokencfg.WBTT)
+		assert.NoError(t, err)
+		return &VaultBalanceStatus{
+			Token:         tokencfg.WBTT,
+			Available:     big.NewInt(100),
+			Commitments:   big.NewInt(100),
+			ToppedUpToday: toppedUp,
+		}
+	}
+
+	// 100 is needed for the target, only the cap of 60 is deposited
+	assert.NoError(t, topUpVault(ctxParams, cfg, status()))
+	assert.Equal(t, []*big.Int{big.NewInt(60)}, v.deposited)
+	toppedUp, err := getToppedUp(ctxParams, tokencfg.WBTT)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(60), toppedUp)
+
+	// nothing more is deposited today once the cap is reached
+	assert.NoError(t, topUpVault(ctxParams, cfg, status()))
+	assert.Len(t, v.deposited, 1)
+	warning, err := getVaultGuardWarning(ctxParams, tokencfg.WBTT, vaultGuardWarningTopUp)
+	assert.NoError(t, err)
+	if assert.NotNil(t, warning) {
+		assert.Equal(t, "cap reached", warning.Reason)
+	}
+
+	// the raised cap is limited by the wallet balance
+	cfg.DailyTopUpCaps[tokencfg.WBTT] = big.NewInt(1000)
+	v.wallet = big.NewInt(30)
+	assert.NoError(t, topUpVault(ctxParams, cfg, status()))
+	assert.Equal(t, []*big.Int{big.NewInt(60), big.NewInt(30)}, v.deposited)
+
+	// the tokens without cap are never topped up
+	delete(cfg.DailyTopUpCaps, tokencfg.WBTT)
+	v.wallet = big.NewInt(1000)
+	assert.NoError(t, topUpVault(ctxParams, cfg, status()))
+	assert.Len(t, v.deposited, 2)
+}
+
+func TestCheckVaultBalance(t *testing.T) {
+	ctxParams := newVaultGuardParams(t, big.NewInt(250))
+	addPendingSession(t, ctxParams, "guard-check", sessions.RssWaitUploadStatus, 100, 2, 0)
+	wbtt := tokencfg.MpTokenAddr[tokencfg.WBTT]
+
+	assert.NoError(t, checkVaultBalance(ctxParams, wbtt, big.NewInt(50)))
+	err := checkVaultBalance(ctxParams, wbtt, big.NewInt(51))
+	assert.True(t, errors.Is(err, ErrVaultBalanceLow))
+}
@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/bittorrent/go-btfs/transaction/sctx"
+	"github.com/bittorrent/go-btfs/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	txGasPriceOptionName        = "gas-price"
+	txGasModeOptionName         = "mode"
+	txGasMultiplierOptionName   = "multiplier"
+	txBumpAfterBlocksOptionName = "bump-after-blocks"
+	txBumpPercentOptionName     = "bump-percent"
+)
+
+var TxCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the on-chain transactions of the node.",
+		ShortDescription: `
+List the pending transactions, bump or cancel a stuck one, and set the gas
+strategy deciding the gas prices and when the stuck transactions are bumped
+automatically.`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"pending": TxPendingCmd,
+		"bump":    TxBumpCmd,
+		"cancel":  TxCancelCmd,
+		"gas":     TxGasCmd,
+	},
+}
+
+type PendingTx struct {
+	Hash          string   `json:"hash"`
+	Nonce         uint64   `json:"nonce"`
+	To            string   `json:"to"`
+	GasPrice      *big.Int `json:"gas_price"`
+	Created       int64    `json:"created"`
+	Block         uint64   `json:"block"`
+	PendingBlocks uint64   `json:"pending_blocks"`
+	Description   string   `json:"description"`
+}
+
+type TxPendingRet struct {
+	Block        uint64       `json:"block"`
+	Transactions []*PendingTx `json:"transactions"`
+}
+
+var TxPendingCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the pending transactions.",
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		txService, err := transactionService()
+		if err != nil {
+			return err
+		}
+		block, err := chain.ChainObject.Backend.BlockNumber(req.Context)
+		if err != nil {
+			return err
+		}
+		pending, err := txService.PendingTransactions()
+		if err != nil {
+			return err
+		}
+
+		ret := &TxPendingRet{Block: block, Transactions: make([]*PendingTx, 0, len(pending))}
+		for _, txHash := range pending {
+			stored, err := txService.StoredTransaction(txHash)
+			if err != nil {
+				return err
+			}
+			to := ""
+			if stored.To != nil {
+				to = stored.To.Hex()
+			}
+			ret.Transactions = append(ret.Transactions, &PendingTx{
+				Hash:          txHash.Hex(),
+				Nonce:         stored.Nonce,
+				To:            to,
+				GasPrice:      stored.GasPrice,
+				Created:       stored.Created,
+				Block:         stored.Block,
+				PendingBlocks: transaction.PendingBlocks(stored, block),
+				Description:   stored.Description,
+			})
+		}
+		return cmds.EmitOnce(res, ret)
+	},
+	Type: &TxPendingRet{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *TxPendingRet) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "HASH\tNONCE\tGAS PRICE\tCREATED\tPENDING BLOCKS\tDESCRIPTION")
+			for _, tx := range out.Transactions {
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d\t%s\n", tx.Hash, tx.Nonce, tx.GasPrice,
+					time.Unix(tx.Created, 0).Format(time.RFC3339), tx.PendingBlocks, tx.Description)
+			}
+			return tw.Flush()
+		}),
+	},
+}
+
+type TxReplaceRet struct {
+	Hash string `json:"hash"`
+}
+
+var TxBumpCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Resend a pending transaction with a higher gas price.",
+		ShortDescription: `
+The transaction is replaced by one of the same nonce, at the given gas price or
+at the price raised by the bump percent of the gas strategy.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("hash", true, false, "Hash of the pending transaction."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(txGasPriceOptionName, "p", "Gas price of the replacement in wei."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		return replaceTransaction(req, res, env, func(txService transaction.Service, txHash common.Hash) (common.Hash, error) {
+			return txService.BumpTransaction(req.Context, txHash)
+		})
+	},
+	Type: &TxReplaceRet{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *TxReplaceRet) error {
+			_, err := fmt.Fprintf(w, "replaced by transaction %s\n", out.Hash)
+			return err
+		}),
+	},
+}
+
+var TxCancelCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Cancel a pending transaction.",
+		ShortDescription: `
+The transaction is replaced by a zero transfer to the node itself of the same
+nonce, at the given gas price or at the price raised by the bump percent of the
+gas strategy.`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("hash", true, false, "Hash of the pending transaction."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(txGasPriceOptionName, "p", "Gas price of the cancellation in wei."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		return replaceTransaction(req, res, env, func(txService transaction.Service, txHash common.Hash) (common.Hash, error) {
+			return txService.CancelTransaction(req.Context, txHash)
+		})
+	},
+	Type: &TxReplaceRet{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *TxReplaceRet) error {
+			_, err := fmt.Fprintf(w, "cancelled by transaction %s\n", out.Hash)
+			return err
+		}),
+	},
+}
+
+func replaceTransaction(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment,
+	replace func(transaction.Service, common.Hash) (common.Hash, error)) error {
+	err := utils.CheckSimpleMode(env)
+	if err != nil {
+		return err
+	}
+	txService, err := transactionService()
+	if err != nil {
+		return err
+	}
+	hashStr := req.Arguments[0]
+	if len(common.FromHex(hashStr)) != common.HashLength {
+		return fmt.Errorf("invalid transaction hash: %s", hashStr)
+	}
+	if s, ok := req.Options[txGasPriceOptionName].(string); ok && s != "" {
+		gasPrice, ok := new(big.Int).SetString(s, 10)
+		if !ok || gasPrice.Sign() <= 0 {
+			return fmt.Errorf("invalid gas price: %s", s)
+		}
+		req.Context = sctx.SetGasPrice(req.Context, gasPrice)
+	}
+	newHash, err := replace(txService, common.HexToHash(hashStr))
+	if err != nil {
+		return err
+	}
+	return cmds.EmitOnce(res, &TxReplaceRet{Hash: newHash.Hex()})
+}
+
+var TxGasCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show or set the gas strategy.",
+		ShortDescription: `
+The gas strategy decides the gas price of the transactions sent without one:
+
+    suggested  the price suggested by the chain times the multiplier
+    capped     as suggested, but no more than the price
+    fixed      always the price
+
+Transactions pending for the bump-after-blocks are resent with the gas price
+raised by the bump percent, at least 10; 0 disables the automatic bump. In
+capped mode the bumps stop at the price.
+
+Examples:
+    # Use 1.2 times the suggested price, up to 500 gwei, and bump after 20 blocks
+    $ btfs tx gas --mode=capped --multiplier=120 --gas-price=500000000000 --bump-after-blocks=20
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption(txGasModeOptionName, "m", "Gas mode, suggested, capped or fixed."),
+		cmds.StringOption(txGasPriceOptionName, "p", "Gas price in wei, the fixed price or the cap."),
+		cmds.Uint64Option(txGasMultiplierOptionName, "Percent of the suggested gas price."),
+		cmds.Uint64Option(txBumpAfterBlocksOptionName, "Bump the transactions pending for the blocks, 0 disables."),
+		cmds.Uint64Option(txBumpPercentOptionName, "Percent the gas price of a bumped transaction is raised by."),
+	},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		err := utils.CheckSimpleMode(env)
+		if err != nil {
+			return err
+		}
+		txService, err := transactionService()
+		if err != nil {
+			return err
+		}
+		strategy, err := txService.GasStrategy()
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		if mode, ok := req.Options[txGasModeOptionName].(string); ok {
+			strategy.Mode = mode
+			changed = true
+		}
+		if s, ok := req.Options[txGasPriceOptionName].(string); ok {
+			price, ok := new(big.Int).SetString(s, 10)
+			if !ok {
+				return fmt.Errorf("invalid gas price: %s", s)
+			}
+			strategy.Price = price
+			changed = true
+		}
+		if v, ok := req.Options[txGasMultiplierOptionName].(uint64); ok {
+			strategy.Multiplier = v
+			changed = true
+		}
+		if v, ok := req.Options[txBumpAfterBlocksOptionName].(uint64); ok {
+			strategy.BumpAfterBlocks = v
+			changed = true
+		}
+		if v, ok := req.Options[txBumpPercentOptionName].(uint64); ok {
+			strategy.BumpPercent = v
+			changed = true
+		}
+		if changed {
+			err = txService.SetGasStrategy(strategy)
+			if err != nil {
+				return err
+			}
+		}
+		return cmds.EmitOnce(res, strategy)
+	},
+	Type: &transaction.GasStrategy{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, out *transaction.GasStrategy) error {
+			tw := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+			price := "-"
+			if out.Price != nil {
+				price = out.Price.String()
+			}
+			fmt.Fprintf(tw, "mode:\t%s\n", out.Mode)
+			fmt.Fprintf(tw, "gas price:\t%s\n", price)
+			fmt.Fprintf(tw, "multiplier:\t%d%%\n", out.Multiplier)
+			fmt.Fprintf(tw, "bump after blocks:\t%d\n", out.BumpAfterBlocks)
+			fmt.Fprintf(tw, "bump percent:\t%d%%\n", out.BumpPercent)
+			return tw.Flush()
+		}),
+	},
+}
+
+func transactionService() (transaction.Service, error) {
+	if chain.ChainObject.TransactionService == nil {
+		return nil, errors.New("transaction service is not initialized")
+	}
+	return chain.ChainObject.TransactionService, nil
+}
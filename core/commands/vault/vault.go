@@ -1,6 +1,8 @@
 package vault
 
 import (
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/upload"
+
 	cmds "github.com/bittorrent/go-btfs-cmds"
 )
 
@@ -8,7 +10,8 @@ var VaultCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Interact with vault services on BTFS.",
 		ShortDescription: `
-Vault services include balance, address, withdraw, deposit operations.`,
+Vault services include balance, address, withdraw, deposit operations, and
+the guard of the balance against the storage commitments.`,
 	},
 	Subcommands: map[string]*cmds.Command{
 		"balance":     VaultBalanceCmd,
@@ -18,5 +21,6 @@ Vault services include balance, address, withdraw, deposit operations.`,
 		"deposit":     VaultDepositCmd,
 		"wbttbalance": VaultWbttBalanceCmd,
 		"upgrade":     VaultUpgradeCmd,
+		"guard":       upload.VaultGuardCmd,
 	},
 }
@@ -0,0 +1,208 @@
+// Package ledger records every movement of the funds of the node in double
+// entry: each entry debits an account and credits another one by the amount
+// of a token, so the balances of all the accounts of a token sum to zero.
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+
+	"github.com/ethereum/go-ethereum/common"
+	logging "github.com/ipfs/go-log"
+)
+
+// accounts of the node, the funds paid to or received from the others are in
+// AccountExternal
+const (
+	AccountWallet     = "wallet"
+	AccountVault      = "vault"
+	AccountReceivable = "cheques-receivable"
+	AccountStake      = "stake"
+	AccountUnlocking  = "stake-unlocking"
+	AccountExchange   = "exchange"
+	AccountProxyUsers = "proxy-users"
+	AccountExternal   = "external"
+	AccountGasFees    = "gas-fees"
+)
+
+// types of the entries
+const (
+	TypeChequeSent     = "cheque-sent"
+	TypeChequeReceived = "cheque-received"
+	TypeCashout        = "cashout"
+	TypeVaultDeposit   = "vault-deposit"
+	TypeVaultWithdraw  = "vault-withdraw"
+	TypeSwap           = "swap"
+	TypeTransfer       = "transfer"
+	TypeStake          = "stake"
+	TypeUnstake        = "unstake"
+	TypeStakeWithdraw  = "stake-withdraw"
+	TypeProxyPayment   = "proxy-payment"
+	TypeProxyUpload    = "proxy-upload"
+	TypeGasFee         = "gas-fee"
+)
+
+// BTT is the native token, it is not in the token config
+const BTT = "BTT"
+
+const entryKeyPrefix = "ledger_entry_"
+
+var log = logging.Logger("ledger")
+
+var (
+	store storage.StateStorer
+	lock  sync.Mutex
+	seq   uint64
+)
+
+// Entry is a movement of a token from the credited account to the debited one
+type Entry struct {
+	ID     string   `json:"id"`
+	Time   int64    `json:"time"`
+	Type   string   `json:"type"`
+	Debit  string   `json:"debit"`
+	Credit string   `json:"credit"`
+	Token  string   `json:"token"`
+	Amount *big.Int `json:"amount"`
+	// peer id or address of the other party
+	Counterparty string `json:"counterparty,omitempty"`
+	// contract id or file hash the movement pays for
+	Reference string `json:"reference,omitempty"`
+	TxHash    string `json:"tx_hash,omitempty"`
+}
+
+// Balance is the sum of the entries of an account in a token, debits are
+// positive
+type Balance struct {
+	Account string   `json:"account"`
+	Token   string   `json:"token"`
+	Debit   *big.Int `json:"debit"`
+	Credit  *big.Int `json:"credit"`
+	Balance *big.Int `json:"balance"`
+}
+
+// Init sets the store the entries are kept in, nothing is recorded before
+func Init(s storage.StateStorer) {
+	lock.Lock()
+	defer lock.Unlock()
+	store = s
+}
+
+// TokenName returns the name of the token in the entries
+func TokenName(token common.Address) string {
+	if name, ok := tokencfg.MpTokenStr[token]; ok {
+		return name
+	}
+	return token.String()
+}
+
+// Record saves the entry, the failures are logged only since the movement has
+// happened anyway
+func Record(e *Entry) {
+	if err := record(e); err != nil {
+		log.Errorf("failed to record %s entry of %s %s: %v", e.Type, e.Amount, e.Token, err)
+	}
+}
+
+func record(e *Entry) error {
+	if e.Amount == nil || e.Amount.Sign() <= 0 {
+		return nil
+	}
+	if e.Debit == e.Credit {
+		return fmt.Errorf("debit and credit are the same account %s", e.Debit)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if store == nil {
+		return errors.New("ledger is not initialized")
+	}
+	now := time.Now()
+	if e.Time == 0 {
+		e.Time = now.Unix()
+	}
+	e.ID = newEntryID(now)
+	return store.Put(entryKeyPrefix+e.ID, e)
+}
+
+// newEntryID returns the id of the entry recorded now, the lock is held
+func newEntryID(now time.Time) string {
+	seq++
+	// keys sort in the order the entries are recorded
+	return fmt.Sprintf("%019d_%06d", now.UnixNano(), seq%1000000)
+}
+
+// List returns the entries recorded within [from, to) in order, zero times are
+// unbounded
+func List(from, to time.Time) ([]*Entry, error) {
+	lock.Lock()
+	s := store
+	lock.Unlock()
+	if s == nil {
+		return nil, errors.New("ledger is not initialized")
+	}
+
+	entries := make([]*Entry, 0)
+	err := s.Iterate(entryKeyPrefix, func(key, value []byte) (bool, error) {
+		e := new(Entry)
+		if err := json.Unmarshal(value, e); err != nil {
+			return false, err
+		}
+		if !from.IsZero() && e.Time < from.Unix() {
+			return false, nil
+		}
+		if !to.IsZero() && e.Time >= to.Unix() {
+			return false, nil
+		}
+		entries = append(entries, e)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// Balances sums the entries by account and token
+func Balances(entries []*Entry) []*Balance {
+	type accountToken struct{ account, token string }
+	balances := make(map[accountToken]*Balance)
+	get := func(account, token string) *Balance {
+		k := accountToken{account, token}
+		if b, ok := balances[k]; ok {
+			return b
+		}
+		b := &Balance{Account: account, Token: token, Debit: new(big.Int), Credit: new(big.Int)}
+		balances[k] = b
+		return b
+	}
+	for _, e := range entries {
+		d := get(e.Debit, e.Token)
+		d.Debit.Add(d.Debit, e.Amount)
+		c := get(e.Credit, e.Token)
+		c.Credit.Add(c.Credit, e.Amount)
+	}
+
+	out := make([]*Balance, 0, len(balances))
+	for _, b := range balances {
+		b.Balance = new(big.Int).Sub(b.Debit, b.Credit)
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Token != out[j].Token {
+			return out[i].Token < out[j].Token
+		}
+		return out[i].Account < out[j].Account
+	})
+	return out
+}
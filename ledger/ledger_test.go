@@ -0,0 +1,66 @@
+package ledger
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore/mock"
+)
+
+func TestRecordAndBalances(t *testing.T) {
+	Init(mock.NewStateStore())
+	defer Init(nil)
+
+	Record(&Entry{Type: TypeVaultDeposit, Debit: AccountVault, Credit: AccountWallet, Token: "WBTT", Amount: big.NewInt(100)})
+	Record(&Entry{Type: TypeChequeSent, Debit: AccountExternal, Credit: AccountVault, Token: "WBTT", Amount: big.NewInt(30)})
+	Record(&Entry{Type: TypeStake, Debit: AccountStake, Credit: AccountWallet, Token: BTT, Amount: big.NewInt(5)})
+	// empty movements are not recorded
+	Record(&Entry{Type: TypeChequeSent, Debit: AccountExternal, Credit: AccountVault, Token: "WBTT", Amount: big.NewInt(0)})
+
+	entries, err := List(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if entries[0].Type != TypeVaultDeposit || entries[2].Type != TypeStake {
+		t.Fatalf("entries out of order: %s, %s", entries[0].Type, entries[2].Type)
+	}
+
+	want := map[string]int64{
+		"WBTT/" + AccountVault:    70,
+		"WBTT/" + AccountWallet:   -100,
+		"WBTT/" + AccountExternal: 30,
+		BTT + "/" + AccountStake:  5,
+		BTT + "/" + AccountWallet: -5,
+	}
+	balances := Balances(entries)
+	if len(balances) != len(want) {
+		t.Fatalf("got %d balances, want %d", len(balances), len(want))
+	}
+	sums := make(map[string]*big.Int)
+	for _, b := range balances {
+		if w := want[b.Token+"/"+b.Account]; b.Balance.Int64() != w {
+			t.Fatalf("balance of %s %s is %s, want %d", b.Account, b.Token, b.Balance, w)
+		}
+		if _, ok := sums[b.Token]; !ok {
+			sums[b.Token] = new(big.Int)
+		}
+		sums[b.Token].Add(sums[b.Token], b.Balance)
+	}
+	for token, sum := range sums {
+		if sum.Sign() != 0 {
+			t.Fatalf("balances of %s sum to %s", token, sum)
+		}
+	}
+
+	entries, err = List(time.Now().Add(time.Hour), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("got %d entries after an hour later", len(entries))
+	}
+}
@@ -0,0 +1,178 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const pendingKeyPrefix = "ledger_pending_"
+
+// pendingTimeout is how long the transaction not found on chain is waited for,
+// it has been dropped after that
+const pendingTimeout = 7 * 24 * time.Hour
+
+// pending are the entries of a broadcast transaction waiting for its receipt
+type pending struct {
+	TxHash  string   `json:"tx_hash"`
+	Time    int64    `json:"time"`
+	Entries []*Entry `json:"entries"`
+	// the entries being recorded after the transaction is mined, their ids are
+	// kept so they are not recorded twice if the recording is interrupted
+	Mined []*Entry `json:"mined,omitempty"`
+}
+
+// ReceiptFunc returns the receipt of the mined transaction, or
+// ethereum.NotFound if it is not mined yet
+type ReceiptFunc func(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+
+// RecordPending saves the entries of the broadcast transaction, they are
+// recorded along with the gas fee of the transaction once it is mined and
+// dropped if it is reverted. The transaction with no entries records its gas
+// fee only.
+func RecordPending(txHash common.Hash, entries ...*Entry) {
+	if err := recordPending(txHash, entries...); err != nil {
+		log.Errorf("failed to record the pending entries of %s: %v", txHash, err)
+	}
+}
+
+func recordPending(txHash common.Hash, entries ...*Entry) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if store == nil {
+		return errors.New("ledger is not initialized")
+	}
+	for _, e := range entries {
+		e.TxHash = txHash.String()
+	}
+	return store.Put(pendingKeyPrefix+txHash.String(), &pending{
+		TxHash:  txHash.String(),
+		Time:    time.Now().Unix(),
+		Entries: entries,
+	})
+}
+
+// StartSettling settles the pending entries now and then every interval until
+// the context is done
+func StartSettling(ctx context.Context, receipt ReceiptFunc, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := Settle(ctx, receipt); err != nil {
+				log.Errorf("failed to settle the pending entries: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Settle records the pending entries whose transactions are mined, the other
+// ones are kept for the next time
+func Settle(ctx context.Context, receipt ReceiptFunc) error {
+	lock.Lock()
+	s := store
+	lock.Unlock()
+	if s == nil {
+		return errors.New("ledger is not initialized")
+	}
+
+	var pendings []*pending
+	err := s.Iterate(pendingKeyPrefix, func(key, value []byte) (bool, error) {
+		p := new(pending)
+		if err := json.Unmarshal(value, p); err != nil {
+			return false, err
+		}
+		pendings = append(pendings, p)
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pendings {
+		r, err := receipt(ctx, common.HexToHash(p.TxHash))
+		if errors.Is(err, ethereum.NotFound) {
+			if time.Since(time.Unix(p.Time, 0)) > pendingTimeout {
+				log.Errorf("transaction %s is not mined in %s, its entries are dropped", p.TxHash, pendingTimeout)
+				if err := s.Delete(pendingKeyPrefix + p.TxHash); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err != nil {
+			log.Errorf("failed to get the receipt of %s: %v", p.TxHash, err)
+			continue
+		}
+		if err := settle(p, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settle records the entries of the mined transaction and its gas fee, only
+// the gas fee is recorded if the transaction is reverted
+func settle(p *pending, r *types.Receipt) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if store == nil {
+		return errors.New("ledger is not initialized")
+	}
+	if p.Mined == nil {
+		var mined []*Entry
+		if r.Status == types.ReceiptStatusSuccessful {
+			mined = append(mined, p.Entries...)
+		} else {
+			log.Infof("transaction %s is reverted, only its gas fee is recorded", p.TxHash)
+		}
+		if fee := gasFee(r); fee.Sign() > 0 {
+			mined = append(mined, &Entry{
+				Type:   TypeGasFee,
+				Debit:  AccountGasFees,
+				Credit: AccountWallet,
+				Token:  BTT,
+				Amount: fee,
+				TxHash: p.TxHash,
+			})
+		}
+		now := time.Now()
+		p.Mined = make([]*Entry, 0, len(mined))
+		for _, e := range mined {
+			if e.Amount == nil || e.Amount.Sign() <= 0 || e.Debit == e.Credit {
+				continue
+			}
+			e.Time = now.Unix()
+			e.ID = newEntryID(now)
+			p.Mined = append(p.Mined, e)
+		}
+		if err := store.Put(pendingKeyPrefix+p.TxHash, p); err != nil {
+			return err
+		}
+	}
+	for _, e := range p.Mined {
+		if err := store.Put(entryKeyPrefix+e.ID, e); err != nil {
+			return err
+		}
+	}
+	return store.Delete(pendingKeyPrefix + p.TxHash)
+}
+
+// gasFee is the gas paid for the transaction
+func gasFee(r *types.Receipt) *big.Int {
+	if r.EffectiveGasPrice == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(r.GasUsed), r.EffectiveGasPrice)
+}
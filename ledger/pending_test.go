@@ -0,0 +1,111 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore/mock"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestSettle(t *testing.T) {
+	Init(mock.NewStateStore())
+	defer Init(nil)
+
+	succeeded := common.HexToHash("0x1")
+	reverted := common.HexToHash("0x2")
+	unmined := common.HexToHash("0x3")
+	failing := common.HexToHash("0x4")
+	receipts := map[common.Hash]*types.Receipt{
+		succeeded: {Status: types.ReceiptStatusSuccessful, GasUsed: 10, EffectiveGasPrice: big.NewInt(3)},
+		reverted:  {Status: types.ReceiptStatusFailed, GasUsed: 5, EffectiveGasPrice: big.NewInt(3)},
+	}
+	receipt := func(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+		if txHash == failing {
+			return nil, errors.New("connection refused")
+		}
+		r, ok := receipts[txHash]
+		if !ok {
+			return nil, ethereum.NotFound
+		}
+		return r, nil
+	}
+	deposit := func() *Entry {
+		return &Entry{Type: TypeVaultDeposit, Debit: AccountVault, Credit: AccountWallet, Token: "WBTT", Amount: big.NewInt(100)}
+	}
+
+	for _, h := range []common.Hash{succeeded, reverted, unmined, failing} {
+		RecordPending(h, deposit())
+	}
+	ctx := context.Background()
+	if err := Settle(ctx, receipt); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := List(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int64)
+	for _, e := range entries {
+		got[e.TxHash+"/"+e.Type] = e.Amount.Int64()
+	}
+	want := map[string]int64{
+		succeeded.String() + "/" + TypeVaultDeposit: 100,
+		succeeded.String() + "/" + TypeGasFee:       30,
+		reverted.String() + "/" + TypeGasFee:        15,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got entries %v, want %v", got, want)
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Fatalf("got entries %v, want %v", got, want)
+		}
+	}
+
+	// the transactions mined later are settled by the next round
+	receipts[unmined] = &types.Receipt{Status: types.ReceiptStatusSuccessful}
+	if err := Settle(ctx, receipt); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = List(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[3].TxHash != unmined.String() {
+		t.Fatalf("got %d entries, want the deposit of the transaction mined later", len(entries))
+	}
+}
+
+func TestSettleInterrupted(t *testing.T) {
+	Init(mock.NewStateStore())
+	defer Init(nil)
+
+	txHash := common.HexToHash("0x1")
+	p := &pending{
+		TxHash:  txHash.String(),
+		Time:    time.Now().Unix(),
+		Entries: []*Entry{{Type: TypeStake, Debit: AccountStake, Credit: AccountWallet, Token: BTT, Amount: big.NewInt(5)}},
+	}
+	r := &types.Receipt{Status: types.ReceiptStatusSuccessful}
+	if err := settle(p, r); err != nil {
+		t.Fatal(err)
+	}
+	// settling again with the saved ids overwrites the recorded entries
+	if err := settle(p, r); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := List(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+}
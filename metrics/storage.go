@@ -0,0 +1,154 @@
+// Package metrics defines the prometheus collectors of the storage
+// marketplace, they are served on the /debug/metrics/prometheus endpoint of
+// the daemon.
+package metrics
+
+import (
+	"math/big"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "btfs"
+
+	ContractActive  = "active"
+	ContractExpired = "expired"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var log = logging.Logger("metrics")
+
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200}
+
+var (
+	// Contracts is the number of the contracts by role, token and state
+	Contracts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "contracts",
+		Help:      "Number of the storage contracts by role, token and state (active or expired).",
+	}, []string{"role", "token", "state"})
+
+	// Sessions is the number of the renter upload sessions by status
+	Sessions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "sessions",
+		Help:      "Number of the upload sessions by status.",
+	}, []string{"status"})
+
+	// SessionStateDuration is the time an upload session spends in a status
+	SessionStateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "session_state_duration_seconds",
+		Help:      "Time the upload sessions spent in each status.",
+		Buckets:   durationBuckets,
+	}, []string{"status"})
+
+	// SessionDuration is the time an upload session takes to complete or fail
+	SessionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "session_duration_seconds",
+		Help:      "Time the upload sessions took until they completed or failed.",
+		Buckets:   durationBuckets,
+	}, []string{"status"})
+
+	// ShardUploadFailures is the number of the failed attempts to contract a
+	// shard with a host by reason
+	ShardUploadFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "shard_upload_failures_total",
+		Help:      "Number of the failed attempts to upload a shard to a host by reason.",
+	}, []string{"reason"})
+
+	// Renewals is the number of the file renewals by type and result
+	Renewals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "renewals_total",
+		Help:      "Number of the file renewals by type (auto or manual) and result.",
+	}, []string{"type", "result"})
+
+	// ChequeReceivedAmount is the amount of the cheques received by token
+	ChequeReceivedAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cheque",
+		Name:      "received_amount_total",
+		Help:      "Amount of the cheques received from the renters by token.",
+	}, []string{"token"})
+
+	// ChequeSentAmount is the amount of the cheques sent by token
+	ChequeSentAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cheque",
+		Name:      "sent_amount_total",
+		Help:      "Amount of the cheques sent to the hosts by token.",
+	}, []string{"token"})
+
+	// CashoutLatency is the time from sending the cashout transaction to its
+	// receipt
+	CashoutLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "cheque",
+		Name:      "cashout_latency_seconds",
+		Help:      "Time from sending the cheque cashout transaction to getting its receipt.",
+		Buckets:   durationBuckets,
+	}, []string{"token", "result"})
+
+	// VaultBalance is the balance of the vault by token and kind (total or
+	// available)
+	VaultBalance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "vault",
+		Name:      "balance",
+		Help:      "Balance of the vault by token and kind (total or available).",
+	}, []string{"token", "kind"})
+)
+
+var collectors = []prometheus.Collector{
+	Contracts,
+	Sessions,
+	SessionStateDuration,
+	SessionDuration,
+	ShardUploadFailures,
+	Renewals,
+	ChequeReceivedAmount,
+	ChequeSentAmount,
+	CashoutLatency,
+	VaultBalance,
+}
+
+// Register registers the storage collectors with the default registry
+func Register() {
+	for _, c := range collectors {
+		if err := prometheus.Register(c); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				log.Errorf("failed to register storage metrics: %v", err)
+			}
+		}
+	}
+}
+
+// Float converts the token amount to float64 for the collectors
+func Float(amount *big.Int) float64 {
+	if amount == nil {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(amount).Float64()
+	return f
+}
+
+// Result returns the result label of the error
+func Result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/bittorrent/go-btfs/plugin"
 	"github.com/bittorrent/go-btfs/repo"
+	"github.com/bittorrent/go-btfs/repo/datadirs"
 	"github.com/bittorrent/go-btfs/repo/fsrepo"
 
 	flatfs "github.com/ipfs/go-ds-flatfs"
@@ -86,5 +87,14 @@ func (c *datastoreConfig) Create(path string) (repo.Datastore, error) {
 		p = filepath.Join(path, p)
 	}
 
-	return flatfs.CreateOrOpen(p, c.shardFun, c.syncField)
+	fs, err := flatfs.CreateOrOpen(p, c.shardFun, c.syncField)
+	if err != nil {
+		return nil, err
+	}
+	// the blocks are also stored in the data directories added to the repo,
+	// only the first flatfs mount, the blockstore by default, has them
+	if _, err := datadirs.Current(); err == nil {
+		return fs, nil
+	}
+	return datadirs.Open(fs, p, path, c.shardFun, c.syncField)
 }
@@ -0,0 +1,370 @@
+package datadirs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	flatfs "github.com/ipfs/go-ds-flatfs"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+const (
+	MigrationRemove    = "remove"
+	MigrationRebalance = "rebalance"
+)
+
+// DirStatus is the usage of a data directory
+type DirStatus struct {
+	Path     string
+	Primary  bool
+	Capacity uint64
+	Used     uint64
+	Free     uint64
+	Draining bool
+}
+
+// Migration is the progress of removing a directory or rebalancing the
+// directories in the background
+type Migration struct {
+	Type      string
+	Path      string `json:",omitempty"`
+	Moved     uint64
+	Bytes     uint64
+	StartedAt time.Time
+	EndedAt   *time.Time `json:",omitempty"`
+	Error     string     `json:",omitempty"`
+}
+
+// Status returns the placement policy, the usage of the directories and the
+// last migration
+func (d *Datastore) Status(ctx context.Context) (string, []*DirStatus, *Migration, error) {
+	out := make([]*DirStatus, 0)
+	for _, dd := range d.snapshot() {
+		used, err := dd.ds.DiskUsage(ctx)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		usage, err := disk.UsageWithContext(ctx, dd.Path)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		out = append(out, &DirStatus{
+			Path:     dd.Path,
+			Primary:  dd.primary,
+			Capacity: dd.Capacity,
+			Used:     used,
+			Free:     usage.Free,
+			Draining: dd.isDraining(),
+		})
+	}
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	var m *Migration
+	if d.migration != nil {
+		c := *d.migration
+		m = &c
+	}
+	return d.policy, out, m, nil
+}
+
+// ExtraFree returns the bytes can be stored in the directories besides the
+// one in the repo
+func (d *Datastore) ExtraFree(ctx context.Context) (uint64, error) {
+	var total uint64
+	for _, dd := range d.snapshot() {
+		if dd.primary || dd.isDraining() {
+			continue
+		}
+		room, err := dd.room(ctx)
+		if err != nil {
+			return 0, err
+		}
+		total += room
+	}
+	return total, nil
+}
+
+// SetPolicy sets the placement policy of the new blocks
+func (d *Datastore) SetPolicy(policy string) error {
+	if !validPolicy(policy) {
+		return ErrInvalidPolicy
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	old := d.policy
+	d.policy = policy
+	if err := d.saveConfig(); err != nil {
+		d.policy = old
+		return err
+	}
+	return nil
+}
+
+func validPolicy(policy string) bool {
+	for _, p := range Policies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// Add adds a data directory storing at most the capacity in bytes, 0 for no
+// limit other than the free space of the disk
+func (d *Datastore) Add(ctx context.Context, path string, capacity uint64) error {
+	if !atomic.CompareAndSwapInt32(&d.migrating, 0, 1) {
+		return ErrMigrationBusy
+	}
+	defer atomic.StoreInt32(&d.migrating, 0)
+	path = filepath.Clean(path)
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("data directory %s is not an absolute path", path)
+	}
+	for _, dd := range d.snapshot() {
+		if dd.Path == path {
+			return ErrDirExists
+		}
+		if within(path, dd.Path) || within(dd.Path, path) {
+			return ErrDirOverlapping
+		}
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	if capacity > 0 {
+		usage, err := disk.UsageWithContext(ctx, path)
+		if err != nil {
+			return err
+		}
+		if usage.Free < capacity {
+			return fmt.Errorf("not enough disk space of %s, expect: ge %v bytes, actual: %v bytes",
+				path, capacity, usage.Free)
+		}
+	}
+	fs, err := flatfs.CreateOrOpen(path, d.shardFun, d.sync)
+	if err != nil {
+		return err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.dirs = append(d.dirs, &dir{DirConfig: DirConfig{Path: path, Capacity: capacity}, ds: fs})
+	if err := d.saveConfig(); err != nil {
+		d.dirs = d.dirs[:len(d.dirs)-1]
+		fs.Close()
+		return err
+	}
+	return nil
+}
+
+func within(path, parent string) bool {
+	return strings.HasPrefix(path, parent+string(filepath.Separator))
+}
+
+// Remove moves the blocks of the data directory to the others in the
+// background, the directory is removed from the datastore once it is empty
+func (d *Datastore) Remove(path string) error {
+	path = filepath.Clean(path)
+	d.lock.Lock()
+	var src *dir
+	for _, dd := range d.dirs {
+		if dd.Path == path {
+			src = dd
+		}
+	}
+	d.lock.Unlock()
+	if src == nil {
+		return ErrDirNotFound
+	}
+	if src.primary {
+		return ErrRemovePrimary
+	}
+	return d.migrate(MigrationRemove, path, func(ctx context.Context, m *Migration) error {
+		// the writes placed before are finished once the lock is taken
+		d.placeLock.Lock()
+		atomic.StoreInt32(&src.draining, 1)
+		d.placeLock.Unlock()
+		err := d.moveAll(ctx, src, m)
+		if err == nil {
+			// the source is queried once more so no block is dropped with it
+			err = d.moveAll(ctx, src, m)
+		}
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		if err != nil {
+			atomic.StoreInt32(&src.draining, 0)
+			return err
+		}
+		dirs := make([]*dir, 0, len(d.dirs))
+		for _, dd := range d.dirs {
+			if dd != src {
+				dirs = append(dirs, dd)
+			}
+		}
+		d.dirs = dirs
+		if err := d.saveConfig(); err != nil {
+			return err
+		}
+		return src.ds.Close()
+	})
+}
+
+// Rebalance moves the blocks from the directories used most to the ones used
+// least in the background, until every directory is used about the same ratio
+// of its capacity
+func (d *Datastore) Rebalance() error {
+	return d.migrate(MigrationRebalance, "", d.rebalance)
+}
+
+func (d *Datastore) migrate(typ string, path string, f func(ctx context.Context, m *Migration) error) error {
+	if !atomic.CompareAndSwapInt32(&d.migrating, 0, 1) {
+		return ErrMigrationBusy
+	}
+	m := &Migration{Type: typ, Path: path, StartedAt: time.Now()}
+	d.lock.Lock()
+	d.migration = m
+	d.lock.Unlock()
+	go func() {
+		defer atomic.StoreInt32(&d.migrating, 0)
+		err := f(context.Background(), m)
+		now := time.Now()
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		m.EndedAt = &now
+		if err != nil {
+			m.Error = err.Error()
+			log.Errorf("Failed to %s data directories: %v", typ, err)
+		}
+	}()
+	return nil
+}
+
+// moveAll moves all blocks of the directory to the others
+func (d *Datastore) moveAll(ctx context.Context, src *dir, m *Migration) error {
+	results, err := src.ds.Query(ctx, dsq.Query{KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		if err := d.move(ctx, src, nil, ds.NewKey(r.Key), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// move moves the block from the source directory to the destination, or the
+// directory placed by the policy if the destination is nil
+func (d *Datastore) move(ctx context.Context, src *dir, dst *dir, key ds.Key, m *Migration) error {
+	v, err := src.ds.Get(ctx, key)
+	if errors.Is(err, ds.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dst == nil {
+		dst, err = d.place(ctx, d.snapshot(), uint64(len(v)), src)
+		if err != nil {
+			return err
+		}
+	} else if room, err := dst.room(ctx); err != nil {
+		return err
+	} else if room < uint64(len(v)) {
+		return ErrNoSpace
+	}
+	if err := dst.ds.Put(ctx, key, v); err != nil {
+		return err
+	}
+	if err := src.ds.Delete(ctx, key); err != nil {
+		return err
+	}
+	d.lock.Lock()
+	m.Moved++
+	m.Bytes += uint64(len(v))
+	d.lock.Unlock()
+	return nil
+}
+
+// utilization returns the ratio of the used bytes to the capacity of the
+// directory, the capacity is the used and the free space if it is not limited
+func (dd *dir) utilization(ctx context.Context) (float64, error) {
+	used, err := dd.ds.DiskUsage(ctx)
+	if err != nil {
+		return 0, err
+	}
+	room, err := dd.room(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if used+room == 0 {
+		return 1, nil
+	}
+	return float64(used) / float64(used+room), nil
+}
+
+func (d *Datastore) rebalance(ctx context.Context, m *Migration) error {
+	dirs := d.snapshot()
+	if len(dirs) < 2 {
+		return nil
+	}
+	for _, src := range dirs {
+		if err := d.drainTo(ctx, src, dirs, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainTo moves the blocks of the directory to the least used directories
+// until it is used no more than the others
+func (d *Datastore) drainTo(ctx context.Context, src *dir, dirs []*dir, m *Migration) error {
+	results, err := src.ds.Query(ctx, dsq.Query{KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		srcUtil, err := src.utilization(ctx)
+		if err != nil {
+			return err
+		}
+		var dst *dir
+		dstUtil := srcUtil
+		for _, dd := range dirs {
+			if dd == src {
+				continue
+			}
+			u, err := dd.utilization(ctx)
+			if err != nil {
+				return err
+			}
+			if u < dstUtil {
+				dst, dstUtil = dd, u
+			}
+		}
+		// stop once moving a block no longer evens the usage
+		if dst == nil || srcUtil-dstUtil < 0.01 {
+			return nil
+		}
+		if err := d.move(ctx, src, dst, ds.NewKey(r.Key), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,439 @@
+// Package datadirs implements a datastore storing the blocks across multiple
+// data directories, every directory is a flatfs datastore.
+package datadirs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	flatfs "github.com/ipfs/go-ds-flatfs"
+	logging "github.com/ipfs/go-log"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+const (
+	// ConfigFile is the file in the repo keeping the data directories
+	ConfigFile = "datadirs.json"
+
+	// PolicyFillFirst places the blocks on the first directory with space
+	PolicyFillFirst = "fill-first"
+	// PolicyRoundRobin places the blocks on the directories in turn
+	PolicyRoundRobin = "round-robin"
+	// PolicyMostFree places the blocks on the directory with the most space
+	PolicyMostFree = "most-free"
+)
+
+var (
+	ErrNotEnabled     = errors.New("data directories are only supported by the flatfs blockstore")
+	ErrNoSpace        = errors.New("no data directory has space for the block")
+	ErrDirNotFound    = errors.New("data directory not found")
+	ErrMigrationBusy  = errors.New("another migration of the data directories is running")
+	ErrRemovePrimary  = errors.New("the data directory of the repo cannot be removed")
+	ErrInvalidPolicy  = fmt.Errorf("invalid placement policy, expect one of %s", strings.Join(Policies, ", "))
+	ErrDirExists      = errors.New("data directory exists already")
+	ErrDirOverlapping = errors.New("data directory overlaps with another one")
+
+	Policies = []string{PolicyFillFirst, PolicyRoundRobin, PolicyMostFree}
+
+	log = logging.Logger("datadirs")
+
+	currentLock sync.Mutex
+	current     *Datastore
+)
+
+// Config is the placement policy and the data directories besides the one
+// in the repo
+type Config struct {
+	Policy string       `json:"policy"`
+	Dirs   []*DirConfig `json:"dirs"`
+}
+
+// DirConfig is a data directory, the blocks stored in the directory are
+// limited to the capacity in bytes if it is not 0
+type DirConfig struct {
+	Path     string `json:"path"`
+	Capacity uint64 `json:"capacity"`
+}
+
+type dir struct {
+	DirConfig
+	ds      *flatfs.Datastore
+	primary bool
+	// set while the blocks are moved out of the directory being removed
+	draining int32
+}
+
+func (dd *dir) isDraining() bool {
+	return atomic.LoadInt32(&dd.draining) == 1
+}
+
+// Datastore is the flatfs datastore of the repo with the data directories
+// added, the blocks are read from any directory and the new blocks are placed
+// by the policy.
+type Datastore struct {
+	lock sync.RWMutex
+	// held for reading while a block is placed and written, a directory is set
+	// draining with it held for writing so no block is written into it after
+	placeLock sync.RWMutex
+	cfgPath   string
+	shardFun  *flatfs.ShardIdV1
+	sync      bool
+	policy    string
+	dirs      []*dir
+	next      uint32
+
+	// set while adding, removing or rebalancing the directories
+	migrating int32
+	migration *Migration
+}
+
+var _ ds.Batching = (*Datastore)(nil)
+var _ ds.PersistentDatastore = (*Datastore)(nil)
+
+// Open adds the data directories in the config of the repo to the flatfs
+// datastore of the repo, it is the current datastore until it is closed
+func Open(primary *flatfs.Datastore, primaryPath string, repoPath string, shardFun *flatfs.ShardIdV1,
+	syncFiles bool) (*Datastore, error) {
+	d := &Datastore{
+		cfgPath:  filepath.Join(repoPath, ConfigFile),
+		shardFun: shardFun,
+		sync:     syncFiles,
+		policy:   PolicyFillFirst,
+		dirs: []*dir{{
+			DirConfig: DirConfig{Path: primaryPath},
+			ds:        primary,
+			primary:   true,
+		}},
+	}
+	cfg, err := readConfig(d.cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Policy != "" {
+		d.policy = cfg.Policy
+	}
+	for _, c := range cfg.Dirs {
+		fs, err := flatfs.CreateOrOpen(c.Path, shardFun, syncFiles)
+		if err != nil {
+			d.Close()
+			return nil, fmt.Errorf("failed to open data directory %s: %v", c.Path, err)
+		}
+		d.dirs = append(d.dirs, &dir{DirConfig: *c, ds: fs})
+	}
+	currentLock.Lock()
+	current = d
+	currentLock.Unlock()
+	return d, nil
+}
+
+// Current returns the datastore of the data directories opened by the repo
+func Current() (*Datastore, error) {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+	if current == nil {
+		return nil, ErrNotEnabled
+	}
+	return current, nil
+}
+
+func readConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", ConfigFile, err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes the config of the directories, it is called with the lock
+// held
+func (d *Datastore) saveConfig() error {
+	cfg := &Config{Policy: d.policy, Dirs: make([]*DirConfig, 0)}
+	for _, dd := range d.dirs {
+		if dd.primary {
+			continue
+		}
+		c := dd.DirConfig
+		cfg.Dirs = append(cfg.Dirs, &c)
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := d.cfgPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.cfgPath)
+}
+
+func (d *Datastore) snapshot() []*dir {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return append([]*dir(nil), d.dirs...)
+}
+
+// find returns the directory storing the key, nil if none
+func (d *Datastore) find(ctx context.Context, key ds.Key, dirs []*dir) (*dir, error) {
+	for _, dd := range dirs {
+		ok, err := dd.ds.Has(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return dd, nil
+		}
+	}
+	return nil, nil
+}
+
+// room returns the bytes can be stored in the directory
+func (dd *dir) room(ctx context.Context) (uint64, error) {
+	usage, err := disk.UsageWithContext(ctx, dd.Path)
+	if err != nil {
+		return 0, err
+	}
+	room := usage.Free
+	if dd.Capacity > 0 {
+		used, err := dd.ds.DiskUsage(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if used >= dd.Capacity {
+			return 0, nil
+		}
+		if left := dd.Capacity - used; left < room {
+			room = left
+		}
+	}
+	return room, nil
+}
+
+// place picks the directory for a new block of the size by the policy, the
+// directories being drained and the excluded one are skipped
+func (d *Datastore) place(ctx context.Context, dirs []*dir, size uint64, exclude *dir) (*dir, error) {
+	candidates := make([]*dir, 0, len(dirs))
+	rooms := make([]uint64, 0, len(dirs))
+	for _, dd := range dirs {
+		if dd.isDraining() || dd == exclude {
+			continue
+		}
+		room, err := dd.room(ctx)
+		if err != nil {
+			log.Warnf("failed to get space of data directory %s: %v", dd.Path, err)
+			continue
+		}
+		if room < size {
+			continue
+		}
+		candidates = append(candidates, dd)
+		rooms = append(rooms, room)
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoSpace
+	}
+	switch d.currentPolicy() {
+	case PolicyRoundRobin:
+		return candidates[int(atomic.AddUint32(&d.next, 1)-1)%len(candidates)], nil
+	case PolicyMostFree:
+		best := 0
+		for i := range candidates {
+			if rooms[i] > rooms[best] {
+				best = i
+			}
+		}
+		return candidates[best], nil
+	default:
+		return candidates[0], nil
+	}
+}
+
+func (d *Datastore) currentPolicy() string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.policy
+}
+
+func (d *Datastore) Put(ctx context.Context, key ds.Key, value []byte) error {
+	d.placeLock.RLock()
+	defer d.placeLock.RUnlock()
+	dirs := d.snapshot()
+	// the directory of the repo alone needs no placement
+	if len(dirs) == 1 {
+		return dirs[0].ds.Put(ctx, key, value)
+	}
+	writable := make([]*dir, 0, len(dirs))
+	for _, dd := range dirs {
+		if !dd.isDraining() {
+			writable = append(writable, dd)
+		}
+	}
+	dd, err := d.find(ctx, key, writable)
+	if err != nil {
+		return err
+	}
+	if dd == nil {
+		dd, err = d.place(ctx, writable, uint64(len(value)), nil)
+		if err != nil {
+			return err
+		}
+	}
+	return dd.ds.Put(ctx, key, value)
+}
+
+func (d *Datastore) Get(ctx context.Context, key ds.Key) ([]byte, error) {
+	for _, dd := range d.snapshot() {
+		v, err := dd.ds.Get(ctx, key)
+		if errors.Is(err, ds.ErrNotFound) {
+			continue
+		}
+		return v, err
+	}
+	return nil, ds.ErrNotFound
+}
+
+func (d *Datastore) Has(ctx context.Context, key ds.Key) (bool, error) {
+	dd, err := d.find(ctx, key, d.snapshot())
+	return dd != nil, err
+}
+
+func (d *Datastore) GetSize(ctx context.Context, key ds.Key) (int, error) {
+	for _, dd := range d.snapshot() {
+		size, err := dd.ds.GetSize(ctx, key)
+		if errors.Is(err, ds.ErrNotFound) {
+			continue
+		}
+		return size, err
+	}
+	return -1, ds.ErrNotFound
+}
+
+func (d *Datastore) Delete(ctx context.Context, key ds.Key) error {
+	for _, dd := range d.snapshot() {
+		if err := dd.ds.Delete(ctx, key); err != nil && !errors.Is(err, ds.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query queries the directories one by one, the orders, the offset and the
+// limit are applied to the results of all directories. A key in several
+// directories while it is being moved is returned from the first one only.
+func (d *Datastore) Query(ctx context.Context, q dsq.Query) (dsq.Results, error) {
+	dirs := d.snapshot()
+	child := q
+	child.Orders = nil
+	child.Offset = 0
+	child.Limit = 0
+
+	var (
+		i   int
+		cur dsq.Results
+	)
+	closeCur := func() error {
+		if cur == nil {
+			return nil
+		}
+		err := cur.Close()
+		cur = nil
+		return err
+	}
+	results := dsq.ResultsFromIterator(q, dsq.Iterator{
+		Next: func() (dsq.Result, bool) {
+			for {
+				if cur == nil {
+					if i >= len(dirs) {
+						return dsq.Result{}, false
+					}
+					r, err := dirs[i].ds.Query(ctx, child)
+					i++
+					if err != nil {
+						return dsq.Result{Error: err}, true
+					}
+					cur = r
+				}
+				if res, ok := cur.NextSync(); ok {
+					if res.Error == nil && i > 1 {
+						seen, err := d.find(ctx, ds.RawKey(res.Key), dirs[:i-1])
+						if err != nil {
+							return dsq.Result{Error: err}, true
+						}
+						if seen != nil {
+							continue
+						}
+					}
+					return res, true
+				}
+				if err := closeCur(); err != nil {
+					return dsq.Result{Error: err}, true
+				}
+			}
+		},
+		Close: closeCur,
+	})
+	return dsq.NaiveQueryApply(dsq.Query{Orders: q.Orders, Offset: q.Offset, Limit: q.Limit}, results), nil
+}
+
+func (d *Datastore) Sync(ctx context.Context, prefix ds.Key) error {
+	for _, dd := range d.snapshot() {
+		if err := dd.ds.Sync(ctx, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Datastore) Batch(ctx context.Context) (ds.Batch, error) {
+	// the blocks are batched by flatfs while the directory of the repo is the
+	// only one, it is never drained
+	if dirs := d.snapshot(); len(dirs) == 1 {
+		return dirs[0].ds.Batch(ctx)
+	}
+	return ds.NewBasicBatch(d), nil
+}
+
+// DiskUsage returns the bytes stored in all directories
+func (d *Datastore) DiskUsage(ctx context.Context) (uint64, error) {
+	var total uint64
+	for _, dd := range d.snapshot() {
+		used, err := dd.ds.DiskUsage(ctx)
+		if err != nil {
+			return 0, err
+		}
+		total += used
+	}
+	return total, nil
+}
+
+func (d *Datastore) Close() error {
+	currentLock.Lock()
+	if current == d {
+		current = nil
+	}
+	currentLock.Unlock()
+	var err error
+	for _, dd := range d.snapshot() {
+		if e := dd.ds.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,219 @@
+package datadirs
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	flatfs "github.com/ipfs/go-ds-flatfs"
+)
+
+func openTestDatastore(t *testing.T) (*Datastore, string) {
+	repoPath := t.TempDir()
+	shardFun := flatfs.NextToLast(2)
+	primaryPath := filepath.Join(repoPath, "blocks")
+	fs, err := flatfs.CreateOrOpen(primaryPath, shardFun, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Open(fs, primaryPath, repoPath, shardFun, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d, repoPath
+}
+
+func testKey(i int) ds.Key {
+	return ds.NewKey(fmt.Sprintf("CIQTESTBLOCK%04d", i))
+}
+
+func waitMigration(t *testing.T, d *Datastore) *Migration {
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		_, _, m, err := d.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != nil && m.EndedAt != nil {
+			return m
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("migration not finished")
+	return nil
+}
+
+func TestRoundRobinPlacement(t *testing.T) {
+	ctx := context.Background()
+	d, repoPath := openTestDatastore(t)
+	extra := filepath.Join(repoPath, "extra")
+	if err := d.Add(ctx, extra, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetPolicy(PolicyRoundRobin); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := d.Put(ctx, testKey(i), []byte("block")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dd := range d.snapshot() {
+		results, err := dd.ds.Query(ctx, dsq.Query{KeysOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := results.Rest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 5 {
+			t.Errorf("expect 5 blocks in %s, got %d", dd.Path, len(entries))
+		}
+	}
+	results, err := d.Query(ctx, dsq.Query{KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 10 {
+		t.Errorf("expect 10 blocks, got %d", len(entries))
+	}
+}
+
+func TestQueryDedupesMovingBlocks(t *testing.T) {
+	ctx := context.Background()
+	d, repoPath := openTestDatastore(t)
+	if err := d.Add(ctx, filepath.Join(repoPath, "extra"), 0); err != nil {
+		t.Fatal(err)
+	}
+	// a block copied to the destination but not deleted from the source yet
+	for _, dd := range d.snapshot() {
+		if err := dd.ds.Put(ctx, testKey(0), []byte("block")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Put(ctx, testKey(1), []byte("block")); err != nil {
+		t.Fatal(err)
+	}
+	results, err := d.Query(ctx, dsq.Query{KeysOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expect 2 blocks, got %d", len(entries))
+	}
+}
+
+func TestRemoveMigratesBlocks(t *testing.T) {
+	ctx := context.Background()
+	d, repoPath := openTestDatastore(t)
+	extra := filepath.Join(repoPath, "extra")
+	if err := d.Add(ctx, extra, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetPolicy(PolicyRoundRobin); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		if err := d.Put(ctx, testKey(i), []byte(fmt.Sprintf("block %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Remove(extra); err != nil {
+		t.Fatal(err)
+	}
+	if m := waitMigration(t, d); m.Error != "" || m.Moved != 3 {
+		t.Fatalf("unexpected migration: %+v", m)
+	}
+	if n := len(d.snapshot()); n != 1 {
+		t.Fatalf("expect 1 data directory, got %d", n)
+	}
+	for i := 0; i < 6; i++ {
+		v, err := d.Get(ctx, testKey(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(v) != fmt.Sprintf("block %d", i) {
+			t.Errorf("unexpected block %d: %s", i, v)
+		}
+	}
+
+	// the removed directory is not opened again
+	cfg, err := readConfig(filepath.Join(repoPath, ConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Dirs) != 0 || cfg.Policy != PolicyRoundRobin {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestAddInvalidDirs(t *testing.T) {
+	ctx := context.Background()
+	d, repoPath := openTestDatastore(t)
+	if err := d.Add(ctx, "relative", 0); err == nil {
+		t.Error("expect error of relative path")
+	}
+	if err := d.Add(ctx, filepath.Join(repoPath, "blocks", "nested"), 0); err != ErrDirOverlapping {
+		t.Errorf("expect %v, got %v", ErrDirOverlapping, err)
+	}
+	if err := d.Remove(filepath.Join(repoPath, "blocks")); err != ErrRemovePrimary {
+		t.Errorf("expect %v, got %v", ErrRemovePrimary, err)
+	}
+	if err := d.SetPolicy("random"); err != ErrInvalidPolicy {
+		t.Errorf("expect %v, got %v", ErrInvalidPolicy, err)
+	}
+}
+
+func TestPutSkipsDrainingDir(t *testing.T) {
+	ctx := context.Background()
+	d, repoPath := openTestDatastore(t)
+	extra := filepath.Join(repoPath, "extra")
+	if err := d.Add(ctx, extra, 0); err != nil {
+		t.Fatal(err)
+	}
+	dirs := d.snapshot()
+	// a block stored in the directory before it is set draining
+	if err := dirs[1].ds.Put(ctx, testKey(0), []byte("block")); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&dirs[1].draining, 1)
+	if err := d.SetPolicy(PolicyRoundRobin); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := d.Put(ctx, testKey(i), []byte("block")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if ok, err := dirs[0].ds.Has(ctx, testKey(i)); err != nil || !ok {
+			t.Errorf("expect block %d in the repo directory, got %v %v", i, ok, err)
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	d, _ := openTestDatastore(t)
+	if c, err := Current(); err != nil || c != d {
+		t.Fatalf("expect the opened datastore current, got %v %v", c, err)
+	}
+	d.Close()
+	if _, err := Current(); err != ErrNotEnabled {
+		t.Errorf("expect %v after close, got %v", ErrNotEnabled, err)
+	}
+}
@@ -192,8 +192,9 @@ func TestFlatfsConfig(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if typ := reflect.TypeOf(ds).String(); typ != "*flatfs.Datastore" {
-		t.Errorf("expected '*flatfs.Datastore' got '%s'", typ)
+	// the flatfs datastore is wrapped to store the blocks across data directories
+	if typ := reflect.TypeOf(ds).String(); typ != "*datadirs.Datastore" {
+		t.Errorf("expected '*datadirs.Datastore' got '%s'", typ)
 	}
 }
 
@@ -29,6 +29,30 @@ const (
 	// GetBucketAclAction - GetBucketACL Rest API action.
 	GetBucketAclAction = "s3:GetBucketACL"
 
+	// PutBucketVersioningAction - PutBucketVersioning Rest API action.
+	PutBucketVersioningAction = "s3:PutBucketVersioning"
+
+	// GetBucketVersioningAction - GetBucketVersioning Rest API action.
+	GetBucketVersioningAction = "s3:GetBucketVersioning"
+
+	// PutBucketLifecycleAction - PutBucketLifecycle Rest API action.
+	PutBucketLifecycleAction = "s3:PutBucketLifecycle"
+
+	// GetBucketLifecycleAction - GetBucketLifecycle Rest API action.
+	GetBucketLifecycleAction = "s3:GetBucketLifecycle"
+
+	// DeleteBucketLifecycleAction - DeleteBucketLifecycle Rest API action.
+	DeleteBucketLifecycleAction = "s3:DeleteBucketLifecycle"
+
+	// PutBucketPolicyAction - PutBucketPolicy Rest API action.
+	PutBucketPolicyAction = "s3:PutBucketPolicy"
+
+	// GetBucketPolicyAction - GetBucketPolicy Rest API action.
+	GetBucketPolicyAction = "s3:GetBucketPolicy"
+
+	// DeleteBucketPolicyAction - DeleteBucketPolicy Rest API action.
+	DeleteBucketPolicyAction = "s3:DeleteBucketPolicy"
+
 	//--- object
 
 	// ListObjectsAction - ListObjects Rest API action.
@@ -37,6 +61,9 @@ const (
 	// ListObjectsV2Action - ListObjectsV2 Rest API action.
 	ListObjectsV2Action = "s3:ListObjectsV2"
 
+	// ListObjectVersionsAction - ListObjectVersions Rest API action.
+	ListObjectVersionsAction = "s3:ListObjectVersions"
+
 	// HeadObjectAction - HeadObject Rest API action.
 	HeadObjectAction = "s3:HeadObject"
 
@@ -55,6 +82,15 @@ const (
 	// DeleteObjectsAction - DeleteObjects Rest API action.
 	DeleteObjectsAction = "s3:DeleteObjects"
 
+	// PutObjectTaggingAction - PutObjectTagging Rest API action.
+	PutObjectTaggingAction = "s3:PutObjectTagging"
+
+	// GetObjectTaggingAction - GetObjectTagging Rest API action.
+	GetObjectTaggingAction = "s3:GetObjectTagging"
+
+	// DeleteObjectTaggingAction - DeleteObjectTagging Rest API action.
+	DeleteObjectTaggingAction = "s3:DeleteObjectTagging"
+
 	//--- multipart upload
 
 	// CreateMultipartUploadAction - CreateMultipartUpload Rest API action.
@@ -68,6 +104,12 @@ const (
 
 	// UploadPartAction - UploadPartUpload Rest API action.
 	UploadPartAction Action = "s3:UploadPartUpload"
+
+	// ListMultipartUploadsAction - ListMultipartUploads Rest API action.
+	ListMultipartUploadsAction Action = "s3:ListBucketMultipartUploads"
+
+	// ListPartsAction - ListParts Rest API action.
+	ListPartsAction Action = "s3:ListMultipartUploadParts"
 )
 
 // SupportedActions List of all supported actions.
@@ -79,19 +121,35 @@ var SupportedActions = map[Action]struct{}{
 	PutBucketAclAction: {},
 	GetBucketAclAction: {},
 
-	ListObjectsAction:   {},
-	ListObjectsV2Action: {},
-	HeadObjectAction:    {},
-	PutObjectAction:     {},
-	GetObjectAction:     {},
-	CopyObjectAction:    {},
-	DeleteObjectAction:  {},
-	DeleteObjectsAction: {},
+	PutBucketVersioningAction:   {},
+	GetBucketVersioningAction:   {},
+	PutBucketLifecycleAction:    {},
+	GetBucketLifecycleAction:    {},
+	DeleteBucketLifecycleAction: {},
+	PutBucketPolicyAction:       {},
+	GetBucketPolicyAction:       {},
+	DeleteBucketPolicyAction:    {},
+
+	ListObjectsAction:        {},
+	ListObjectsV2Action:      {},
+	ListObjectVersionsAction: {},
+	HeadObjectAction:         {},
+	PutObjectAction:          {},
+	GetObjectAction:          {},
+	CopyObjectAction:         {},
+	DeleteObjectAction:       {},
+	DeleteObjectsAction:      {},
+
+	PutObjectTaggingAction:    {},
+	GetObjectTaggingAction:    {},
+	DeleteObjectTaggingAction: {},
 
 	CreateMultipartUploadAction:   {},
 	AbortMultipartUploadAction:    {},
 	CompleteMultipartUploadAction: {},
 	UploadPartAction:              {},
+	ListMultipartUploadsAction:    {},
+	ListPartsAction:               {},
 }
 
 // IsValid - checks if action is valid or not.
@@ -118,6 +176,15 @@ var supportedBucketActions = map[Action]struct{}{
 	DeleteBucketAction: {},
 	PutBucketAclAction: {},
 	GetBucketAclAction: {},
+
+	PutBucketVersioningAction:   {},
+	GetBucketVersioningAction:   {},
+	PutBucketLifecycleAction:    {},
+	GetBucketLifecycleAction:    {},
+	DeleteBucketLifecycleAction: {},
+	PutBucketPolicyAction:       {},
+	GetBucketPolicyAction:       {},
+	DeleteBucketPolicyAction:    {},
 }
 
 // IsBucketAction - returns whether action is bucket type or not.
@@ -128,19 +195,26 @@ func (action Action) IsBucketAction() bool {
 
 // List of all supported object actions.
 var supportedObjectActions = map[Action]struct{}{
-	ListObjectsAction:   {},
-	ListObjectsV2Action: {},
-	HeadObjectAction:    {},
-	PutObjectAction:     {},
-	GetObjectAction:     {},
-	CopyObjectAction:    {},
-	DeleteObjectAction:  {},
-	DeleteObjectsAction: {},
+	ListObjectsAction:        {},
+	ListObjectsV2Action:      {},
+	ListObjectVersionsAction: {},
+	HeadObjectAction:         {},
+	PutObjectAction:          {},
+	GetObjectAction:          {},
+	CopyObjectAction:         {},
+	DeleteObjectAction:       {},
+	DeleteObjectsAction:      {},
+
+	PutObjectTaggingAction:    {},
+	GetObjectTaggingAction:    {},
+	DeleteObjectTaggingAction: {},
 
 	CreateMultipartUploadAction:   {},
 	AbortMultipartUploadAction:    {},
 	CompleteMultipartUploadAction: {},
 	UploadPartAction:              {},
+	ListMultipartUploadsAction:    {},
+	ListPartsAction:               {},
 }
 
 // IsObjectAction - returns whether action is object type or not.
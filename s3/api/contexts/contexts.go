@@ -11,6 +11,7 @@ const (
 	keyOfAccessKey   key = "ctx-access-key"
 	keyOfHandleInf   key = "ctx-handle-inf"
 	keyOfRequestArgs key = "ctx-request-args"
+	keyOfSourceIP    key = "ctx-source-ip"
 )
 
 func set(r *http.Request, k key, v any) {
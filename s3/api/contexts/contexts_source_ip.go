@@ -0,0 +1,24 @@
+package contexts
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+func SetSourceIP(r *http.Request) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	set(r, keyOfSourceIP, ip)
+	return
+}
+
+// GetSourceIP get the source ip from the request context, it is used by
+// services which only hold the context of the request
+func GetSourceIP(ctx context.Context) (ip string) {
+	v := ctx.Value(keyOfSourceIP)
+	ip, _ = v.(string)
+	return
+}
@@ -95,12 +95,64 @@ func (h *Handlers) toResponseErr(err error) (rerr *responses.Error) {
 		rerr = responses.ErrInvalidRequest
 	case requests.ErrPartNumberInvalid:
 		rerr = responses.ErrInvalidPartNumber
+	case requests.ErrPartNumberMarkerInvalid:
+		rerr = responses.ErrInvalidPartNumberMarker
+	case requests.ErrMaxUploadsInvalid:
+		rerr = responses.ErrInvalidUploads
+	case requests.ErrMaxPartsInvalid:
+		rerr = responses.ErrInvalidMaxParts
+	case requests.ErrUploadIdMarkerInvalid:
+		rerr = responses.ErrInvalidUploadIDKeyCombination
 	case requests.ErrPartsCountInvalid:
 		rerr = responses.ErrInvalidRequest
 	case requests.ErrPartInvalid:
 		rerr = responses.ErrInvalidPart
 	case requests.ErrPartOrderInvalid:
 		rerr = responses.ErrInvalidPartOrder
+	case requests.ErrVersioningStatusInvalid:
+		rerr = responses.ErrMalformedXML
+	case requests.ErrVersionIdInvalid:
+		rerr = responses.ErrInvalidVersionID
+	case requests.ErrVersionIdMarkerInvalid:
+		rerr = responses.ErrInvalidVersionIDMarker
+	case requests.ErrLifecycleRulesCountInvalid:
+		rerr = responses.ErrInvalidRequest
+	case requests.ErrLifecycleRuleIdInvalid:
+		rerr = responses.ErrInvalidRequest
+	case requests.ErrLifecycleRuleStatusInvalid:
+		rerr = responses.ErrMalformedXML
+	case requests.ErrLifecycleRuleFilterInvalid:
+		rerr = responses.ErrMalformedXML
+	case requests.ErrLifecycleRuleActionInvalid:
+		rerr = responses.ErrInvalidRequest
+	case requests.ErrLifecycleRuleUnsupported:
+		rerr = responses.ErrNotImplemented
+	case requests.ErrBucketPolicyInvalid:
+		rerr = responses.ErrMalformedPolicy
+	case requests.ErrMetadataInvalid:
+		rerr = responses.ErrInvalidRequest
+	case requests.ErrMetadataTooLarge:
+		rerr = responses.ErrMetadataTooLarge
+	case requests.ErrTaggingInvalid:
+		rerr = responses.ErrInvalidTag
+	case requests.ErrTagsCountInvalid:
+		rerr = responses.ErrTooManyTags
+	case requests.ErrSSEInvalid:
+		rerr = responses.ErrInvalidEncryptionMethod
+	case requests.ErrSSECustomerAlgorithmInvalid:
+		rerr = responses.ErrInvalidSSECustomerAlgorithm
+	case requests.ErrSSECustomerKeyInvalid:
+		rerr = responses.ErrInvalidSSECustomerKey
+	case requests.ErrSSECustomerKeyMD5Invalid:
+		rerr = responses.ErrSSECustomerKeyMD5Mismatch
+	case requests.ErrSSECustomerParamsMissing:
+		rerr = responses.ErrMissingSSECustomerKeyParams
+	case requests.ErrSSEConflict:
+		rerr = responses.ErrInvalidEncryptionParameters
+	case requests.ErrSourceCidInvalid:
+		rerr = responses.ErrInvalidSourceCid
+	case requests.ErrSourceCidConflict:
+		rerr = responses.ErrInvalidRequest
 	// Errors from Object service
 	case object.ErrBucketNotFound:
 		rerr = responses.ErrNoSuchBucket
@@ -120,6 +172,28 @@ func (h *Handlers) toResponseErr(err error) (rerr *responses.Error) {
 		rerr = responses.ErrInvalidPart
 	case object.ErrPartTooSmall:
 		rerr = responses.ErrEntityTooSmall
+	case object.ErrVersionNotFound:
+		rerr = responses.ErrNoSuchVersion
+	case object.ErrVersionIsMarker:
+		rerr = responses.ErrMethodNotAllowed
+	case object.ErrLifecycleNotFound:
+		rerr = responses.ErrNoSuchLifecycleConfiguration
+	case object.ErrPreconditionFailed:
+		rerr = responses.ErrPreconditionFailed
+	case object.ErrInvalidRange:
+		rerr = responses.ErrInvalidRange
+	case object.ErrPolicyNotFound:
+		rerr = responses.ErrNoSuchBucketPolicy
+	case object.ErrSSEKeyMissing:
+		rerr = responses.ErrSSEEncryptedObject
+	case object.ErrSSEKeyMismatch:
+		rerr = responses.ErrSSECustomerKeyMismatch
+	case object.ErrSSENotEncrypted:
+		rerr = responses.ErrInvalidEncryptionParameters
+	case object.ErrSSEUnsupported:
+		rerr = responses.ErrInvalidEncryptionMethod
+	case object.ErrSourceCidNotFound:
+		rerr = responses.ErrNoSuchCid
 	case object.ErrCanceled:
 		rerr = responses.ErrClientDisconnected
 	case object.ErrTimout:
@@ -145,3 +145,187 @@ func (h *Handlers) GetBucketACLHandler(w http.ResponseWriter, r *http.Request) {
 	responses.WriteGetBucketACLResponse(w, r, acl)
 	return
 }
+
+func (h *Handlers) PutBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.PutBucketVersioningArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParsePutBucketVersioningRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	err = h.objsvc.PutBucketVersioning(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WritePutBucketVersioningResponse(w, r)
+	return
+}
+
+func (h *Handlers) GetBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.GetBucketVersioningArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseGetBucketVersioningRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	versioning, err := h.objsvc.GetBucketVersioning(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteGetBucketVersioningResponse(w, r, versioning)
+	return
+}
+
+func (h *Handlers) PutBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.PutBucketLifecycleArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParsePutBucketLifecycleRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	err = h.objsvc.PutBucketLifecycle(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WritePutBucketLifecycleResponse(w, r)
+	return
+}
+
+func (h *Handlers) GetBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.GetBucketLifecycleArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseGetBucketLifecycleRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	rules, err := h.objsvc.GetBucketLifecycle(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteGetBucketLifecycleResponse(w, r, rules)
+	return
+}
+
+func (h *Handlers) DeleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.DeleteBucketLifecycleArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseDeleteBucketLifecycleRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	err = h.objsvc.DeleteBucketLifecycle(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteDeleteBucketLifecycleResponse(w, r)
+	return
+}
+
+func (h *Handlers) PutBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.PutBucketPolicyArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParsePutBucketPolicyRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	err = h.objsvc.PutBucketPolicy(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WritePutBucketPolicyResponse(w, r)
+	return
+}
+
+func (h *Handlers) GetBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.GetBucketPolicyArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseGetBucketPolicyRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	p, err := h.objsvc.GetBucketPolicy(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteGetBucketPolicyResponse(w, r, p)
+	return
+}
+
+func (h *Handlers) DeleteBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.DeleteBucketPolicyArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseDeleteBucketPolicyRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	err = h.objsvc.DeleteBucketPolicy(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteDeleteBucketPolicyResponse(w, r)
+	return
+}
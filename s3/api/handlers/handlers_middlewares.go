@@ -79,6 +79,7 @@ func (h *Handlers) Sign(handler http.Handler) http.Handler {
 		}
 
 		contexts.SetAccessKey(r, ack)
+		contexts.SetSourceIP(r)
 
 		handler.ServeHTTP(w, r)
 	})
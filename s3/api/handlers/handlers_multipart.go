@@ -44,13 +44,13 @@ func (h *Handlers) UploadPartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	part, err := h.objsvc.UploadPart(r.Context(), args)
+	part, enc, err := h.objsvc.UploadPart(r.Context(), args)
 	if err != nil {
 		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
 		return
 	}
 
-	responses.WriteUploadPartResponse(w, r, part)
+	responses.WriteUploadPartResponse(w, r, part, enc)
 	return
 }
 
@@ -99,3 +99,49 @@ func (h *Handlers) CompleteMultipartUploadHandler(w http.ResponseWriter, r *http
 	responses.WriteCompleteMultipartUploadResponse(w, r, obj)
 	return
 }
+
+func (h *Handlers) ListMultipartUploadsHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.ListMultipartUploadsArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseListMultipartUploadsRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	list, err := h.objsvc.ListMultipartUploads(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteListMultipartUploadsResponse(w, r, list)
+	return
+}
+
+func (h *Handlers) ListPartsHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.ListPartsArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseListPartsRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	list, err := h.objsvc.ListParts(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteListPartsResponse(w, r, list)
+	return
+}
@@ -71,12 +71,20 @@ func (h *Handlers) HeadObjectHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	obj, _, err := h.objsvc.GetObject(r.Context(), args)
+	if err == object.ErrNotModified {
+		responses.WriteNotModifiedResponse(w, r, obj)
+		return
+	}
+	if err == object.ErrInvalidRange {
+		responses.WriteInvalidRangeResponse(w, r, obj)
+		return
+	}
 	if err != nil {
 		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
 		return
 	}
 
-	responses.WriteHeadObjectResponse(w, r, obj)
+	responses.WriteHeadObjectResponse(w, r, obj, args.Range)
 	return
 }
 
@@ -95,12 +103,20 @@ func (h *Handlers) GetObjectHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	obj, body, err := h.objsvc.GetObject(r.Context(), args)
+	if err == object.ErrNotModified {
+		responses.WriteNotModifiedResponse(w, r, obj)
+		return
+	}
+	if err == object.ErrInvalidRange {
+		responses.WriteInvalidRangeResponse(w, r, obj)
+		return
+	}
 	if err != nil {
 		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
 		return
 	}
 
-	responses.WriteGetObjectResponse(w, r, obj, body)
+	responses.WriteGetObjectResponse(w, r, obj, body, args.Range)
 	return
 }
 
@@ -117,13 +133,13 @@ func (h *Handlers) DeleteObjectHandler(w http.ResponseWriter, r *http.Request) {
 		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
 		return
 	}
-	err = h.objsvc.DeleteObject(r.Context(), args)
+	obj, err := h.objsvc.DeleteObject(r.Context(), args)
 	if err != nil {
 		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
 		return
 	}
 
-	responses.WriteDeleteObjectResponse(w, r, nil)
+	responses.WriteDeleteObjectResponse(w, r, obj)
 	return
 }
 
@@ -199,6 +215,30 @@ func (h *Handlers) ListObjectsV2Handler(w http.ResponseWriter, r *http.Request)
 	return
 }
 
+// ListObjectVersionsHandler .
+func (h *Handlers) ListObjectVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.ListObjectVersionsArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseListObjectVersionsRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	list, err := h.objsvc.ListObjectVersions(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteListObjectVersionsResponse(w, r, list)
+	return
+}
+
 // GetObjectACLHandler - GET Object ACL
 func (h *Handlers) GetObjectACLHandler(w http.ResponseWriter, r *http.Request) {
 	var args *object.GetObjectACLArgs
@@ -222,3 +262,75 @@ func (h *Handlers) GetObjectACLHandler(w http.ResponseWriter, r *http.Request) {
 	responses.WriteGetObjectACLResponse(w, r, acl)
 	return
 }
+
+// PutObjectTaggingHandler - PUT Object tagging
+func (h *Handlers) PutObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.PutObjectTaggingArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParsePutObjectTaggingRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	obj, err := h.objsvc.PutObjectTagging(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WritePutObjectTaggingResponse(w, r, obj)
+	return
+}
+
+// GetObjectTaggingHandler - GET Object tagging
+func (h *Handlers) GetObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.GetObjectTaggingArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseGetObjectTaggingRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	obj, err := h.objsvc.GetObjectTagging(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteGetObjectTaggingResponse(w, r, obj)
+	return
+}
+
+// DeleteObjectTaggingHandler - DELETE Object tagging
+func (h *Handlers) DeleteObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	var args *object.DeleteObjectTaggingArgs
+	var err error
+	defer func() {
+		contexts.SetHandleInf(r, h.name(), args, err)
+	}()
+
+	args, err = requests.ParseDeleteObjectTaggingRequest(r)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	obj, err := h.objsvc.DeleteObjectTagging(r.Context(), args)
+	if err != nil {
+		responses.WriteErrorResponse(w, r, h.toResponseErr(err))
+		return
+	}
+
+	responses.WriteDeleteObjectTaggingResponse(w, r, obj)
+	return
+}
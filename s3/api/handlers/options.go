@@ -40,6 +40,10 @@ var defaultCorsHeaders = []string{
 	consts.Range,
 	consts.UserAgent,
 	consts.Cid,
+	consts.BtfsCid,
+	consts.BtfsSourceCid,
+	consts.BtfsStorageStatus,
+	consts.BtfsStorageSession,
 	"Amz-*",
 	"amz-*",
 	"X-Amz*",
@@ -19,6 +19,14 @@ type Handlerser interface {
 	ListBucketsHandler(w http.ResponseWriter, r *http.Request)
 	PutBucketACLHandler(w http.ResponseWriter, r *http.Request)
 	GetBucketACLHandler(w http.ResponseWriter, r *http.Request)
+	PutBucketVersioningHandler(w http.ResponseWriter, r *http.Request)
+	GetBucketVersioningHandler(w http.ResponseWriter, r *http.Request)
+	PutBucketLifecycleHandler(w http.ResponseWriter, r *http.Request)
+	GetBucketLifecycleHandler(w http.ResponseWriter, r *http.Request)
+	DeleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request)
+	PutBucketPolicyHandler(w http.ResponseWriter, r *http.Request)
+	GetBucketPolicyHandler(w http.ResponseWriter, r *http.Request)
+	DeleteBucketPolicyHandler(w http.ResponseWriter, r *http.Request)
 
 	// Object
 
@@ -30,7 +38,11 @@ type Handlerser interface {
 	DeleteObjectsHandler(w http.ResponseWriter, r *http.Request)
 	ListObjectsHandler(w http.ResponseWriter, r *http.Request)
 	ListObjectsV2Handler(w http.ResponseWriter, r *http.Request)
+	ListObjectVersionsHandler(w http.ResponseWriter, r *http.Request)
 	GetObjectACLHandler(w http.ResponseWriter, r *http.Request)
+	PutObjectTaggingHandler(w http.ResponseWriter, r *http.Request)
+	GetObjectTaggingHandler(w http.ResponseWriter, r *http.Request)
+	DeleteObjectTaggingHandler(w http.ResponseWriter, r *http.Request)
 
 	// Multipart
 
@@ -38,4 +50,6 @@ type Handlerser interface {
 	UploadPartHandler(w http.ResponseWriter, r *http.Request)
 	AbortMultipartUploadHandler(w http.ResponseWriter, r *http.Request)
 	CompleteMultipartUploadHandler(w http.ResponseWriter, r *http.Request)
+	ListMultipartUploadsHandler(w http.ResponseWriter, r *http.Request)
+	ListPartsHandler(w http.ResponseWriter, r *http.Request)
 }
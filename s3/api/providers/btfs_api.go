@@ -1,6 +1,8 @@
 package providers
 
 import (
+	"context"
+	"fmt"
 	shell "github.com/bittorrent/go-btfs-api"
 	"github.com/mitchellh/go-homedir"
 	"io"
@@ -12,6 +14,7 @@ import (
 )
 
 var _ FileStorer = (*BtfsAPI)(nil)
+var _ StorageUploader = (*BtfsAPI)(nil)
 
 type BtfsAPI struct {
 	shell        *shell.Shell
@@ -68,6 +71,98 @@ func (api *BtfsAPI) Cat(id string) (rc io.ReadCloser, err error) {
 	return
 }
 
+// CatRange read length bytes from the offset of the file, the seek is done
+// by the node, so only the dag nodes cover the range will be fetched
+func (api *BtfsAPI) CatRange(id string, offset, length int64) (rc io.ReadCloser, err error) {
+	resp, err := api.shell.Request("cat", id).
+		Option("offset", offset).
+		Option("length", length).
+		Send(context.Background())
+	if err != nil {
+		return
+	}
+	if resp.Error != nil {
+		_ = resp.Close()
+		err = resp.Error
+		return
+	}
+	rc = resp.Output
+	return
+}
+
+// Pin pin the file added by others, so it will be kept as the stored ones
+// and can be removed with the same way
+func (api *BtfsAPI) Pin(id string) (err error) {
+	err = api.shell.Pin(id)
+	return
+}
+
+// IsPinned check if the file is pinned recursively by the node
+func (api *BtfsAPI) IsPinned(id string) (pinned bool, err error) {
+	var out struct {
+		Keys map[string]shell.PinInfo
+	}
+	err = api.shell.Request("pin/ls", id).
+		Option("type", shell.RecursivePin).
+		Exec(context.Background(), &out)
+	if err != nil {
+		if strings.Contains(err.Error(), "not pinned") {
+			err = nil
+		}
+		return
+	}
+	pinned = len(out.Keys) > 0
+	return
+}
+
+// Size get the size of the file, directories are not files
+func (api *BtfsAPI) Size(id string) (size int64, err error) {
+	stat, err := api.shell.FilesStat(context.Background(), "/btfs/"+id)
+	if err != nil {
+		return
+	}
+	if stat.Type != "file" {
+		err = ErrFileStoreNotFile
+		return
+	}
+	size = int64(stat.Size)
+	return
+}
+
+// Upload start a storage upload session of the file, the file is not reed-solomon
+// encoded, so it is stored as the copies of the whole file
+func (api *BtfsAPI) Upload(id string, options *StorageUploadOptions) (sessionId string, err error) {
+	req := api.shell.Request("storage/upload", id).
+		Option("copy", options.Copies-1).
+		Option("storage-length", options.StorageLength).
+		Option("autorenew", options.AutoRenew)
+	if options.Token != "" {
+		req.Option("token-type", options.Token)
+	}
+	// The price option of the upload is the price offered to hosts, the price
+	// cap of the policy limits the hosts selected instead
+	if options.MaxPrice > 0 {
+		req.Option("host-constraints", fmt.Sprintf("max-price=%d", options.MaxPrice))
+	}
+	var out struct {
+		ID string
+	}
+	err = req.Exec(context.Background(), &out)
+	if err != nil {
+		return
+	}
+	sessionId = out.ID
+	return
+}
+
+// UploadStatus get the status of the storage upload session
+func (api *BtfsAPI) UploadStatus(sessionId string) (status *StorageUploadStatus, err error) {
+	status = new(StorageUploadStatus)
+	err = api.shell.Request("storage/upload/status", sessionId).
+		Exec(context.Background(), status)
+	return
+}
+
 func (api *BtfsAPI) getLocalUrl() (url string, err error) {
 	baseDir := os.Getenv(shell.EnvDir)
 	if baseDir == "" {
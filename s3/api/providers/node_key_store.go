@@ -0,0 +1,82 @@
+package providers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"sync"
+
+	"github.com/bittorrent/go-btfs/keystore"
+	"github.com/bittorrent/go-btfs/s3/sse"
+	ci "github.com/libp2p/go-libp2p/core/crypto"
+)
+
+var _ KeyStorer = (*NodeKeyStore)(nil)
+
+const defaultNodeKeyStoreKeyName = "s3-sse"
+
+// NodeKeyStore wrap the data keys with the master key derived from a private
+// key kept in the node keystore, the private key will be generated at the
+// first use if it does not exist
+type NodeKeyStore struct {
+	ks      keystore.Keystore
+	keyName string
+	mu      sync.Mutex
+	master  []byte
+}
+
+func NewNodeKeyStore(ks keystore.Keystore) *NodeKeyStore {
+	return &NodeKeyStore{
+		ks:      ks,
+		keyName: defaultNodeKeyStoreKeyName,
+	}
+}
+
+func (n *NodeKeyStore) Wrap(key []byte) (wrapped []byte, err error) {
+	master, err := n.masterKey()
+	if err != nil {
+		return
+	}
+	wrapped, err = sse.Seal(master, key)
+	return
+}
+
+func (n *NodeKeyStore) Unwrap(wrapped []byte) (key []byte, err error) {
+	master, err := n.masterKey()
+	if err != nil {
+		return
+	}
+	key, err = sse.Unseal(master, wrapped)
+	return
+}
+
+func (n *NodeKeyStore) masterKey() (master []byte, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.master == nil {
+		n.master, err = n.loadMasterKey()
+	}
+	master = n.master
+	return
+}
+
+func (n *NodeKeyStore) loadMasterKey() (master []byte, err error) {
+	priv, err := n.ks.Get(n.keyName)
+	if errors.Is(err, keystore.ErrNoSuchKey) {
+		priv, _, err = ci.GenerateEd25519Key(rand.Reader)
+		if err != nil {
+			return
+		}
+		err = n.ks.Put(n.keyName, priv)
+	}
+	if err != nil {
+		return
+	}
+	raw, err := priv.Raw()
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(append([]byte(n.keyName+":"), raw...))
+	master = sum[:]
+	return
+}
@@ -8,17 +8,26 @@ import (
 var (
 	ErrStateStoreNotFound = errors.New("not found in state store")
 	ErrFileStoreNotFound  = errors.New("not found in file store")
+	ErrFileStoreNotFile   = errors.New("not a file in file store")
+	ErrKeyStoreNotSet     = errors.New("key store not set")
+	ErrUploaderNotSet     = errors.New("storage uploader not set")
 )
 
 type Providerser interface {
 	FileStore() FileStorer
 	StateStore() StateStorer
+	KeyStore() KeyStorer
+	StorageUploader() StorageUploader
 }
 
 type FileStorer interface {
 	Store(r io.Reader) (id string, err error)
 	Remove(id string) (err error)
 	Cat(id string) (readCloser io.ReadCloser, err error)
+	CatRange(id string, offset, length int64) (readCloser io.ReadCloser, err error)
+	Pin(id string) (err error)
+	IsPinned(id string) (pinned bool, err error)
+	Size(id string) (size int64, err error)
 }
 
 type StateStorer interface {
@@ -28,4 +37,31 @@ type StateStorer interface {
 	Iterate(prefix string, iterFunc StateStoreIterFunc) (err error)
 }
 
+// KeyStorer wrap the data keys with the key managed by the node, so the
+// data keys can be persisted in the state store
+type KeyStorer interface {
+	Wrap(key []byte) (wrapped []byte, err error)
+	Unwrap(wrapped []byte) (key []byte, err error)
+}
+
+// StorageUploader push the stored files into the paid decentralized storage
+// by the storage upload sessions of the node
+type StorageUploader interface {
+	Upload(id string, options *StorageUploadOptions) (sessionId string, err error)
+	UploadStatus(sessionId string) (status *StorageUploadStatus, err error)
+}
+
+type StorageUploadOptions struct {
+	Copies        int
+	StorageLength int
+	Token         string
+	MaxPrice      int64
+	AutoRenew     bool
+}
+
+type StorageUploadStatus struct {
+	Status  string
+	Message string
+}
+
 type StateStoreIterFunc func(key, value []byte) (stop bool, err error)
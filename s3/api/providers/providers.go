@@ -5,6 +5,8 @@ var _ Providerser = (*Providers)(nil)
 type Providers struct {
 	stateStore StateStorer
 	fileStore  FileStorer
+	keyStore   KeyStorer
+	uploader   StorageUploader
 }
 
 func NewProviders(stateStore StateStorer, fileStore FileStorer, options ...Option) (providers *Providers) {
@@ -25,3 +27,11 @@ func (p *Providers) StateStore() StateStorer {
 func (p *Providers) FileStore() FileStorer {
 	return p.fileStore
 }
+
+func (p *Providers) KeyStore() KeyStorer {
+	return p.keyStore
+}
+
+func (p *Providers) StorageUploader() StorageUploader {
+	return p.uploader
+}
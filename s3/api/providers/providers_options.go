@@ -1,3 +1,15 @@
 package providers
 
 type Option func(providers *Providers)
+
+func WithKeyStore(keyStore KeyStorer) Option {
+	return func(providers *Providers) {
+		providers.keyStore = keyStore
+	}
+}
+
+func WithStorageUploader(uploader StorageUploader) Option {
+	return func(providers *Providers) {
+		providers.uploader = uploader
+	}
+}
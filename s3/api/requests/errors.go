@@ -29,9 +29,35 @@ var (
 	ErrContinuationTokenInvalid       = errors.New("the continuation-token is invalid")
 	ErrStartAfterInvalid              = errors.New("the start-after is invalid")
 	ErrPartNumberInvalid              = errors.New("the part-number is invalid")
+	ErrPartNumberMarkerInvalid        = errors.New("the part-number-marker is invalid")
+	ErrMaxUploadsInvalid              = errors.New("the max-uploads is invalid")
+	ErrMaxPartsInvalid                = errors.New("the max-parts is invalid")
+	ErrUploadIdMarkerInvalid          = errors.New("the upload-id-marker is invalid")
 	ErrPartsCountInvalid              = errors.New("the parts-count is invalid")
 	ErrPartInvalid                    = errors.New("the part is invalid")
 	ErrPartOrderInvalid               = errors.New("the part-order is invalid")
+	ErrVersioningStatusInvalid        = errors.New("the versioning-status is invalid")
+	ErrVersionIdInvalid               = errors.New("the version-id is invalid")
+	ErrVersionIdMarkerInvalid         = errors.New("the version-id-marker is invalid")
+	ErrLifecycleRulesCountInvalid     = errors.New("the lifecycle rules-count is invalid")
+	ErrLifecycleRuleIdInvalid         = errors.New("the lifecycle rule-id is invalid")
+	ErrLifecycleRuleStatusInvalid     = errors.New("the lifecycle rule-status is invalid")
+	ErrLifecycleRuleFilterInvalid     = errors.New("the lifecycle rule-filter is invalid")
+	ErrLifecycleRuleActionInvalid     = errors.New("the lifecycle rule-action is invalid")
+	ErrLifecycleRuleUnsupported       = errors.New("the lifecycle rule is not supported by this server")
+	ErrBucketPolicyInvalid            = errors.New("the bucket policy is invalid")
+	ErrMetadataInvalid                = errors.New("the metadata is invalid")
+	ErrMetadataTooLarge               = errors.New("the metadata is too large")
+	ErrTaggingInvalid                 = errors.New("the tagging is invalid")
+	ErrTagsCountInvalid               = errors.New("the tags-count is invalid")
+	ErrSSEInvalid                     = errors.New("the server-side-encryption is invalid")
+	ErrSSECustomerAlgorithmInvalid    = errors.New("the sse-customer-algorithm is invalid")
+	ErrSSECustomerKeyInvalid          = errors.New("the sse-customer-key is invalid")
+	ErrSSECustomerKeyMD5Invalid       = errors.New("the sse-customer-key-md5 is invalid")
+	ErrSSECustomerParamsMissing       = errors.New("the sse-customer params are missing")
+	ErrSSEConflict                    = errors.New("the server-side-encryption conflicts with sse-customer params")
+	ErrSourceCidInvalid               = errors.New("the source-cid is invalid")
+	ErrSourceCidConflict              = errors.New("the source-cid conflicts with body or encryption")
 )
 
 // ErrInvalidInputValue .
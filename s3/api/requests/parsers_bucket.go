@@ -4,6 +4,10 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bittorrent/go-btfs/s3/api/contexts"
 	"github.com/bittorrent/go-btfs/s3/api/services/object"
+	"github.com/bittorrent/go-btfs/s3/consts"
+	"github.com/bittorrent/go-btfs/s3/hash"
+	"github.com/bittorrent/go-btfs/s3/policy"
+	"io"
 	"net/http"
 )
 
@@ -120,3 +124,190 @@ func ParseGetBucketACLRequest(r *http.Request) (args *object.GetBucketACLArgs, e
 	args.Bucket, err = ValidateBucketName(input.Bucket)
 	return
 }
+
+var putBucketVersioningSupports = fields{
+	"Bucket":                  true,
+	"VersioningConfiguration": true,
+}
+
+func ParsePutBucketVersioningRequest(r *http.Request) (args *object.PutBucketVersioningArgs, err error) {
+	var input s3.PutBucketVersioningInput
+	err = ParseLocation(r, &input, putBucketVersioningSupports)
+	if err != nil {
+		return
+	}
+	args = &object.PutBucketVersioningArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	size, err := ValidateContentLength(&r.ContentLength, consts.MaxXMLBodySize)
+	if err != nil {
+		return
+	}
+	r.Body, err = hash.NewReader(r.Body, size, "", "", size)
+	if err != nil {
+		return
+	}
+	err = ParseXMLBody(r, &input)
+	if err != nil {
+		return
+	}
+	args.Versioning, err = ValidateVersioningConfiguration(input.VersioningConfiguration)
+	return
+}
+
+var getBucketVersioningSupports = fields{
+	"Bucket": true,
+}
+
+func ParseGetBucketVersioningRequest(r *http.Request) (args *object.GetBucketVersioningArgs, err error) {
+	var input s3.GetBucketVersioningInput
+	err = ParseLocation(r, &input, getBucketVersioningSupports)
+	if err != nil {
+		return
+	}
+	args = &object.GetBucketVersioningArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	return
+}
+
+var putBucketLifecycleSupports = fields{
+	"Bucket":                 true,
+	"LifecycleConfiguration": true,
+}
+
+func ParsePutBucketLifecycleRequest(r *http.Request) (args *object.PutBucketLifecycleArgs, err error) {
+	var input s3.PutBucketLifecycleConfigurationInput
+	err = ParseLocation(r, &input, putBucketLifecycleSupports)
+	if err != nil {
+		return
+	}
+	args = &object.PutBucketLifecycleArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	size, err := ValidateContentLength(&r.ContentLength, consts.MaxXMLBodySize)
+	if err != nil {
+		return
+	}
+	r.Body, err = hash.NewReader(r.Body, size, "", "", size)
+	if err != nil {
+		return
+	}
+	err = ParseXMLBody(r, &input)
+	if err != nil {
+		return
+	}
+	args.Rules, err = ValidateLifecycleConfiguration(input.LifecycleConfiguration)
+	return
+}
+
+var getBucketLifecycleSupports = fields{
+	"Bucket": true,
+}
+
+func ParseGetBucketLifecycleRequest(r *http.Request) (args *object.GetBucketLifecycleArgs, err error) {
+	var input s3.GetBucketLifecycleConfigurationInput
+	err = ParseLocation(r, &input, getBucketLifecycleSupports)
+	if err != nil {
+		return
+	}
+	args = &object.GetBucketLifecycleArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	return
+}
+
+var deleteBucketLifecycleSupports = fields{
+	"Bucket": true,
+}
+
+func ParseDeleteBucketLifecycleRequest(r *http.Request) (args *object.DeleteBucketLifecycleArgs, err error) {
+	var input s3.DeleteBucketLifecycleInput
+	err = ParseLocation(r, &input, deleteBucketLifecycleSupports)
+	if err != nil {
+		return
+	}
+	args = &object.DeleteBucketLifecycleArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	return
+}
+
+var putBucketPolicySupports = fields{
+	"Bucket": true,
+	"Policy": true,
+}
+
+func ParsePutBucketPolicyRequest(r *http.Request) (args *object.PutBucketPolicyArgs, err error) {
+	var input s3.PutBucketPolicyInput
+	err = ParseLocation(r, &input, putBucketPolicySupports)
+	if err != nil {
+		return
+	}
+	args = &object.PutBucketPolicyArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	size, err := ValidateContentLength(&r.ContentLength, policy.MaxBucketPolicySize)
+	if err != nil {
+		return
+	}
+	r.Body, err = hash.NewReader(r.Body, size, "", "", size)
+	if err != nil {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	args.Policy, err = ValidateBucketPolicy(body, args.Bucket)
+	return
+}
+
+var getBucketPolicySupports = fields{
+	"Bucket": true,
+}
+
+func ParseGetBucketPolicyRequest(r *http.Request) (args *object.GetBucketPolicyArgs, err error) {
+	var input s3.GetBucketPolicyInput
+	err = ParseLocation(r, &input, getBucketPolicySupports)
+	if err != nil {
+		return
+	}
+	args = &object.GetBucketPolicyArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	return
+}
+
+var deleteBucketPolicySupports = fields{
+	"Bucket": true,
+}
+
+func ParseDeleteBucketPolicyRequest(r *http.Request) (args *object.DeleteBucketPolicyArgs, err error) {
+	var input s3.DeleteBucketPolicyInput
+	err = ParseLocation(r, &input, deleteBucketPolicySupports)
+	if err != nil {
+		return
+	}
+	args = &object.DeleteBucketPolicyArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	return
+}
@@ -12,13 +12,19 @@ import (
 )
 
 var createMultipartUploadSupports = fields{
-	"Bucket":          true,
-	"Key":             true,
-	"CacheControl":    true,
-	"ContentLength":   true,
-	"ContentEncoding": true,
-	"ContentType":     true,
-	"Expires":         true,
+	"Bucket":               true,
+	"Key":                  true,
+	"CacheControl":         true,
+	"ContentLength":        true,
+	"ContentEncoding":      true,
+	"ContentType":          true,
+	"Expires":              true,
+	"Metadata":             true,
+	"Tagging":              true,
+	"ServerSideEncryption": true,
+	"SSECustomerAlgorithm": true,
+	"SSECustomerKey":       true,
+	"SSECustomerKeyMD5":    true,
 }
 
 func ParseCreateMultipartUploadRequest(r *http.Request) (args *object.CreateMultipartUploadArgs, err error) {
@@ -47,18 +53,33 @@ func ParseCreateMultipartUploadRequest(r *http.Request) (args *object.CreateMult
 		return
 	}
 	args.Expires, err = ValidateExpires(input.Expires)
+	if err != nil {
+		return
+	}
+	args.Metadata, err = ValidateMetadata(input.Metadata)
+	if err != nil {
+		return
+	}
+	args.Tags, err = ValidateTagging(input.Tagging)
+	if err != nil {
+		return
+	}
+	args.SSE, err = ValidateServerSideEncryption(input.ServerSideEncryption, input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
 	return
 }
 
 var uploadPartSupports = fields{
-	"Body":           true,
-	"Bucket":         true,
-	"Key":            true,
-	"UploadId":       true,
-	"PartNumber":     true,
-	"ContentLength":  true,
-	"ContentMD5":     true,
-	"ChecksumSHA256": true,
+	"Body":                 true,
+	"Bucket":               true,
+	"Key":                  true,
+	"UploadId":             true,
+	"PartNumber":           true,
+	"ContentLength":        true,
+	"ContentMD5":           true,
+	"ChecksumSHA256":       true,
+	"SSECustomerAlgorithm": true,
+	"SSECustomerKey":       true,
+	"SSECustomerKeyMD5":    true,
 }
 
 func ParseUploadPartRequest(r *http.Request) (args *object.UploadPartArgs, err error) {
@@ -102,6 +123,10 @@ func ParseUploadPartRequest(r *http.Request) (args *object.UploadPartArgs, err e
 	if err != nil {
 		return
 	}
+	args.SSE, err = ValidateSSECustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return
+	}
 	args.Body, err = hash.NewReader(
 		r.Body, args.ContentLength, contentMD5,
 		checksumSHA256, args.ContentLength,
@@ -184,3 +209,102 @@ func ParseCompleteMultipartUploadRequest(r *http.Request) (args *object.Complete
 	args.CompletedParts, err = ValidateCompletedMultipartUpload(input.MultipartUpload)
 	return
 }
+
+var listMultipartUploadsSupports = fields{
+	"Bucket":         true,
+	"Delimiter":      true,
+	"EncodingType":   true,
+	"KeyMarker":      true,
+	"MaxUploads":     true,
+	"Prefix":         true,
+	"UploadIdMarker": true,
+}
+
+func ParseListMultipartUploadsRequest(r *http.Request) (args *object.ListMultipartUploadsArgs, err error) {
+	var input s3.ListMultipartUploadsInput
+	err = ParseLocation(r, &input, listMultipartUploadsSupports)
+	if err != nil {
+		var er ErrFailedParseValue
+		if errors.As(err, &er) && er.Name() == consts.MaxUploads {
+			err = ErrMaxUploadsInvalid
+		}
+		return
+	}
+	args = &object.ListMultipartUploadsArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	args.MaxUploads, err = ValidateMaxUploads(input.MaxUploads)
+	if err != nil {
+		return
+	}
+	args.Prefix, err = ValidatePrefix(input.Prefix)
+	if err != nil {
+		return
+	}
+	args.KeyMarker, err = ValidateMarker(input.KeyMarker)
+	if err != nil {
+		return
+	}
+	args.UploadIDMarker, err = ValidateUploadIdMarker(input.UploadIdMarker, args.KeyMarker)
+	if err != nil {
+		return
+	}
+	err = ValidateMarkerAndPrefixCombination(args.KeyMarker, args.Prefix)
+	if err != nil {
+		return
+	}
+	args.Delimiter, err = ValidateDelimiter(input.Delimiter)
+	if err != nil {
+		return
+	}
+	args.EncodingType, err = ValidateEncodingType(input.EncodingType)
+	return
+}
+
+var listPartsSupports = fields{
+	"Bucket":           true,
+	"Key":              true,
+	"UploadId":         true,
+	"MaxParts":         true,
+	"PartNumberMarker": true,
+}
+
+func ParseListPartsRequest(r *http.Request) (args *object.ListPartsArgs, err error) {
+	var input s3.ListPartsInput
+	err = ParseLocation(r, &input, listPartsSupports)
+	if err != nil {
+		var er ErrFailedParseValue
+		if errors.As(err, &er) && er.Name() == consts.MaxParts {
+			err = ErrMaxPartsInvalid
+		}
+		if errors.As(err, &er) && er.Name() == consts.PartNumberMarker {
+			err = ErrPartNumberMarkerInvalid
+		}
+		return
+	}
+	args = &object.ListPartsArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	args.Object, err = ValidateObjectName(input.Key)
+	if err != nil {
+		return
+	}
+	args.UploadId, err = ValidateUploadId(input.UploadId)
+	if err != nil {
+		return
+	}
+	args.MaxParts, err = ValidateMaxParts(input.MaxParts)
+	if err != nil {
+		return
+	}
+	args.PartNumberMarker, err = ValidatePartNumberMarker(input.PartNumberMarker)
+	return
+}
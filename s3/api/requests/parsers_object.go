@@ -17,13 +17,19 @@ var putObjectSupports = fields{
 	"Key":    true,
 	// The browser some time automatically add this CacheControl header
 	// just allow, do not handle
-	"CacheControl":    true,
-	"ContentLength":   true,
-	"ContentEncoding": true,
-	"ContentType":     true,
-	"Expires":         true,
-	"ContentMD5":      true,
-	"ChecksumSHA256":  true,
+	"CacheControl":         true,
+	"ContentLength":        true,
+	"ContentEncoding":      true,
+	"ContentType":          true,
+	"Expires":              true,
+	"ContentMD5":           true,
+	"ChecksumSHA256":       true,
+	"Metadata":             true,
+	"Tagging":              true,
+	"ServerSideEncryption": true,
+	"SSECustomerAlgorithm": true,
+	"SSECustomerKey":       true,
+	"SSECustomerKeyMD5":    true,
 }
 
 func ParsePutObjectRequest(r *http.Request) (args *object.PutObjectArgs, err error) {
@@ -59,6 +65,26 @@ func ParsePutObjectRequest(r *http.Request) (args *object.PutObjectArgs, err err
 	if err != nil {
 		return
 	}
+	args.Metadata, err = ValidateMetadata(input.Metadata)
+	if err != nil {
+		return
+	}
+	args.Tags, err = ValidateTagging(input.Tagging)
+	if err != nil {
+		return
+	}
+	args.SSE, err = ValidateServerSideEncryption(input.ServerSideEncryption, input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return
+	}
+	args.SourceCID, err = ValidateSourceCid(r.Header.Get(consts.BtfsSourceCid))
+	if err != nil {
+		return
+	}
+	if args.SourceCID != "" && (args.ContentLength > 0 || args.SSE != nil) {
+		err = ErrSourceCidConflict
+		return
+	}
 	contentMD5, err := ValidateContentMD5(input.ContentMD5)
 	if err != nil {
 		return
@@ -80,11 +106,21 @@ var copyObjectSupports = fields{
 	"CopySource": true,
 	// The browser some time automatically add this CacheControl header
 	// just allow, do not handle
-	"CacheControl":      true,
-	"ContentEncoding":   true,
-	"ContentType":       true,
-	"Expires":           true,
-	"MetadataDirective": true,
+	"CacheControl":                   true,
+	"ContentEncoding":                true,
+	"ContentType":                    true,
+	"Expires":                        true,
+	"MetadataDirective":              true,
+	"Metadata":                       true,
+	"TaggingDirective":               true,
+	"Tagging":                        true,
+	"ServerSideEncryption":           true,
+	"SSECustomerAlgorithm":           true,
+	"SSECustomerKey":                 true,
+	"SSECustomerKeyMD5":              true,
+	"CopySourceSSECustomerAlgorithm": true,
+	"CopySourceSSECustomerKey":       true,
+	"CopySourceSSECustomerKeyMD5":    true,
 }
 
 func ParseCopyObjectRequest(r *http.Request) (args *object.CopyObjectArgs, err error) {
@@ -104,7 +140,7 @@ func ParseCopyObjectRequest(r *http.Request) (args *object.CopyObjectArgs, err e
 	if err != nil {
 		return
 	}
-	args.SrcBucket, args.SrcObject, err = ValidateCopySource(input.CopySource)
+	args.SrcBucket, args.SrcObject, args.SrcVersionID, err = ValidateCopySource(input.CopySource)
 	if err != nil {
 		return
 	}
@@ -112,6 +148,10 @@ func ParseCopyObjectRequest(r *http.Request) (args *object.CopyObjectArgs, err e
 	if err != nil {
 		return
 	}
+	args.ReplaceTags, err = ValidateTaggingDirective(input.TaggingDirective)
+	if err != nil {
+		return
+	}
 	if args.Bucket == args.SrcBucket && args.Object == args.SrcObject {
 		err = ErrCopyDestInvalid
 		return
@@ -125,12 +165,37 @@ func ParseCopyObjectRequest(r *http.Request) (args *object.CopyObjectArgs, err e
 		return
 	}
 	args.Expires, err = ValidateExpires(input.Expires)
+	if err != nil {
+		return
+	}
+	args.Metadata, err = ValidateMetadata(input.Metadata)
+	if err != nil {
+		return
+	}
+	args.Tags, err = ValidateTagging(input.Tagging)
+	if err != nil {
+		return
+	}
+	args.SSE, err = ValidateServerSideEncryption(input.ServerSideEncryption, input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return
+	}
+	args.SrcSSE, err = ValidateSSECustomerKey(input.CopySourceSSECustomerAlgorithm, input.CopySourceSSECustomerKey, input.CopySourceSSECustomerKeyMD5)
 	return
 }
 
 var headObjectSupports = fields{
-	"Bucket": true,
-	"Key":    true,
+	"Bucket":               true,
+	"Key":                  true,
+	"VersionId":            true,
+	"Range":                true,
+	"IfMatch":              true,
+	"IfNoneMatch":          true,
+	"IfModifiedSince":      true,
+	"IfUnmodifiedSince":    true,
+	"SSECustomerAlgorithm": true,
+	"SSECustomerKey":       true,
+	"SSECustomerKeyMD5":    true,
 }
 
 func ParseHeadObjectRequest(r *http.Request) (args *object.GetObjectArgs, err error) {
@@ -150,13 +215,38 @@ func ParseHeadObjectRequest(r *http.Request) (args *object.GetObjectArgs, err er
 	if err != nil {
 		return
 	}
+	args.VersionID, err = ValidateVersionId(input.VersionId)
+	if err != nil {
+		return
+	}
+	args.Range, err = ValidateRange(input.Range)
+	if err != nil {
+		return
+	}
+	args.Conditions, err = ValidateObjectConditions(input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	if err != nil {
+		return
+	}
+	args.SSE, err = ValidateSSECustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return
+	}
 	args.WithBody = false
 	return
 }
 
 var getObjectSupports = fields{
-	"Bucket": true,
-	"Key":    true,
+	"Bucket":               true,
+	"Key":                  true,
+	"VersionId":            true,
+	"Range":                true,
+	"IfMatch":              true,
+	"IfNoneMatch":          true,
+	"IfModifiedSince":      true,
+	"IfUnmodifiedSince":    true,
+	"SSECustomerAlgorithm": true,
+	"SSECustomerKey":       true,
+	"SSECustomerKeyMD5":    true,
 }
 
 func ParseGetObjectRequest(r *http.Request) (args *object.GetObjectArgs, err error) {
@@ -176,13 +266,30 @@ func ParseGetObjectRequest(r *http.Request) (args *object.GetObjectArgs, err err
 	if err != nil {
 		return
 	}
+	args.VersionID, err = ValidateVersionId(input.VersionId)
+	if err != nil {
+		return
+	}
+	args.Range, err = ValidateRange(input.Range)
+	if err != nil {
+		return
+	}
+	args.Conditions, err = ValidateObjectConditions(input.IfMatch, input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	if err != nil {
+		return
+	}
+	args.SSE, err = ValidateSSECustomerKey(input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return
+	}
 	args.WithBody = true
 	return
 }
 
 var deleteObjectSupports = fields{
-	"Bucket": true,
-	"Key":    true,
+	"Bucket":    true,
+	"Key":       true,
+	"VersionId": true,
 }
 
 func ParseDeleteObjectRequest(r *http.Request) (args *object.DeleteObjectArgs, err error) {
@@ -199,6 +306,10 @@ func ParseDeleteObjectRequest(r *http.Request) (args *object.DeleteObjectArgs, e
 		return
 	}
 	args.Object, err = ValidateObjectName(input.Key)
+	if err != nil {
+		return
+	}
+	args.VersionID, err = ValidateVersionId(input.VersionId)
 	return
 }
 
@@ -346,6 +457,61 @@ func ParseListObjectsV2Request(r *http.Request) (args *object.ListObjectsV2Args,
 	return
 }
 
+var listObjectVersionsSupports = fields{
+	"Bucket":          true,
+	"MaxKeys":         true,
+	"Prefix":          true,
+	"KeyMarker":       true,
+	"VersionIdMarker": true,
+	"Delimiter":       true,
+	"EncodingType":    true,
+}
+
+func ParseListObjectVersionsRequest(r *http.Request) (args *object.ListObjectVersionsArgs, err error) {
+	var input s3.ListObjectVersionsInput
+	err = ParseLocation(r, &input, listObjectVersionsSupports)
+	if err != nil {
+		var er ErrFailedParseValue
+		if errors.As(err, &er) && er.Name() == consts.MaxKeys {
+			err = ErrMaxKeysInvalid
+		}
+		return
+	}
+	args = &object.ListObjectVersionsArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	args.MaxKeys, err = ValidateMaxKeys(input.MaxKeys)
+	if err != nil {
+		return
+	}
+	args.Prefix, err = ValidatePrefix(input.Prefix)
+	if err != nil {
+		return
+	}
+	args.KeyMarker, err = ValidateMarker(input.KeyMarker)
+	if err != nil {
+		return
+	}
+	args.VersionIDMarker, err = ValidateVersionIdMarker(input.VersionIdMarker, args.KeyMarker)
+	if err != nil {
+		return
+	}
+	err = ValidateMarkerAndPrefixCombination(args.KeyMarker, args.Prefix)
+	if err != nil {
+		return
+	}
+	args.Delimiter, err = ValidateDelimiter(input.Delimiter)
+	if err != nil {
+		return
+	}
+	args.EncodingType, err = ValidateEncodingType(input.EncodingType)
+	return
+}
+
 var getObjectACLSupports = fields{
 	"Bucket": true,
 	"Key":    true,
@@ -367,3 +533,101 @@ func ParseGetObjectACLRequest(r *http.Request) (args *object.GetObjectACLArgs, e
 	args.Object, err = ValidateObjectName(input.Key)
 	return
 }
+
+var putObjectTaggingSupports = fields{
+	"Bucket":    true,
+	"Key":       true,
+	"VersionId": true,
+	"Tagging":   true,
+}
+
+func ParsePutObjectTaggingRequest(r *http.Request) (args *object.PutObjectTaggingArgs, err error) {
+	var input s3.PutObjectTaggingInput
+	err = ParseLocation(r, &input, putObjectTaggingSupports)
+	if err != nil {
+		return
+	}
+	args = &object.PutObjectTaggingArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	args.Object, err = ValidateObjectName(input.Key)
+	if err != nil {
+		return
+	}
+	args.VersionID, err = ValidateVersionId(input.VersionId)
+	if err != nil {
+		return
+	}
+	size, err := ValidateContentLength(&r.ContentLength, consts.MaxXMLBodySize)
+	if err != nil {
+		return
+	}
+	r.Body, err = hash.NewReader(r.Body, size, "", "", size)
+	if err != nil {
+		return
+	}
+	err = ParseXMLBody(r, &input)
+	if err != nil {
+		return
+	}
+	args.Tags, err = ValidateTagSet(input.Tagging)
+	return
+}
+
+var getObjectTaggingSupports = fields{
+	"Bucket":    true,
+	"Key":       true,
+	"VersionId": true,
+}
+
+func ParseGetObjectTaggingRequest(r *http.Request) (args *object.GetObjectTaggingArgs, err error) {
+	var input s3.GetObjectTaggingInput
+	err = ParseLocation(r, &input, getObjectTaggingSupports)
+	if err != nil {
+		return
+	}
+	args = &object.GetObjectTaggingArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	args.Object, err = ValidateObjectName(input.Key)
+	if err != nil {
+		return
+	}
+	args.VersionID, err = ValidateVersionId(input.VersionId)
+	return
+}
+
+var deleteObjectTaggingSupports = fields{
+	"Bucket":    true,
+	"Key":       true,
+	"VersionId": true,
+}
+
+func ParseDeleteObjectTaggingRequest(r *http.Request) (args *object.DeleteObjectTaggingArgs, err error) {
+	var input s3.DeleteObjectTaggingInput
+	err = ParseLocation(r, &input, deleteObjectTaggingSupports)
+	if err != nil {
+		return
+	}
+	args = &object.DeleteObjectTaggingArgs{
+		UserId: contexts.GetAccessKey(r),
+	}
+	args.Bucket, err = ValidateBucketName(input.Bucket)
+	if err != nil {
+		return
+	}
+	args.Object, err = ValidateObjectName(input.Key)
+	if err != nil {
+		return
+	}
+	args.VersionID, err = ValidateVersionId(input.VersionId)
+	return
+}
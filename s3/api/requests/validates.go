@@ -5,13 +5,18 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bittorrent/go-btfs/s3/api/services/object"
 	"github.com/bittorrent/go-btfs/s3/consts"
 	"github.com/bittorrent/go-btfs/s3/etag"
+	"github.com/bittorrent/go-btfs/s3/policy"
+	"github.com/bittorrent/go-btfs/s3/sse"
+	"github.com/ipfs/go-cid"
 	"net/url"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -114,6 +119,20 @@ func ValidateContentMD5(contentMD5 *string) (val string, err error) {
 	return
 }
 
+// ValidateSourceCid check the cid of the file imported as the object body
+func ValidateSourceCid(sourceCid string) (val string, err error) {
+	if sourceCid == "" {
+		return
+	}
+	c, err := cid.Decode(sourceCid)
+	if err != nil {
+		err = ErrSourceCidInvalid
+		return
+	}
+	val = c.String()
+	return
+}
+
 func ValidateChecksumSHA256(checksumSHA256 *string) (val string, err error) {
 	if checksumSHA256 == nil || *checksumSHA256 == "" {
 		return
@@ -181,11 +200,25 @@ func ValidateExpires(expires *time.Time) (val time.Time, err error) {
 	return
 }
 
-func ValidateCopySource(copySource *string) (val1, val2 string, err error) {
+func ValidateCopySource(copySource *string) (val1, val2, val3 string, err error) {
 	if copySource == nil {
 		return
 	}
-	src, err := url.PathUnescape(*copySource)
+	src := *copySource
+	if idx := strings.Index(src, "?"); idx >= 0 {
+		var query url.Values
+		query, err = url.ParseQuery(src[idx+1:])
+		if err != nil {
+			err = ErrCopySrcInvalid
+			return
+		}
+		val3, err = ValidateVersionId(aws.String(query.Get(consts.VersionId)))
+		if err != nil {
+			return
+		}
+		src = src[:idx]
+	}
+	src, err = url.PathUnescape(src)
 	if err != nil {
 		err = ErrCopySrcInvalid
 		return
@@ -220,6 +253,152 @@ func ValidateMetadataDirective(metadataDirective *string) (val bool, err error)
 	return
 }
 
+func ValidateTaggingDirective(taggingDirective *string) (val bool, err error) {
+	if taggingDirective == nil {
+		return
+	}
+	if *taggingDirective == "REPLACE" {
+		val = true
+	}
+	return
+}
+
+// ValidateMetadata check the user-defined metadata of x-amz-meta-* headers, the
+// total size of the keys and values cannot exceed the max user metadata size
+func ValidateMetadata(metadata map[string]*string) (val map[string]string, err error) {
+	if len(metadata) == 0 {
+		return
+	}
+	size := 0
+	val = make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if k == "" {
+			err = ErrMetadataInvalid
+			return
+		}
+		val[k] = aws.StringValue(v)
+		size += len(k) + len(val[k])
+	}
+	if size > consts.MaxUserMetadata {
+		err = ErrMetadataTooLarge
+	}
+	return
+}
+
+// ValidateTagging parse the url query encoded tags of x-amz-tagging header
+func ValidateTagging(tagging *string) (val map[string]string, err error) {
+	if tagging == nil || *tagging == "" {
+		return
+	}
+	query, err := url.ParseQuery(*tagging)
+	if err != nil {
+		err = ErrTaggingInvalid
+		return
+	}
+	val = make(map[string]string, len(query))
+	for k, vs := range query {
+		if len(vs) != 1 {
+			err = ErrTaggingInvalid
+			return
+		}
+		err = validateTag(k, vs[0], val)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ValidateTagSet check the tag set of the object tagging request body
+func ValidateTagSet(tagging *s3.Tagging) (val map[string]string, err error) {
+	if tagging == nil {
+		err = ErrTaggingInvalid
+		return
+	}
+	val = make(map[string]string, len(tagging.TagSet))
+	for _, tag := range tagging.TagSet {
+		if tag == nil {
+			err = ErrTaggingInvalid
+			return
+		}
+		err = validateTag(aws.StringValue(tag.Key), aws.StringValue(tag.Value), val)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func validateTag(key, value string, tags map[string]string) (err error) {
+	if key == "" || utf8.RuneCountInString(key) > consts.MaxTagKeyLength ||
+		utf8.RuneCountInString(value) > consts.MaxTagValueLength {
+		err = ErrTaggingInvalid
+		return
+	}
+	if _, ok := tags[key]; ok {
+		err = ErrTaggingInvalid
+		return
+	}
+	if len(tags) >= consts.MaxObjectTags {
+		err = ErrTagsCountInvalid
+		return
+	}
+	tags[key] = value
+	return
+}
+
+// ValidateServerSideEncryption check the sse headers of the request writing new
+// object, the SSE-S3 and SSE-C cannot be requested at the same time
+func ValidateServerSideEncryption(serverSideEncryption, algorithm, key, keyMD5 *string) (val *object.ServerSideEncryption, err error) {
+	val, err = ValidateSSECustomerKey(algorithm, key, keyMD5)
+	if err != nil || serverSideEncryption == nil {
+		return
+	}
+	if val != nil {
+		err = ErrSSEConflict
+		return
+	}
+	if *serverSideEncryption != s3.ServerSideEncryptionAes256 {
+		err = ErrSSEInvalid
+		return
+	}
+	val = &object.ServerSideEncryption{
+		Type: sse.TypeSSES3,
+	}
+	return
+}
+
+// ValidateSSECustomerKey check the SSE-C headers, the key must be a base64
+// encoded 256-bit key and match the base64 encoded md5
+func ValidateSSECustomerKey(algorithm, key, keyMD5 *string) (val *object.ServerSideEncryption, err error) {
+	if algorithm == nil && key == nil && keyMD5 == nil {
+		return
+	}
+	if algorithm == nil || key == nil || keyMD5 == nil {
+		err = ErrSSECustomerParamsMissing
+		return
+	}
+	if *algorithm != sse.AlgorithmAES256 {
+		err = ErrSSECustomerAlgorithmInvalid
+		return
+	}
+	k, er := base64.StdEncoding.DecodeString(*key)
+	if er != nil || len(k) != sse.KeySize {
+		err = ErrSSECustomerKeyInvalid
+		return
+	}
+	if sse.KeyMD5(k) != *keyMD5 {
+		err = ErrSSECustomerKeyMD5Invalid
+		return
+	}
+	val = &object.ServerSideEncryption{
+		Type:           sse.TypeSSEC,
+		CustomerKey:    k,
+		CustomerKeyMD5: *keyMD5,
+	}
+	return
+}
+
 func ValidateObjectsDelete(delete *s3.Delete) (vals []*object.ToDeleteObject, quite bool, err error) {
 	if delete == nil {
 		err = ErrFailedDecodeXML{errors.New("delete is nil")}
@@ -235,11 +414,48 @@ func ValidateObjectsDelete(delete *s3.Delete) (vals []*object.ToDeleteObject, qu
 	for _, obj := range delete.Objects {
 		deleteObj := &object.ToDeleteObject{}
 		deleteObj.Object, deleteObj.ValidateErr = ValidateObjectName(obj.Key)
+		if deleteObj.ValidateErr == nil {
+			deleteObj.VersionID, deleteObj.ValidateErr = ValidateVersionId(obj.VersionId)
+		}
 		vals = append(vals, deleteObj)
 	}
 	return
 }
 
+func ValidateVersioningConfiguration(configuration *s3.VersioningConfiguration) (val string, err error) {
+	if configuration == nil || configuration.Status == nil {
+		err = ErrVersioningStatusInvalid
+		return
+	}
+	val = *configuration.Status
+	if val != object.VersioningEnabled && val != object.VersioningSuspended {
+		err = ErrVersioningStatusInvalid
+	}
+	return
+}
+
+func ValidateVersionId(versionId *string) (val string, err error) {
+	if versionId == nil || *versionId == "" {
+		return
+	}
+	val = *versionId
+	if len(val) > consts.MaxVersionIdLength || strings.Contains(val, consts.SlashSeparator) {
+		err = ErrVersionIdInvalid
+	}
+	return
+}
+
+func ValidateVersionIdMarker(versionIdMarker *string, keyMarker string) (val string, err error) {
+	val, err = ValidateVersionId(versionIdMarker)
+	if err != nil {
+		return
+	}
+	if val != "" && keyMarker == "" {
+		err = ErrVersionIdMarkerInvalid
+	}
+	return
+}
+
 func ValidateMaxKeys(maxKeys *int64) (val int64, err error) {
 	if maxKeys == nil || *maxKeys > consts.MaxObjectList {
 		val = consts.MaxObjectList
@@ -346,6 +562,55 @@ func ValidateUploadId(uploadId *string) (val string, err error) {
 	return
 }
 
+func ValidateMaxUploads(maxUploads *int64) (val int64, err error) {
+	if maxUploads == nil || *maxUploads > consts.MaxUploadsList {
+		val = consts.MaxUploadsList
+		return
+	}
+	if *maxUploads < 0 {
+		err = ErrMaxUploadsInvalid
+		return
+	}
+	val = *maxUploads
+	return
+}
+
+func ValidateUploadIdMarker(uploadIdMarker *string, keyMarker string) (val string, err error) {
+	if uploadIdMarker == nil || *uploadIdMarker == "" {
+		return
+	}
+	val = *uploadIdMarker
+	if keyMarker == "" || strings.Contains(val, consts.SlashSeparator) {
+		err = ErrUploadIdMarkerInvalid
+	}
+	return
+}
+
+func ValidateMaxParts(maxParts *int64) (val int64, err error) {
+	if maxParts == nil || *maxParts > consts.MaxPartsList {
+		val = consts.MaxPartsList
+		return
+	}
+	if *maxParts < 0 {
+		err = ErrMaxPartsInvalid
+		return
+	}
+	val = *maxParts
+	return
+}
+
+func ValidatePartNumberMarker(partNumberMarker *int64) (val int64, err error) {
+	if partNumberMarker == nil {
+		return
+	}
+	if *partNumberMarker < 0 {
+		err = ErrPartNumberMarkerInvalid
+		return
+	}
+	val = *partNumberMarker
+	return
+}
+
 func ValidatePartNumber(partNumber *int64) (val int64, err error) {
 	if partNumber == nil {
 		return
@@ -387,3 +652,235 @@ func ValidateCompletedMultipartUpload(upload *s3.CompletedMultipartUpload) (val
 	}
 	return
 }
+
+func ValidateLifecycleConfiguration(configuration *s3.BucketLifecycleConfiguration) (vals []*object.LifecycleRule, err error) {
+	if configuration == nil {
+		err = ErrFailedDecodeXML{errors.New("lifecycle configuration is nil")}
+		return
+	}
+	if len(configuration.Rules) < 1 || len(configuration.Rules) > consts.MaxLifecycleRules {
+		err = ErrLifecycleRulesCountInvalid
+		return
+	}
+	ids := make(map[string]struct{}, len(configuration.Rules))
+	for _, rule := range configuration.Rules {
+		var val *object.LifecycleRule
+		val, err = ValidateLifecycleRule(rule)
+		if err != nil {
+			return
+		}
+		if val.ID != "" {
+			if _, ok := ids[val.ID]; ok {
+				err = ErrLifecycleRuleIdInvalid
+				return
+			}
+			ids[val.ID] = struct{}{}
+		}
+		vals = append(vals, val)
+	}
+	return
+}
+
+func ValidateLifecycleRule(rule *s3.LifecycleRule) (val *object.LifecycleRule, err error) {
+	if rule == nil {
+		err = ErrFailedDecodeXML{errors.New("lifecycle rule is nil")}
+		return
+	}
+	if len(rule.Transitions) > 0 || len(rule.NoncurrentVersionTransitions) > 0 {
+		err = ErrLifecycleRuleUnsupported
+		return
+	}
+	val = &object.LifecycleRule{
+		ID: aws.StringValue(rule.ID),
+	}
+	if len(val.ID) > consts.MaxLifecycleRuleId {
+		err = ErrLifecycleRuleIdInvalid
+		return
+	}
+	switch aws.StringValue(rule.Status) {
+	case s3.ExpirationStatusEnabled:
+		val.Enabled = true
+	case s3.ExpirationStatusDisabled:
+	default:
+		err = ErrLifecycleRuleStatusInvalid
+		return
+	}
+	err = validateLifecycleRuleFilter(rule, val)
+	if err != nil {
+		return
+	}
+	if rule.Expiration == nil && rule.NoncurrentVersionExpiration == nil &&
+		rule.AbortIncompleteMultipartUpload == nil {
+		err = ErrLifecycleRuleActionInvalid
+		return
+	}
+	if exp := rule.Expiration; exp != nil {
+		if aws.BoolValue(exp.ExpiredObjectDeleteMarker) {
+			err = ErrLifecycleRuleUnsupported
+			return
+		}
+		days, date := aws.Int64Value(exp.Days), aws.TimeValue(exp.Date)
+		if (days > 0) == !date.IsZero() || days < 0 {
+			err = ErrLifecycleRuleActionInvalid
+			return
+		}
+		val.ExpirationDays, val.ExpirationDate = days, date.UTC()
+	}
+	if exp := rule.NoncurrentVersionExpiration; exp != nil {
+		if exp.NewerNoncurrentVersions != nil {
+			err = ErrLifecycleRuleUnsupported
+			return
+		}
+		val.NoncurrentExpirationDays = aws.Int64Value(exp.NoncurrentDays)
+		if val.NoncurrentExpirationDays <= 0 {
+			err = ErrLifecycleRuleActionInvalid
+			return
+		}
+	}
+	if abort := rule.AbortIncompleteMultipartUpload; abort != nil {
+		if len(val.Tags) > 0 || val.SizeGreaterThan > 0 || val.SizeLessThan > 0 {
+			err = ErrLifecycleRuleActionInvalid
+			return
+		}
+		val.AbortIncompleteUploadDays = aws.Int64Value(abort.DaysAfterInitiation)
+		if val.AbortIncompleteUploadDays <= 0 {
+			err = ErrLifecycleRuleActionInvalid
+			return
+		}
+	}
+	return
+}
+
+func validateLifecycleRuleFilter(rule *s3.LifecycleRule, val *object.LifecycleRule) (err error) {
+	filter := rule.Filter
+	if filter == nil {
+		val.Prefix = aws.StringValue(rule.Prefix)
+		return
+	}
+	if rule.Prefix != nil {
+		err = ErrLifecycleRuleFilterInvalid
+		return
+	}
+	var count int
+	if filter.Prefix != nil {
+		val.Prefix = *filter.Prefix
+		count++
+	}
+	if filter.Tag != nil {
+		val.Tags = make(map[string]string)
+		err = validateLifecycleTag(filter.Tag, val.Tags)
+		if err != nil {
+			return
+		}
+		count++
+	}
+	if filter.ObjectSizeGreaterThan != nil {
+		val.SizeGreaterThan = *filter.ObjectSizeGreaterThan
+		count++
+	}
+	if filter.ObjectSizeLessThan != nil {
+		val.SizeLessThan = *filter.ObjectSizeLessThan
+		count++
+	}
+	if and := filter.And; and != nil {
+		val.Prefix = aws.StringValue(and.Prefix)
+		val.SizeGreaterThan = aws.Int64Value(and.ObjectSizeGreaterThan)
+		val.SizeLessThan = aws.Int64Value(and.ObjectSizeLessThan)
+		if len(and.Tags) > 0 {
+			val.Tags = make(map[string]string, len(and.Tags))
+		}
+		for _, tag := range and.Tags {
+			err = validateLifecycleTag(tag, val.Tags)
+			if err != nil {
+				return
+			}
+		}
+		count++
+	}
+	if count > 1 || val.SizeGreaterThan < 0 || val.SizeLessThan < 0 ||
+		(val.SizeLessThan > 0 && val.SizeGreaterThan >= val.SizeLessThan) {
+		err = ErrLifecycleRuleFilterInvalid
+	}
+	return
+}
+
+func validateLifecycleTag(tag *s3.Tag, tags map[string]string) (err error) {
+	if tag == nil || aws.StringValue(tag.Key) == "" {
+		err = ErrLifecycleRuleFilterInvalid
+		return
+	}
+	if _, ok := tags[*tag.Key]; ok {
+		err = ErrLifecycleRuleFilterInvalid
+		return
+	}
+	tags[*tag.Key] = aws.StringValue(tag.Value)
+	return
+}
+
+// ValidateRange parse the single byte range of RFC 7233, the malformed or
+// multiple ranges are ignored as allowed by the RFC, then the whole object
+// will be responded
+func ValidateRange(rng *string) (val *object.ObjectRange, err error) {
+	if rng == nil {
+		return
+	}
+	spec, ok := strings.CutPrefix(strings.TrimSpace(*rng), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return
+	}
+	start, end := int64(-1), int64(-1)
+	if first != "" {
+		start, err = strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			err = nil
+			return
+		}
+	}
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < 0 {
+			err = nil
+			return
+		}
+	}
+	if first == "" && last == "" {
+		return
+	}
+	if first != "" && last != "" && end < start {
+		return
+	}
+	val = &object.ObjectRange{
+		Start: start,
+		End:   end,
+	}
+	return
+}
+
+func ValidateObjectConditions(ifMatch, ifNoneMatch *string, ifModifiedSince, ifUnmodifiedSince *time.Time) (val *object.ObjectConditions, err error) {
+	if ifMatch == nil && ifNoneMatch == nil && ifModifiedSince == nil && ifUnmodifiedSince == nil {
+		return
+	}
+	val = &object.ObjectConditions{
+		IfMatch:     aws.StringValue(ifMatch),
+		IfNoneMatch: aws.StringValue(ifNoneMatch),
+	}
+	if ifModifiedSince != nil {
+		val.IfModifiedSince = *ifModifiedSince
+	}
+	if ifUnmodifiedSince != nil {
+		val.IfUnmodifiedSince = *ifUnmodifiedSince
+	}
+	return
+}
+
+func ValidateBucketPolicy(data []byte, bucket string) (val *policy.Policy, err error) {
+	val, err = policy.ParseBucketPolicy(data, bucket)
+	if err != nil {
+		err = ErrBucketPolicyInvalid
+	}
+	return
+}
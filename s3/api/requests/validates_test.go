@@ -0,0 +1,147 @@
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/bittorrent/go-btfs/s3/api/services/object"
+	"github.com/bittorrent/go-btfs/s3/consts"
+)
+
+func TestValidateRange(t *testing.T) {
+	tests := []struct {
+		rng      string
+		expected *object.ObjectRange
+	}{
+		{"bytes=0-9", &object.ObjectRange{Start: 0, End: 9}},
+		{"bytes=5-5", &object.ObjectRange{Start: 5, End: 5}},
+		{" bytes= 3- ", &object.ObjectRange{Start: 3, End: -1}},
+		{"bytes=3-", &object.ObjectRange{Start: 3, End: -1}},
+		{"bytes=-4", &object.ObjectRange{Start: -1, End: 4}},
+		{"bytes=100-200", &object.ObjectRange{Start: 100, End: 200}},
+		// the ranges ignored as no range, the whole object is returned
+		{"bytes=-", nil},
+		{"bytes=5-3", nil},
+		{"bytes=0-1,3-4", nil},
+		{"items=0-1", nil},
+		{"bytes=a-1", nil},
+		{"bytes=0-b", nil},
+		{"bytes=--1", nil},
+		{"bytes=1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			rng := tt.rng
+			val, err := ValidateRange(&rng)
+			if err != nil {
+				t.Fatalf("ValidateRange() error = %v", err)
+			}
+			if (val == nil) != (tt.expected == nil) {
+				t.Fatalf("ValidateRange() = %+v, want %+v", val, tt.expected)
+			}
+			if val != nil && *val != *tt.expected {
+				t.Errorf("ValidateRange() = %+v, want %+v", *val, *tt.expected)
+			}
+		})
+	}
+
+	val, err := ValidateRange(nil)
+	if val != nil || err != nil {
+		t.Errorf("ValidateRange(nil) = %+v, %v", val, err)
+	}
+}
+
+func testTagSet(n int) *s3.Tagging {
+	tagging := &s3.Tagging{}
+	for i := 0; i < n; i++ {
+		tagging.TagSet = append(tagging.TagSet, &s3.Tag{
+			Key:   aws.String(fmt.Sprintf("key%d", i)),
+			Value: aws.String(fmt.Sprintf("value%d", i)),
+		})
+	}
+	return tagging
+}
+
+func TestValidateTagSet(t *testing.T) {
+	tag := func(key, value string) *s3.Tagging {
+		return &s3.Tagging{TagSet: []*s3.Tag{{Key: aws.String(key), Value: aws.String(value)}}}
+	}
+	tests := []struct {
+		name    string
+		tagging *s3.Tagging
+		err     error
+	}{
+		{"empty", testTagSet(0), nil},
+		{"max count", testTagSet(consts.MaxObjectTags), nil},
+		{"too many", testTagSet(consts.MaxObjectTags + 1), ErrTagsCountInvalid},
+		{"max key", tag(strings.Repeat("k", consts.MaxTagKeyLength), "v"), nil},
+		{"long key", tag(strings.Repeat("k", consts.MaxTagKeyLength+1), "v"), ErrTaggingInvalid},
+		{"max multibyte key", tag(strings.Repeat("键", consts.MaxTagKeyLength), "v"), nil},
+		{"max value", tag("k", strings.Repeat("v", consts.MaxTagValueLength)), nil},
+		{"long value", tag("k", strings.Repeat("v", consts.MaxTagValueLength+1)), ErrTaggingInvalid},
+		{"empty value", tag("k", ""), nil},
+		{"empty key", tag("", "v"), ErrTaggingInvalid},
+		{"duplicate key", &s3.Tagging{TagSet: []*s3.Tag{
+			{Key: aws.String("k"), Value: aws.String("1")},
+			{Key: aws.String("k"), Value: aws.String("2")},
+		}}, ErrTaggingInvalid},
+		{"nil tagging", nil, ErrTaggingInvalid},
+		{"nil tag", &s3.Tagging{TagSet: []*s3.Tag{nil}}, ErrTaggingInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := ValidateTagSet(tt.tagging)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ValidateTagSet() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && len(val) != len(tt.tagging.TagSet) {
+				t.Errorf("ValidateTagSet() got %d tags, want %d", len(val), len(tt.tagging.TagSet))
+			}
+		})
+	}
+}
+
+func TestValidateTagging(t *testing.T) {
+	header := func(n int) string {
+		query := url.Values{}
+		for i := 0; i < n; i++ {
+			query.Set(fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i))
+		}
+		return query.Encode()
+	}
+	tests := []struct {
+		name    string
+		tagging string
+		tags    map[string]string
+		err     error
+	}{
+		{"empty", "", nil, nil},
+		{"tags", "a=1&b=", map[string]string{"a": "1", "b": ""}, nil},
+		{"escaped", "a%20b=c%26d", map[string]string{"a b": "c&d"}, nil},
+		{"max count", header(consts.MaxObjectTags), nil, nil},
+		{"too many", header(consts.MaxObjectTags + 1), nil, ErrTagsCountInvalid},
+		{"repeated key", "a=1&a=2", nil, ErrTaggingInvalid},
+		{"empty key", "=1", nil, ErrTaggingInvalid},
+		{"long value", "a=" + strings.Repeat("v", consts.MaxTagValueLength+1), nil, ErrTaggingInvalid},
+		{"bad escape", "a=%zz", nil, ErrTaggingInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tagging := tt.tagging
+			val, err := ValidateTagging(&tagging)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ValidateTagging() error = %v, want %v", err, tt.err)
+			}
+			for k, v := range tt.tags {
+				if val[k] != v {
+					t.Errorf("ValidateTagging() tag %q = %q, want %q", k, val[k], v)
+				}
+			}
+		})
+	}
+}
@@ -69,6 +69,11 @@ var (
 		description:    "Part number must be an integer between 1 and 10000, inclusive",
 		httpStatusCode: http.StatusBadRequest,
 	}
+	ErrInvalidMaxParts = &Error{
+		code:           "InvalidArgument",
+		description:    "Argument max-parts must be an integer between 0 and 2147483647",
+		httpStatusCode: http.StatusBadRequest,
+	}
 	ErrInvalidPartNumberMarker = &Error{
 		code:           "InvalidArgument",
 		description:    "Argument partNumberMarker must be an integer.",
@@ -214,6 +219,16 @@ var (
 		description:    "Invalid version id specified",
 		httpStatusCode: http.StatusBadRequest,
 	}
+	ErrInvalidUploadIDKeyCombination = &Error{
+		code:           "InvalidArgument",
+		description:    "Unknown upload-id-marker, or upload-id-marker cannot be specified without a key-marker.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidVersionIDMarker = &Error{
+		code:           "InvalidArgument",
+		description:    "A version-id marker cannot be specified without a key marker.",
+		httpStatusCode: http.StatusBadRequest,
+	}
 	ErrNoSuchVersion = &Error{
 		code:           "NoSuchVersion",
 		description:    "The specified version does not exist.",
@@ -530,16 +545,96 @@ var (
 		description:    "Unknown tag directive.",
 		httpStatusCode: http.StatusBadRequest,
 	}
+	ErrInvalidTag = &Error{
+		code:           "InvalidTag",
+		description:    "The tag provided was not a valid tag.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrTooManyTags = &Error{
+		code:           "BadRequest",
+		description:    "Object tags cannot be greater than 10",
+		httpStatusCode: http.StatusBadRequest,
+	}
 	ErrInvalidEncryptionMethod = &Error{
 		code:           "InvalidRequest",
 		description:    "The encryption method specified is not supported",
 		httpStatusCode: http.StatusBadRequest,
 	}
+	ErrInvalidSSECustomerAlgorithm = &Error{
+		code:           "InvalidArgument",
+		description:    "Requests specifying Server Side Encryption with Customer provided keys must provide a valid encryption algorithm.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidSSECustomerKey = &Error{
+		code:           "InvalidArgument",
+		description:    "The secret key was invalid for the specified algorithm.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrSSECustomerKeyMD5Mismatch = &Error{
+		code:           "InvalidArgument",
+		description:    "The calculated MD5 hash of the key did not match the hash that was provided.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrMissingSSECustomerKeyParams = &Error{
+		code:           "InvalidArgument",
+		description:    "Requests specifying Server Side Encryption with Customer provided keys must provide an appropriate secret key and its MD5.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrInvalidEncryptionParameters = &Error{
+		code:           "InvalidRequest",
+		description:    "The encryption parameters are not applicable to this object.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrSSEEncryptedObject = &Error{
+		code:           "InvalidRequest",
+		description:    "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrSSECustomerKeyMismatch = &Error{
+		code:           "AccessDenied",
+		description:    "The provided SSE-C key does not match the key of the object.",
+		httpStatusCode: http.StatusForbidden,
+	}
+	ErrInvalidSourceCid = &Error{
+		code:           "InvalidArgument",
+		description:    "The source cid specified is not a valid cid.",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrNoSuchCid = &Error{
+		code:           "NoSuchCid",
+		description:    "The source cid specified is not a file can be found.",
+		httpStatusCode: http.StatusNotFound,
+	}
 	ErrInvalidQueryParams = &Error{
 		code:           "AuthorizationQueryParametersError",
 		description:    "Query-string authentication version 4 requires the X-Amz-Algorithm, X-Amz-Credential, X-Amz-Signature, X-Amz-Date, X-Amz-SignedHeaders, and X-Amz-Expires parameters.",
 		httpStatusCode: http.StatusBadRequest,
 	}
+	ErrInvalidQuerySignatureAlgo = &Error{
+		code:           "AuthorizationQueryParametersError",
+		description:    "X-Amz-Algorithm only supports \"AWS4-HMAC-SHA256\".",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrMalformedPresignedDate = &Error{
+		code:           "AuthorizationQueryParametersError",
+		description:    "X-Amz-Date must be in the ISO8601 Long Format \"yyyyMMdd'T'HHmmss'Z'\"",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrMalformedExpires = &Error{
+		code:           "AuthorizationQueryParametersError",
+		description:    "X-Amz-Expires should be a number",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrNegativeExpires = &Error{
+		code:           "AuthorizationQueryParametersError",
+		description:    "X-Amz-Expires must be non-negative",
+		httpStatusCode: http.StatusBadRequest,
+	}
+	ErrMaximumExpires = &Error{
+		code:           "AuthorizationQueryParametersError",
+		description:    "X-Amz-Expires must be less than a week (in seconds) that is 604800",
+		httpStatusCode: http.StatusBadRequest,
+	}
 	ErrNoAccessKey = &Error{
 		code:           "AccessDenied",
 		description:    "No AWSAccessKey was presented",
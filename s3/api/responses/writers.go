@@ -20,8 +20,9 @@ import (
 )
 
 const (
-	mimeTypeXml = "application/xml"
-	noPayload   = "nopayload"
+	mimeTypeXml  = "application/xml"
+	mimeTypeJson = "application/json"
+	noPayload    = "nopayload"
 )
 
 var errValueNotSet = fmt.Errorf("value not set")
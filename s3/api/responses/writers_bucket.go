@@ -1,11 +1,14 @@
 package responses
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bittorrent/go-btfs/s3/api/services/object"
 	"github.com/bittorrent/go-btfs/s3/consts"
+	"github.com/bittorrent/go-btfs/s3/policy"
 	"net/http"
+	"strconv"
 )
 
 func newS3Owner(userId string) *s3.Owner {
@@ -80,3 +83,137 @@ func WriteGetBucketACLResponse(w http.ResponseWriter, r *http.Request, acl *obje
 	WriteSuccessResponse(w, output, "AccessControlPolicy")
 	return
 }
+
+func WritePutBucketVersioningResponse(w http.ResponseWriter, r *http.Request) {
+	output := new(s3.PutBucketVersioningOutput)
+	WriteSuccessResponse(w, output, "")
+	return
+}
+
+func WriteGetBucketVersioningResponse(w http.ResponseWriter, r *http.Request, versioning string) {
+	output := new(s3.GetBucketVersioningOutput)
+	if versioning != "" {
+		output.SetStatus(versioning)
+	}
+	WriteSuccessResponse(w, output, "VersioningConfiguration")
+	return
+}
+
+func WritePutBucketLifecycleResponse(w http.ResponseWriter, r *http.Request) {
+	output := new(s3.PutBucketLifecycleConfigurationOutput)
+	WriteSuccessResponse(w, output, "")
+	return
+}
+
+func WriteGetBucketLifecycleResponse(w http.ResponseWriter, r *http.Request, rules []*object.LifecycleRule) {
+	output := new(s3.GetBucketLifecycleConfigurationOutput)
+	s3Rules := make([]*s3.LifecycleRule, 0, len(rules))
+	for _, rule := range rules {
+		s3Rules = append(s3Rules, toS3LifecycleRule(rule))
+	}
+	output.SetRules(s3Rules)
+	WriteSuccessResponse(w, output, "LifecycleConfiguration")
+	return
+}
+
+func WriteDeleteBucketLifecycleResponse(w http.ResponseWriter, r *http.Request) {
+	output := new(s3.DeleteBucketLifecycleOutput)
+	WriteSuccessResponse(w, output, "")
+	return
+}
+
+func toS3LifecycleRule(rule *object.LifecycleRule) (s3Rule *s3.LifecycleRule) {
+	s3Rule = new(s3.LifecycleRule)
+	if rule.ID != "" {
+		s3Rule.SetID(rule.ID)
+	}
+	if rule.Enabled {
+		s3Rule.SetStatus(s3.ExpirationStatusEnabled)
+	} else {
+		s3Rule.SetStatus(s3.ExpirationStatusDisabled)
+	}
+
+	// Filter
+	filter := new(s3.LifecycleRuleFilter)
+	tags := toS3Tags(rule.Tags)
+	conds := len(tags)
+	if rule.Prefix != "" {
+		conds++
+	}
+	if rule.SizeGreaterThan > 0 {
+		conds++
+	}
+	if rule.SizeLessThan > 0 {
+		conds++
+	}
+	if conds > 1 {
+		and := new(s3.LifecycleRuleAndOperator)
+		if rule.Prefix != "" {
+			and.SetPrefix(rule.Prefix)
+		}
+		if rule.SizeGreaterThan > 0 {
+			and.SetObjectSizeGreaterThan(rule.SizeGreaterThan)
+		}
+		if rule.SizeLessThan > 0 {
+			and.SetObjectSizeLessThan(rule.SizeLessThan)
+		}
+		if len(tags) > 0 {
+			and.SetTags(tags)
+		}
+		filter.SetAnd(and)
+	} else if len(tags) == 1 {
+		filter.SetTag(tags[0])
+	} else if rule.SizeGreaterThan > 0 {
+		filter.SetObjectSizeGreaterThan(rule.SizeGreaterThan)
+	} else if rule.SizeLessThan > 0 {
+		filter.SetObjectSizeLessThan(rule.SizeLessThan)
+	} else {
+		filter.SetPrefix(rule.Prefix)
+	}
+	s3Rule.SetFilter(filter)
+
+	// Actions
+	if rule.ExpirationDays > 0 {
+		s3Rule.SetExpiration(new(s3.LifecycleExpiration).SetDays(rule.ExpirationDays))
+	} else if !rule.ExpirationDate.IsZero() {
+		s3Rule.SetExpiration(new(s3.LifecycleExpiration).SetDate(rule.ExpirationDate))
+	}
+	if rule.NoncurrentExpirationDays > 0 {
+		s3Rule.SetNoncurrentVersionExpiration(
+			new(s3.NoncurrentVersionExpiration).SetNoncurrentDays(rule.NoncurrentExpirationDays),
+		)
+	}
+	if rule.AbortIncompleteUploadDays > 0 {
+		s3Rule.SetAbortIncompleteMultipartUpload(
+			new(s3.AbortIncompleteMultipartUpload).SetDaysAfterInitiation(rule.AbortIncompleteUploadDays),
+		)
+	}
+	return
+}
+
+func WritePutBucketPolicyResponse(w http.ResponseWriter, r *http.Request) {
+	output := new(s3.PutBucketPolicyOutput)
+	WriteSuccessResponse(w, output, "")
+	return
+}
+
+// WriteGetBucketPolicyResponse write the policy document as json body
+func WriteGetBucketPolicyResponse(w http.ResponseWriter, r *http.Request, p *policy.Policy) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		WriteErrorResponse(w, r, ErrInternalError)
+		return
+	}
+	setCommonHeaders(w.Header())
+	w.Header().Set(consts.ContentType, mimeTypeJson)
+	w.Header().Set(consts.ContentLength, strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+	return
+}
+
+func WriteDeleteBucketPolicyResponse(w http.ResponseWriter, r *http.Request) {
+	output := new(s3.DeleteBucketPolicyOutput)
+	WriteSuccessResponse(w, output, "")
+	return
+}
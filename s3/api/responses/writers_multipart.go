@@ -4,6 +4,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bittorrent/go-btfs/s3/api/services/object"
 	"github.com/bittorrent/go-btfs/s3/consts"
+	"github.com/bittorrent/go-btfs/s3/utils"
 	"net/http"
 )
 
@@ -12,13 +13,15 @@ func WriteCreateMultipartUploadResponse(w http.ResponseWriter, r *http.Request,
 	output.SetBucket(multipart.Bucket)
 	output.SetKey(multipart.Object)
 	output.SetUploadId(multipart.UploadID)
+	setEncryptionHeaders(w.Header(), multipart.Encryption)
 	WriteSuccessResponse(w, output, "InitiateMultipartUploadResult")
 }
 
-func WriteUploadPartResponse(w http.ResponseWriter, r *http.Request, part *object.Part) {
+func WriteUploadPartResponse(w http.ResponseWriter, r *http.Request, part *object.Part, enc *object.Encryption) {
 	output := new(s3.UploadPartOutput)
 	output.SetETag(`"` + part.ETag + `"`)
-	w.Header().Set(consts.Cid, part.CID)
+	setCidHeaders(w.Header(), part.CID)
+	setEncryptionHeaders(w.Header(), enc)
 	WriteSuccessResponse(w, output, "")
 }
 
@@ -32,6 +35,78 @@ func WriteCompleteMultipartUploadResponse(w http.ResponseWriter, r *http.Request
 	output.SetBucket(obj.Bucket)
 	output.SetKey(obj.Name)
 	output.SetETag(`"` + obj.ETag + `"`)
-	w.Header().Set(consts.Cid, obj.CID)
+	if obj.VersionID != "" {
+		output.SetVersionId(obj.VersionID)
+	}
+	setCidHeaders(w.Header(), obj.CID)
+	setEncryptionHeaders(w.Header(), obj.Encryption)
 	WriteSuccessResponse(w, output, "CompleteMultipartUploadResult")
 }
+
+func WriteListMultipartUploadsResponse(w http.ResponseWriter, r *http.Request, list *object.MultipartUploadsList) {
+	output := new(s3.ListMultipartUploadsOutput)
+	output.SetBucket(list.Args.Bucket)
+	output.SetEncodingType(list.Args.EncodingType)
+	output.SetPrefix(utils.S3Encode(list.Args.Prefix, list.Args.EncodingType))
+	output.SetKeyMarker(utils.S3Encode(list.Args.KeyMarker, list.Args.EncodingType))
+	output.SetUploadIdMarker(list.Args.UploadIDMarker)
+	output.SetDelimiter(utils.S3Encode(list.Args.Delimiter, list.Args.EncodingType))
+	output.SetMaxUploads(list.Args.MaxUploads)
+	output.SetIsTruncated(list.IsTruncated)
+	if list.IsTruncated {
+		output.SetNextKeyMarker(utils.S3Encode(list.NextKeyMarker, list.Args.EncodingType))
+		output.SetNextUploadIdMarker(list.NextUploadIDMarker)
+	}
+	s3Uploads := make([]*s3.MultipartUpload, 0, len(list.Uploads))
+	for _, multipart := range list.Uploads {
+		s3Upload := new(s3.MultipartUpload)
+		s3Upload.SetKey(utils.S3Encode(multipart.Object, list.Args.EncodingType))
+		s3Upload.SetUploadId(multipart.UploadID)
+		s3Upload.SetInitiated(multipart.Initiated)
+		s3Upload.SetInitiator(newS3Initiator(list.Owner))
+		s3Upload.SetOwner(newS3Owner(list.Owner))
+		s3Upload.SetStorageClass("")
+		s3Uploads = append(s3Uploads, s3Upload)
+	}
+	output.SetUploads(s3Uploads)
+	s3CommPrefixes := make([]*s3.CommonPrefix, len(list.Prefixes))
+	for i, cpf := range list.Prefixes {
+		pfx := new(s3.CommonPrefix)
+		pfx.SetPrefix(utils.S3Encode(cpf, list.Args.EncodingType))
+		s3CommPrefixes[i] = pfx
+	}
+	output.SetCommonPrefixes(s3CommPrefixes)
+	WriteSuccessResponse(w, output, "ListMultipartUploadsResult")
+}
+
+func WriteListPartsResponse(w http.ResponseWriter, r *http.Request, list *object.PartsList) {
+	output := new(s3.ListPartsOutput)
+	output.SetBucket(list.Args.Bucket)
+	output.SetKey(list.Args.Object)
+	output.SetUploadId(list.Args.UploadId)
+	output.SetInitiator(newS3Initiator(list.Owner))
+	output.SetOwner(newS3Owner(list.Owner))
+	output.SetStorageClass("")
+	output.SetMaxParts(list.Args.MaxParts)
+	output.SetPartNumberMarker(list.Args.PartNumberMarker)
+	output.SetIsTruncated(list.IsTruncated)
+	if list.IsTruncated {
+		output.SetNextPartNumberMarker(list.NextPartNumberMarker)
+	}
+	s3Parts := make([]*s3.Part, 0, len(list.Parts))
+	for _, part := range list.Parts {
+		s3Part := new(s3.Part)
+		s3Part.SetPartNumber(part.Number)
+		s3Part.SetETag(`"` + part.ETag + `"`)
+		s3Part.SetSize(part.Size)
+		s3Part.SetLastModified(part.ModTime)
+		s3Parts = append(s3Parts, s3Part)
+		w.Header().Add(consts.Cid, part.CID)
+	}
+	output.SetParts(s3Parts)
+	WriteSuccessResponse(w, output, "ListPartsResult")
+}
+
+func newS3Initiator(userId string) *s3.Initiator {
+	return new(s3.Initiator).SetID(userId).SetDisplayName(userId)
+}
@@ -3,18 +3,26 @@ package responses
 import (
 	"encoding/base64"
 	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bittorrent/go-btfs/s3/api/services/object"
 	"github.com/bittorrent/go-btfs/s3/consts"
+	"github.com/bittorrent/go-btfs/s3/sse"
 	"github.com/bittorrent/go-btfs/s3/utils"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
 )
 
 func WritePutObjectResponse(w http.ResponseWriter, r *http.Request, obj *object.Object) {
 	output := new(s3.PutObjectOutput)
 	output.SetETag(`"` + obj.ETag + `"`)
-	w.Header().Set(consts.Cid, obj.CID)
+	if obj.VersionID != "" {
+		output.SetVersionId(obj.VersionID)
+	}
+	setCidHeaders(w.Header(), obj.CID)
+	setEncryptionHeaders(w.Header(), obj.Encryption)
 	WriteSuccessResponse(w, output, "")
 }
 
@@ -22,29 +30,52 @@ func WriteCopyObjectResponse(w http.ResponseWriter, r *http.Request, obj *object
 	output := new(s3.CopyObjectResult)
 	output.SetETag(`"` + obj.ETag + `"`)
 	output.SetLastModified(obj.ModTime)
-	w.Header().Set(consts.Cid, obj.CID)
+	if obj.VersionID != "" {
+		w.Header().Set(consts.AmzVersionID, obj.VersionID)
+	}
+	setCidHeaders(w.Header(), obj.CID)
+	setEncryptionHeaders(w.Header(), obj.Encryption)
 	WriteSuccessResponse(w, output, "CopyObjectResult")
 }
 
-func WriteHeadObjectResponse(w http.ResponseWriter, r *http.Request, obj *object.Object) {
+func WriteHeadObjectResponse(w http.ResponseWriter, r *http.Request, obj *object.Object, rng *object.ObjectRange) {
 	output := new(s3.HeadObjectOutput)
 	output.SetETag(`"` + obj.ETag + `"`)
 	output.SetLastModified(obj.ModTime)
 	output.SetContentLength(obj.Size)
+	statusCode := http.StatusOK
+	if rng != nil {
+		offset, length, _ := rng.Resolve(obj.Size)
+		output.SetContentLength(length)
+		w.Header().Set(consts.ContentRange, formatContentRange(offset, length, obj.Size))
+		statusCode = http.StatusPartialContent
+	}
+	if obj.VersionID != "" {
+		output.SetVersionId(obj.VersionID)
+	}
 	output.SetContentType(obj.ContentType)
 	output.SetContentEncoding(obj.ContentEncoding)
 	if !obj.Expires.IsZero() {
 		output.SetExpiration(obj.Expires.UTC().Format(http.TimeFormat))
 	}
-	w.Header().Set(consts.Cid, obj.CID)
-	output.SetMetadata(map[string]*string{
-		consts.Cid: &obj.CID,
-	})
-	WriteSuccessResponse(w, output, "")
+	setCidHeaders(w.Header(), obj.CID)
+	setEncryptionHeaders(w.Header(), obj.Encryption)
+	setStorageHeaders(w.Header(), obj.Storage)
+	output.SetMetadata(toS3Metadata(obj))
+	if len(obj.Tags) > 0 {
+		w.Header().Set(consts.AmzTaggingCount, strconv.Itoa(len(obj.Tags)))
+	}
+	_ = WriteResponse(w, statusCode, output, "")
 }
 
 func WriteDeleteObjectResponse(w http.ResponseWriter, r *http.Request, obj *object.Object) {
 	output := new(s3.DeleteObjectOutput)
+	if obj != nil && obj.VersionID != "" {
+		output.SetVersionId(obj.VersionID)
+	}
+	if obj != nil && obj.DeleteMarker {
+		output.SetDeleteMarker(true)
+	}
 	WriteSuccessResponse(w, output, "")
 }
 
@@ -64,6 +95,15 @@ func WriteDeleteObjectsResponse(w http.ResponseWriter, r *http.Request, toErr fu
 		}
 		s3Obj := new(s3.DeletedObject)
 		s3Obj.SetKey(obj.Object)
+		if obj.VersionID != "" {
+			s3Obj.SetVersionId(obj.VersionID)
+		}
+		if obj.DeleteMarker {
+			s3Obj.SetDeleteMarker(true)
+		}
+		if obj.DeleteMarkerVersionID != "" {
+			s3Obj.SetDeleteMarkerVersionId(obj.DeleteMarkerVersionID)
+		}
 		objs = append(objs, s3Obj)
 	}
 	if len(errs) > 0 {
@@ -75,21 +115,55 @@ func WriteDeleteObjectsResponse(w http.ResponseWriter, r *http.Request, toErr fu
 	WriteSuccessResponse(w, output, "DeleteResult")
 }
 
-func WriteGetObjectResponse(w http.ResponseWriter, r *http.Request, obj *object.Object, body io.ReadCloser) {
+func WriteGetObjectResponse(w http.ResponseWriter, r *http.Request, obj *object.Object, body io.ReadCloser, rng *object.ObjectRange) {
 	output := new(s3.GetObjectOutput)
+	output.SetETag(`"` + obj.ETag + `"`)
 	output.SetLastModified(obj.ModTime)
 	output.SetContentLength(obj.Size)
+	statusCode := http.StatusOK
+	if rng != nil {
+		offset, length, _ := rng.Resolve(obj.Size)
+		output.SetContentLength(length)
+		output.SetContentRange(formatContentRange(offset, length, obj.Size))
+		statusCode = http.StatusPartialContent
+	}
+	if obj.VersionID != "" {
+		output.SetVersionId(obj.VersionID)
+	}
 	output.SetContentType(obj.ContentType)
 	output.SetContentEncoding(obj.ContentEncoding)
 	output.SetBody(body)
 	if !obj.Expires.IsZero() {
 		output.SetExpiration(obj.Expires.UTC().Format(http.TimeFormat))
 	}
-	w.Header().Set(consts.Cid, obj.CID)
-	output.SetMetadata(map[string]*string{
-		consts.Cid: &obj.CID,
-	})
-	WriteSuccessResponse(w, output, "")
+	setCidHeaders(w.Header(), obj.CID)
+	setEncryptionHeaders(w.Header(), obj.Encryption)
+	setStorageHeaders(w.Header(), obj.Storage)
+	output.SetMetadata(toS3Metadata(obj))
+	if len(obj.Tags) > 0 {
+		output.SetTagCount(int64(len(obj.Tags)))
+	}
+	_ = WriteResponse(w, statusCode, output, "")
+}
+
+// WriteNotModifiedResponse write the 304 response without body, only the
+// validator headers of the object are included
+func WriteNotModifiedResponse(w http.ResponseWriter, r *http.Request, obj *object.Object) {
+	setCommonHeaders(w.Header())
+	w.Header().Set(consts.ETag, `"`+obj.ETag+`"`)
+	w.Header().Set(consts.LastModified, obj.ModTime.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// WriteInvalidRangeResponse write the 416 error response with the object
+// size in the Content-Range header
+func WriteInvalidRangeResponse(w http.ResponseWriter, r *http.Request, obj *object.Object) {
+	w.Header().Set(consts.ContentRange, fmt.Sprintf("bytes */%d", obj.Size))
+	WriteErrorResponse(w, r, ErrInvalidRange)
+}
+
+func formatContentRange(offset, length, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size)
 }
 
 func WriteListObjectsResponse(w http.ResponseWriter, r *http.Request, list *object.ObjectsList) {
@@ -162,6 +236,57 @@ func WriteListObjectsV2Response(w http.ResponseWriter, r *http.Request, list *ob
 	WriteSuccessResponse(w, out, "ListBucketResult")
 }
 
+func WriteListObjectVersionsResponse(w http.ResponseWriter, r *http.Request, list *object.ObjectVersionsList) {
+	out := new(s3.ListObjectVersionsOutput)
+	out.SetName(list.Args.Bucket)
+	out.SetEncodingType(list.Args.EncodingType)
+	out.SetPrefix(utils.S3Encode(list.Args.Prefix, list.Args.EncodingType))
+	out.SetKeyMarker(utils.S3Encode(list.Args.KeyMarker, list.Args.EncodingType))
+	out.SetVersionIdMarker(list.Args.VersionIDMarker)
+	out.SetDelimiter(utils.S3Encode(list.Args.Delimiter, list.Args.EncodingType))
+	out.SetMaxKeys(list.Args.MaxKeys)
+	out.SetIsTruncated(list.IsTruncated)
+	if list.IsTruncated {
+		out.SetNextKeyMarker(utils.S3Encode(list.NextKeyMarker, list.Args.EncodingType))
+		out.SetNextVersionIdMarker(list.NextVersionIDMarker)
+	}
+	s3Vers := make([]*s3.ObjectVersion, 0)
+	s3Dels := make([]*s3.DeleteMarkerEntry, 0)
+	for _, obj := range list.Versions {
+		if obj.DeleteMarker {
+			s3Del := new(s3.DeleteMarkerEntry)
+			s3Del.SetKey(utils.S3Encode(obj.Name, list.Args.EncodingType))
+			s3Del.SetVersionId(obj.VersionID)
+			s3Del.SetIsLatest(obj.IsLatest)
+			s3Del.SetLastModified(obj.ModTime)
+			s3Del.SetOwner(newS3Owner(list.Owner))
+			s3Dels = append(s3Dels, s3Del)
+			continue
+		}
+		s3Ver := new(s3.ObjectVersion)
+		s3Ver.SetETag(`"` + obj.ETag + `"`)
+		s3Ver.SetKey(utils.S3Encode(obj.Name, list.Args.EncodingType))
+		s3Ver.SetVersionId(obj.VersionID)
+		s3Ver.SetIsLatest(obj.IsLatest)
+		s3Ver.SetLastModified(obj.ModTime)
+		s3Ver.SetSize(obj.Size)
+		s3Ver.SetOwner(newS3Owner(list.Owner))
+		s3Ver.SetStorageClass("")
+		s3Vers = append(s3Vers, s3Ver)
+		w.Header().Add(consts.Cid, obj.CID)
+	}
+	out.SetVersions(s3Vers)
+	out.SetDeleteMarkers(s3Dels)
+	s3CommPrefixes := make([]*s3.CommonPrefix, len(list.Prefixes))
+	for i, cpf := range list.Prefixes {
+		pfx := new(s3.CommonPrefix)
+		pfx.SetPrefix(utils.S3Encode(cpf, list.Args.EncodingType))
+		s3CommPrefixes[i] = pfx
+	}
+	out.SetCommonPrefixes(s3CommPrefixes)
+	WriteSuccessResponse(w, out, "ListVersionsResult")
+}
+
 func WriteGetObjectACLResponse(w http.ResponseWriter, r *http.Request, acl *object.ACL) {
 	output := new(s3.GetObjectAclOutput)
 	output.SetOwner(newS3Owner(acl.Owner))
@@ -180,3 +305,84 @@ func WriteGetObjectACLResponse(w http.ResponseWriter, r *http.Request, acl *obje
 	WriteSuccessResponse(w, output, "AccessControlPolicy")
 	return
 }
+
+func WritePutObjectTaggingResponse(w http.ResponseWriter, r *http.Request, obj *object.Object) {
+	output := new(s3.PutObjectTaggingOutput)
+	if obj.VersionID != "" {
+		output.SetVersionId(obj.VersionID)
+	}
+	WriteSuccessResponse(w, output, "")
+}
+
+func WriteGetObjectTaggingResponse(w http.ResponseWriter, r *http.Request, obj *object.Object) {
+	output := new(s3.GetObjectTaggingOutput)
+	if obj.VersionID != "" {
+		output.SetVersionId(obj.VersionID)
+	}
+	output.SetTagSet(toS3Tags(obj.Tags))
+	WriteSuccessResponse(w, output, "Tagging")
+}
+
+func WriteDeleteObjectTaggingResponse(w http.ResponseWriter, r *http.Request, obj *object.Object) {
+	output := new(s3.DeleteObjectTaggingOutput)
+	if obj.VersionID != "" {
+		output.SetVersionId(obj.VersionID)
+	}
+	WriteSuccessResponse(w, output, "")
+}
+
+// setCidHeaders set the btfs cid of the object body, so it can be handed to
+// the other btfs commands or fetched by the gateway
+func setCidHeaders(h http.Header, cid string) {
+	h.Set(consts.Cid, cid)
+	h.Set(consts.BtfsCid, cid)
+}
+
+// setStorageHeaders set the state of pushing the object body into the
+// decentralized storage
+func setStorageHeaders(h http.Header, storage *object.ObjectStorage) {
+	if storage == nil {
+		return
+	}
+	h.Set(consts.BtfsStorageStatus, storage.Status)
+	if storage.SessionID != "" {
+		h.Set(consts.BtfsStorageSession, storage.SessionID)
+	}
+}
+
+// setEncryptionHeaders set the server side encryption headers of the encrypted
+// object, the customer key md5 is echoed back for SSE-C
+func setEncryptionHeaders(h http.Header, enc *object.Encryption) {
+	if enc == nil {
+		return
+	}
+	switch enc.Type {
+	case sse.TypeSSES3:
+		h.Set(consts.AmzServerSideEncryption, sse.AlgorithmAES256)
+	case sse.TypeSSEC:
+		h.Set(consts.AmzServerSideEncryptionCustomerAlgorithm, sse.AlgorithmAES256)
+		h.Set(consts.AmzServerSideEncryptionCustomerKeyMD5, enc.KeyMD5)
+	}
+}
+
+// toS3Metadata merge the object cid into the user-defined metadata
+func toS3Metadata(obj *object.Object) (metadata map[string]*string) {
+	metadata = make(map[string]*string, len(obj.Metadata)+1)
+	for k, v := range obj.Metadata {
+		metadata[k] = aws.String(v)
+	}
+	metadata[consts.Cid] = aws.String(obj.CID)
+	return
+}
+
+// toS3Tags convert the tags to the s3 tag set sorted by key
+func toS3Tags(tags map[string]string) (s3Tags []*s3.Tag) {
+	s3Tags = make([]*s3.Tag, 0, len(tags))
+	for k, v := range tags {
+		s3Tags = append(s3Tags, new(s3.Tag).SetKey(k).SetValue(v))
+	}
+	sort.Slice(s3Tags, func(i, j int) bool {
+		return *s3Tags[i].Key < *s3Tags[j].Key
+	})
+	return
+}
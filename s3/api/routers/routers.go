@@ -42,18 +42,30 @@ func (routers *Routers) Register() http.Handler {
 	// UploadPart
 	bucket.Methods(http.MethodPut).Path("/{Key:.+}").HandlerFunc(hs.UploadPartHandler).Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}")
 
+	// PutObjectTagging
+	bucket.Methods(http.MethodPut).Path("/{Key:.+}").HandlerFunc(hs.PutObjectTaggingHandler).Queries("tagging", "")
+
 	// CopyObject
 	bucket.Methods(http.MethodPut).Path("/{Key:.+}").HeadersRegexp("X-Amz-Copy-Source", ".*?(\\/|%2F).*?").HandlerFunc(hs.CopyObjectHandler)
 
 	// PutObject
 	bucket.Methods(http.MethodPut).Path("/{Key:.+}").HandlerFunc(hs.PutObjectHandler)
 
+	// DeleteObjectTagging
+	bucket.Methods(http.MethodDelete).Path("/{Key:.+}").HandlerFunc(hs.DeleteObjectTaggingHandler).Queries("tagging", "")
+
 	// AbortMultipart
 	bucket.Methods(http.MethodDelete).Path("/{Key:.+}").HandlerFunc(hs.AbortMultipartUploadHandler).Queries("uploadId", "{uploadId:.*}")
 
 	// DeleteObject
 	bucket.Methods(http.MethodDelete).Path("/{Key:.+}").HandlerFunc(hs.DeleteObjectHandler)
 
+	// ListParts
+	bucket.Methods(http.MethodGet).Path("/{Key:.+}").HandlerFunc(hs.ListPartsHandler).Queries("uploadId", "{uploadId:.*}")
+
+	// GetObjectTagging
+	bucket.Methods(http.MethodGet).Path("/{Key:.+}").HandlerFunc(hs.GetObjectTaggingHandler).Queries("tagging", "")
+
 	// GetObjectACL
 	bucket.Methods(http.MethodGet).Path("/{Key:.+}").HandlerFunc(hs.GetObjectACLHandler).Queries("acl", "")
 
@@ -63,6 +75,21 @@ func (routers *Routers) Register() http.Handler {
 	// GetBucketACL
 	bucket.Methods(http.MethodGet).HandlerFunc(hs.GetBucketACLHandler).Queries("acl", "")
 
+	// GetBucketVersioning
+	bucket.Methods(http.MethodGet).HandlerFunc(hs.GetBucketVersioningHandler).Queries("versioning", "")
+
+	// GetBucketPolicy
+	bucket.Methods(http.MethodGet).HandlerFunc(hs.GetBucketPolicyHandler).Queries("policy", "")
+
+	// GetBucketLifecycle
+	bucket.Methods(http.MethodGet).HandlerFunc(hs.GetBucketLifecycleHandler).Queries("lifecycle", "")
+
+	// ListMultipartUploads
+	bucket.Methods(http.MethodGet).HandlerFunc(hs.ListMultipartUploadsHandler).Queries("uploads", "")
+
+	// ListObjectVersions
+	bucket.Methods(http.MethodGet).HandlerFunc(hs.ListObjectVersionsHandler).Queries("versions", "")
+
 	// ListObjectsV2
 	bucket.Methods(http.MethodGet).HandlerFunc(hs.ListObjectsV2Handler).Queries("list-type", "2")
 
@@ -72,6 +99,15 @@ func (routers *Routers) Register() http.Handler {
 	// PutBucketACL
 	bucket.Methods(http.MethodPut).HandlerFunc(hs.PutBucketACLHandler).Queries("acl", "")
 
+	// PutBucketVersioning
+	bucket.Methods(http.MethodPut).HandlerFunc(hs.PutBucketVersioningHandler).Queries("versioning", "")
+
+	// PutBucketPolicy
+	bucket.Methods(http.MethodPut).HandlerFunc(hs.PutBucketPolicyHandler).Queries("policy", "")
+
+	// PutBucketLifecycle
+	bucket.Methods(http.MethodPut).HandlerFunc(hs.PutBucketLifecycleHandler).Queries("lifecycle", "")
+
 	// CreateBucket
 	bucket.Methods(http.MethodPut).HandlerFunc(hs.CreateBucketHandler)
 
@@ -81,6 +117,12 @@ func (routers *Routers) Register() http.Handler {
 	// DeleteObjects
 	bucket.Methods(http.MethodPost).HandlerFunc(hs.DeleteObjectsHandler).Queries("delete", "")
 
+	// DeleteBucketPolicy
+	bucket.Methods(http.MethodDelete).HandlerFunc(hs.DeleteBucketPolicyHandler).Queries("policy", "")
+
+	// DeleteBucketLifecycle
+	bucket.Methods(http.MethodDelete).HandlerFunc(hs.DeleteBucketLifecycleHandler).Queries("lifecycle", "")
+
 	// DeleteBucket
 	bucket.Methods(http.MethodDelete).HandlerFunc(hs.DeleteBucketHandler)
 
@@ -11,3 +11,9 @@ func WithAddress(address string) Option {
 		}
 	}
 }
+
+func WithWorkers(workers ...Worker) Option {
+	return func(s *Server) {
+		s.workers = append(s.workers, workers...)
+	}
+}
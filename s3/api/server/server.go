@@ -13,9 +13,16 @@ var (
 	ErrServerNotStarted = errors.New("server not started")
 )
 
+// Worker is a background job runs along with the server
+type Worker interface {
+	Start() error
+	Stop() error
+}
+
 type Server struct {
 	routers routers.Routerser
 	address string
+	workers []Worker
 
 	shutdown func() error
 	mutex    sync.Mutex
@@ -56,6 +63,10 @@ func (s *Server) Start() (err error) {
 		_ = httpSvr.ListenAndServe()
 	}()
 
+	for _, worker := range s.workers {
+		_ = worker.Start()
+	}
+
 	return
 }
 
@@ -66,6 +77,9 @@ func (s *Server) Stop() (err error) {
 		err = ErrServerNotStarted
 		return
 	}
+	for _, worker := range s.workers {
+		_ = worker.Stop()
+	}
 	err = s.shutdown()
 	s.shutdown = nil
 	return
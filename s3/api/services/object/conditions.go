@@ -0,0 +1,52 @@
+package object
+
+import (
+	"strings"
+	"time"
+)
+
+// Check evaluate the conditions with the object in the order of RFC 7232,
+// ErrPreconditionFailed or ErrNotModified will be returned if the object
+// does not meet them
+func (cond *ObjectConditions) Check(object *Object) (err error) {
+	modTime := object.ModTime.Truncate(time.Second)
+
+	// If-Match, or If-Unmodified-Since when If-Match is absent
+	if cond.IfMatch != "" {
+		if !matchETag(cond.IfMatch, object.ETag) {
+			err = ErrPreconditionFailed
+			return
+		}
+	} else if !cond.IfUnmodifiedSince.IsZero() && modTime.After(cond.IfUnmodifiedSince) {
+		err = ErrPreconditionFailed
+		return
+	}
+
+	// If-None-Match, or If-Modified-Since when If-None-Match is absent
+	if cond.IfNoneMatch != "" {
+		if matchETag(cond.IfNoneMatch, object.ETag) {
+			err = ErrNotModified
+		}
+	} else if !cond.IfModifiedSince.IsZero() && !modTime.After(cond.IfModifiedSince) {
+		err = ErrNotModified
+	}
+
+	return
+}
+
+// matchETag check if the etag is in the comma separated entity-tag list,
+// the weak indicator is ignored since our etags are always strong
+func matchETag(list, etag string) (match bool) {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		tag = strings.Trim(tag, `"`)
+		if tag == etag {
+			return true
+		}
+	}
+	return
+}
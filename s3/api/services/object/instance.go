@@ -0,0 +1,36 @@
+package object
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bittorrent/go-btfs/s3/api/providers"
+)
+
+var svcInstance *service
+
+var once sync.Once
+
+// InitService init the service instance used by the node commands, the
+// operations of the instance are done as the node owner without ACL checks
+func InitService(providers providers.Providerser, options ...Option) {
+	once.Do(func() {
+		svcInstance = NewService(providers, options...).(*service)
+	})
+}
+
+func PutBucketStorage(bucname string, storage *StoragePolicy) (err error) {
+	return svcInstance.PutBucketStorage(context.Background(), bucname, storage)
+}
+
+func GetBucketStorage(bucname string) (storage *StoragePolicy, err error) {
+	return svcInstance.GetBucketStorage(context.Background(), bucname)
+}
+
+func DeleteBucketStorage(bucname string) (err error) {
+	return svcInstance.DeleteBucketStorage(context.Background(), bucname)
+}
+
+func GetObjectStorage(bucname, objname, verid string) (object *Object, err error) {
+	return svcInstance.GetObjectStorage(context.Background(), bucname, objname, verid)
+}
@@ -0,0 +1,76 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bittorrent/go-btfs/s3/api/providers"
+)
+
+var (
+	ErrSweeperStarted    = errors.New("lifecycle sweeper started")
+	ErrSweeperNotStarted = errors.New("lifecycle sweeper not started")
+)
+
+// LifecycleSweeper periodically applies the lifecycle rules of all buckets,
+// it shares the state store and locks with the object service
+type LifecycleSweeper struct {
+	svc    *service
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+}
+
+func NewLifecycleSweeper(providers providers.Providerser, options ...Option) *LifecycleSweeper {
+	return &LifecycleSweeper{
+		svc: NewService(providers, options...).(*service),
+	}
+}
+
+func (sw *LifecycleSweeper) Start() (err error) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	if sw.cancel != nil {
+		err = ErrSweeperStarted
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sw.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(sw.svc.lifecycleSweep)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				er := sw.svc.sweepLifecycles(ctx)
+				if er != nil && ctx.Err() == nil {
+					fmt.Printf("s3-api: lifecycle sweep, err: %v\n", er)
+				}
+			}
+		}
+	}()
+
+	return
+}
+
+func (sw *LifecycleSweeper) Stop() (err error) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	if sw.cancel == nil {
+		err = ErrSweeperNotStarted
+		return
+	}
+
+	sw.cancel()
+	sw.cancel = nil
+
+	return
+}
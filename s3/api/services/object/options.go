@@ -10,9 +10,15 @@ const (
 	defaultBucketSpace      = "s3:bkt"
 	defaultObjectSpace      = "s3:obj"
 	defaultUploadSpace      = "s3:upl"
+	defaultVersionSpace     = "s3:ver"
 	defaultCidrefSpace      = "s3:cid"
+	defaultDataKeySpace     = "s3:dky"
+	defaultImportSpace      = "s3:imp"
+	defaultStorageSpace     = "s3:sto"
 	defaultOperationTimeout = 5 * time.Minute
 	defaultCloseBodyTimeout = 10 * time.Minute
+	defaultLifecycleSweep   = 1 * time.Hour
+	defaultStorageSync      = 10 * time.Minute
 )
 
 var defaultLock = ctxmu.NewDefaultMultiCtxRWMutex()
@@ -43,12 +49,36 @@ func WithUploadSpace(space string) Option {
 	}
 }
 
+func WithVersionSpace(space string) Option {
+	return func(svc *service) {
+		svc.versionSpace = space
+	}
+}
+
 func WithCidrefSpace(space string) Option {
 	return func(svc *service) {
 		svc.cidrefSpace = space
 	}
 }
 
+func WithImportSpace(space string) Option {
+	return func(svc *service) {
+		svc.importSpace = space
+	}
+}
+
+func WithStorageSpace(space string) Option {
+	return func(svc *service) {
+		svc.storageSpace = space
+	}
+}
+
+func WithDataKeySpace(space string) Option {
+	return func(svc *service) {
+		svc.dataKeySpace = space
+	}
+}
+
 func WithOperationTimeout(timeout time.Duration) Option {
 	return func(svc *service) {
 		svc.operationTimeout = timeout
@@ -61,6 +91,18 @@ func WithCloseBodyTimeout(timeout time.Duration) Option {
 	}
 }
 
+func WithLifecycleSweep(interval time.Duration) Option {
+	return func(svc *service) {
+		svc.lifecycleSweep = interval
+	}
+}
+
+func WithStorageSync(interval time.Duration) Option {
+	return func(svc *service) {
+		svc.storageSync = interval
+	}
+}
+
 func WithLock(lock ctxmu.MultiCtxRWLocker) Option {
 	return func(svc *service) {
 		svc.lock = lock
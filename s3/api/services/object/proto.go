@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"github.com/bittorrent/go-btfs/s3/hash"
+	"github.com/bittorrent/go-btfs/s3/policy"
+	"github.com/bittorrent/go-btfs/s3/sse"
 	"io"
 	"time"
 )
@@ -18,10 +20,36 @@ var (
 	ErrPartNotExists       = errors.New("part not exists")
 	ErrPartETagNotMatch    = errors.New("part etag not match")
 	ErrPartTooSmall        = errors.New("part size too small")
+	ErrVersionNotFound     = errors.New("version not found")
+	ErrVersionIsMarker     = errors.New("version is a delete marker")
+	ErrLifecycleNotFound   = errors.New("lifecycle not found")
+	ErrNotModified         = errors.New("object not modified")
+	ErrPreconditionFailed  = errors.New("precondition failed")
+	ErrInvalidRange        = errors.New("range not satisfiable")
+	ErrPolicyNotFound      = errors.New("bucket policy not found")
+	ErrSSEKeyMissing       = errors.New("encryption key missing")
+	ErrSSEKeyMismatch      = errors.New("encryption key mismatch")
+	ErrSSENotEncrypted     = errors.New("object not encrypted with customer key")
+	ErrSSEUnsupported      = errors.New("encryption not supported")
+	ErrSourceCidNotFound   = errors.New("source cid not found")
+	ErrStorageNotFound     = errors.New("storage policy not found")
 	ErrCanceled            = context.Canceled
 	ErrTimout              = context.DeadlineExceeded
 )
 
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+	NullVersionID       = "null"
+)
+
+const (
+	StorageStatusQueued    = "queued"
+	StorageStatusUploading = "uploading"
+	StorageStatusStored    = "stored"
+	StorageStatusFailed    = "failed"
+)
+
 type Service interface {
 	CreateBucket(ctx context.Context, args *CreateBucketArgs) (bucket *Bucket, err error)
 	GetBucket(ctx context.Context, args *GetBucketArgs) (bucket *Bucket, err error)
@@ -29,20 +57,34 @@ type Service interface {
 	ListBuckets(ctx context.Context, args *ListBucketsArgs) (list *BucketList, err error)
 	PutBucketACL(ctx context.Context, args *PutBucketACLArgs) (err error)
 	GetBucketACL(ctx context.Context, args *GetBucketACLArgs) (acl *ACL, err error)
+	PutBucketVersioning(ctx context.Context, args *PutBucketVersioningArgs) (err error)
+	GetBucketVersioning(ctx context.Context, args *GetBucketVersioningArgs) (versioning string, err error)
+	PutBucketLifecycle(ctx context.Context, args *PutBucketLifecycleArgs) (err error)
+	GetBucketLifecycle(ctx context.Context, args *GetBucketLifecycleArgs) (rules []*LifecycleRule, err error)
+	DeleteBucketLifecycle(ctx context.Context, args *DeleteBucketLifecycleArgs) (err error)
+	PutBucketPolicy(ctx context.Context, args *PutBucketPolicyArgs) (err error)
+	GetBucketPolicy(ctx context.Context, args *GetBucketPolicyArgs) (p *policy.Policy, err error)
+	DeleteBucketPolicy(ctx context.Context, args *DeleteBucketPolicyArgs) (err error)
 
 	PutObject(ctx context.Context, args *PutObjectArgs) (object *Object, err error)
 	CopyObject(ctx context.Context, args *CopyObjectArgs) (object *Object, err error)
 	GetObject(ctx context.Context, args *GetObjectArgs) (object *Object, body io.ReadCloser, err error)
-	DeleteObject(ctx context.Context, args *DeleteObjectArgs) (err error)
+	DeleteObject(ctx context.Context, args *DeleteObjectArgs) (object *Object, err error)
 	DeleteObjects(ctx context.Context, args *DeleteObjectsArgs) (deletes []*DeletedObject, err error)
 	ListObjects(ctx context.Context, args *ListObjectsArgs) (list *ObjectsList, err error)
 	ListObjectsV2(ctx context.Context, args *ListObjectsV2Args) (list *ObjectsListV2, err error)
+	ListObjectVersions(ctx context.Context, args *ListObjectVersionsArgs) (list *ObjectVersionsList, err error)
 	GetObjectACL(ctx context.Context, args *GetObjectACLArgs) (acl *ACL, err error)
+	PutObjectTagging(ctx context.Context, args *PutObjectTaggingArgs) (object *Object, err error)
+	GetObjectTagging(ctx context.Context, args *GetObjectTaggingArgs) (object *Object, err error)
+	DeleteObjectTagging(ctx context.Context, args *DeleteObjectTaggingArgs) (object *Object, err error)
 
 	CreateMultipartUpload(ctx context.Context, args *CreateMultipartUploadArgs) (multipart *Multipart, err error)
-	UploadPart(ctx context.Context, args *UploadPartArgs) (part *Part, err error)
+	UploadPart(ctx context.Context, args *UploadPartArgs) (part *Part, enc *Encryption, err error)
 	AbortMultipartUpload(ctx context.Context, args *AbortMultipartUploadArgs) (err error)
 	CompleteMultiPartUpload(ctx context.Context, args *CompleteMultipartUploadArgs) (object *Object, err error)
+	ListMultipartUploads(ctx context.Context, args *ListMultipartUploadsArgs) (list *MultipartUploadsList, err error)
+	ListParts(ctx context.Context, args *ListPartsArgs) (list *PartsList, err error)
 }
 
 type CreateBucketArgs struct {
@@ -77,6 +119,49 @@ type PutBucketACLArgs struct {
 	Bucket string
 }
 
+type PutBucketVersioningArgs struct {
+	UserId     string
+	Bucket     string
+	Versioning string
+}
+
+type GetBucketVersioningArgs struct {
+	UserId string
+	Bucket string
+}
+
+type PutBucketLifecycleArgs struct {
+	UserId string
+	Bucket string
+	Rules  []*LifecycleRule
+}
+
+type GetBucketLifecycleArgs struct {
+	UserId string
+	Bucket string
+}
+
+type DeleteBucketLifecycleArgs struct {
+	UserId string
+	Bucket string
+}
+
+type PutBucketPolicyArgs struct {
+	UserId string
+	Bucket string
+	Policy *policy.Policy
+}
+
+type GetBucketPolicyArgs struct {
+	UserId string
+	Bucket string
+}
+
+type DeleteBucketPolicyArgs struct {
+	UserId string
+	Bucket string
+}
+
 type PutObjectArgs struct {
 	UserId          string
 	Body            *hash.Reader
@@ -86,6 +171,10 @@ type PutObjectArgs struct {
 	ContentLength   int64
 	ContentType     string
 	Expires         time.Time
+	Metadata        map[string]string
+	Tags            map[string]string
+	SSE             *ServerSideEncryption
+	SourceCID       string
 }
 
 type CopyObjectArgs struct {
@@ -94,23 +183,58 @@ type CopyObjectArgs struct {
 	Object          string
 	SrcBucket       string
 	SrcObject       string
+	SrcVersionID    string
 	ContentEncoding string
 	ContentType     string
 	Expires         time.Time
+	Metadata        map[string]string
+	Tags            map[string]string
 	ReplaceMeta     bool
+	ReplaceTags     bool
+	SSE             *ServerSideEncryption
+	SrcSSE          *ServerSideEncryption
 }
 
 type GetObjectArgs struct {
-	UserId   string
-	Bucket   string
-	Object   string
-	WithBody bool
+	UserId     string
+	Bucket     string
+	Object     string
+	VersionID  string
+	WithBody   bool
+	Range      *ObjectRange
+	Conditions *ObjectConditions
+	SSE        *ServerSideEncryption
+}
+
+// ObjectRange is a single byte range of the object, positions are inclusive.
+// Start < 0 means the suffix range of the last End bytes, End < 0 means the
+// range is open to the end of object
+type ObjectRange struct {
+	Start int64
+	End   int64
+}
+
+// ServerSideEncryption is the encryption requested by the sse headers, the
+// customer key is only set for SSE-C and never persisted
+type ServerSideEncryption struct {
+	Type           string
+	CustomerKey    []byte
+	CustomerKeyMD5 string
+}
+
+// ObjectConditions is the conditional headers of the get or head object request
+type ObjectConditions struct {
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   time.Time
+	IfUnmodifiedSince time.Time
 }
 
 type DeleteObjectArgs struct {
-	UserId string
-	Bucket string
-	Object string
+	UserId    string
+	Bucket    string
+	Object    string
+	VersionID string
 }
 
 type DeleteObjectsArgs struct {
@@ -122,6 +246,7 @@ type DeleteObjectsArgs struct {
 
 type ToDeleteObject struct {
 	Object      string
+	VersionID   string
 	ValidateErr error
 }
 
@@ -147,12 +272,45 @@ type ListObjectsV2Args struct {
 	FetchOwner   bool
 }
 
+type ListObjectVersionsArgs struct {
+	UserId          string
+	Bucket          string
+	MaxKeys         int64
+	Prefix          string
+	Delimiter       string
+	EncodingType    string
+	KeyMarker       string
+	VersionIDMarker string
+}
+
 type GetObjectACLArgs struct {
 	UserId string
 	Bucket string
 	Object string
 }
 
+type PutObjectTaggingArgs struct {
+	UserId    string
+	Bucket    string
+	Object    string
+	VersionID string
+	Tags      map[string]string
+}
+
+type GetObjectTaggingArgs struct {
+	UserId    string
+	Bucket    string
+	Object    string
+	VersionID string
+}
+
+type DeleteObjectTaggingArgs struct {
+	UserId    string
+	Bucket    string
+	Object    string
+	VersionID string
+}
+
 type CreateMultipartUploadArgs struct {
 	UserId          string
 	Bucket          string
@@ -160,6 +318,9 @@ type CreateMultipartUploadArgs struct {
 	ContentEncoding string
 	ContentType     string
 	Expires         time.Time
+	Metadata        map[string]string
+	Tags            map[string]string
+	SSE             *ServerSideEncryption
 }
 
 type UploadPartArgs struct {
@@ -170,6 +331,7 @@ type UploadPartArgs struct {
 	UploadId      string
 	PartNumber    int64
 	ContentLength int64
+	SSE           *ServerSideEncryption
 }
 
 type AbortMultipartUploadArgs struct {
@@ -188,22 +350,72 @@ type CompleteMultipartUploadArgs struct {
 	CompletedParts CompletedParts
 }
 
+type ListMultipartUploadsArgs struct {
+	UserId         string
+	Bucket         string
+	MaxUploads     int64
+	Prefix         string
+	Delimiter      string
+	EncodingType   string
+	KeyMarker      string
+	UploadIDMarker string
+}
+
+type ListPartsArgs struct {
+	UserId           string
+	Bucket           string
+	Object           string
+	UploadId         string
+	MaxParts         int64
+	PartNumberMarker int64
+}
+
 type ACL struct {
 	Owner string
 	ACL   string
 }
 
 type DeletedObject struct {
-	Object    string
-	DeleteErr error
+	Object                string
+	VersionID             string
+	DeleteMarker          bool
+	DeleteMarkerVersionID string
+	DeleteErr             error
 }
 
 type Bucket struct {
-	Name    string
-	Region  string
-	Owner   string
-	ACL     string
-	Created time.Time
+	Name       string
+	Region     string
+	Owner      string
+	ACL        string
+	Versioning string
+	Lifecycle  []*LifecycleRule
+	Policy     *policy.Policy
+	Storage    *StoragePolicy
+	Created    time.Time
+}
+
+// StoragePolicy is the policy of pushing the new object bodies of the bucket
+// into the paid decentralized storage, the storage is paid by the node
+type StoragePolicy struct {
+	Replication   int
+	StorageLength int
+	Token         string
+	MaxPrice      int64
+	AutoRenew     bool
+}
+
+type LifecycleRule struct {
+	ID                        string
+	Enabled                   bool
+	Prefix                    string
+	Tags                      map[string]string
+	SizeGreaterThan           int64
+	SizeLessThan              int64
+	ExpirationDays            int64
+	ExpirationDate            time.Time
+	NoncurrentExpirationDays  int64
+	AbortIncompleteUploadDays int64
 }
 
 type BucketList struct {
@@ -225,11 +437,24 @@ type Object struct {
 	DeleteMarker     bool
 	ContentType      string
 	ContentEncoding  string
+	Metadata         map[string]string
+	Tags             map[string]string
+	Encryption       *Encryption
+	Storage          *ObjectStorage
 	Expires          time.Time
 	AccTime          time.Time
 	SuccessorModTime time.Time
 }
 
+// ObjectStorage is the state of pushing the object body into the decentralized
+// storage by the storage upload session
+type ObjectStorage struct {
+	SessionID string
+	Status    string
+	Message   string
+	Updated   time.Time
+}
+
 type Multipart struct {
 	Bucket          string
 	Object          string
@@ -238,17 +463,30 @@ type Multipart struct {
 	ContentType     string
 	ContentEncoding string
 	Expires         time.Time
+	Metadata        map[string]string
+	Tags            map[string]string
+	Encryption      *Encryption
 	Parts           []*Part
 }
 
 type Part struct {
 	ETag    string    `json:"etag,omitempty"`
 	CID     string    `json:"cid,omitempty"`
+	IV      []byte    `json:"iv,omitempty"`
 	Number  int64     `json:"number"`
 	Size    int64     `json:"size"`
 	ModTime time.Time `json:"mod_time"`
 }
 
+// Encryption is the server side encryption of the object body, the object key
+// is sealed by the customer key of SSE-C or the bucket data key of SSE-S3
+type Encryption struct {
+	Type      string
+	KeyMD5    string
+	SealedKey []byte
+	Segments  []*sse.Segment
+}
+
 type ObjectsList struct {
 	Args        *ListObjectsArgs
 	Owner       string
@@ -267,6 +505,35 @@ type ObjectsListV2 struct {
 	Prefixes              []string
 }
 
+type ObjectVersionsList struct {
+	Args                *ListObjectVersionsArgs
+	Owner               string
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIDMarker string
+	Versions            []*Object
+	Prefixes            []string
+}
+
+type MultipartUploadsList struct {
+	Args               *ListMultipartUploadsArgs
+	Owner              string
+	IsTruncated        bool
+	NextKeyMarker      string
+	NextUploadIDMarker string
+	Uploads            []*Multipart
+	Prefixes           []string
+}
+
+type PartsList struct {
+	Args                 *ListPartsArgs
+	Owner                string
+	Multipart            *Multipart
+	IsTruncated          bool
+	NextPartNumberMarker int64
+	Parts                []*Part
+}
+
 type CompletePart struct {
 	PartNumber int64
 	ETag       string
@@ -0,0 +1,36 @@
+package object
+
+// Resolve the range with the object size, get the offset and length of
+// the range body, ErrInvalidRange will be returned if the range is not
+// satisfiable
+func (rng *ObjectRange) Resolve(size int64) (offset, length int64, err error) {
+	// Suffix range
+	if rng.Start < 0 {
+		if rng.End <= 0 || size == 0 {
+			err = ErrInvalidRange
+			return
+		}
+		length = rng.End
+		if length > size {
+			length = size
+		}
+		offset = size - length
+		return
+	}
+
+	// First byte position must be in the object
+	if rng.Start >= size {
+		err = ErrInvalidRange
+		return
+	}
+
+	// Last byte position is trimmed to the end of object
+	end := rng.End
+	if end < 0 || end >= size {
+		end = size - 1
+	}
+
+	offset = rng.Start
+	length = end - rng.Start + 1
+	return
+}
@@ -0,0 +1,90 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestObjectRangeResolve(t *testing.T) {
+	const size = 10
+	tests := []struct {
+		name   string
+		rng    ObjectRange
+		size   int64
+		offset int64
+		length int64
+		err    error
+	}{
+		{name: "closed", rng: ObjectRange{Start: 2, End: 5}, size: size, offset: 2, length: 4},
+		{name: "single byte", rng: ObjectRange{Start: 0, End: 0}, size: size, offset: 0, length: 1},
+		{name: "open ended", rng: ObjectRange{Start: 3, End: -1}, size: size, offset: 3, length: 7},
+		{name: "last byte", rng: ObjectRange{Start: 9, End: -1}, size: size, offset: 9, length: 1},
+		{name: "end past eof", rng: ObjectRange{Start: 8, End: 100}, size: size, offset: 8, length: 2},
+		{name: "suffix", rng: ObjectRange{Start: -1, End: 3}, size: size, offset: 7, length: 3},
+		{name: "suffix whole", rng: ObjectRange{Start: -1, End: 10}, size: size, offset: 0, length: 10},
+		{name: "suffix past size", rng: ObjectRange{Start: -1, End: 100}, size: size, offset: 0, length: 10},
+		{name: "start at eof", rng: ObjectRange{Start: 10, End: -1}, size: size, err: ErrInvalidRange},
+		{name: "start past eof", rng: ObjectRange{Start: 20, End: 30}, size: size, err: ErrInvalidRange},
+		{name: "zero suffix", rng: ObjectRange{Start: -1, End: 0}, size: size, err: ErrInvalidRange},
+		{name: "suffix of empty", rng: ObjectRange{Start: -1, End: 5}, size: 0, err: ErrInvalidRange},
+		{name: "range of empty", rng: ObjectRange{Start: 0, End: -1}, size: 0, err: ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, length, err := tt.rng.Resolve(tt.size)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Resolve() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if offset != tt.offset || length != tt.length {
+				t.Errorf("Resolve() = (%d, %d), want (%d, %d)", offset, length, tt.offset, tt.length)
+			}
+		})
+	}
+}
+
+func TestGetObjectRange(t *testing.T) {
+	const bucket = "range"
+	ts := newTestService(t, bucket)
+	ts.put(t, bucket, "obj", "0123456789")
+
+	get := func(rng *ObjectRange) (string, error) {
+		_, body, err := ts.GetObject(context.Background(), &GetObjectArgs{
+			UserId:   testUser,
+			Bucket:   bucket,
+			Object:   "obj",
+			WithBody: true,
+			Range:    rng,
+		})
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		return string(data), err
+	}
+
+	for rng, expected := range map[ObjectRange]string{
+		{Start: 2, End: 4}:   "234",
+		{Start: 7, End: -1}:  "789",
+		{Start: -1, End: 2}:  "89",
+		{Start: 8, End: 100}: "89",
+	} {
+		rng := rng
+		got, err := get(&rng)
+		if err != nil {
+			t.Fatalf("range %+v: %v", rng, err)
+		}
+		if got != expected {
+			t.Errorf("range %+v: expect %q, got %q", rng, expected, got)
+		}
+	}
+
+	if _, err := get(&ObjectRange{Start: 10, End: -1}); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expect %v, got %v", ErrInvalidRange, err)
+	}
+}
@@ -2,8 +2,10 @@ package object
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/bittorrent/go-btfs/s3/action"
+	"github.com/bittorrent/go-btfs/s3/api/contexts"
 	"github.com/bittorrent/go-btfs/s3/api/providers"
 	"github.com/bittorrent/go-btfs/s3/ctxmu"
 	"github.com/bittorrent/go-btfs/s3/policy"
@@ -21,9 +23,15 @@ type service struct {
 	bucketSpace      string
 	objectSpace      string
 	uploadSpace      string
+	versionSpace     string
 	cidrefSpace      string
+	dataKeySpace     string
+	importSpace      string
+	storageSpace     string
 	operationTimeout time.Duration
 	closeBodyTimeout time.Duration
+	lifecycleSweep   time.Duration
+	storageSync      time.Duration
 }
 
 func NewService(providers providers.Providerser, options ...Option) Service {
@@ -34,9 +42,15 @@ func NewService(providers providers.Providerser, options ...Option) Service {
 		bucketSpace:      defaultBucketSpace,
 		objectSpace:      defaultObjectSpace,
 		uploadSpace:      defaultUploadSpace,
+		versionSpace:     defaultVersionSpace,
 		cidrefSpace:      defaultCidrefSpace,
+		dataKeySpace:     defaultDataKeySpace,
+		importSpace:      defaultImportSpace,
+		storageSpace:     defaultStorageSpace,
 		operationTimeout: defaultOperationTimeout,
 		closeBodyTimeout: defaultCloseBodyTimeout,
+		lifecycleSweep:   defaultLifecycleSweep,
+		storageSync:      defaultStorageSync,
 	}
 	for _, option := range options {
 		option(s)
@@ -81,16 +95,56 @@ func (s *service) getUploadPartKey(uplkey string, idx int) (key string) {
 	return
 }
 
+func (s *service) getAllVersionsKeyPrefix(bucname string) (prefix string) {
+	prefix = strings.Join([]string{s.versionSpace, bucname, ""}, s.keySeparator)
+	return
+}
+
+func (s *service) getObjectVersionsKeyPrefix(bucname, objname string) (prefix string) {
+	prefix = s.getAllVersionsKeyPrefix(bucname) + objname + s.keySeparator
+	return
+}
+
+func (s *service) getVersionKey(bucname, objname, verid string) (key string) {
+	key = s.getObjectVersionsKeyPrefix(bucname, objname) + verid
+	return
+}
+
 func (s *service) getAllCidrefsKeyPrefix(cid string) (prefix string) {
 	prefix = strings.Join([]string{s.cidrefSpace, cid, ""}, s.keySeparator)
 	return
 }
 
+// getImportedCidKey the key marks the imported cid was pinned by the node
+// before the gateway referenced it, so the gateway must never unpin it
+func (s *service) getImportedCidKey(cid string) (key string) {
+	key = strings.Join([]string{s.importSpace, cid}, s.keySeparator)
+	return
+}
+
+// getAllStorageIndexesKeyPrefix the keys with the prefix index the objects
+// which storage have not been finished, so they are synced without scanning
+// all objects
+func (s *service) getAllStorageIndexesKeyPrefix() (prefix string) {
+	prefix = strings.Join([]string{s.storageSpace, ""}, s.keySeparator)
+	return
+}
+
+func (s *service) getStorageIndexKey(bucname, objname string) (key string) {
+	key = s.getAllStorageIndexesKeyPrefix() + strings.Join([]string{bucname, objname}, s.keySeparator)
+	return
+}
+
 func (s *service) getCidrefKey(cid, to string) (key string) {
 	key = s.getAllCidrefsKeyPrefix(cid) + to
 	return
 }
 
+func (s *service) getDataKeyKey(bucname string) (key string) {
+	key = strings.Join([]string{s.dataKeySpace, bucname}, s.keySeparator)
+	return
+}
+
 func (s *service) opctx(parent context.Context) (ctx context.Context, cancel context.CancelFunc) {
 	ctx, cancel = context.WithTimeout(parent, s.operationTimeout)
 	return
@@ -102,6 +156,58 @@ func (s *service) checkACL(owner, acl, user string, act action.Action) (allow bo
 	return
 }
 
+// checkAccess check if the user is allowed to do the action on the bucket or
+// the object of it. The bucket policy is evaluated first, its explicit deny
+// always wins and its explicit allow grants the access, otherwise the canned
+// ACL decides. The owner always can manage the bucket policy, so it will not
+// be locked out of its bucket by a wrong policy
+func (s *service) checkAccess(ctx context.Context, bucket *Bucket, user string, act action.Action, objname string) (allow bool) {
+	allow = s.checkPolicyAndACL(ctx, bucket, user, act, objname, nil)
+	return
+}
+
+// checkListAccess is like checkAccess but used by the list actions, the
+// listing prefix is evaluated as the s3:prefix condition of the policy
+func (s *service) checkListAccess(ctx context.Context, bucket *Bucket, user string, act action.Action, prefix string) (allow bool) {
+	allow = s.checkPolicyAndACL(ctx, bucket, user, act, "", map[string][]string{
+		policy.KeyPrefix: {prefix},
+	})
+	return
+}
+
+func (s *service) checkPolicyAndACL(ctx context.Context, bucket *Bucket, user string, act action.Action, objname string, conds map[string][]string) (allow bool) {
+	own := user != "" && user == bucket.Owner
+	if own && (act == action.PutBucketPolicyAction || act == action.GetBucketPolicyAction ||
+		act == action.DeleteBucketPolicyAction) {
+		return true
+	}
+
+	if bucket.Policy != nil {
+		if conds == nil {
+			conds = make(map[string][]string)
+		}
+		if ip := contexts.GetSourceIP(ctx); ip != "" {
+			conds[policy.KeySourceIP] = []string{ip}
+		}
+		effect := bucket.Policy.Evaluate(&policy.Args{
+			AccountID:  user,
+			Action:     act,
+			BucketName: bucket.Name,
+			ObjectName: objname,
+			Conditions: conds,
+		})
+		switch effect {
+		case policy.Deny:
+			return false
+		case policy.Allow:
+			return true
+		}
+	}
+
+	allow = policy.IsAllowed(own, bucket.ACL, act)
+	return
+}
+
 func (s *service) addBodyRef(ctx context.Context, cid, tokey string) (err error) {
 	// Cid reference key
 	crfkey := s.getCidrefKey(cid, tokey)
@@ -142,6 +248,77 @@ func (s *service) storeBody(ctx context.Context, body io.Reader, tokey string) (
 	return
 }
 
+// importBody reference the existing file as the object body without copying
+// it. The file is pinned if it is not yet, so it can be removed as the stored
+// ones; a file pinned by the node before is marked as imported and will never
+// be unpinned by the gateway
+func (s *service) importBody(ctx context.Context, cid, tokey string) (size int64, err error) {
+	// RLock all cid refs to enable no cid will be deleted
+	err = s.lock.RLock(ctx, s.cidrefSpace)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(s.cidrefSpace)
+
+	// Get the file size
+	size, err = s.providers.FileStore().Size(cid)
+	if err != nil {
+		err = ErrSourceCidNotFound
+		return
+	}
+
+	pinned, err := s.providers.FileStore().IsPinned(cid)
+	if err != nil {
+		return
+	}
+
+	// Pin the file, or mark it as imported if it is pinned by others
+	var pinnedHere, markedHere bool
+	if !pinned {
+		err = s.providers.FileStore().Pin(cid)
+		if err != nil {
+			return
+		}
+		pinnedHere = true
+	} else {
+		var referenced bool
+		referenced, err = s.hasBodyRefs(cid)
+		if err != nil {
+			return
+		}
+		if !referenced {
+			err = s.providers.StateStore().Put(s.getImportedCidKey(cid), true)
+			if err != nil {
+				return
+			}
+			markedHere = true
+		}
+	}
+
+	// Add cid reference, roll back the pin or the mark if failed
+	err = s.addBodyRef(ctx, cid, tokey)
+	if err != nil {
+		if pinnedHere {
+			_ = s.providers.FileStore().Remove(cid)
+		}
+		if markedHere {
+			_ = s.providers.StateStore().Delete(s.getImportedCidKey(cid))
+		}
+	}
+
+	return
+}
+
+// hasBodyRefs check if the cid is referenced by any object
+func (s *service) hasBodyRefs(cid string) (referenced bool, err error) {
+	err = s.providers.StateStore().Iterate(s.getAllCidrefsKeyPrefix(cid), func(key, _ []byte) (stop bool, err error) {
+		referenced = true
+		stop = true
+		return
+	})
+	return
+}
+
 func (s *service) removeBody(ctx context.Context, cid, tokey string) (err error) {
 	// Flag to mark cid be referenced by other object
 	otherRef := false
@@ -165,16 +342,8 @@ func (s *service) removeBody(ctx context.Context, cid, tokey string) (err error)
 		return
 	}
 
-	// All this cid references prefix
-	allRefsPrefix := s.getAllCidrefsKeyPrefix(cid)
-
-	// Iterate all this cid refs, if exists other object's ref, set
-	// the otherRef mark to true
-	err = s.providers.StateStore().Iterate(allRefsPrefix, func(key, _ []byte) (stop bool, err error) {
-		otherRef = true
-		stop = true
-		return
-	})
+	// Check if exists other object's ref to this cid
+	otherRef, err = s.hasBodyRefs(cid)
 	if err != nil {
 		return
 	}
@@ -184,6 +353,18 @@ func (s *service) removeBody(ctx context.Context, cid, tokey string) (err error)
 		return
 	}
 
+	// The imported cid pinned by others is kept, only the mark is removed
+	impkey := s.getImportedCidKey(cid)
+	var imported bool
+	err = s.providers.StateStore().Get(impkey, &imported)
+	if err == nil {
+		err = s.providers.StateStore().Delete(impkey)
+		return
+	}
+	if !errors.Is(err, providers.ErrStateStoreNotFound) {
+		return
+	}
+
 	// No other refs to this cid, remove it
 	err = s.providers.FileStore().Remove(cid)
 
@@ -88,7 +88,7 @@ func (s *service) GetBucket(ctx context.Context, args *GetBucketArgs) (bucket *B
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.HeadBucketAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.HeadBucketAction, "")
 	if !allow {
 		err = ErrNotAllowed
 	}
@@ -123,7 +123,7 @@ func (s *service) DeleteBucket(ctx context.Context, args *DeleteBucketArgs) (err
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.DeleteBucketAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.DeleteBucketAction, "")
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -141,6 +141,12 @@ func (s *service) DeleteBucket(ctx context.Context, args *DeleteBucketArgs) (err
 
 	// Delete bucket
 	err = s.providers.StateStore().Delete(buckey)
+	if err != nil {
+		return
+	}
+
+	// Try to delete the bucket data key of SSE-S3
+	_ = s.providers.StateStore().Delete(s.getDataKeyKey(args.Bucket))
 
 	return
 }
@@ -227,7 +233,7 @@ func (s *service) PutBucketACL(ctx context.Context, args *PutBucketACLArgs) (err
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.PutBucketAclAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.PutBucketAclAction, "")
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -269,7 +275,7 @@ func (s *service) GetBucketACL(ctx context.Context, args *GetBucketACLArgs) (acl
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.GetBucketAclAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.GetBucketAclAction, "")
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -284,6 +290,88 @@ func (s *service) GetBucketACL(ctx context.Context, args *GetBucketACLArgs) (acl
 	return
 }
 
+// PutBucketVersioning update user specified bucket's versioning state
+func (s *service) PutBucketVersioning(ctx context.Context, args *PutBucketVersioningArgs) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// Lock bucket
+	err = s.lock.Lock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.PutBucketVersioningAction, "")
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Update bucket versioning
+	bucket.Versioning = args.Versioning
+
+	// Put bucket
+	err = s.providers.StateStore().Put(buckey, bucket)
+
+	return
+}
+
+// GetBucketVersioning get user specified bucket's versioning state, it is
+// empty if the versioning has never been enabled on the bucket
+func (s *service) GetBucketVersioning(ctx context.Context, args *GetBucketVersioningArgs) (versioning string, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.GetBucketVersioningAction, "")
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Versioning
+	versioning = bucket.Versioning
+
+	return
+}
+
 // EmptyBucket check if the user specified bucked is empty
 func (s *service) isBucketEmpty(bucname string) (empty bool, err error) {
 	// All bucket objects prefix
@@ -301,11 +389,27 @@ func (s *service) isBucketEmpty(bucname string) (empty bool, err error) {
 	})
 
 	// If bucket have at least one object, return not empty, else check if bucket
-	// have at least one upload
+	// have at least one object version
 	if !empty {
 		return
 	}
 
+	// All bucket versions prefix
+	versionsPrefix := s.getAllVersionsKeyPrefix(bucname)
+
+	// Set empty to false if bucket has at least one version(include delete markers)
+	err = s.providers.StateStore().Iterate(versionsPrefix, func(_, _ []byte) (stop bool, er error) {
+		empty = false
+		stop = true
+		return
+	})
+
+	// If bucket have at least one version, return not empty, else check if bucket
+	// have at least one upload
+	if err != nil || !empty {
+		return
+	}
+
 	// All bucket uploads prefix
 	uploadsPrefix := s.getAllUploadsKeyPrefix(bucname)
 
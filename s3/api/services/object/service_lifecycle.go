@@ -0,0 +1,458 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bittorrent/go-btfs/s3/action"
+)
+
+// PutBucketLifecycle replace user specified bucket's lifecycle rules
+func (s *service) PutBucketLifecycle(ctx context.Context, args *PutBucketLifecycleArgs) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// Lock bucket
+	err = s.lock.Lock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.PutBucketLifecycleAction, "")
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Update bucket lifecycle
+	bucket.Lifecycle = args.Rules
+
+	// Put bucket
+	err = s.providers.StateStore().Put(buckey, bucket)
+
+	return
+}
+
+// GetBucketLifecycle get user specified bucket's lifecycle rules
+func (s *service) GetBucketLifecycle(ctx context.Context, args *GetBucketLifecycleArgs) (rules []*LifecycleRule, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.GetBucketLifecycleAction, "")
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// No lifecycle rules
+	if len(bucket.Lifecycle) == 0 {
+		err = ErrLifecycleNotFound
+		return
+	}
+
+	// Rules
+	rules = bucket.Lifecycle
+
+	return
+}
+
+// DeleteBucketLifecycle remove all lifecycle rules of user specified bucket
+func (s *service) DeleteBucketLifecycle(ctx context.Context, args *DeleteBucketLifecycleArgs) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// Lock bucket
+	err = s.lock.Lock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.DeleteBucketLifecycleAction, "")
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Clear bucket lifecycle
+	bucket.Lifecycle = nil
+
+	// Put bucket
+	err = s.providers.StateStore().Put(buckey, bucket)
+
+	return
+}
+
+// sweepLifecycles apply lifecycle rules of all buckets
+func (s *service) sweepLifecycles(ctx context.Context) (err error) {
+	// Collect buckets with lifecycle rules
+	var buckets []*Bucket
+	err = s.providers.StateStore().Iterate(s.getAllBucketsKeyPrefix(), func(key, _ []byte) (stop bool, er error) {
+		bucket, er := s.getBucket(string(key))
+		if er != nil || bucket == nil {
+			return
+		}
+		if len(bucket.Lifecycle) > 0 {
+			buckets = append(buckets, bucket)
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	// Apply rules bucket by bucket, the failure of one bucket will not
+	// stop applying rules of others
+	for _, bucket := range buckets {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			return
+		}
+		er := s.sweepBucketLifecycle(ctx, bucket)
+		if er != nil {
+			fmt.Printf("s3-api: lifecycle bucket <%s>, err: %v\n", bucket.Name, er)
+		}
+	}
+
+	return
+}
+
+// sweepBucketLifecycle expire objects and abort incomplete multipart uploads
+// of the bucket according to its lifecycle rules
+func (s *service) sweepBucketLifecycle(ctx context.Context, bucket *Bucket) (err error) {
+	// Now
+	now := time.Now().UTC()
+
+	// Collect expired objects names
+	var expiredObjects []string
+	allObjectsKeyPrefix := s.getAllObjectsKeyPrefix(bucket.Name)
+	err = s.providers.StateStore().Iterate(allObjectsKeyPrefix, func(key, _ []byte) (stop bool, er error) {
+		var object *Object
+		er = s.providers.StateStore().Get(string(key), &object)
+		if er != nil {
+			return
+		}
+		if s.isObjectExpired(bucket.Lifecycle, object, now) {
+			expiredObjects = append(expiredObjects, object.Name)
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	// Collect objects with noncurrent versions may be expired
+	var versionedObjects []string
+	if bucket.Versioning != "" && hasNoncurrentExpiration(bucket.Lifecycle) {
+		seen := make(map[string]bool)
+		err = s.providers.StateStore().Iterate(s.getAllVersionsKeyPrefix(bucket.Name), func(key, _ []byte) (stop bool, er error) {
+			var version *Object
+			er = s.providers.StateStore().Get(string(key), &version)
+			if er != nil {
+				return
+			}
+			if !seen[version.Name] {
+				seen[version.Name] = true
+				versionedObjects = append(versionedObjects, version.Name)
+			}
+			return
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	// Collect incomplete multipart uploads should be aborted
+	var abortUploads []*Multipart
+	allUploadsKeyPrefix := s.getAllUploadsKeyPrefix(bucket.Name)
+	err = s.providers.StateStore().Iterate(allUploadsKeyPrefix, func(key, _ []byte) (stop bool, er error) {
+		var multipart *Multipart
+		er = s.providers.StateStore().Get(string(key), &multipart)
+		if er != nil {
+			return
+		}
+		if s.isUploadAborted(bucket.Lifecycle, multipart, now) {
+			abortUploads = append(abortUploads, multipart)
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	// Expire objects
+	for _, objname := range expiredObjects {
+		er := s.expireObject(ctx, bucket.Name, objname, now)
+		if er != nil {
+			fmt.Printf("s3-api: lifecycle expire <%s/%s>, err: %v\n", bucket.Name, objname, er)
+		}
+	}
+
+	// Expire noncurrent versions
+	for _, objname := range versionedObjects {
+		er := s.expireNoncurrentVersions(ctx, bucket.Name, objname, now)
+		if er != nil {
+			fmt.Printf("s3-api: lifecycle expire versions <%s/%s>, err: %v\n", bucket.Name, objname, er)
+		}
+	}
+
+	// Abort uploads, uploads are always owned by the bucket owner
+	for _, multipart := range abortUploads {
+		er := s.AbortMultipartUpload(ctx, &AbortMultipartUploadArgs{
+			UserId:   bucket.Owner,
+			Bucket:   multipart.Bucket,
+			Object:   multipart.Object,
+			UploadId: multipart.UploadID,
+		})
+		if er != nil && er != ErrUploadNotFound {
+			fmt.Printf("s3-api: lifecycle abort <%s/%s> upload <%s>, err: %v\n", bucket.Name, multipart.Object, multipart.UploadID, er)
+		}
+	}
+
+	return
+}
+
+// expireObject delete the object if it is still expired, a delete marker will be
+// added if the bucket versioning has ever been enabled
+func (s *service) expireObject(ctx context.Context, bucname, objname string, now time.Time) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(bucname)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil || bucket == nil {
+		return
+	}
+
+	// Object key
+	objkey := s.getObjectKey(bucname, objname)
+
+	// Lock object
+	err = s.lock.Lock(ctx, objkey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(objkey)
+
+	// Get object, it may be changed or removed after collected
+	object, err := s.getObject(objkey)
+	if err != nil || object == nil {
+		return
+	}
+	if !s.isObjectExpired(bucket.Lifecycle, object, now) {
+		return
+	}
+
+	// Delete object
+	_, err = s.deleteObjectOfVersion(ctx, bucket, objname, "")
+
+	return
+}
+
+// expireNoncurrentVersions remove the noncurrent versions of the object which
+// are expired, the current object is not changed
+func (s *service) expireNoncurrentVersions(ctx context.Context, bucname, objname string, now time.Time) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(bucname)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil || bucket == nil {
+		return
+	}
+
+	// Object key
+	objkey := s.getObjectKey(bucname, objname)
+
+	// Lock object
+	err = s.lock.Lock(ctx, objkey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(objkey)
+
+	// Get versions, they may be changed after collected
+	versions, err := s.getObjectVersions(bucname, objname)
+	if err != nil {
+		return
+	}
+
+	// Remove expired versions
+	for _, version := range s.expiredNoncurrentVersions(bucket.Lifecycle, versions, now) {
+		_, err = s.removeObjectVersion(ctx, bucname, objname, version.VersionID)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// expiredNoncurrentVersions select the noncurrent versions expired by any of
+// the rules, the versions are ordered the latest first, a version becomes
+// noncurrent when its successor is put
+func (s *service) expiredNoncurrentVersions(rules []*LifecycleRule, versions []*Object, now time.Time) (expired []*Object) {
+	for i := 1; i < len(versions); i++ {
+		version, noncurrentTime := versions[i], versions[i-1].ModTime
+		for _, rule := range rules {
+			if !rule.Enabled || rule.NoncurrentExpirationDays <= 0 || !rule.matchObject(version) {
+				continue
+			}
+			if !now.Before(lifecycleDue(noncurrentTime, rule.NoncurrentExpirationDays)) {
+				expired = append(expired, version)
+				break
+			}
+		}
+	}
+	return
+}
+
+func hasNoncurrentExpiration(rules []*LifecycleRule) bool {
+	for _, rule := range rules {
+		if rule.Enabled && rule.NoncurrentExpirationDays > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isObjectExpired check if the object is expired by any of the rules
+func (s *service) isObjectExpired(rules []*LifecycleRule, object *Object, now time.Time) (expired bool) {
+	for _, rule := range rules {
+		if !rule.Enabled || !rule.matchObject(object) {
+			continue
+		}
+		if !rule.ExpirationDate.IsZero() && !now.Before(rule.ExpirationDate) {
+			return true
+		}
+		if rule.ExpirationDays > 0 && !now.Before(lifecycleDue(object.ModTime, rule.ExpirationDays)) {
+			return true
+		}
+	}
+	return
+}
+
+// isUploadAborted check if the incomplete multipart upload should be aborted by any of the rules
+func (s *service) isUploadAborted(rules []*LifecycleRule, multipart *Multipart, now time.Time) (abort bool) {
+	for _, rule := range rules {
+		if !rule.Enabled || rule.AbortIncompleteUploadDays <= 0 {
+			continue
+		}
+		if !strings.HasPrefix(multipart.Object, rule.Prefix) {
+			continue
+		}
+		if !now.Before(lifecycleDue(multipart.Initiated, rule.AbortIncompleteUploadDays)) {
+			return true
+		}
+	}
+	return
+}
+
+// matchObject check if the object matches the rule filter
+func (rule *LifecycleRule) matchObject(object *Object) (match bool) {
+	if !strings.HasPrefix(object.Name, rule.Prefix) {
+		return
+	}
+	if rule.SizeGreaterThan > 0 && object.Size <= rule.SizeGreaterThan {
+		return
+	}
+	if rule.SizeLessThan > 0 && object.Size >= rule.SizeLessThan {
+		return
+	}
+	for k, v := range rule.Tags {
+		if tv, ok := object.Tags[k]; !ok || tv != v {
+			return
+		}
+	}
+	match = true
+	return
+}
+
+// lifecycleDue calculate the due time of the rule action, like S3 it is rounded up
+// to the next midnight UTC after the days passed
+func lifecycleDue(start time.Time, days int64) (due time.Time) {
+	due = start.UTC().Add(time.Duration(days) * 24 * time.Hour)
+	midnight := due.Truncate(24 * time.Hour)
+	if midnight.Before(due) {
+		midnight = midnight.Add(24 * time.Hour)
+	}
+	due = midnight
+	return
+}
@@ -0,0 +1,259 @@
+package object
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLifecycleDue(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		days  int64
+		due   time.Time
+	}{
+		{
+			name:  "rounded up to midnight",
+			start: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			days:  1,
+			due:   time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "at midnight",
+			start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			days:  30,
+			due:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "local time",
+			start: time.Date(2024, 1, 1, 23, 0, 0, 0, time.FixedZone("UTC-2", -2*3600)),
+			days:  1,
+			due:   time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if due := lifecycleDue(tt.start, tt.days); !due.Equal(tt.due) {
+				t.Errorf("lifecycleDue() = %v, want %v", due, tt.due)
+			}
+		})
+	}
+}
+
+func TestLifecycleMatchObject(t *testing.T) {
+	object := &Object{
+		Name: "logs/2024/app.log",
+		Size: 100,
+		Tags: map[string]string{"env": "dev", "team": "s3"},
+	}
+	tests := []struct {
+		name  string
+		rule  *LifecycleRule
+		match bool
+	}{
+		{"no filter", &LifecycleRule{}, true},
+		{"prefix", &LifecycleRule{Prefix: "logs/"}, true},
+		{"other prefix", &LifecycleRule{Prefix: "data/"}, false},
+		{"tag", &LifecycleRule{Tags: map[string]string{"env": "dev"}}, true},
+		{"tag value", &LifecycleRule{Tags: map[string]string{"env": "prod"}}, false},
+		{"missing tag", &LifecycleRule{Tags: map[string]string{"owner": "me"}}, false},
+		{"all tags", &LifecycleRule{Tags: map[string]string{"env": "dev", "team": "s3"}}, true},
+		{"prefix and tag", &LifecycleRule{Prefix: "logs/", Tags: map[string]string{"env": "dev"}}, true},
+		{"prefix and other tag", &LifecycleRule{Prefix: "logs/", Tags: map[string]string{"env": "prod"}}, false},
+		{"size greater", &LifecycleRule{SizeGreaterThan: 99}, true},
+		{"size not greater", &LifecycleRule{SizeGreaterThan: 100}, false},
+		{"size less", &LifecycleRule{SizeLessThan: 101}, true},
+		{"size not less", &LifecycleRule{SizeLessThan: 100}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if match := tt.rule.matchObject(object); match != tt.match {
+				t.Errorf("matchObject() = %v, want %v", match, tt.match)
+			}
+		})
+	}
+}
+
+func TestIsObjectExpired(t *testing.T) {
+	s := &service{}
+	modTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	object := &Object{
+		Name:    "logs/app.log",
+		ModTime: modTime,
+		Tags:    map[string]string{"env": "dev"},
+	}
+	date := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		rules   []*LifecycleRule
+		now     time.Time
+		expired bool
+	}{
+		{
+			name:    "days not due",
+			rules:   []*LifecycleRule{{Enabled: true, ExpirationDays: 1}},
+			now:     time.Date(2024, 1, 2, 23, 59, 59, 0, time.UTC),
+			expired: false,
+		},
+		{
+			name:    "days due",
+			rules:   []*LifecycleRule{{Enabled: true, ExpirationDays: 1}},
+			now:     time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			expired: true,
+		},
+		{
+			name:    "date not due",
+			rules:   []*LifecycleRule{{Enabled: true, ExpirationDate: date}},
+			now:     date.Add(-time.Second),
+			expired: false,
+		},
+		{
+			name:    "date due",
+			rules:   []*LifecycleRule{{Enabled: true, ExpirationDate: date}},
+			now:     date,
+			expired: true,
+		},
+		{
+			name:    "disabled",
+			rules:   []*LifecycleRule{{Enabled: false, ExpirationDays: 1}},
+			now:     date,
+			expired: false,
+		},
+		{
+			name:    "prefix not matched",
+			rules:   []*LifecycleRule{{Enabled: true, Prefix: "data/", ExpirationDays: 1}},
+			now:     date,
+			expired: false,
+		},
+		{
+			name:    "tag not matched",
+			rules:   []*LifecycleRule{{Enabled: true, Tags: map[string]string{"env": "prod"}, ExpirationDays: 1}},
+			now:     date,
+			expired: false,
+		},
+		{
+			name: "any rule",
+			rules: []*LifecycleRule{
+				{Enabled: true, Prefix: "data/", ExpirationDays: 1},
+				{Enabled: true, Tags: map[string]string{"env": "dev"}, ExpirationDays: 1},
+			},
+			now:     date,
+			expired: true,
+		},
+		{
+			name:    "noncurrent only",
+			rules:   []*LifecycleRule{{Enabled: true, NoncurrentExpirationDays: 1}},
+			now:     date,
+			expired: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if expired := s.isObjectExpired(tt.rules, object, tt.now); expired != tt.expired {
+				t.Errorf("isObjectExpired() = %v, want %v", expired, tt.expired)
+			}
+		})
+	}
+}
+
+func TestExpiredNoncurrentVersions(t *testing.T) {
+	s := &service{}
+	day := 24 * time.Hour
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	// v3 is current, v2 is noncurrent since v3 put, v1 since v2 put
+	versions := []*Object{
+		{Name: "logs/app.log", VersionID: "v3", ModTime: start.Add(10 * day)},
+		{Name: "logs/app.log", VersionID: "v2", ModTime: start.Add(5 * day)},
+		{Name: "logs/app.log", VersionID: "v1", ModTime: start},
+	}
+	tests := []struct {
+		name     string
+		rules    []*LifecycleRule
+		now      time.Time
+		expected []string
+	}{
+		{
+			name:     "none due",
+			rules:    []*LifecycleRule{{Enabled: true, NoncurrentExpirationDays: 1}},
+			now:      start.Add(6 * day),
+			expected: nil,
+		},
+		{
+			name:     "older due",
+			rules:    []*LifecycleRule{{Enabled: true, NoncurrentExpirationDays: 1}},
+			now:      start.Add(7 * day),
+			expected: []string{"v1"},
+		},
+		{
+			name:     "all noncurrent due",
+			rules:    []*LifecycleRule{{Enabled: true, NoncurrentExpirationDays: 1}},
+			now:      start.Add(100 * day),
+			expected: []string{"v2", "v1"},
+		},
+		{
+			name:     "current expiration only",
+			rules:    []*LifecycleRule{{Enabled: true, ExpirationDays: 1}},
+			now:      start.Add(100 * day),
+			expected: nil,
+		},
+		{
+			name:     "prefix not matched",
+			rules:    []*LifecycleRule{{Enabled: true, Prefix: "data/", NoncurrentExpirationDays: 1}},
+			now:      start.Add(100 * day),
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expired := s.expiredNoncurrentVersions(tt.rules, versions, tt.now)
+			var got []string
+			for _, v := range expired {
+				got = append(got, v.VersionID)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expiredNoncurrentVersions() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("expiredNoncurrentVersions() = %v, want %v", got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestExpireNoncurrentVersions(t *testing.T) {
+	const (
+		bucket = "lifecycle"
+		name   = "obj"
+	)
+	ctx := context.Background()
+	ts := newTestService(t, bucket)
+	ts.setVersioning(t, bucket, VersioningEnabled)
+	err := ts.PutBucketLifecycle(ctx, &PutBucketLifecycleArgs{
+		UserId: testUser,
+		Bucket: bucket,
+		Rules:  []*LifecycleRule{{ID: "noncurrent", Enabled: true, NoncurrentExpirationDays: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := ts.putVersion(t, bucket, name, "v1")
+	v2 := ts.putVersion(t, bucket, name, "v2")
+
+	err = ts.expireNoncurrentVersions(ctx, bucket, name, time.Now().Add(3*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.expectRefs(t, v1.CID)
+	ts.expectPinned(t, v1.CID, false)
+	ts.expectRefs(t, v2.CID, ts.getObjectKey(bucket, name), ts.getVersionKey(bucket, name, v2.VersionID))
+	versions, err := ts.getObjectVersions(bucket, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].VersionID != v2.VersionID {
+		t.Errorf("expect only version %s kept, got %d versions", v2.VersionID, len(versions))
+	}
+}
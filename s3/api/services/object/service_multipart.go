@@ -7,9 +7,12 @@ import (
 	"github.com/bittorrent/go-btfs/s3/api/providers"
 	"github.com/bittorrent/go-btfs/s3/consts"
 	"github.com/bittorrent/go-btfs/s3/etag"
+	"github.com/bittorrent/go-btfs/s3/sse"
 	"github.com/google/uuid"
 	"io"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -40,7 +43,7 @@ func (s *service) CreateMultipartUpload(ctx context.Context, args *CreateMultipa
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.CreateMultipartUploadAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.CreateMultipartUploadAction, args.Object)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -59,6 +62,12 @@ func (s *service) CreateMultipartUpload(ctx context.Context, args *CreateMultipa
 	}
 	defer s.lock.Unlock(uplkey)
 
+	// Multipart encryption, the object key is used to encrypt all parts
+	enc, _, err := s.newEncryption(ctx, args.Bucket, args.SSE)
+	if err != nil {
+		return
+	}
+
 	// now
 	now := time.Now().UTC()
 
@@ -70,6 +79,9 @@ func (s *service) CreateMultipartUpload(ctx context.Context, args *CreateMultipa
 		ContentType:     args.ContentType,
 		ContentEncoding: args.ContentEncoding,
 		Expires:         args.Expires,
+		Metadata:        args.Metadata,
+		Tags:            args.Tags,
+		Encryption:      enc,
 		Initiated:       now,
 	}
 
@@ -80,7 +92,7 @@ func (s *service) CreateMultipartUpload(ctx context.Context, args *CreateMultipa
 }
 
 // UploadPart upload user specified multipart part
-func (s *service) UploadPart(ctx context.Context, args *UploadPartArgs) (part *Part, err error) {
+func (s *service) UploadPart(ctx context.Context, args *UploadPartArgs) (part *Part, enc *Encryption, err error) {
 	// Operation context
 	ctx, cancel := s.opctx(ctx)
 	defer cancel()
@@ -106,7 +118,7 @@ func (s *service) UploadPart(ctx context.Context, args *UploadPartArgs) (part *P
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.UploadPartAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.UploadPartAction, args.Object)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -132,11 +144,33 @@ func (s *service) UploadPart(ctx context.Context, args *UploadPartArgs) (part *P
 		return
 	}
 
+	// Multipart encryption
+	enc = multipart.Encryption
+
+	// Object key, the customer key must be provided for every part if the
+	// multipart upload is encrypted with SSE-C
+	key, err := s.checkEncryption(ctx, args.Bucket, enc, args.SSE)
+	if err != nil {
+		return
+	}
+
+	// Encrypt part body as one segment
+	var (
+		body io.Reader = args.Body
+		iv   []byte
+	)
+	if enc != nil {
+		body, iv, err = s.encryptBody(key, body)
+		if err != nil {
+			return
+		}
+	}
+
 	// Upload part key
 	prtkey := s.getUploadPartKey(uplkey, len(multipart.Parts))
 
 	// Store part body
-	cid, err := s.storeBody(ctx, args.Body, prtkey)
+	cid, err := s.storeBody(ctx, body, prtkey)
 	if err != nil {
 		return
 	}
@@ -160,6 +194,7 @@ func (s *service) UploadPart(ctx context.Context, args *UploadPartArgs) (part *P
 		Number:  args.PartNumber,
 		ETag:    args.Body.ETag().String(),
 		CID:     cid,
+		IV:      iv,
 		Size:    args.ContentLength,
 		ModTime: now,
 	}
@@ -206,7 +241,7 @@ func (s *service) AbortMultipartUpload(ctx context.Context, args *AbortMultipart
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.AbortMultipartUploadAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.AbortMultipartUploadAction, args.Object)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -274,7 +309,7 @@ func (s *service) CompleteMultiPartUpload(ctx context.Context, args *CompleteMul
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.CompleteMultipartUploadAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.CompleteMultipartUploadAction, args.Object)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -329,6 +364,9 @@ func (s *service) CompleteMultiPartUpload(ctx context.Context, args *CompleteMul
 	// Total object size
 	var size int64
 
+	// Encrypted segments of all parts
+	var segments []*sse.Segment
+
 	// Mapping of part number to part index in multipart.Parts
 	idxmp := s.partIdxMap(multipart.Parts)
 
@@ -364,6 +402,11 @@ func (s *service) CompleteMultiPartUpload(ctx context.Context, args *CompleteMul
 		// Save for total object size.
 		size += gotPart.Size
 
+		// Every encrypted part is one segment of the object body
+		if multipart.Encryption != nil {
+			segments = append(segments, &sse.Segment{Size: gotPart.Size, IV: gotPart.IV})
+		}
+
 		// Get part body reader
 		var rdr io.ReadCloser
 		rdr, err = s.providers.FileStore().Cat(gotPart.CID)
@@ -395,6 +438,17 @@ func (s *service) CompleteMultiPartUpload(ctx context.Context, args *CompleteMul
 		}
 	}()
 
+	// Object encryption
+	var enc *Encryption
+	if multipart.Encryption != nil {
+		enc = &Encryption{
+			Type:      multipart.Encryption.Type,
+			KeyMD5:    multipart.Encryption.KeyMD5,
+			SealedKey: multipart.Encryption.SealedKey,
+			Segments:  segments,
+		}
+	}
+
 	// Calculate multipart etag
 	multiEtag, err := s.calcMultiETag(args.CompletedParts)
 	if err != nil {
@@ -419,11 +473,27 @@ func (s *service) CompleteMultiPartUpload(ctx context.Context, args *CompleteMul
 		DeleteMarker:     false,
 		ContentType:      multipart.ContentType,
 		ContentEncoding:  multipart.ContentEncoding,
+		Metadata:         multipart.Metadata,
+		Tags:             multipart.Tags,
+		Encryption:       enc,
+		Storage:          s.newObjectStorage(bucket, now),
 		Expires:          multipart.Expires,
 		AccTime:          time.Time{},
 		SuccessorModTime: now,
 	}
 
+	// Index the queued storage of the object
+	err = s.putStorageIndex(object)
+	if err != nil {
+		return
+	}
+
+	// Put object version
+	err = s.putObjectVersion(ctx, bucket, object, objectOld)
+	if err != nil {
+		return
+	}
+
 	// Put object
 	err = s.providers.StateStore().Put(objkey, object)
 	if err != nil {
@@ -433,8 +503,9 @@ func (s *service) CompleteMultiPartUpload(ctx context.Context, args *CompleteMul
 	// Set remove object body flag to false, because it has been referenced by the object
 	removeObjectBody = false
 
-	// Try to remove old object body if exists, because it has been covered by new one
-	if objectOld != nil {
+	// Try to remove old object body if exists, because it has been covered by new one,
+	// the body is still referenced by the old version if bucket versioning enabled
+	if objectOld != nil && objectOld.CID != object.CID {
 		_ = s.removeBody(ctx, objectOld.CID, objkey)
 	}
 
@@ -453,6 +524,253 @@ func (s *service) CompleteMultiPartUpload(ctx context.Context, args *CompleteMul
 	return
 }
 
+// ListMultipartUploads list the in-progress multipart uploads of user specified bucket
+func (s *service) ListMultipartUploads(ctx context.Context, args *ListMultipartUploadsArgs) (list *MultipartUploadsList, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Uploads list
+	list = &MultipartUploadsList{
+		Args: args,
+	}
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkListAccess(ctx, bucket, args.UserId, action.ListMultipartUploadsAction, args.Prefix)
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Set uploads owner(uploads owner is the bucket owner included them)
+	list.Owner = bucket.Owner
+
+	// MaxUploads is zero
+	if args.MaxUploads == 0 {
+		list.IsTruncated = true
+		return
+	}
+
+	// Mapping of object name to all its uploads
+	uploads := make(map[string][]*Multipart)
+
+	// Collect all uploads with the specified prefix
+	listUploadsKeyPrefix := s.getAllUploadsKeyPrefix(args.Bucket) + args.Prefix
+	err = s.providers.StateStore().Iterate(listUploadsKeyPrefix, func(key, _ []byte) (stop bool, er error) {
+		var multipart *Multipart
+		er = s.providers.StateStore().Get(string(key), &multipart)
+		if er != nil {
+			return
+		}
+		uploads[multipart.Object] = append(uploads[multipart.Object], multipart)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	// Sorted object names
+	names := make([]string, 0, len(uploads))
+	for name := range uploads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Accumulate count
+	count := int64(0)
+
+	// Seen common prefixes
+	seen := make(map[string]bool)
+
+	// Delimiter length
+	dl := len(args.Delimiter)
+
+	// Prefix length
+	pl := len(args.Prefix)
+
+	// Add an upload or a common prefix into the list, return false if
+	// the list is full
+	collect := func(multipart *Multipart, commonPrefix string) (ok bool) {
+		if count == args.MaxUploads {
+			list.IsTruncated = true
+			return
+		}
+		if multipart != nil {
+			list.Uploads = append(list.Uploads, multipart)
+			list.NextKeyMarker = multipart.Object
+			list.NextUploadIDMarker = multipart.UploadID
+		} else {
+			list.Prefixes = append(list.Prefixes, commonPrefix)
+			list.NextKeyMarker = commonPrefix
+			list.NextUploadIDMarker = ""
+		}
+		count++
+		ok = true
+		return
+	}
+
+	for _, objname := range names {
+		// Skip the objects before the key marker
+		if objname < args.KeyMarker {
+			continue
+		}
+
+		// Common prefix: same as ListObjects
+		commonPrefix := ""
+		if dl > 0 {
+			di := strings.Index(objname[pl:], args.Delimiter)
+			if di >= 0 {
+				commonPrefix = objname[:(pl + di + dl)]
+			}
+		}
+
+		// Objects with common prefix grouped into one, the group has
+		// been listed if the key marker is the common prefix
+		if commonPrefix != "" {
+			if seen[commonPrefix] || commonPrefix == args.KeyMarker {
+				continue
+			}
+			seen[commonPrefix] = true
+			if !collect(nil, commonPrefix) {
+				return
+			}
+			continue
+		}
+
+		// All uploads of the object, the earliest initiated first
+		objectUploads := uploads[objname]
+		sort.Slice(objectUploads, func(i, j int) bool {
+			if objectUploads[i].Initiated.Equal(objectUploads[j].Initiated) {
+				return objectUploads[i].UploadID < objectUploads[j].UploadID
+			}
+			return objectUploads[i].Initiated.Before(objectUploads[j].Initiated)
+		})
+
+		// Begin collect from the upload after upload id marker if the
+		// object is the key marker
+		begin := objname != args.KeyMarker
+		for _, multipart := range objectUploads {
+			if !begin {
+				begin = args.UploadIDMarker != "" && multipart.UploadID == args.UploadIDMarker
+				continue
+			}
+			if !collect(multipart, "") {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// ListParts list the uploaded parts of user specified multipart upload
+func (s *service) ListParts(ctx context.Context, args *ListPartsArgs) (list *PartsList, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Parts list
+	list = &PartsList{
+		Args: args,
+	}
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.ListPartsAction, args.Object)
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Set parts owner(parts owner is the bucket owner included them)
+	list.Owner = bucket.Owner
+
+	// Multipart upload key
+	uplkey := s.getUploadKey(args.Bucket, args.Object, args.UploadId)
+
+	// RLock upload
+	err = s.lock.RLock(ctx, uplkey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(uplkey)
+
+	// Get multipart upload
+	multipart, err := s.getMultipart(uplkey)
+	if err != nil {
+		return
+	}
+	if multipart == nil {
+		err = ErrUploadNotFound
+		return
+	}
+	list.Multipart = multipart
+
+	// The part uploaded later replaces the earlier one with the same number
+	idxMap := s.partIdxMap(multipart.Parts)
+	parts := make([]*Part, 0, len(idxMap))
+	for _, idx := range idxMap {
+		parts = append(parts, multipart.Parts[idx])
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+
+	// Collect the parts after the part number marker
+	for _, part := range parts {
+		if part.Number <= args.PartNumberMarker {
+			continue
+		}
+		if int64(len(list.Parts)) == args.MaxParts {
+			list.IsTruncated = true
+			break
+		}
+		list.Parts = append(list.Parts, part)
+		list.NextPartNumberMarker = part.Number
+	}
+
+	return
+}
+
 func (s *service) getMultipart(uplkey string) (multipart *Multipart, err error) {
 	err = s.providers.StateStore().Get(uplkey, &multipart)
 	if errors.Is(err, providers.ErrStateStoreNotFound) {
@@ -0,0 +1,320 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bittorrent/go-btfs/s3/hash"
+)
+
+// createUpload creates the multipart upload later than the previous one, so
+// the uploads of the same object are ordered by the time initiated
+func (ts *testService) createUpload(t *testing.T, bucket, name string) *Multipart {
+	time.Sleep(2 * time.Millisecond)
+	multipart, err := ts.CreateMultipartUpload(context.Background(), &CreateMultipartUploadArgs{
+		UserId: testUser,
+		Bucket: bucket,
+		Object: name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return multipart
+}
+
+func (ts *testService) uploadPart(t *testing.T, bucket, name, uplid string, number int64, body string) {
+	r, err := hash.NewReader(strings.NewReader(body), int64(len(body)), "", "", int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = ts.UploadPart(context.Background(), &UploadPartArgs{
+		UserId:        testUser,
+		Body:          r,
+		Bucket:        bucket,
+		Object:        name,
+		UploadId:      uplid,
+		PartNumber:    number,
+		ContentLength: int64(len(body)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (ts *testService) listUploads(t *testing.T, args *ListMultipartUploadsArgs) *MultipartUploadsList {
+	args.UserId = testUser
+	list, err := ts.ListMultipartUploads(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+// uploadNames returns "object/upload" of the listed uploads
+func uploadNames(list *MultipartUploadsList, ids map[string]string) []string {
+	var names []string
+	for _, upload := range list.Uploads {
+		names = append(names, upload.Object+"/"+ids[upload.UploadID])
+	}
+	return names
+}
+
+func TestListMultipartUploads(t *testing.T) {
+	const bucket = "uploads"
+
+	ts := newTestService(t, bucket)
+
+	// Short names of the upload ids
+	ids := make(map[string]string)
+	uploads := make(map[string]*Multipart)
+	for _, u := range []struct{ object, name string }{
+		{"a", "1"}, {"a", "2"}, {"a", "3"},
+		{"b", "1"},
+		{"dir/x", "1"}, {"dir/y", "1"},
+		{"dir2/z", "1"},
+		{"e", "1"},
+	} {
+		multipart := ts.createUpload(t, bucket, u.object)
+		ids[multipart.UploadID] = u.name
+		uploads[u.object+"/"+u.name] = multipart
+	}
+
+	t.Run("all", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{Bucket: bucket, MaxUploads: 1000})
+		expected := []string{"a/1", "a/2", "a/3", "b/1", "dir/x/1", "dir/y/1", "dir2/z/1", "e/1"}
+		if got := uploadNames(list, ids); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expect uploads %v, got %v", expected, got)
+		}
+		if list.IsTruncated {
+			t.Error("expect list not truncated")
+		}
+	})
+
+	t.Run("max uploads", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{Bucket: bucket, MaxUploads: 2})
+		expected := []string{"a/1", "a/2"}
+		if got := uploadNames(list, ids); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expect uploads %v, got %v", expected, got)
+		}
+		if !list.IsTruncated {
+			t.Error("expect list truncated")
+		}
+		if list.NextKeyMarker != "a" || list.NextUploadIDMarker != uploads["a/2"].UploadID {
+			t.Errorf("expect next markers a/2, got %s/%s", list.NextKeyMarker, ids[list.NextUploadIDMarker])
+		}
+
+		// Continue from the next markers
+		list = ts.listUploads(t, &ListMultipartUploadsArgs{
+			Bucket:         bucket,
+			MaxUploads:     2,
+			KeyMarker:      list.NextKeyMarker,
+			UploadIDMarker: list.NextUploadIDMarker,
+		})
+		expected = []string{"a/3", "b/1"}
+		if got := uploadNames(list, ids); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expect uploads %v, got %v", expected, got)
+		}
+		if !list.IsTruncated {
+			t.Error("expect list truncated")
+		}
+	})
+
+	t.Run("max uploads exactly", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{Bucket: bucket, MaxUploads: 8})
+		if len(list.Uploads) != 8 || list.IsTruncated {
+			t.Errorf("expect 8 uploads not truncated, got %d truncated %v", len(list.Uploads), list.IsTruncated)
+		}
+	})
+
+	t.Run("zero max uploads", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{Bucket: bucket, MaxUploads: 0})
+		if len(list.Uploads) != 0 || !list.IsTruncated {
+			t.Errorf("expect no uploads truncated, got %d truncated %v", len(list.Uploads), list.IsTruncated)
+		}
+	})
+
+	t.Run("key marker", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{Bucket: bucket, MaxUploads: 1000, KeyMarker: "a"})
+		expected := []string{"b/1", "dir/x/1", "dir/y/1", "dir2/z/1", "e/1"}
+		if got := uploadNames(list, ids); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expect uploads %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("upload id marker", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{
+			Bucket:         bucket,
+			MaxUploads:     1000,
+			KeyMarker:      "a",
+			UploadIDMarker: uploads["a/1"].UploadID,
+		})
+		expected := []string{"a/2", "a/3", "b/1", "dir/x/1", "dir/y/1", "dir2/z/1", "e/1"}
+		if got := uploadNames(list, ids); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expect uploads %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{Bucket: bucket, MaxUploads: 1000, Prefix: "dir/"})
+		expected := []string{"dir/x/1", "dir/y/1"}
+		if got := uploadNames(list, ids); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expect uploads %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("delimiter", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{Bucket: bucket, MaxUploads: 1000, Delimiter: "/"})
+		expected := []string{"a/1", "a/2", "a/3", "b/1", "e/1"}
+		if got := uploadNames(list, ids); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expect uploads %v, got %v", expected, got)
+		}
+		if prefixes := []string{"dir/", "dir2/"}; !reflect.DeepEqual(list.Prefixes, prefixes) {
+			t.Errorf("expect prefixes %v, got %v", prefixes, list.Prefixes)
+		}
+	})
+
+	t.Run("delimiter truncated at prefix", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{
+			Bucket:     bucket,
+			MaxUploads: 1,
+			Delimiter:  "/",
+			KeyMarker:  "b",
+		})
+		if !reflect.DeepEqual(list.Prefixes, []string{"dir/"}) || len(list.Uploads) != 0 {
+			t.Fatalf("expect prefix dir/ only, got prefixes %v uploads %v", list.Prefixes, uploadNames(list, ids))
+		}
+		if !list.IsTruncated || list.NextKeyMarker != "dir/" || list.NextUploadIDMarker != "" {
+			t.Fatalf("expect truncated at dir/, got truncated %v next %q/%q",
+				list.IsTruncated, list.NextKeyMarker, list.NextUploadIDMarker)
+		}
+
+		// The listed common prefix is skipped by the next page
+		list = ts.listUploads(t, &ListMultipartUploadsArgs{
+			Bucket:     bucket,
+			MaxUploads: 1000,
+			Delimiter:  "/",
+			KeyMarker:  list.NextKeyMarker,
+		})
+		if !reflect.DeepEqual(list.Prefixes, []string{"dir2/"}) {
+			t.Errorf("expect prefixes [dir2/], got %v", list.Prefixes)
+		}
+		if expected := []string{"e/1"}; !reflect.DeepEqual(uploadNames(list, ids), expected) {
+			t.Errorf("expect uploads %v, got %v", expected, uploadNames(list, ids))
+		}
+	})
+
+	t.Run("prefix and delimiter", func(t *testing.T) {
+		list := ts.listUploads(t, &ListMultipartUploadsArgs{
+			Bucket:     bucket,
+			MaxUploads: 1000,
+			Prefix:     "d",
+			Delimiter:  "/",
+		})
+		if len(list.Uploads) != 0 {
+			t.Errorf("expect no uploads, got %v", uploadNames(list, ids))
+		}
+		if prefixes := []string{"dir/", "dir2/"}; !reflect.DeepEqual(list.Prefixes, prefixes) {
+			t.Errorf("expect prefixes %v, got %v", prefixes, list.Prefixes)
+		}
+	})
+}
+
+func TestListParts(t *testing.T) {
+	const bucket = "parts"
+
+	ts := newTestService(t, bucket)
+	multipart := ts.createUpload(t, bucket, "obj")
+	for _, number := range []int64{3, 1, 5, 2, 4} {
+		ts.uploadPart(t, bucket, "obj", multipart.UploadID, number, strings.Repeat("p", int(number)))
+	}
+
+	// The part uploaded again replaces the earlier one
+	ts.uploadPart(t, bucket, "obj", multipart.UploadID, 2, "replaced")
+
+	listParts := func(max, marker int64) *PartsList {
+		list, err := ts.ListParts(context.Background(), &ListPartsArgs{
+			UserId:           testUser,
+			Bucket:           bucket,
+			Object:           "obj",
+			UploadId:         multipart.UploadID,
+			MaxParts:         max,
+			PartNumberMarker: marker,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+	numbers := func(list *PartsList) []int64 {
+		var nums []int64
+		for _, part := range list.Parts {
+			nums = append(nums, part.Number)
+		}
+		return nums
+	}
+
+	t.Run("all", func(t *testing.T) {
+		list := listParts(1000, 0)
+		if expected := []int64{1, 2, 3, 4, 5}; !reflect.DeepEqual(numbers(list), expected) {
+			t.Fatalf("expect parts %v, got %v", expected, numbers(list))
+		}
+		if list.Parts[1].Size != int64(len("replaced")) {
+			t.Errorf("expect part 2 replaced, got size %d", list.Parts[1].Size)
+		}
+		if list.IsTruncated {
+			t.Error("expect list not truncated")
+		}
+	})
+
+	t.Run("max parts", func(t *testing.T) {
+		list := listParts(2, 0)
+		if expected := []int64{1, 2}; !reflect.DeepEqual(numbers(list), expected) {
+			t.Errorf("expect parts %v, got %v", expected, numbers(list))
+		}
+		if !list.IsTruncated || list.NextPartNumberMarker != 2 {
+			t.Errorf("expect truncated at 2, got truncated %v next %d", list.IsTruncated, list.NextPartNumberMarker)
+		}
+
+		list = listParts(2, list.NextPartNumberMarker)
+		if expected := []int64{3, 4}; !reflect.DeepEqual(numbers(list), expected) {
+			t.Errorf("expect parts %v, got %v", expected, numbers(list))
+		}
+
+		list = listParts(2, list.NextPartNumberMarker)
+		if expected := []int64{5}; !reflect.DeepEqual(numbers(list), expected) {
+			t.Errorf("expect parts %v, got %v", expected, numbers(list))
+		}
+		if list.IsTruncated {
+			t.Error("expect last page not truncated")
+		}
+	})
+
+	t.Run("part number marker", func(t *testing.T) {
+		list := listParts(1000, 3)
+		if expected := []int64{4, 5}; !reflect.DeepEqual(numbers(list), expected) {
+			t.Errorf("expect parts %v, got %v", expected, numbers(list))
+		}
+
+		list = listParts(1000, 5)
+		if len(list.Parts) != 0 || list.IsTruncated {
+			t.Errorf("expect no parts, got %v", numbers(list))
+		}
+	})
+
+	t.Run("upload not found", func(t *testing.T) {
+		_, err := ts.ListParts(context.Background(), &ListPartsArgs{
+			UserId:   testUser,
+			Bucket:   bucket,
+			Object:   "obj",
+			UploadId: "notfound",
+			MaxParts: 1000,
+		})
+		if !errors.Is(err, ErrUploadNotFound) {
+			t.Errorf("expect %v, got %v", ErrUploadNotFound, err)
+		}
+	})
+}
@@ -2,9 +2,12 @@ package object
 
 import (
 	"context"
+	"crypto/md5"
 	"errors"
 	"github.com/bittorrent/go-btfs/s3/action"
 	"github.com/bittorrent/go-btfs/s3/api/providers"
+	"github.com/bittorrent/go-btfs/s3/etag"
+	"github.com/bittorrent/go-btfs/s3/sse"
 	"github.com/bittorrent/go-btfs/s3/utils"
 	"io"
 	"strings"
@@ -38,7 +41,7 @@ func (s *service) PutObject(ctx context.Context, args *PutObjectArgs) (object *O
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.PutObjectAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.PutObjectAction, args.Object)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -60,8 +63,25 @@ func (s *service) PutObject(ctx context.Context, args *PutObjectArgs) (object *O
 		return
 	}
 
-	// Store object body
-	cid, err := s.storeBody(ctx, args.Body, objkey)
+	// Object body, imported from the source cid or stored from the request
+	var (
+		cid  string
+		size int64
+		etg  string
+		enc  *Encryption
+	)
+	if args.SourceCID != "" {
+		cid = args.SourceCID
+		size, err = s.importBody(ctx, cid, objkey)
+		// the content md5 is unknown without reading the whole file, so an
+		// opaque etag in multipart style is used, clients do not verify it
+		sum := md5.Sum([]byte(cid))
+		etg = etag.Multipart(etag.ETag(sum[:])).String()
+	} else {
+		size = args.ContentLength
+		etg = args.Body.ETag().String()
+		cid, enc, err = s.putBody(ctx, args, objkey)
+	}
 	if err != nil {
 		return
 	}
@@ -85,9 +105,9 @@ func (s *service) PutObject(ctx context.Context, args *PutObjectArgs) (object *O
 		Bucket:           args.Bucket,
 		Name:             args.Object,
 		ModTime:          now,
-		Size:             args.ContentLength,
+		Size:             size,
 		IsDir:            false,
-		ETag:             args.Body.ETag().String(),
+		ETag:             etg,
 		CID:              cid,
 		VersionID:        "",
 		IsLatest:         true,
@@ -95,10 +115,26 @@ func (s *service) PutObject(ctx context.Context, args *PutObjectArgs) (object *O
 		ACL:              "",
 		ContentType:      args.ContentType,
 		ContentEncoding:  args.ContentEncoding,
+		Metadata:         args.Metadata,
+		Tags:             args.Tags,
+		Encryption:       enc,
+		Storage:          s.newObjectStorage(bucket, now),
 		SuccessorModTime: now,
 		Expires:          args.Expires,
 	}
 
+	// Index the queued storage of the object
+	err = s.putStorageIndex(object)
+	if err != nil {
+		return
+	}
+
+	// Put object version
+	err = s.putObjectVersion(ctx, bucket, object, objectOld)
+	if err != nil {
+		return
+	}
+
 	// put object
 	err = s.putObject(objkey, object)
 	if err != nil {
@@ -108,14 +144,40 @@ func (s *service) PutObject(ctx context.Context, args *PutObjectArgs) (object *O
 	// Set remove object body flag to false, because it has been referenced by the object
 	removeObjectBody = false
 
-	// Try to remove old object body if exists, because it has been covered by new one
-	if objectOld != nil {
+	// Try to remove old object body if exists, because it has been covered by new one,
+	// the body is still referenced by the old version if bucket versioning enabled
+	if objectOld != nil && objectOld.CID != object.CID {
 		_ = s.removeBody(ctx, objectOld.CID, objkey)
 	}
 
 	return
 }
 
+// putBody store the request body encrypted as one segment if requested
+func (s *service) putBody(ctx context.Context, args *PutObjectArgs, objkey string) (cid string, enc *Encryption, err error) {
+	// Object encryption
+	enc, key, err := s.newEncryption(ctx, args.Bucket, args.SSE)
+	if err != nil {
+		return
+	}
+
+	// Encrypt object body as one segment
+	var body io.Reader = args.Body
+	if enc != nil {
+		var iv []byte
+		body, iv, err = s.encryptBody(key, body)
+		if err != nil {
+			return
+		}
+		enc.Segments = []*sse.Segment{{Size: args.ContentLength, IV: iv}}
+	}
+
+	// Store object body
+	cid, err = s.storeBody(ctx, body, objkey)
+
+	return
+}
+
 // CopyObject copy from a user specified source object to a desert object
 func (s *service) CopyObject(ctx context.Context, args *CopyObjectArgs) (dstObject *Object, err error) {
 	// Operation context
@@ -143,7 +205,7 @@ func (s *service) CopyObject(ctx context.Context, args *CopyObjectArgs) (dstObje
 	}
 
 	// Check source action ACL
-	srcAllow := s.checkACL(srcBucket.Owner, srcBucket.ACL, args.UserId, action.GetObjectAction)
+	srcAllow := s.checkAccess(ctx, srcBucket, args.UserId, action.GetObjectAction, args.SrcObject)
 	if !srcAllow {
 		err = ErrNotAllowed
 		return
@@ -160,14 +222,22 @@ func (s *service) CopyObject(ctx context.Context, args *CopyObjectArgs) (dstObje
 	defer s.lock.RUnlock(srcObjkey)
 
 	// Get source object
-	srcObject, err := s.getObject(srcObjkey)
+	srcObject, err := s.getObjectOfVersion(args.SrcBucket, args.SrcObject, args.SrcVersionID)
 	if err != nil {
 		return
 	}
+	if srcObject == nil && args.SrcVersionID != "" {
+		err = ErrVersionNotFound
+		return
+	}
 	if srcObject == nil {
 		err = ErrObjectNotFound
 		return
 	}
+	if srcObject.DeleteMarker {
+		err = ErrVersionIsMarker
+		return
+	}
 
 	// Desert bucket key
 	dstBuckey := s.getBucketKey(args.Bucket)
@@ -190,7 +260,7 @@ func (s *service) CopyObject(ctx context.Context, args *CopyObjectArgs) (dstObje
 	}
 
 	// Check destination action ACL
-	dstAllow := s.checkACL(dstBucket.Owner, dstBucket.ACL, args.UserId, action.PutObjectAction)
+	dstAllow := s.checkAccess(ctx, dstBucket, args.UserId, action.PutObjectAction, args.Object)
 	if !dstAllow {
 		err = ErrNotAllowed
 		return
@@ -206,19 +276,27 @@ func (s *service) CopyObject(ctx context.Context, args *CopyObjectArgs) (dstObje
 	}
 	defer s.lock.Unlock(dstObjkey)
 
-	// Add body Refer
-	err = s.addBodyRef(ctx, srcObject.CID, dstObjkey)
+	// Source object key, the source customer key must be provided if
+	// the source object is encrypted with SSE-C
+	srcKey, err := s.checkEncryption(ctx, args.SrcBucket, srcObject.Encryption, args.SrcSSE)
 	if err != nil {
 		return
 	}
 
-	// Mark if delete the cid ref
-	deleteRef := true
+	// Copy body
+	cid, enc, err := s.copyBody(ctx, srcObject, srcKey, args.Bucket, args.SSE, dstObjkey)
+	if err != nil {
+		return
+	}
 
-	// If put new object failed, try to delete its reference
+	// Mark if delete the body
+	deleteBody := true
+
+	// If put new object failed, try to delete its reference, the shared
+	// source body will not be removed
 	defer func() {
-		if deleteRef {
-			_ = s.removeBodyRef(ctx, srcObject.CID, dstObjkey)
+		if deleteBody {
+			_ = s.removeBody(ctx, cid, dstObjkey)
 		}
 	}()
 
@@ -239,12 +317,16 @@ func (s *service) CopyObject(ctx context.Context, args *CopyObjectArgs) (dstObje
 		Size:             srcObject.Size,
 		IsDir:            false,
 		ETag:             srcObject.ETag,
-		CID:              srcObject.CID,
+		CID:              cid,
 		VersionID:        "",
 		IsLatest:         true,
 		DeleteMarker:     false,
 		ContentType:      srcObject.ContentType,
 		ContentEncoding:  srcObject.ContentEncoding,
+		Metadata:         srcObject.Metadata,
+		Tags:             srcObject.Tags,
+		Encryption:       enc,
+		Storage:          s.newObjectStorage(dstBucket, now),
 		SuccessorModTime: now,
 		Expires:          args.Expires,
 	}
@@ -253,6 +335,24 @@ func (s *service) CopyObject(ctx context.Context, args *CopyObjectArgs) (dstObje
 	if args.ReplaceMeta {
 		dstObject.ContentType = args.ContentType
 		dstObject.ContentEncoding = args.ContentEncoding
+		dstObject.Metadata = args.Metadata
+	}
+
+	// Replace tags
+	if args.ReplaceTags {
+		dstObject.Tags = args.Tags
+	}
+
+	// Index the queued storage of the destination object
+	err = s.putStorageIndex(dstObject)
+	if err != nil {
+		return
+	}
+
+	// Put destination object version
+	err = s.putObjectVersion(ctx, dstBucket, dstObject, oldDstObject)
+	if err != nil {
+		return
 	}
 
 	// Put destination object
@@ -261,11 +361,11 @@ func (s *service) CopyObject(ctx context.Context, args *CopyObjectArgs) (dstObje
 		return
 	}
 
-	// Mark the delete ref to false
-	deleteRef = false
+	// Mark the delete body to false
+	deleteBody = false
 
 	// Try to remove the old object body
-	if oldDstObject != nil {
+	if oldDstObject != nil && oldDstObject.CID != dstObject.CID {
 		_ = s.removeBody(ctx, oldDstObject.CID, dstObjkey)
 	}
 
@@ -308,7 +408,7 @@ func (s *service) GetObject(ctx context.Context, args *GetObjectArgs) (object *O
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.GetObjectAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.GetObjectAction, args.Object)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -329,26 +429,70 @@ func (s *service) GetObject(ctx context.Context, args *GetObjectArgs) (object *O
 	}()
 
 	// Get object
-	object, err = s.getObject(objkey)
+	object, err = s.getObjectOfVersion(args.Bucket, args.Object, args.VersionID)
 	if err != nil {
 		return
 	}
+	if object == nil && args.VersionID != "" {
+		err = ErrVersionNotFound
+		return
+	}
 	if object == nil {
 		err = ErrObjectNotFound
 		return
 	}
+	if object.DeleteMarker {
+		err = ErrVersionIsMarker
+		return
+	}
+
+	// Check conditions
+	if args.Conditions != nil {
+		err = args.Conditions.Check(object)
+		if err != nil {
+			return
+		}
+	}
+
+	// Object key, the customer key must be provided if the object is
+	// encrypted with SSE-C, even no body needed
+	key, err := s.checkEncryption(ctx, args.Bucket, object.Encryption, args.SSE)
+	if err != nil {
+		return
+	}
+
+	// Resolve range
+	var offset, length int64
+	if args.Range != nil {
+		offset, length, err = args.Range.Resolve(object.Size)
+		if err != nil {
+			return
+		}
+	}
 
 	// no need body
 	if !args.WithBody {
 		return
 	}
 
-	// Get object body
-	body, err = s.providers.FileStore().Cat(object.CID)
+	// Get object body, only the range part if specified
+	if args.Range != nil {
+		body, err = s.providers.FileStore().CatRange(object.CID, offset, length)
+	} else {
+		body, err = s.providers.FileStore().Cat(object.CID)
+	}
 	if err != nil {
 		return
 	}
 
+	// Decrypt the object body from the offset
+	if object.Encryption != nil {
+		body, err = s.decryptBody(key, object.Encryption, offset, body)
+		if err != nil {
+			return
+		}
+	}
+
 	// Set unlock-later flag to true to enable the bucket and object
 	// will not be unlocked before completely written the response body
 	unlockLater = true
@@ -365,8 +509,9 @@ func (s *service) GetObject(ctx context.Context, args *GetObjectArgs) (object *O
 	return
 }
 
-// DeleteObject delete a user specified object
-func (s *service) DeleteObject(ctx context.Context, args *DeleteObjectArgs) (err error) {
+// DeleteObject delete a user specified object or object version, the deleted
+// version or the added delete marker will be returned
+func (s *service) DeleteObject(ctx context.Context, args *DeleteObjectArgs) (object *Object, err error) {
 	// Operation context
 	ctx, cancel := s.opctx(ctx)
 	defer cancel()
@@ -392,7 +537,7 @@ func (s *service) DeleteObject(ctx context.Context, args *DeleteObjectArgs) (err
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.DeleteObjectAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.DeleteObjectAction, args.Object)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -408,24 +553,8 @@ func (s *service) DeleteObject(ctx context.Context, args *DeleteObjectArgs) (err
 	}
 	defer s.lock.Unlock(objkey)
 
-	// Get object
-	object, err := s.getObject(objkey)
-	if err != nil {
-		return
-	}
-	if object == nil {
-		err = ErrObjectNotFound
-		return
-	}
-
 	// Delete object
-	err = s.deleteObject(objkey)
-	if err != nil {
-		return
-	}
-
-	// Try to delete object body
-	_ = s.removeBody(ctx, object.CID, objkey)
+	object, err = s.deleteObjectOfVersion(ctx, bucket, args.Object, args.VersionID)
 
 	return
 }
@@ -456,23 +585,16 @@ func (s *service) DeleteObjects(ctx context.Context, args *DeleteObjectsArgs) (d
 		return
 	}
 
-	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.DeleteObjectAction)
-	if !allow {
-		err = ErrNotAllowed
-		return
-	}
-
 	for _, deleteObj := range args.ToDeleteObjects {
 		func(deleteObj *ToDeleteObject) {
-			var er error
+			var (
+				er      error
+				deleted *Object
+			)
 			// Collection delete result
 			defer func() {
 				if er != nil || !args.Quite {
-					deletes = append(deletes, &DeletedObject{
-						Object:    deleteObj.Object,
-						DeleteErr: er,
-					})
+					deletes = append(deletes, s.toDeletedObject(deleteObj, deleted, er))
 				}
 			}()
 
@@ -482,6 +604,13 @@ func (s *service) DeleteObjects(ctx context.Context, args *DeleteObjectsArgs) (d
 				return
 			}
 
+			// Check action ACL, the bucket policy may allow or deny part of the objects
+			allow := s.checkAccess(ctx, bucket, args.UserId, action.DeleteObjectAction, deleteObj.Object)
+			if !allow {
+				er = ErrNotAllowed
+				return
+			}
+
 			// Object key
 			objkey := s.getObjectKey(args.Bucket, deleteObj.Object)
 
@@ -492,24 +621,8 @@ func (s *service) DeleteObjects(ctx context.Context, args *DeleteObjectsArgs) (d
 			}
 			defer s.lock.Unlock(objkey)
 
-			// Get object
-			object, er := s.getObject(objkey)
-			if er != nil {
-				return
-			}
-			if object == nil {
-				er = ErrObjectNotFound
-				return
-			}
-
 			// Delete object
-			er = s.deleteObject(objkey)
-			if er != nil {
-				return
-			}
-
-			// Try to delete object body
-			_ = s.removeBody(ctx, object.CID, objkey)
+			deleted, er = s.deleteObjectOfVersion(ctx, bucket, deleteObj.Object, deleteObj.VersionID)
 
 		}(deleteObj)
 	}
@@ -517,6 +630,23 @@ func (s *service) DeleteObjects(ctx context.Context, args *DeleteObjectsArgs) (d
 	return
 }
 
+// toDeletedObject convert the delete result to deleted object
+func (s *service) toDeletedObject(deleteObj *ToDeleteObject, deleted *Object, err error) (deletedObj *DeletedObject) {
+	deletedObj = &DeletedObject{
+		Object:    deleteObj.Object,
+		VersionID: deleteObj.VersionID,
+		DeleteErr: err,
+	}
+	if deleted == nil || !deleted.DeleteMarker {
+		return
+	}
+	deletedObj.DeleteMarker = true
+	if deleteObj.VersionID == "" {
+		deletedObj.DeleteMarkerVersionID = deleted.VersionID
+	}
+	return
+}
+
 // ListObjects list user specified objects
 func (s *service) ListObjects(ctx context.Context, args *ListObjectsArgs) (list *ObjectsList, err error) {
 	// Operation context
@@ -549,7 +679,7 @@ func (s *service) ListObjects(ctx context.Context, args *ListObjectsArgs) (list
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.ListObjectsAction)
+	allow := s.checkListAccess(ctx, bucket, args.UserId, action.ListObjectsAction, args.Prefix)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -733,7 +863,7 @@ func (s *service) GetObjectACL(ctx context.Context, args *GetObjectACLArgs) (acl
 	}
 
 	// Check action ACL
-	allow := s.checkACL(bucket.Owner, bucket.ACL, args.UserId, action.GetBucketAclAction)
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.GetBucketAclAction, args.Object)
 	if !allow {
 		err = ErrNotAllowed
 		return
@@ -0,0 +1,141 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// failingStateStore fails putting the keys with the prefix
+type failingStateStore struct {
+	*memStateStore
+	failPrefix string
+}
+
+func (f *failingStateStore) Put(key string, i interface{}) error {
+	if f.failPrefix != "" && strings.HasPrefix(key, f.failPrefix) {
+		return errors.New("put failed")
+	}
+	return f.memStateStore.Put(key, i)
+}
+
+func (ts *testService) importCID(bucket, name, cid string) (*Object, error) {
+	return ts.PutObject(context.Background(), &PutObjectArgs{
+		UserId:    testUser,
+		Bucket:    bucket,
+		Object:    name,
+		SourceCID: cid,
+	})
+}
+
+func (ts *testService) remove(t *testing.T, bucket, name, verid string) *Object {
+	obj, err := ts.DeleteObject(context.Background(), &DeleteObjectArgs{
+		UserId:    testUser,
+		Bucket:    bucket,
+		Object:    name,
+		VersionID: verid,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestImportObject(t *testing.T) {
+	const bucket = "import"
+
+	t.Run("unpinned cid", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		cid := ts.files.add([]byte("unpinned"))
+
+		obj, err := ts.importCID(bucket, "obj", cid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if obj.Size != int64(len("unpinned")) {
+			t.Errorf("expect size %d, got %d", len("unpinned"), obj.Size)
+		}
+		if !ts.files.isPinned(cid) {
+			t.Fatal("expect imported cid pinned")
+		}
+
+		ts.remove(t, bucket, "obj", "")
+		if ts.files.isPinned(cid) {
+			t.Error("expect cid pinned by the gateway unpinned after delete")
+		}
+	})
+
+	t.Run("cid pinned by the user", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		cid := ts.files.add([]byte("pinned"))
+		if err := ts.files.Pin(cid); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.importCID(bucket, "obj", cid); err != nil {
+			t.Fatal(err)
+		}
+		ts.remove(t, bucket, "obj", "")
+		if !ts.files.isPinned(cid) {
+			t.Error("expect the pin of the user kept after delete")
+		}
+		var imported bool
+		if err := ts.states.Get(ts.getImportedCidKey(cid), &imported); err == nil {
+			t.Error("expect imported mark removed after delete")
+		}
+	})
+
+	t.Run("overwrite cid pinned by the user", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		cid := ts.files.add([]byte("pinned"))
+		if err := ts.files.Pin(cid); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ts.importCID(bucket, "obj", cid); err != nil {
+			t.Fatal(err)
+		}
+		ts.put(t, bucket, "obj", "new body")
+		if !ts.files.isPinned(cid) {
+			t.Error("expect the pin of the user kept after overwrite")
+		}
+	})
+
+	t.Run("rollback pin", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		cid := ts.files.add([]byte("unpinned"))
+		ts.providers = &testProviders{
+			states: &failingStateStore{memStateStore: ts.states, failPrefix: ts.getAllCidrefsKeyPrefix(cid)},
+			files:  ts.files,
+		}
+
+		if _, err := ts.importCID(bucket, "obj", cid); err == nil {
+			t.Fatal("expect import failed")
+		}
+		if ts.files.isPinned(cid) {
+			t.Error("expect pin rolled back")
+		}
+	})
+
+	t.Run("etag", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		cid := ts.files.add([]byte("etag"))
+
+		obj, err := ts.importCID(bucket, "obj", cid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(obj.ETag, "-1") {
+			t.Errorf("expect multipart style etag, got %s", obj.ETag)
+		}
+	})
+
+	t.Run("cid not found", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		_, err := ts.importCID(bucket, "obj", "bafynotfound")
+		if !errors.Is(err, ErrSourceCidNotFound) {
+			t.Errorf("expect %v, got %v", ErrSourceCidNotFound, err)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package object
+
+import (
+	"context"
+
+	"github.com/bittorrent/go-btfs/s3/action"
+	"github.com/bittorrent/go-btfs/s3/policy"
+)
+
+// PutBucketPolicy replace user specified bucket's policy
+func (s *service) PutBucketPolicy(ctx context.Context, args *PutBucketPolicyArgs) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// Lock bucket
+	err = s.lock.Lock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.PutBucketPolicyAction, "")
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Update bucket policy
+	bucket.Policy = args.Policy
+
+	// Put bucket
+	err = s.providers.StateStore().Put(buckey, bucket)
+
+	return
+}
+
+// GetBucketPolicy get user specified bucket's policy
+func (s *service) GetBucketPolicy(ctx context.Context, args *GetBucketPolicyArgs) (p *policy.Policy, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.GetBucketPolicyAction, "")
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// No policy
+	if bucket.Policy == nil {
+		err = ErrPolicyNotFound
+		return
+	}
+
+	// Policy
+	p = bucket.Policy
+
+	return
+}
+
+// DeleteBucketPolicy remove user specified bucket's policy
+func (s *service) DeleteBucketPolicy(ctx context.Context, args *DeleteBucketPolicyArgs) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// Lock bucket
+	err = s.lock.Lock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.DeleteBucketPolicyAction, "")
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Clear bucket policy
+	bucket.Policy = nil
+
+	// Put bucket
+	err = s.providers.StateStore().Put(buckey, bucket)
+
+	return
+}
@@ -0,0 +1,240 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/bittorrent/go-btfs/s3/api/providers"
+	"github.com/bittorrent/go-btfs/s3/sse"
+)
+
+// newEncryption create the encryption of a new object or multipart upload with
+// a random object key sealed as requested, both the encryption and the key are
+// nil if no encryption requested
+func (s *service) newEncryption(ctx context.Context, bucname string, req *ServerSideEncryption) (enc *Encryption, key []byte, err error) {
+	if req == nil || req.Type == "" {
+		return
+	}
+
+	// Object key
+	key, err = sse.GenerateKey()
+	if err != nil {
+		return
+	}
+
+	// Seal the object key
+	enc = &Encryption{
+		Type: req.Type,
+	}
+	err = s.sealObjectKey(ctx, bucname, enc, req, key)
+	if err != nil {
+		enc, key = nil, nil
+	}
+
+	return
+}
+
+// sealObjectKey seal the object key into the encryption with the key encryption
+// key of the request
+func (s *service) sealObjectKey(ctx context.Context, bucname string, enc *Encryption, req *ServerSideEncryption, key []byte) (err error) {
+	var kek []byte
+	switch req.Type {
+	case sse.TypeSSEC:
+		kek = req.CustomerKey
+		enc.KeyMD5 = req.CustomerKeyMD5
+	case sse.TypeSSES3:
+		kek, err = s.getDataKey(ctx, bucname)
+		if err != nil {
+			return
+		}
+	default:
+		err = ErrSSEUnsupported
+		return
+	}
+	enc.SealedKey, err = sse.Seal(kek, key)
+	return
+}
+
+// unsealObjectKey get the object key of the encryption, the customer key must be
+// provided by the request for SSE-C
+func (s *service) unsealObjectKey(ctx context.Context, bucname string, enc *Encryption, req *ServerSideEncryption) (key []byte, err error) {
+	var kek []byte
+	switch enc.Type {
+	case sse.TypeSSEC:
+		if req == nil || req.Type != sse.TypeSSEC {
+			err = ErrSSEKeyMissing
+			return
+		}
+		if req.CustomerKeyMD5 != enc.KeyMD5 {
+			err = ErrSSEKeyMismatch
+			return
+		}
+		kek = req.CustomerKey
+	case sse.TypeSSES3:
+		kek, err = s.getDataKey(ctx, bucname)
+		if err != nil {
+			return
+		}
+	default:
+		err = ErrSSEUnsupported
+		return
+	}
+	key, err = sse.Unseal(kek, enc.SealedKey)
+	if err != nil {
+		err = ErrSSEKeyMismatch
+	}
+	return
+}
+
+// checkEncryption check the request encryption with the object encryption, and
+// return the object key if the object is encrypted
+func (s *service) checkEncryption(ctx context.Context, bucname string, enc *Encryption, req *ServerSideEncryption) (key []byte, err error) {
+	if enc == nil {
+		if req != nil && req.Type == sse.TypeSSEC {
+			err = ErrSSENotEncrypted
+		}
+		return
+	}
+	key, err = s.unsealObjectKey(ctx, bucname, enc, req)
+	return
+}
+
+// encryptBody wrap the body with the encryption reader as a new segment
+func (s *service) encryptBody(key []byte, body io.Reader) (encrypted io.Reader, iv []byte, err error) {
+	iv, err = sse.GenerateIV()
+	if err != nil {
+		return
+	}
+	encrypted, err = sse.EncryptReader(key, iv, body)
+	return
+}
+
+// decryptBody wrap the body read from offset with the decryption reader, the
+// body will be closed if failed
+func (s *service) decryptBody(key []byte, enc *Encryption, offset int64, body io.ReadCloser) (decrypted io.ReadCloser, err error) {
+	rdr, err := sse.DecryptReader(key, enc.Segments, offset, body)
+	if err != nil {
+		_ = body.Close()
+		return
+	}
+	decrypted = struct {
+		io.Reader
+		io.Closer
+	}{rdr, body}
+	return
+}
+
+// copyBody reference the source object body for the destination object, the
+// encrypted body is shared by resealing the object key with the destination
+// encryption, the body will be transformed and stored again if the destination
+// encryption is different from the source one
+func (s *service) copyBody(ctx context.Context, srcObject *Object, srcKey []byte, dstBucname string, req *ServerSideEncryption,
+	dstObjkey string) (cid string, enc *Encryption, err error) {
+	srcEncrypted := srcObject.Encryption != nil
+	dstEncrypted := req != nil && req.Type != ""
+
+	// Share the plaintext body
+	if !srcEncrypted && !dstEncrypted {
+		cid = srcObject.CID
+		err = s.addBodyRef(ctx, cid, dstObjkey)
+		return
+	}
+
+	// Share the encrypted body with resealed object key
+	if srcEncrypted && dstEncrypted {
+		enc = &Encryption{
+			Type:     req.Type,
+			Segments: srcObject.Encryption.Segments,
+		}
+		err = s.sealObjectKey(ctx, dstBucname, enc, req, srcKey)
+		if err != nil {
+			return
+		}
+		cid = srcObject.CID
+		err = s.addBodyRef(ctx, cid, dstObjkey)
+		return
+	}
+
+	// Source body
+	srcBody, err := s.providers.FileStore().Cat(srcObject.CID)
+	if err != nil {
+		return
+	}
+	defer srcBody.Close()
+
+	// Decrypt source body
+	var body io.Reader = srcBody
+	if srcEncrypted {
+		body, err = sse.DecryptReader(srcKey, srcObject.Encryption.Segments, 0, body)
+		if err != nil {
+			return
+		}
+	}
+
+	// Encrypt destination body
+	enc, key, err := s.newEncryption(ctx, dstBucname, req)
+	if err != nil {
+		return
+	}
+	if enc != nil {
+		var iv []byte
+		body, iv, err = s.encryptBody(key, body)
+		if err != nil {
+			return
+		}
+		enc.Segments = []*sse.Segment{{Size: srcObject.Size, IV: iv}}
+	}
+
+	// Store destination body
+	cid, err = s.storeBody(ctx, body, dstObjkey)
+
+	return
+}
+
+// getDataKey get the data key of the bucket unwrapped by the key store, the data
+// key will be generated at the first use
+func (s *service) getDataKey(ctx context.Context, bucname string) (key []byte, err error) {
+	// Key store
+	ks := s.providers.KeyStore()
+	if ks == nil {
+		err = ErrSSEUnsupported
+		return
+	}
+
+	// Data key key
+	dkykey := s.getDataKeyKey(bucname)
+
+	// Lock data key
+	err = s.lock.Lock(ctx, dkykey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(dkykey)
+
+	// Get wrapped data key
+	var wrapped []byte
+	err = s.providers.StateStore().Get(dkykey, &wrapped)
+	if err == nil {
+		key, err = ks.Unwrap(wrapped)
+		return
+	}
+	if !errors.Is(err, providers.ErrStateStoreNotFound) {
+		return
+	}
+
+	// Generate a new data key
+	key, err = sse.GenerateKey()
+	if err != nil {
+		return
+	}
+	wrapped, err = ks.Wrap(key)
+	if err != nil {
+		return
+	}
+
+	// Put wrapped data key
+	err = s.providers.StateStore().Put(dkykey, wrapped)
+
+	return
+}
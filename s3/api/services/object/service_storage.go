@@ -0,0 +1,387 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bittorrent/go-btfs/s3/api/providers"
+)
+
+// Statuses of the storage upload session of the node
+const (
+	uploadSessionComplete = "complete"
+	uploadSessionError    = "error"
+)
+
+// PutBucketStorage set the storage policy of the bucket, the new objects of
+// the bucket will be pushed into the decentralized storage
+func (s *service) PutBucketStorage(ctx context.Context, bucname string, storage *StoragePolicy) (err error) {
+	err = s.updateBucketStorage(ctx, bucname, storage)
+	return
+}
+
+// GetBucketStorage get the storage policy of the bucket
+func (s *service) GetBucketStorage(ctx context.Context, bucname string) (storage *StoragePolicy, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(bucname)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+	if bucket.Storage == nil {
+		err = ErrStorageNotFound
+		return
+	}
+
+	storage = bucket.Storage
+
+	return
+}
+
+// DeleteBucketStorage remove the storage policy of the bucket, the objects
+// already queued will not be pushed any more
+func (s *service) DeleteBucketStorage(ctx context.Context, bucname string) (err error) {
+	err = s.updateBucketStorage(ctx, bucname, nil)
+	return
+}
+
+// GetObjectStorage get the object version with its storage state
+func (s *service) GetObjectStorage(ctx context.Context, bucname, objname, verid string) (object *Object, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(bucname)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Object key
+	objkey := s.getObjectKey(bucname, objname)
+
+	// RLock object
+	err = s.lock.RLock(ctx, objkey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(objkey)
+
+	// Get object
+	object, err = s.getTaggingObject(bucname, objname, verid)
+
+	return
+}
+
+func (s *service) updateBucketStorage(ctx context.Context, bucname string, storage *StoragePolicy) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(bucname)
+
+	// Lock bucket
+	err = s.lock.Lock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Update bucket storage policy
+	bucket.Storage = storage
+
+	// Put bucket
+	err = s.providers.StateStore().Put(buckey, bucket)
+
+	return
+}
+
+// newObjectStorage create the queued storage state of the new object if the
+// bucket has storage policy
+func (s *service) newObjectStorage(bucket *Bucket, now time.Time) (storage *ObjectStorage) {
+	if bucket.Storage == nil {
+		return
+	}
+	storage = &ObjectStorage{
+		Status:  StorageStatusQueued,
+		Updated: now,
+	}
+	return
+}
+
+// syncStorages push the queued objects of all buckets with storage policy and
+// refresh the states of the uploading ones, only the indexed objects are synced
+func (s *service) syncStorages(ctx context.Context) (err error) {
+	// Storage uploader
+	uploader := s.providers.StorageUploader()
+	if uploader == nil {
+		err = providers.ErrUploaderNotSet
+		return
+	}
+
+	// Collect indexed objects
+	type indexed struct {
+		bucname string
+		objname string
+	}
+	var objects []*indexed
+	prefix := s.getAllStorageIndexesKeyPrefix()
+	err = s.providers.StateStore().Iterate(prefix, func(key, _ []byte) (stop bool, er error) {
+		bucname, objname, ok := strings.Cut(strings.TrimPrefix(string(key), prefix), s.keySeparator)
+		if ok {
+			objects = append(objects, &indexed{bucname: bucname, objname: objname})
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	// Sync object by object, the failure of one object will not stop
+	// syncing others
+	buckets := make(map[string]*Bucket)
+	for _, idx := range objects {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			return
+		}
+		bucket, ok := buckets[idx.bucname]
+		if !ok {
+			bucket, err = s.getBucket(s.getBucketKey(idx.bucname))
+			if err != nil {
+				return
+			}
+			buckets[idx.bucname] = bucket
+		}
+		er := s.syncIndexedStorage(ctx, uploader, bucket, idx.bucname, idx.objname)
+		if er != nil {
+			fmt.Printf("s3-api: storage <%s/%s>, err: %v\n", idx.bucname, idx.objname, er)
+		}
+	}
+
+	return
+}
+
+// syncIndexedStorage push or refresh the indexed object, the index is removed
+// if the object storage has been finished or the object has gone, the objects
+// of the bucket which storage policy has been removed are kept queued
+func (s *service) syncIndexedStorage(ctx context.Context, uploader providers.StorageUploader, bucket *Bucket,
+	bucname, objname string) (err error) {
+	object, err := s.getObject(s.getObjectKey(bucname, objname))
+	if err != nil {
+		return
+	}
+	if !isStoragePending(object) {
+		err = s.removeStorageIndex(ctx, bucname, objname)
+		return
+	}
+	if bucket == nil || bucket.Storage == nil {
+		return
+	}
+	storage := s.syncObjectStorage(uploader, bucket.Storage, object)
+	err = s.updateObjectStorage(ctx, object, storage)
+	return
+}
+
+// isStoragePending reports whether the object storage has not been finished
+func isStoragePending(object *Object) bool {
+	if object == nil || object.Storage == nil || object.DeleteMarker {
+		return false
+	}
+	switch object.Storage.Status {
+	case StorageStatusQueued, StorageStatusUploading:
+		return true
+	}
+	return false
+}
+
+// putStorageIndex index the object if its storage is pending
+func (s *service) putStorageIndex(object *Object) (err error) {
+	if !isStoragePending(object) {
+		return
+	}
+	err = s.providers.StateStore().Put(s.getStorageIndexKey(object.Bucket, object.Name), true)
+	return
+}
+
+// removeStorageIndex remove the index of the object, unless the object has
+// been replaced by a new pending one after checked
+func (s *service) removeStorageIndex(ctx context.Context, bucname, objname string) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Object key
+	objkey := s.getObjectKey(bucname, objname)
+
+	// Lock object
+	err = s.lock.Lock(ctx, objkey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(objkey)
+
+	// Check the current object again
+	object, err := s.getObject(objkey)
+	if err != nil || isStoragePending(object) {
+		return
+	}
+
+	// Delete index
+	err = s.providers.StateStore().Delete(s.getStorageIndexKey(bucname, objname))
+	if errors.Is(err, providers.ErrStateStoreNotFound) {
+		err = nil
+	}
+
+	return
+}
+
+// syncObjectStorage start the upload session of the queued object or refresh
+// the state of the uploading one, the failed upload will be retried at next
+// round if the session has not been started
+func (s *service) syncObjectStorage(uploader providers.StorageUploader, policy *StoragePolicy, object *Object) (storage *ObjectStorage) {
+	storage = &ObjectStorage{
+		SessionID: object.Storage.SessionID,
+		Status:    object.Storage.Status,
+		Updated:   time.Now().UTC(),
+	}
+
+	// Start the upload session
+	if storage.Status == StorageStatusQueued {
+		sessionId, err := uploader.Upload(object.CID, &providers.StorageUploadOptions{
+			Copies:        policy.Replication,
+			StorageLength: policy.StorageLength,
+			Token:         policy.Token,
+			MaxPrice:      policy.MaxPrice,
+			AutoRenew:     policy.AutoRenew,
+		})
+		if err != nil {
+			storage.Message = err.Error()
+			return
+		}
+		storage.SessionID = sessionId
+		storage.Status = StorageStatusUploading
+		return
+	}
+
+	// Refresh the session status
+	status, err := uploader.UploadStatus(storage.SessionID)
+	if err != nil {
+		storage.Message = err.Error()
+		return
+	}
+	switch status.Status {
+	case uploadSessionComplete:
+		storage.Status = StorageStatusStored
+	case uploadSessionError:
+		storage.Status = StorageStatusFailed
+	}
+	storage.Message = status.Message
+
+	return
+}
+
+// updateObjectStorage update the storage state of the object, both the current
+// object record and its version record will be updated if they are the same
+// version with the same body
+func (s *service) updateObjectStorage(ctx context.Context, object *Object, storage *ObjectStorage) (err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Object key
+	objkey := s.getObjectKey(object.Bucket, object.Name)
+
+	// Lock object
+	err = s.lock.Lock(ctx, objkey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(objkey)
+
+	// Current object, it may be changed or removed after collected
+	current, err := s.getObject(objkey)
+	if err != nil || current == nil {
+		return
+	}
+	if current.VersionID != object.VersionID || current.CID != object.CID {
+		return
+	}
+
+	// Put the current object
+	current.Storage = storage
+	err = s.putObject(objkey, current)
+	if err != nil {
+		return
+	}
+
+	// Remove the index once the storage has been finished
+	if !isStoragePending(current) {
+		err = s.providers.StateStore().Delete(s.getStorageIndexKey(object.Bucket, object.Name))
+		if errors.Is(err, providers.ErrStateStoreNotFound) {
+			err = nil
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	// Put the version record if it has been recorded
+	if object.VersionID == "" {
+		return
+	}
+	verkey := s.getVersionKey(object.Bucket, object.Name, object.VersionID)
+	version, err := s.getObject(verkey)
+	if err != nil || version == nil {
+		return
+	}
+	version.Storage = storage
+	err = s.putObject(verkey, version)
+
+	return
+}
@@ -0,0 +1,112 @@
+package object
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bittorrent/go-btfs/s3/api/providers"
+)
+
+// memUploader records the uploads, all sessions are in the status
+type memUploader struct {
+	uploads []*providers.StorageUploadOptions
+	status  string
+}
+
+func (m *memUploader) Upload(id string, options *providers.StorageUploadOptions) (string, error) {
+	m.uploads = append(m.uploads, options)
+	return "session-" + id, nil
+}
+
+func (m *memUploader) UploadStatus(sessionId string) (*providers.StorageUploadStatus, error) {
+	return &providers.StorageUploadStatus{Status: m.status}, nil
+}
+
+func (ts *testService) indexed(t *testing.T) []string {
+	var keys []string
+	prefix := ts.getAllStorageIndexesKeyPrefix()
+	err := ts.states.Iterate(prefix, func(key, _ []byte) (bool, error) {
+		keys = append(keys, string(key[len(prefix):]))
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func (ts *testService) storageStatus(t *testing.T, bucket, name string) string {
+	obj, err := ts.GetObjectStorage(context.Background(), bucket, name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Storage == nil {
+		return ""
+	}
+	return obj.Storage.Status
+}
+
+func TestSyncStorages(t *testing.T) {
+	const bucket = "storage"
+	ctx := context.Background()
+	ts := newTestService(t, bucket)
+	uploader := &memUploader{}
+	ts.providers.(*testProviders).uploader = uploader
+
+	// objects put before the policy are not pushed
+	ts.put(t, bucket, "before", "before")
+	err := ts.PutBucketStorage(ctx, bucket, &StoragePolicy{
+		Replication:   2,
+		StorageLength: 30,
+		MaxPrice:      500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.put(t, bucket, "a", "a")
+	ts.put(t, bucket, "b", "b")
+	if got := ts.indexed(t); len(got) != 2 || got[0] != bucket+"/a" || got[1] != bucket+"/b" {
+		t.Fatalf("expect a and b indexed, got %v", got)
+	}
+
+	// queued -> uploading
+	if err = ts.syncStorages(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(uploader.uploads) != 2 {
+		t.Fatalf("expect 2 uploads, got %d", len(uploader.uploads))
+	}
+	if opts := uploader.uploads[0]; opts.Copies != 2 || opts.StorageLength != 30 || opts.MaxPrice != 500 {
+		t.Errorf("unexpected upload options %+v", opts)
+	}
+	if status := ts.storageStatus(t, bucket, "a"); status != StorageStatusUploading {
+		t.Errorf("expect %s, got %s", StorageStatusUploading, status)
+	}
+	if status := ts.storageStatus(t, bucket, "before"); status != "" {
+		t.Errorf("expect no storage of the object put before the policy, got %s", status)
+	}
+
+	// the index of the deleted object is removed
+	ts.remove(t, bucket, "b", "")
+
+	// uploading -> stored
+	uploader.status = uploadSessionComplete
+	if err = ts.syncStorages(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status := ts.storageStatus(t, bucket, "a"); status != StorageStatusStored {
+		t.Errorf("expect %s, got %s", StorageStatusStored, status)
+	}
+	if got := ts.indexed(t); len(got) != 0 {
+		t.Errorf("expect no indexed objects, got %v", got)
+	}
+	if len(uploader.uploads) != 2 {
+		t.Errorf("expect no more uploads, got %d", len(uploader.uploads))
+	}
+
+	// the overwritten object is queued again
+	ts.put(t, bucket, "a", "new a")
+	if got := ts.indexed(t); len(got) != 1 || got[0] != bucket+"/a" {
+		t.Errorf("expect a indexed again, got %v", got)
+	}
+}
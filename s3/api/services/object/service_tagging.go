@@ -0,0 +1,175 @@
+package object
+
+import (
+	"context"
+
+	"github.com/bittorrent/go-btfs/s3/action"
+)
+
+// PutObjectTagging replace the tags of user specified object version
+func (s *service) PutObjectTagging(ctx context.Context, args *PutObjectTaggingArgs) (object *Object, err error) {
+	object, err = s.updateObjectTags(ctx, args.UserId, args.Bucket, args.Object, args.VersionID,
+		action.PutObjectTaggingAction, args.Tags)
+	return
+}
+
+// GetObjectTagging get the tags of user specified object version
+func (s *service) GetObjectTagging(ctx context.Context, args *GetObjectTaggingArgs) (object *Object, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, args.UserId, action.GetObjectTaggingAction, args.Object)
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Object key
+	objkey := s.getObjectKey(args.Bucket, args.Object)
+
+	// RLock object
+	err = s.lock.RLock(ctx, objkey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(objkey)
+
+	// Get object
+	object, err = s.getTaggingObject(args.Bucket, args.Object, args.VersionID)
+
+	return
+}
+
+// DeleteObjectTagging remove all the tags of user specified object version
+func (s *service) DeleteObjectTagging(ctx context.Context, args *DeleteObjectTaggingArgs) (object *Object, err error) {
+	object, err = s.updateObjectTags(ctx, args.UserId, args.Bucket, args.Object, args.VersionID,
+		action.DeleteObjectTaggingAction, nil)
+	return
+}
+
+// updateObjectTags replace the tags of the object version, both the current object
+// record and its version record will be updated if they are the same version
+func (s *service) updateObjectTags(ctx context.Context, user, bucname, objname, verid string,
+	act action.Action, tags map[string]string) (object *Object, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Bucket key
+	buckey := s.getBucketKey(bucname)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkAccess(ctx, bucket, user, act, objname)
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Object key
+	objkey := s.getObjectKey(bucname, objname)
+
+	// Lock object
+	err = s.lock.Lock(ctx, objkey)
+	if err != nil {
+		return
+	}
+	defer s.lock.Unlock(objkey)
+
+	// Get object
+	object, err = s.getTaggingObject(bucname, objname, verid)
+	if err != nil {
+		return
+	}
+
+	// Update tags
+	object.Tags = tags
+
+	// Current object
+	current, err := s.getObject(objkey)
+	if err != nil {
+		return
+	}
+
+	// Put the current object if it is the tagging version
+	if current != nil && current.VersionID == object.VersionID {
+		current.Tags = tags
+		err = s.putObject(objkey, current)
+		if err != nil {
+			return
+		}
+	}
+
+	// Put the version record if it has been recorded
+	if object.VersionID == "" {
+		return
+	}
+	verkey := s.getVersionKey(bucname, objname, object.VersionID)
+	version, err := s.getObject(verkey)
+	if err != nil || version == nil {
+		return
+	}
+	version.Tags = tags
+	err = s.putObject(verkey, version)
+
+	return
+}
+
+// getTaggingObject get the object version to be tagged, the object key must be
+// locked before calling it, delete markers have no tags
+func (s *service) getTaggingObject(bucname, objname, verid string) (object *Object, err error) {
+	object, err = s.getObjectOfVersion(bucname, objname, verid)
+	if err != nil {
+		return
+	}
+	if object == nil && verid != "" {
+		err = ErrVersionNotFound
+		return
+	}
+	if object == nil {
+		err = ErrObjectNotFound
+		return
+	}
+	if object.DeleteMarker {
+		err = ErrVersionIsMarker
+	}
+	return
+}
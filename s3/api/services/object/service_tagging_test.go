@@ -0,0 +1,120 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func (ts *testService) putTags(t *testing.T, bucket, name, verid string, tags map[string]string) {
+	_, err := ts.PutObjectTagging(context.Background(), &PutObjectTaggingArgs{
+		UserId:    testUser,
+		Bucket:    bucket,
+		Object:    name,
+		VersionID: verid,
+		Tags:      tags,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (ts *testService) expectTags(t *testing.T, bucket, name, verid string, expected map[string]string) {
+	t.Helper()
+	obj, err := ts.GetObjectTagging(context.Background(), &GetObjectTaggingArgs{
+		UserId:    testUser,
+		Bucket:    bucket,
+		Object:    name,
+		VersionID: verid,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obj.Tags) == 0 && len(expected) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(obj.Tags, expected) {
+		t.Errorf("expect tags %v, got %v", expected, obj.Tags)
+	}
+}
+
+func TestObjectTagging(t *testing.T) {
+	const bucket = "tagging"
+
+	t.Run("replace tags", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		ts.put(t, bucket, "obj", "body")
+
+		ts.putTags(t, bucket, "obj", "", map[string]string{"a": "1", "b": "2"})
+		ts.expectTags(t, bucket, "obj", "", map[string]string{"a": "1", "b": "2"})
+
+		ts.putTags(t, bucket, "obj", "", map[string]string{"c": "3"})
+		ts.expectTags(t, bucket, "obj", "", map[string]string{"c": "3"})
+	})
+
+	t.Run("delete tags", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		ts.put(t, bucket, "obj", "body")
+		ts.putTags(t, bucket, "obj", "", map[string]string{"a": "1"})
+
+		_, err := ts.DeleteObjectTagging(context.Background(), &DeleteObjectTaggingArgs{
+			UserId: testUser,
+			Bucket: bucket,
+			Object: "obj",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts.expectTags(t, bucket, "obj", "", nil)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		ts.setVersioning(t, bucket, VersioningEnabled)
+		v1 := ts.putVersion(t, bucket, "obj", "v1")
+		v2 := ts.putVersion(t, bucket, "obj", "v2")
+
+		// Tagging the noncurrent version leaves the current one untouched
+		ts.putTags(t, bucket, "obj", v1.VersionID, map[string]string{"old": "1"})
+		ts.expectTags(t, bucket, "obj", v1.VersionID, map[string]string{"old": "1"})
+		ts.expectTags(t, bucket, "obj", "", nil)
+
+		// Tagging the current object replaces the tags of its version record too
+		ts.putTags(t, bucket, "obj", "", map[string]string{"new": "2"})
+		ts.putTags(t, bucket, "obj", "", map[string]string{"newer": "3"})
+		ts.expectTags(t, bucket, "obj", v2.VersionID, map[string]string{"newer": "3"})
+		ts.expectTags(t, bucket, "obj", v1.VersionID, map[string]string{"old": "1"})
+	})
+
+	t.Run("delete marker", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		ts.setVersioning(t, bucket, VersioningEnabled)
+		ts.putVersion(t, bucket, "obj", "v1")
+		marker := ts.remove(t, bucket, "obj", "")
+
+		_, err := ts.PutObjectTagging(context.Background(), &PutObjectTaggingArgs{
+			UserId:    testUser,
+			Bucket:    bucket,
+			Object:    "obj",
+			VersionID: marker.VersionID,
+			Tags:      map[string]string{"a": "1"},
+		})
+		if !errors.Is(err, ErrVersionIsMarker) {
+			t.Errorf("expect %v, got %v", ErrVersionIsMarker, err)
+		}
+	})
+
+	t.Run("object not found", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		_, err := ts.PutObjectTagging(context.Background(), &PutObjectTaggingArgs{
+			UserId: testUser,
+			Bucket: bucket,
+			Object: "obj",
+			Tags:   map[string]string{"a": "1"},
+		})
+		if !errors.Is(err, ErrObjectNotFound) {
+			t.Errorf("expect %v, got %v", ErrObjectNotFound, err)
+		}
+	})
+}
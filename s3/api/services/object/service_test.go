@@ -0,0 +1,252 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bittorrent/go-btfs/s3/api/providers"
+	"github.com/bittorrent/go-btfs/s3/hash"
+)
+
+// memStateStore is a state store iterating the keys in order as the leveldb
+// one does
+type memStateStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemStateStore() *memStateStore {
+	return &memStateStore{data: make(map[string][]byte)}
+}
+
+func (m *memStateStore) Get(key string, i interface{}) error {
+	m.mu.Lock()
+	v, ok := m.data[key]
+	m.mu.Unlock()
+	if !ok {
+		return providers.ErrStateStoreNotFound
+	}
+	return json.Unmarshal(v, i)
+}
+
+func (m *memStateStore) Put(key string, i interface{}) error {
+	v, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.data[key] = v
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memStateStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.data[key]; !ok {
+		return providers.ErrStateStoreNotFound
+	}
+	delete(m.data, key)
+	return nil
+}
+
+func (m *memStateStore) Iterate(prefix string, iterFunc providers.StateStoreIterFunc) error {
+	m.mu.Lock()
+	keys := make([]string, 0)
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	vals := make([][]byte, len(keys))
+	for i, k := range keys {
+		vals[i] = m.data[k]
+	}
+	m.mu.Unlock()
+	for i, k := range keys {
+		stop, err := iterFunc([]byte(k), vals[i])
+		if err != nil {
+			return err
+		}
+		if stop {
+			return nil
+		}
+	}
+	return nil
+}
+
+// memFileStore keeps the files in memory, the stored files are pinned
+type memFileStore struct {
+	mu     sync.Mutex
+	files  map[string][]byte
+	pinned map[string]bool
+}
+
+func newMemFileStore() *memFileStore {
+	return &memFileStore{files: make(map[string][]byte), pinned: make(map[string]bool)}
+}
+
+// add adds the file without pinning it, as it is added by others
+func (m *memFileStore) add(data []byte) string {
+	sum := sha256.Sum256(data)
+	cid := "bafy" + hex.EncodeToString(sum[:16])
+	m.mu.Lock()
+	m.files[cid] = data
+	m.mu.Unlock()
+	return cid
+}
+
+func (m *memFileStore) isPinned(cid string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pinned[cid]
+}
+
+func (m *memFileStore) Store(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	cid := m.add(data)
+	m.mu.Lock()
+	m.pinned[cid] = true
+	m.mu.Unlock()
+	return cid, nil
+}
+
+func (m *memFileStore) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.pinned[id] {
+		return errors.New("not pinned")
+	}
+	delete(m.pinned, id)
+	return nil
+}
+
+func (m *memFileStore) Cat(id string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[id]
+	if !ok {
+		return nil, providers.ErrFileStoreNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memFileStore) CatRange(id string, offset, length int64) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[id]
+	if !ok {
+		return nil, providers.ErrFileStoreNotFound
+	}
+	end := offset + length
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	return io.NopCloser(bytes.NewReader(data[offset:end])), nil
+}
+
+func (m *memFileStore) Pin(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[id]; !ok {
+		return providers.ErrFileStoreNotFound
+	}
+	m.pinned[id] = true
+	return nil
+}
+
+func (m *memFileStore) IsPinned(id string) (bool, error) {
+	return m.isPinned(id), nil
+}
+
+func (m *memFileStore) Size(id string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[id]
+	if !ok {
+		return 0, providers.ErrFileStoreNotFound
+	}
+	return int64(len(data)), nil
+}
+
+// testProviders allows replacing the stores of the service in tests
+type testProviders struct {
+	states   providers.StateStorer
+	files    providers.FileStorer
+	uploader providers.StorageUploader
+}
+
+func (p *testProviders) FileStore() providers.FileStorer { return p.files }
+
+func (p *testProviders) StateStore() providers.StateStorer { return p.states }
+
+func (p *testProviders) KeyStore() providers.KeyStorer { return nil }
+
+func (p *testProviders) StorageUploader() providers.StorageUploader { return p.uploader }
+
+const testUser = "user"
+
+type testService struct {
+	*service
+	states *memStateStore
+	files  *memFileStore
+}
+
+func newTestService(t *testing.T, bucket string) *testService {
+	states := newMemStateStore()
+	files := newMemFileStore()
+	s := NewService(&testProviders{states: states, files: files}).(*service)
+	_, err := s.CreateBucket(context.Background(), &CreateBucketArgs{
+		UserId: testUser,
+		Bucket: bucket,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testService{service: s, states: states, files: files}
+}
+
+func (ts *testService) put(t *testing.T, bucket, name, body string) *Object {
+	r, err := hash.NewReader(strings.NewReader(body), int64(len(body)), "", "", int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := ts.PutObject(context.Background(), &PutObjectArgs{
+		UserId:        testUser,
+		Body:          r,
+		Bucket:        bucket,
+		Object:        name,
+		ContentLength: int64(len(body)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+// refs returns the objects referencing the cid
+func (ts *testService) refs(t *testing.T, cid string) []string {
+	var refs []string
+	prefix := ts.getAllCidrefsKeyPrefix(cid)
+	err := ts.states.Iterate(prefix, func(key, _ []byte) (bool, error) {
+		refs = append(refs, strings.TrimPrefix(string(key), prefix))
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return refs
+}
@@ -0,0 +1,510 @@
+package object
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bittorrent/go-btfs/s3/action"
+	"github.com/google/uuid"
+)
+
+// ListObjectVersions list all versions(include delete markers) of user specified objects
+func (s *service) ListObjectVersions(ctx context.Context, args *ListObjectVersionsArgs) (list *ObjectVersionsList, err error) {
+	// Operation context
+	ctx, cancel := s.opctx(ctx)
+	defer cancel()
+
+	// Versions list
+	list = &ObjectVersionsList{
+		Args: args,
+	}
+
+	// Bucket key
+	buckey := s.getBucketKey(args.Bucket)
+
+	// RLock bucket
+	err = s.lock.RLock(ctx, buckey)
+	if err != nil {
+		return
+	}
+	defer s.lock.RUnlock(buckey)
+
+	// Get bucket
+	bucket, err := s.getBucket(buckey)
+	if err != nil {
+		return
+	}
+	if bucket == nil {
+		err = ErrBucketNotFound
+		return
+	}
+
+	// Check action ACL
+	allow := s.checkListAccess(ctx, bucket, args.UserId, action.ListObjectVersionsAction, args.Prefix)
+	if !allow {
+		err = ErrNotAllowed
+		return
+	}
+
+	// Set versions owner(versions owner is the bucket owner included them)
+	list.Owner = bucket.Owner
+
+	// MaxKeys is zero
+	if args.MaxKeys == 0 {
+		list.IsTruncated = true
+		return
+	}
+
+	// Mapping of object name to all its versions
+	versions := make(map[string][]*Object)
+
+	// Collect all recorded versions with the specified prefix
+	listVersionsKeyPrefix := s.getAllVersionsKeyPrefix(args.Bucket) + args.Prefix
+	err = s.providers.StateStore().Iterate(listVersionsKeyPrefix, func(key, _ []byte) (stop bool, er error) {
+		var version *Object
+		er = s.providers.StateStore().Get(string(key), &version)
+		if er != nil {
+			return
+		}
+		versions[version.Name] = append(versions[version.Name], version)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	// The objects put before versioning enabled have no version records,
+	// collect them as the null versions
+	listObjectsKeyPrefix := s.getAllObjectsKeyPrefix(args.Bucket) + args.Prefix
+	err = s.providers.StateStore().Iterate(listObjectsKeyPrefix, func(key, _ []byte) (stop bool, er error) {
+		var object *Object
+		er = s.providers.StateStore().Get(string(key), &object)
+		if er != nil {
+			return
+		}
+		if object.VersionID != "" {
+			return
+		}
+		object.VersionID = NullVersionID
+		versions[object.Name] = append(versions[object.Name], object)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	// Sorted object names
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Accumulate count
+	count := int64(0)
+
+	// Seen common prefixes
+	seen := make(map[string]bool)
+
+	// Delimiter length
+	dl := len(args.Delimiter)
+
+	// Prefix length
+	pl := len(args.Prefix)
+
+	// Add a version or a common prefix into the list, return false if
+	// the list is full
+	collect := func(version *Object, commonPrefix string) (ok bool) {
+		if count == args.MaxKeys {
+			list.IsTruncated = true
+			return
+		}
+		if version != nil {
+			list.Versions = append(list.Versions, version)
+			list.NextKeyMarker = version.Name
+			list.NextVersionIDMarker = version.VersionID
+		} else {
+			list.Prefixes = append(list.Prefixes, commonPrefix)
+			list.NextKeyMarker = commonPrefix
+			list.NextVersionIDMarker = ""
+		}
+		count++
+		ok = true
+		return
+	}
+
+	for _, objname := range names {
+		// Skip the objects before the key marker
+		if objname < args.KeyMarker {
+			continue
+		}
+
+		// Common prefix: same as ListObjects
+		commonPrefix := ""
+		if dl > 0 {
+			di := strings.Index(objname[pl:], args.Delimiter)
+			if di >= 0 {
+				commonPrefix = objname[:(pl + di + dl)]
+			}
+		}
+
+		// Objects with common prefix grouped into one, the group has
+		// been listed if the key marker is the common prefix
+		if commonPrefix != "" {
+			if seen[commonPrefix] || commonPrefix == args.KeyMarker {
+				continue
+			}
+			seen[commonPrefix] = true
+			if !collect(nil, commonPrefix) {
+				return
+			}
+			continue
+		}
+
+		// All versions of the object, the latest first
+		objectVersions := versions[objname]
+		s.sortVersions(objectVersions)
+
+		// Begin collect from the version after version id marker if the
+		// object is the key marker
+		begin := objname != args.KeyMarker
+		for i, version := range objectVersions {
+			version.IsLatest = i == 0
+			if !begin {
+				begin = args.VersionIDMarker != "" && version.VersionID == args.VersionIDMarker
+				continue
+			}
+			if !collect(version, "") {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// newVersionID generate a new version id for the object will be put into
+// the bucket, all objects share the null version if the bucket versioning
+// is suspended
+func (s *service) newVersionID(bucket *Bucket) (verid string) {
+	if bucket.Versioning != VersioningEnabled {
+		verid = NullVersionID
+		return
+	}
+	verid = uuid.NewString()
+	return
+}
+
+// putObjectVersion allocate a version id to the object which will be the current
+// one and record it as a version, the old unversioned object will be kept as the
+// null version. It does nothing if the bucket versioning has never been enabled
+func (s *service) putObjectVersion(ctx context.Context, bucket *Bucket, object, objectOld *Object) (err error) {
+	if bucket.Versioning == "" {
+		return
+	}
+
+	// Version id
+	object.VersionID = s.newVersionID(bucket)
+
+	// Keep the old object put before versioning enabled as the null version,
+	// unless it will be replaced by the new null version
+	if objectOld != nil && objectOld.VersionID == "" && object.VersionID != NullVersionID {
+		nullObject := *objectOld
+		nullObject.VersionID = NullVersionID
+		err = s.addObjectVersion(ctx, &nullObject)
+		if err != nil {
+			return
+		}
+	}
+
+	// Only one null version can be kept
+	if object.VersionID == NullVersionID {
+		_, err = s.removeObjectVersion(ctx, object.Bucket, object.Name, NullVersionID)
+		if err != nil {
+			return
+		}
+	}
+
+	// Record the new version
+	err = s.addObjectVersion(ctx, object)
+
+	return
+}
+
+// addObjectVersion record the object version and reference its body
+func (s *service) addObjectVersion(ctx context.Context, version *Object) (err error) {
+	// Version key
+	verkey := s.getVersionKey(version.Bucket, version.Name, version.VersionID)
+
+	// Delete marker has no body
+	if !version.DeleteMarker {
+		err = s.addBodyRef(ctx, version.CID, verkey)
+		if err != nil {
+			return
+		}
+	}
+
+	// Put version
+	err = s.putObject(verkey, version)
+	if err != nil && !version.DeleteMarker {
+		_ = s.removeBodyRef(ctx, version.CID, verkey)
+	}
+
+	return
+}
+
+// removeObjectVersion remove the object version record and try to remove its body
+func (s *service) removeObjectVersion(ctx context.Context, bucname, objname, verid string) (version *Object, err error) {
+	// Version key
+	verkey := s.getVersionKey(bucname, objname, verid)
+
+	// Get version
+	version, err = s.getObject(verkey)
+	if err != nil || version == nil {
+		return
+	}
+
+	// Delete version
+	err = s.deleteObject(verkey)
+	if err != nil {
+		return
+	}
+
+	// Try to remove version body
+	if !version.DeleteMarker {
+		_ = s.removeBody(ctx, version.CID, verkey)
+	}
+
+	return
+}
+
+// getObjectVersions get all recorded versions of the object, the latest first
+func (s *service) getObjectVersions(bucname, objname string) (versions []*Object, err error) {
+	// All this object versions prefix
+	versionsPrefix := s.getObjectVersionsKeyPrefix(bucname, objname)
+
+	// Collect versions, the keys of the objects named with this object name
+	// as prefix are ignored
+	err = s.providers.StateStore().Iterate(versionsPrefix, func(key, _ []byte) (stop bool, er error) {
+		verkey := string(key)
+		if strings.Contains(strings.TrimPrefix(verkey, versionsPrefix), s.keySeparator) {
+			return
+		}
+		var version *Object
+		er = s.providers.StateStore().Get(verkey, &version)
+		if er != nil {
+			return
+		}
+		versions = append(versions, version)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	s.sortVersions(versions)
+
+	return
+}
+
+// getObjectOfVersion get the object with the specified version id, the empty
+// version id means the current version
+func (s *service) getObjectOfVersion(bucname, objname, verid string) (object *Object, err error) {
+	// Object key
+	objkey := s.getObjectKey(bucname, objname)
+
+	// Current version
+	if verid == "" {
+		object, err = s.getObject(objkey)
+		return
+	}
+
+	// Recorded version
+	object, err = s.getObject(s.getVersionKey(bucname, objname, verid))
+	if err != nil || object != nil || verid != NullVersionID {
+		return
+	}
+
+	// The current object put before versioning enabled is the null version
+	current, err := s.getObject(objkey)
+	if err != nil || current == nil || current.VersionID != "" {
+		return
+	}
+	object = current
+
+	return
+}
+
+// deleteObjectOfVersion delete the object with the specified version id, the object key
+// must be locked before calling it. If the version id is empty, a delete marker will be
+// added as the latest version when the bucket versioning has ever been enabled, else the
+// object will be deleted directly
+func (s *service) deleteObjectOfVersion(ctx context.Context, bucket *Bucket, objname, verid string) (deleted *Object, err error) {
+	// Object key
+	objkey := s.getObjectKey(bucket.Name, objname)
+
+	// Delete the specified version
+	if verid != "" {
+		deleted, err = s.removeObjectVersion(ctx, bucket.Name, objname, verid)
+		if err != nil {
+			return
+		}
+
+		// The current object put before versioning enabled is the null version
+		if deleted == nil && verid == NullVersionID {
+			deleted, err = s.getObject(objkey)
+			if err != nil {
+				return
+			}
+			if deleted != nil && deleted.VersionID == "" {
+				err = s.deleteObject(objkey)
+				if err != nil {
+					return
+				}
+				_ = s.removeBody(ctx, deleted.CID, objkey)
+				deleted.VersionID = NullVersionID
+				return
+			}
+			deleted = nil
+		}
+
+		if deleted == nil {
+			err = ErrVersionNotFound
+			return
+		}
+
+		// The latest version may be changed
+		err = s.refreshCurrentVersion(ctx, bucket.Name, objname)
+
+		return
+	}
+
+	// Get current object
+	object, err := s.getObject(objkey)
+	if err != nil {
+		return
+	}
+
+	// Versioning has never been enabled, delete the object directly
+	if bucket.Versioning == "" {
+		if object == nil {
+			err = ErrObjectNotFound
+			return
+		}
+
+		err = s.deleteObject(objkey)
+		if err != nil {
+			return
+		}
+
+		// Try to delete object body
+		_ = s.removeBody(ctx, object.CID, objkey)
+
+		deleted = object
+
+		return
+	}
+
+	// Delete marker
+	deleted = &Object{
+		Bucket:       bucket.Name,
+		Name:         objname,
+		ModTime:      time.Now().UTC(),
+		IsLatest:     true,
+		DeleteMarker: true,
+	}
+
+	// Add delete marker as the latest version
+	err = s.putObjectVersion(ctx, bucket, deleted, object)
+	if err != nil {
+		return
+	}
+
+	// The object has been deleted before
+	if object == nil {
+		return
+	}
+
+	// Delete current object
+	err = s.deleteObject(objkey)
+	if err != nil {
+		return
+	}
+
+	// Release current object body reference, it is kept by its version
+	_ = s.removeBody(ctx, object.CID, objkey)
+
+	return
+}
+
+// refreshCurrentVersion make the latest version of the object as the current object,
+// the object will be absent if the latest version is a delete marker
+func (s *service) refreshCurrentVersion(ctx context.Context, bucname, objname string) (err error) {
+	// Object key
+	objkey := s.getObjectKey(bucname, objname)
+
+	// Get current object
+	current, err := s.getObject(objkey)
+	if err != nil {
+		return
+	}
+
+	// Current object put before versioning enabled is not recorded in versions
+	if current != nil && current.VersionID == "" {
+		return
+	}
+
+	// Get all versions
+	versions, err := s.getObjectVersions(bucname, objname)
+	if err != nil {
+		return
+	}
+
+	// Latest version
+	var latest *Object
+	if len(versions) > 0 && !versions[0].DeleteMarker {
+		latest = versions[0]
+	}
+
+	// Current object is already the latest version
+	if current != nil && latest != nil && current.VersionID == latest.VersionID {
+		return
+	}
+
+	// Release current object, its body reference is removed first to
+	// avoid the reference of the same body be removed
+	if current != nil {
+		err = s.deleteObject(objkey)
+		if err != nil {
+			return
+		}
+		_ = s.removeBody(ctx, current.CID, objkey)
+	}
+
+	// No latest version, object is absent
+	if latest == nil {
+		return
+	}
+
+	// Put latest version as current object
+	latest.IsLatest = true
+	err = s.addBodyRef(ctx, latest.CID, objkey)
+	if err != nil {
+		return
+	}
+	err = s.putObject(objkey, latest)
+
+	return
+}
+
+// sortVersions sort versions by modify time, the latest first
+func (s *service) sortVersions(versions []*Object) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].ModTime.Equal(versions[j].ModTime) {
+			return versions[i].VersionID > versions[j].VersionID
+		}
+		return versions[i].ModTime.After(versions[j].ModTime)
+	})
+}
@@ -0,0 +1,160 @@
+package object
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func (ts *testService) setVersioning(t *testing.T, bucket, versioning string) {
+	err := ts.PutBucketVersioning(context.Background(), &PutBucketVersioningArgs{
+		UserId:     testUser,
+		Bucket:     bucket,
+		Versioning: versioning,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// putVersion puts the object later than the previous one, so the versions
+// are ordered by the time put
+func (ts *testService) putVersion(t *testing.T, bucket, name, body string) *Object {
+	time.Sleep(2 * time.Millisecond)
+	return ts.put(t, bucket, name, body)
+}
+
+func (ts *testService) expectRefs(t *testing.T, cid string, expected ...string) {
+	t.Helper()
+	got := ts.refs(t, cid)
+	if len(got) == 0 && len(expected) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expect refs of %s %v, got %v", cid, expected, got)
+	}
+}
+
+func (ts *testService) expectPinned(t *testing.T, cid string, pinned bool) {
+	t.Helper()
+	if ts.files.isPinned(cid) != pinned {
+		t.Errorf("expect %s pinned %v", cid, pinned)
+	}
+}
+
+func TestVersionRefcount(t *testing.T) {
+	const (
+		bucket = "versions"
+		name   = "obj"
+	)
+
+	t.Run("overwrite null version", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		objkey := ts.getObjectKey(bucket, name)
+		nullkey := ts.getVersionKey(bucket, name, NullVersionID)
+
+		// unversioned object becomes the null version
+		a := ts.put(t, bucket, name, "a")
+		ts.setVersioning(t, bucket, VersioningEnabled)
+		b := ts.putVersion(t, bucket, name, "b")
+		ts.expectRefs(t, a.CID, nullkey)
+		ts.expectRefs(t, b.CID, objkey, ts.getVersionKey(bucket, name, b.VersionID))
+
+		// the new null version replaces the old one
+		ts.setVersioning(t, bucket, VersioningSuspended)
+		c := ts.putVersion(t, bucket, name, "c")
+		if c.VersionID != NullVersionID {
+			t.Fatalf("expect null version, got %s", c.VersionID)
+		}
+		ts.expectRefs(t, a.CID)
+		ts.expectPinned(t, a.CID, false)
+		ts.expectRefs(t, b.CID, ts.getVersionKey(bucket, name, b.VersionID))
+		ts.expectPinned(t, b.CID, true)
+		ts.expectRefs(t, c.CID, objkey, nullkey)
+
+		list, err := ts.ListObjectVersions(context.Background(), &ListObjectVersionsArgs{
+			UserId:  testUser,
+			Bucket:  bucket,
+			MaxKeys: 100,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Versions) != 2 {
+			t.Fatalf("expect 2 versions, got %d", len(list.Versions))
+		}
+		if list.Versions[0].VersionID != NullVersionID || list.Versions[1].VersionID != b.VersionID {
+			t.Errorf("expect versions null and %s, got %s and %s", b.VersionID,
+				list.Versions[0].VersionID, list.Versions[1].VersionID)
+		}
+	})
+
+	t.Run("delete marker", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		objkey := ts.getObjectKey(bucket, name)
+		ts.setVersioning(t, bucket, VersioningEnabled)
+		v1 := ts.putVersion(t, bucket, name, "v1")
+		verkey := ts.getVersionKey(bucket, name, v1.VersionID)
+
+		time.Sleep(2 * time.Millisecond)
+		marker := ts.remove(t, bucket, name, "")
+		if !marker.DeleteMarker || marker.VersionID == "" {
+			t.Fatalf("expect delete marker, got %+v", marker)
+		}
+		current, err := ts.getObject(objkey)
+		if err != nil || current != nil {
+			t.Fatalf("expect no current object, got %+v, %v", current, err)
+		}
+		ts.expectRefs(t, v1.CID, verkey)
+		ts.expectPinned(t, v1.CID, true)
+
+		// removing the marker restores the version
+		ts.remove(t, bucket, name, marker.VersionID)
+		current, err = ts.getObject(objkey)
+		if err != nil || current == nil || current.VersionID != v1.VersionID {
+			t.Fatalf("expect current version %s, got %+v, %v", v1.VersionID, current, err)
+		}
+		ts.expectRefs(t, v1.CID, objkey, verkey)
+	})
+
+	t.Run("delete specified version", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		objkey := ts.getObjectKey(bucket, name)
+		ts.setVersioning(t, bucket, VersioningEnabled)
+		v1 := ts.putVersion(t, bucket, name, "v1")
+		v2 := ts.putVersion(t, bucket, name, "v2")
+
+		ts.remove(t, bucket, name, v2.VersionID)
+		ts.expectRefs(t, v2.CID)
+		ts.expectPinned(t, v2.CID, false)
+		ts.expectRefs(t, v1.CID, objkey, ts.getVersionKey(bucket, name, v1.VersionID))
+		current, err := ts.getObject(objkey)
+		if err != nil || current == nil || current.VersionID != v1.VersionID {
+			t.Fatalf("expect current version %s, got %+v, %v", v1.VersionID, current, err)
+		}
+
+		ts.remove(t, bucket, name, v1.VersionID)
+		ts.expectRefs(t, v1.CID)
+		ts.expectPinned(t, v1.CID, false)
+		current, err = ts.getObject(objkey)
+		if err != nil || current != nil {
+			t.Fatalf("expect no current object, got %+v, %v", current, err)
+		}
+	})
+
+	t.Run("versions share body", func(t *testing.T) {
+		ts := newTestService(t, bucket)
+		objkey := ts.getObjectKey(bucket, name)
+		ts.setVersioning(t, bucket, VersioningEnabled)
+		v1 := ts.putVersion(t, bucket, name, "same")
+		v2 := ts.putVersion(t, bucket, name, "same")
+		if v1.CID != v2.CID {
+			t.Fatal("expect the same body")
+		}
+
+		ts.remove(t, bucket, name, v1.VersionID)
+		ts.expectRefs(t, v1.CID, objkey, ts.getVersionKey(bucket, name, v2.VersionID))
+		ts.expectPinned(t, v1.CID, true)
+	})
+}
@@ -0,0 +1,77 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bittorrent/go-btfs/s3/api/providers"
+)
+
+var (
+	ErrPusherStarted    = errors.New("storage pusher started")
+	ErrPusherNotStarted = errors.New("storage pusher not started")
+)
+
+// StoragePusher periodically pushes the queued objects into the decentralized
+// storage and refreshes the states of their upload sessions, it shares the
+// state store and locks with the object service
+type StoragePusher struct {
+	svc    *service
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+}
+
+func NewStoragePusher(providers providers.Providerser, options ...Option) *StoragePusher {
+	return &StoragePusher{
+		svc: NewService(providers, options...).(*service),
+	}
+}
+
+func (sp *StoragePusher) Start() (err error) {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	if sp.cancel != nil {
+		err = ErrPusherStarted
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sp.cancel = cancel
+
+	go func() {
+		ticker := time.NewTicker(sp.svc.storageSync)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				er := sp.svc.syncStorages(ctx)
+				if er != nil && ctx.Err() == nil {
+					fmt.Printf("s3-api: storage sync, err: %v\n", er)
+				}
+			}
+		}
+	}()
+
+	return
+}
+
+func (sp *StoragePusher) Stop() (err error) {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	if sp.cancel == nil {
+		err = ErrPusherNotStarted
+		return
+	}
+
+	sp.cancel()
+	sp.cancel = nil
+
+	return
+}
@@ -42,6 +42,9 @@ func (s *service) VerifyRequestSignature(r *http.Request) (ack string, rerr *res
 	case AuthTypeStreamingSigned:
 		ack, rerr = s.setReqBodySignV4ChunkedReader(r, "")
 		return
+	case AuthTypePresigned:
+		ack, rerr = s.reqPresignedSignatureV4Verify(r, "")
+		return
 	default:
 		rerr = responses.ErrSignatureVersionNotSupported
 		return
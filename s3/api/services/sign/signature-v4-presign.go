@@ -0,0 +1,216 @@
+package sign
+
+import (
+	"github.com/bittorrent/go-btfs/s3/api/responses"
+	"github.com/bittorrent/go-btfs/s3/consts"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPresignExpires is the longest valid duration of a presigned url.
+	maxPresignExpires = 7 * 24 * time.Hour
+
+	// maxSkewTime is the tolerance of the clock of the signer ahead of us.
+	maxSkewTime = 15 * time.Minute
+)
+
+// preSignValues data type represents structured form of AWS Signature V4 query string.
+type preSignValues struct {
+	signValues
+	Date    time.Time
+	Expires time.Duration
+}
+
+// Parses signature version '4' query string of the following form.
+//
+//	querystring = X-Amz-Algorithm=algorithm
+//	querystring += &X-Amz-Credential= urlencode(accessKey + '/' + credential_scope)
+//	querystring += &X-Amz-Date=date
+//	querystring += &X-Amz-Expires=timeout interval
+//	querystring += &X-Amz-SignedHeaders=signed_headers
+//	querystring += &X-Amz-Signature=signature
+func parsePreSignV4(query url.Values, region string) (psv preSignValues, rerr *responses.Error) {
+	// Verify whether the required query params are present.
+	for _, param := range []string{consts.AmzAlgorithm, consts.AmzCredential, consts.AmzSignature,
+		consts.AmzDate, consts.AmzSignedHeaders, consts.AmzExpires} {
+		if _, ok := query[param]; !ok {
+			rerr = responses.ErrInvalidQueryParams
+			return
+		}
+	}
+
+	// Verify if the query algorithm is supported or not.
+	if query.Get(consts.AmzAlgorithm) != signV4Algorithm {
+		rerr = responses.ErrInvalidQuerySignatureAlgo
+		return
+	}
+
+	// Initialize signature version '4' structured header.
+	preSignV4Values := preSignValues{}
+
+	// Save credential.
+	preSignV4Values.Credential, rerr = parseCredentialHeader("Credential="+query.Get(consts.AmzCredential), region)
+	if rerr != nil {
+		return
+	}
+
+	// Save date in native time.Time.
+	var err error
+	preSignV4Values.Date, err = time.Parse(iso8601Format, query.Get(consts.AmzDate))
+	if err != nil {
+		rerr = responses.ErrMalformedPresignedDate
+		return
+	}
+
+	// Save expires in native time.Duration.
+	expires, err := strconv.ParseInt(query.Get(consts.AmzExpires), 10, 64)
+	if err != nil {
+		rerr = responses.ErrMalformedExpires
+		return
+	}
+	if expires < 0 {
+		rerr = responses.ErrNegativeExpires
+		return
+	}
+	preSignV4Values.Expires = time.Duration(expires) * time.Second
+	if preSignV4Values.Expires > maxPresignExpires {
+		rerr = responses.ErrMaximumExpires
+		return
+	}
+
+	// Save signed headers.
+	preSignV4Values.SignedHeaders, rerr = parseSignedHeader("SignedHeaders=" + query.Get(consts.AmzSignedHeaders))
+	if rerr != nil {
+		return
+	}
+
+	// Save signature.
+	preSignV4Values.Signature, rerr = parseSignature("Signature=" + query.Get(consts.AmzSignature))
+	if rerr != nil {
+		return
+	}
+
+	// Return structured form of signature query string.
+	psv = preSignV4Values
+	return
+}
+
+func (s *service) reqPresignedSignatureV4Verify(r *http.Request, region string) (ack string, rerr *responses.Error) {
+	// The payload of presigned request is unsigned unless the signer
+	// explicitly put its checksum into the query string.
+	hashedPayload := r.Form.Get(consts.AmzContentSha256)
+	if hashedPayload == "" {
+		hashedPayload = consts.UnsignedSHA256
+	}
+	ack, rerr = s.doesPresignedSignatureMatch(hashedPayload, r, region)
+	return
+}
+
+// doesPresignedSignatureMatch - Verify query headers with presigned signature
+//   - http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+//
+// returns nil if signature matches.
+func (s *service) doesPresignedSignatureMatch(hashedPayload string, r *http.Request, region string) (ack string, rerr *responses.Error) {
+	// Copy request
+	req := *r
+
+	// Parse request query string.
+	pSignValues, rerr := parsePreSignV4(req.Form, region)
+	if rerr != nil {
+		return
+	}
+
+	ack = pSignValues.Credential.accessKey
+	secret, rerr := s.checkKeyValid(ack)
+	if rerr != nil {
+		return
+	}
+
+	// Extract all the signed headers along with its values.
+	extractedSignedHeaders, rerr := extractSignedHeaders(pSignValues.SignedHeaders, r)
+	if rerr != nil {
+		return
+	}
+
+	// If the host which signed the request is slightly ahead in time (by less than maxSkewTime) the
+	// request should still be allowed.
+	now := time.Now().UTC()
+	if pSignValues.Date.After(now.Add(maxSkewTime)) {
+		rerr = responses.ErrRequestNotReadyYet
+		return
+	}
+	if now.Sub(pSignValues.Date) > pSignValues.Expires {
+		rerr = responses.ErrExpiredPresignRequest
+		return
+	}
+
+	// Construct the query string without signature, all the other
+	// parameters provided in the request url are signed as well.
+	query := make(url.Values)
+	for k, v := range req.Form {
+		if k != consts.AmzSignature {
+			query[k] = v
+		}
+	}
+
+	// Get canonical request.
+	presignedCanonicalReq := GetCanonicalRequest(extractedSignedHeaders, hashedPayload, query.Encode(), req.URL.Path, req.Method)
+
+	// Get string to sign from canonical request.
+	presignedStringToSign := GetStringToSign(presignedCanonicalReq, pSignValues.Date, pSignValues.Credential.getScope())
+
+	// Get hmac presigned signing key.
+	presignedSigningKey := GetSigningKey(secret, pSignValues.Credential.scope.date,
+		pSignValues.Credential.scope.region)
+
+	// Get new signature.
+	newSignature := GetSignature(presignedSigningKey, presignedStringToSign)
+
+	// Verify signature.
+	if !compareSignatureV4(newSignature, pSignValues.Signature) {
+		rerr = responses.ErrSignatureDoesNotMatch
+		return
+	}
+
+	return
+}
+
+// PresignV4 generate a query string signed url of the path under the endpoint,
+// only the host header is signed, and the payload is left unsigned, the url
+// is valid from the time t until the expires duration passed.
+func PresignV4(method, endpoint, path, accessKey, secret, region string, t time.Time, expires time.Duration) (presigned string, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return
+	}
+	u.Path = path
+	u.RawPath = EncodePath(path)
+
+	// Construct the query string.
+	t = t.UTC()
+	scope := getScope(t, region)
+	query := make(url.Values)
+	query.Set(consts.AmzAlgorithm, signV4Algorithm)
+	query.Set(consts.AmzCredential, accessKey+consts.SlashSeparator+scope)
+	query.Set(consts.AmzDate, t.Format(iso8601Format))
+	query.Set(consts.AmzExpires, strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set(consts.AmzSignedHeaders, "host")
+
+	// Sign the host header only.
+	signedHeaders := make(http.Header)
+	signedHeaders.Set("host", u.Host)
+
+	// Calculate signature.
+	canonicalRequest := GetCanonicalRequest(signedHeaders, consts.UnsignedSHA256, query.Encode(), path, method)
+	stringToSign := GetStringToSign(canonicalRequest, t, scope)
+	signature := GetSignature(GetSigningKey(secret, t, region), stringToSign)
+	query.Set(consts.AmzSignature, signature)
+
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	presigned = u.String()
+	return
+}
@@ -0,0 +1,130 @@
+package sign
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bittorrent/go-btfs/s3/api/responses"
+)
+
+const (
+	testAccessKey = "access-key"
+	testSecret    = "secret"
+	testRegion    = "us-east-1"
+)
+
+func newTestService() *service {
+	return NewService(func(svc *service) {
+		svc.getSecret = func(key string) (secret string, exists, enable bool, err error) {
+			if key != testAccessKey {
+				return
+			}
+			return testSecret, true, true, nil
+		}
+	}).(*service)
+}
+
+func TestPresignV4(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name    string
+		secret  string
+		t       time.Time
+		expires time.Duration
+		tamper  func(r *http.Request)
+		rerr    *responses.Error
+	}{
+		{
+			name:    "valid",
+			t:       now,
+			expires: time.Hour,
+		},
+		{
+			name:    "signer clock slightly ahead",
+			t:       now.Add(maxSkewTime - time.Minute),
+			expires: time.Hour,
+		},
+		{
+			name:    "beyond max skew time",
+			t:       now.Add(maxSkewTime + time.Minute),
+			expires: time.Hour,
+			rerr:    responses.ErrRequestNotReadyYet,
+		},
+		{
+			name:    "expired",
+			t:       now.Add(-2 * time.Hour),
+			expires: time.Hour,
+			rerr:    responses.ErrExpiredPresignRequest,
+		},
+		{
+			name:    "expires above 7 days",
+			t:       now,
+			expires: maxPresignExpires + time.Second,
+			rerr:    responses.ErrMaximumExpires,
+		},
+		{
+			name:    "negative expires",
+			t:       now,
+			expires: -time.Minute,
+			rerr:    responses.ErrNegativeExpires,
+		},
+		{
+			name:    "wrong secret",
+			secret:  "other-secret",
+			t:       now,
+			expires: time.Hour,
+			rerr:    responses.ErrSignatureDoesNotMatch,
+		},
+		{
+			name:    "tampered query parameter",
+			t:       now,
+			expires: time.Hour,
+			tamper: func(r *http.Request) {
+				r.Form.Set("versionId", "1")
+			},
+			rerr: responses.ErrSignatureDoesNotMatch,
+		},
+		{
+			name:    "tampered expires",
+			t:       now.Add(-2 * time.Hour),
+			expires: time.Hour,
+			tamper: func(r *http.Request) {
+				r.Form.Set("X-Amz-Expires", "86400")
+			},
+			rerr: responses.ErrSignatureDoesNotMatch,
+		},
+	}
+
+	s := newTestService()
+	for _, tc := range testCases {
+		secret := tc.secret
+		if secret == "" {
+			secret = testSecret
+		}
+		presigned, err := PresignV4(http.MethodGet, "http://127.0.0.1:6001", "/bucket/dir/a b.txt",
+			testAccessKey, secret, testRegion, tc.t, tc.expires)
+		if err != nil {
+			t.Fatalf("%s: failed to presign: %v", tc.name, err)
+		}
+		r := httptest.NewRequest(http.MethodGet, presigned, nil)
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("%s: failed to parse form: %v", tc.name, err)
+		}
+		if GetRequestAuthType(r) != AuthTypePresigned {
+			t.Fatalf("%s: request is not presigned", tc.name)
+		}
+		if tc.tamper != nil {
+			tc.tamper(r)
+		}
+		ack, rerr := s.reqPresignedSignatureV4Verify(r, testRegion)
+		if rerr != tc.rerr {
+			t.Errorf("%s: expected error %v, got %v", tc.name, tc.rerr, rerr)
+			continue
+		}
+		if rerr == nil && ack != testAccessKey {
+			t.Errorf("%s: expected access key %s, got %s", tc.name, testAccessKey, ack)
+		}
+	}
+}
@@ -36,6 +36,21 @@ const (
 	AmzContentSha256 = "X-Amz-Content-Sha256"
 	AmzDate          = "X-Amz-Date"
 	AmzRequestID     = "x-amz-request-id"
+	AmzVersionID     = "x-amz-version-id"
+	AmzMetaPrefix    = "x-amz-meta-"
+	AmzTaggingCount  = "x-amz-tagging-count"
+
+	// Server side encryption headers
+	AmzServerSideEncryption                  = "X-Amz-Server-Side-Encryption"
+	AmzServerSideEncryptionCustomerAlgorithm = "X-Amz-Server-Side-Encryption-Customer-Algorithm"
+	AmzServerSideEncryptionCustomerKeyMD5    = "X-Amz-Server-Side-Encryption-Customer-Key-Md5"
+
+	// Presigned query string parameters
+	AmzAlgorithm     = "X-Amz-Algorithm"
+	AmzCredential    = "X-Amz-Credential"
+	AmzSignedHeaders = "X-Amz-SignedHeaders"
+	AmzSignature     = "X-Amz-Signature"
+	AmzExpires       = "X-Amz-Expires"
 )
 
 // Standard S3 HTTP response constants
@@ -64,6 +79,10 @@ const (
 	Range              = "Range"
 	UserAgent          = "User-Agent"
 	Cid                = "Cid"
+	BtfsCid            = "X-Btfs-Cid"
+	BtfsSourceCid      = "X-Btfs-Source-Cid"
+	BtfsStorageStatus  = "X-Btfs-Storage-Status"
+	BtfsStorageSession = "X-Btfs-Storage-Session"
 )
 
 // Standard HTTP cors headers
@@ -78,18 +97,31 @@ const (
 
 // object const
 const (
-	MaxXMLBodySize = 5 * humanize.MiByte
-	MaxObjectSize  = 5 * humanize.TiByte
-	MinPartSize    = 5 * humanize.MiByte
-	MaxPartSize    = 5 * humanize.GiByte
-	MinPartNumber  = 1
-	MaxPartNumber  = 10000
-	MaxObjectList  = 1000 // Limit number of objects in a listObjectsResponse/listObjectsVersionsResponse.
-	MaxDeleteList  = 1000 // Limit number of objects deleted in a delete call.
+	MaxXMLBodySize     = 5 * humanize.MiByte
+	MaxObjectSize      = 5 * humanize.TiByte
+	MinPartSize        = 5 * humanize.MiByte
+	MaxPartSize        = 5 * humanize.GiByte
+	MinPartNumber      = 1
+	MaxPartNumber      = 10000
+	MaxObjectList      = 1000 // Limit number of objects in a listObjectsResponse/listObjectsVersionsResponse.
+	MaxUploadsList     = 1000 // Limit number of uploads in a listMultipartUploadsResponse.
+	MaxPartsList       = 1000 // Limit number of parts in a listPartsResponse.
+	MaxDeleteList      = 1000 // Limit number of objects deleted in a delete call.
+	MaxVersionIdLength = 1024
+	MaxLifecycleRules  = 1000 // Limit number of rules in a lifecycle configuration.
+	MaxLifecycleRuleId = 255
+	MaxUserMetadata    = 2 * humanize.KiByte // Limit size of the user-defined metadata of an object.
+	MaxObjectTags      = 10                  // Limit number of tags of an object.
+	MaxTagKeyLength    = 128
+	MaxTagValueLength  = 256
 )
 
 // Common http query params S3 API
 const (
-	MaxKeys    = "max-keys"
-	PartNumber = "partNumber"
+	MaxKeys          = "max-keys"
+	MaxUploads       = "max-uploads"
+	MaxParts         = "max-parts"
+	PartNumber       = "partNumber"
+	PartNumberMarker = "part-number-marker"
+	VersionId        = "versionId"
 )
@@ -0,0 +1,360 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	s3action "github.com/bittorrent/go-btfs/s3/action"
+	"github.com/bittorrent/go-btfs/s3/set"
+)
+
+const (
+	// DefaultVersion is the current version of the policy language
+	DefaultVersion = "2012-10-17"
+
+	// ResourceARNPrefix is the prefix of all the s3 resources
+	ResourceARNPrefix = "arn:aws:s3:::"
+
+	// MaxBucketPolicySize is the max size of the bucket policy document
+	MaxBucketPolicySize = 20 * 1024
+)
+
+// Effect is the effect of a statement
+type Effect string
+
+const (
+	Allow Effect = "Allow"
+	Deny  Effect = "Deny"
+)
+
+// Supported condition operators
+const (
+	StringEquals    = "StringEquals"
+	StringNotEquals = "StringNotEquals"
+	StringLike      = "StringLike"
+	StringNotLike   = "StringNotLike"
+	IpAddress       = "IpAddress"
+	NotIpAddress    = "NotIpAddress"
+)
+
+// Supported condition keys
+const (
+	KeySourceIP = "aws:SourceIp"
+	KeyPrefix   = "s3:prefix"
+)
+
+var (
+	ErrPolicyMalformed        = errors.New("policy is malformed")
+	ErrPolicyVersionInvalid   = errors.New("policy version is invalid")
+	ErrPolicyEffectInvalid    = errors.New("policy statement effect is invalid")
+	ErrPolicyPrincipalEmpty   = errors.New("policy statement principal is empty")
+	ErrPolicyActionInvalid    = errors.New("policy statement action is invalid")
+	ErrPolicyResourceInvalid  = errors.New("policy statement resource is invalid")
+	ErrPolicyConditionInvalid = errors.New("policy statement condition is invalid")
+)
+
+// Policy is the IAM-style bucket policy document
+type Policy struct {
+	ID         string       `json:"ID,omitempty"`
+	Version    string       `json:"Version"`
+	Statements []*Statement `json:"Statement"`
+}
+
+// Statement is one rule of the policy
+type Statement struct {
+	SID        string                              `json:"Sid,omitempty"`
+	Effect     Effect                              `json:"Effect"`
+	Principal  Principal                           `json:"Principal"`
+	Actions    set.StringSet                       `json:"Action"`
+	Resources  set.StringSet                       `json:"Resource"`
+	Conditions map[string]map[string]set.StringSet `json:"Condition,omitempty"`
+}
+
+// Principal is the access keys the statement applies to, "*" means everyone
+// including the anonymous user
+type Principal struct {
+	AWS set.StringSet `json:"AWS"`
+}
+
+// UnmarshalJSON decode the principal from both "*" and {"AWS": ...} forms
+func (p *Principal) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		if s != "*" {
+			err = ErrPolicyMalformed
+			return
+		}
+		p.AWS = set.CreateStringSet("*")
+		return
+	}
+	type principal Principal
+	var pp principal
+	err = json.Unmarshal(data, &pp)
+	if err != nil {
+		return
+	}
+	*p = Principal(pp)
+	return
+}
+
+// Args is the request to be evaluated by the policy
+type Args struct {
+	AccountID  string
+	Action     s3action.Action
+	BucketName string
+	ObjectName string
+	Conditions map[string][]string
+}
+
+// ParseBucketPolicy decode the policy document and validate it for the bucket
+func ParseBucketPolicy(data []byte, bucket string) (p *Policy, err error) {
+	if len(data) > MaxBucketPolicySize {
+		err = ErrPolicyMalformed
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&p)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrPolicyMalformed, err)
+		return
+	}
+	if p == nil {
+		err = ErrPolicyMalformed
+		return
+	}
+	err = p.Validate(bucket)
+	return
+}
+
+// Validate check if the policy is valid for the bucket
+func (p *Policy) Validate(bucket string) (err error) {
+	if p.Version != DefaultVersion {
+		err = ErrPolicyVersionInvalid
+		return
+	}
+	if len(p.Statements) == 0 {
+		err = ErrPolicyMalformed
+		return
+	}
+	for _, st := range p.Statements {
+		err = st.validate(bucket)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (st *Statement) validate(bucket string) (err error) {
+	if st == nil {
+		err = ErrPolicyMalformed
+		return
+	}
+	if st.Effect != Allow && st.Effect != Deny {
+		err = ErrPolicyEffectInvalid
+		return
+	}
+	if st.Principal.AWS.IsEmpty() {
+		err = ErrPolicyPrincipalEmpty
+		return
+	}
+	if st.Actions.IsEmpty() {
+		err = ErrPolicyActionInvalid
+		return
+	}
+	for act := range st.Actions {
+		if !s3action.Action(act).IsValid() {
+			err = ErrPolicyActionInvalid
+			return
+		}
+	}
+	if st.Resources.IsEmpty() {
+		err = ErrPolicyResourceInvalid
+		return
+	}
+	for res := range st.Resources {
+		name, ok := strings.CutPrefix(res, ResourceARNPrefix)
+		if !ok {
+			err = ErrPolicyResourceInvalid
+			return
+		}
+		bucketPattern, _, _ := strings.Cut(name, "/")
+		if !set.Match(bucketPattern, bucket) {
+			err = ErrPolicyResourceInvalid
+			return
+		}
+	}
+	for op, conds := range st.Conditions {
+		for key, vals := range conds {
+			err = validateCondition(op, key, vals)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func validateCondition(op, key string, vals set.StringSet) (err error) {
+	if vals.IsEmpty() {
+		err = ErrPolicyConditionInvalid
+		return
+	}
+	switch op {
+	case StringEquals, StringNotEquals, StringLike, StringNotLike:
+		if key != KeyPrefix && key != KeySourceIP {
+			err = ErrPolicyConditionInvalid
+		}
+	case IpAddress, NotIpAddress:
+		if key != KeySourceIP {
+			err = ErrPolicyConditionInvalid
+			return
+		}
+		for val := range vals {
+			if parseCIDR(val) == nil {
+				err = ErrPolicyConditionInvalid
+				return
+			}
+		}
+	default:
+		err = ErrPolicyConditionInvalid
+	}
+	return
+}
+
+// Evaluate the request with all the statements, Deny will be returned if
+// any statement denies it, otherwise Allow if any statement allows it, the
+// empty effect means no statement applies to the request
+func (p *Policy) Evaluate(args *Args) (effect Effect) {
+	for _, st := range p.Statements {
+		if !st.isMatch(args) {
+			continue
+		}
+		if st.Effect == Deny {
+			return Deny
+		}
+		effect = Allow
+	}
+	return
+}
+
+func (st *Statement) isMatch(args *Args) (match bool) {
+	return st.matchPrincipal(args.AccountID) &&
+		st.matchAction(args.Action) &&
+		st.matchResource(args.BucketName, args.ObjectName) &&
+		st.matchConditions(args.Conditions)
+}
+
+func (st *Statement) matchPrincipal(account string) (match bool) {
+	if st.Principal.AWS.Contains("*") {
+		return true
+	}
+	return account != "" && st.Principal.AWS.Contains(account)
+}
+
+func (st *Statement) matchAction(act s3action.Action) (match bool) {
+	for pattern := range st.Actions {
+		if s3action.Action(pattern).Match(act) {
+			return true
+		}
+	}
+	return
+}
+
+func (st *Statement) matchResource(bucket, object string) (match bool) {
+	res := ResourceARNPrefix + bucket
+	if object != "" {
+		res += "/" + object
+	}
+	for pattern := range st.Resources {
+		if set.Match(pattern, res) {
+			return true
+		}
+	}
+	return
+}
+
+// matchConditions check all the conditions, they are combined with AND
+func (st *Statement) matchConditions(values map[string][]string) (match bool) {
+	for op, conds := range st.Conditions {
+		for key, patterns := range conds {
+			if !matchCondition(op, patterns, values[key]) {
+				return
+			}
+		}
+	}
+	match = true
+	return
+}
+
+func matchCondition(op string, patterns set.StringSet, values []string) (match bool) {
+	switch op {
+	case StringEquals:
+		return anyMatch(values, patterns.Contains)
+	case StringNotEquals:
+		return !anyMatch(values, patterns.Contains)
+	case StringLike:
+		return anyMatch(values, func(val string) bool {
+			return !patterns.FuncMatch(set.Match, val).IsEmpty()
+		})
+	case StringNotLike:
+		return !anyMatch(values, func(val string) bool {
+			return !patterns.FuncMatch(set.Match, val).IsEmpty()
+		})
+	case IpAddress:
+		return anyMatch(values, func(val string) bool {
+			return containsIP(patterns, val)
+		})
+	case NotIpAddress:
+		return !anyMatch(values, func(val string) bool {
+			return containsIP(patterns, val)
+		})
+	}
+	return
+}
+
+func anyMatch(values []string, fn func(string) bool) (match bool) {
+	for _, val := range values {
+		if fn(val) {
+			return true
+		}
+	}
+	return
+}
+
+func containsIP(cidrs set.StringSet, addr string) (contains bool) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return
+	}
+	for cidr := range cidrs {
+		ipnet := parseCIDR(cidr)
+		if ipnet != nil && ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return
+}
+
+// parseCIDR parse the network, a single ip is treated as the host network
+func parseCIDR(s string) (ipnet *net.IPNet) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		ipnet = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		return
+	}
+	_, ipnet, _ = net.ParseCIDR(s)
+	return
+}
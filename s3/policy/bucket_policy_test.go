@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	s3action "github.com/bittorrent/go-btfs/s3/action"
+)
+
+const testPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Principal": {"AWS": ["reader"]},
+			"Action": ["s3:GetObject", "s3:HeadObject"],
+			"Resource": "arn:aws:s3:::bucket/photos/*"
+		},
+		{
+			"Effect": "Allow",
+			"Principal": {"AWS": "reader"},
+			"Action": "s3:ListObjects*",
+			"Resource": "arn:aws:s3:::bucket",
+			"Condition": {"StringLike": {"s3:prefix": "photos/*"}}
+		},
+		{
+			"Effect": "Deny",
+			"Principal": "*",
+			"Action": "s3:*",
+			"Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"NotIpAddress": {"aws:SourceIp": ["10.0.0.0/8", "127.0.0.1"]}}
+		}
+	]
+}`
+
+func TestParseBucketPolicy(t *testing.T) {
+	testCases := []struct {
+		policy string
+		bucket string
+		err    error
+	}{
+		// Test case - 1.
+		// Valid policy for the bucket.
+		{testPolicy, "bucket", nil},
+		// Test case - 2.
+		// Resource of another bucket.
+		{testPolicy, "other", ErrPolicyResourceInvalid},
+		// Test case - 3.
+		// Unknown version.
+		{`{"Version": "2000-01-01", "Statement": []}`, "bucket", ErrPolicyVersionInvalid},
+		// Test case - 4.
+		// Unknown action.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*",
+			"Action": "s3:Unknown", "Resource": "arn:aws:s3:::bucket"}]}`, "bucket", ErrPolicyActionInvalid},
+		// Test case - 5.
+		// Unsupported condition key.
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*",
+			"Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"IpAddress": {"s3:prefix": "a"}}}]}`, "bucket", ErrPolicyConditionInvalid},
+		// Test case - 6.
+		// Unknown field.
+		{`{"Version": "2012-10-17", "Statements": []}`, "bucket", ErrPolicyMalformed},
+	}
+
+	for i, testCase := range testCases {
+		_, err := ParseBucketPolicy([]byte(testCase.policy), testCase.bucket)
+		if !errors.Is(err, testCase.err) {
+			t.Errorf("Test %d: Expected error %v, got %v", i+1, testCase.err, err)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	p, err := ParseBucketPolicy([]byte(testPolicy), "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		args   Args
+		effect Effect
+	}{
+		// Test case - 1.
+		// Read object under the prefix.
+		{Args{"reader", s3action.GetObjectAction, "bucket", "photos/a.png",
+			map[string][]string{KeySourceIP: {"10.1.2.3"}}}, Allow},
+		// Test case - 2.
+		// Read object out of the prefix.
+		{Args{"reader", s3action.GetObjectAction, "bucket", "docs/a.txt",
+			map[string][]string{KeySourceIP: {"10.1.2.3"}}}, ""},
+		// Test case - 3.
+		// Write object is not granted.
+		{Args{"reader", s3action.PutObjectAction, "bucket", "photos/a.png",
+			map[string][]string{KeySourceIP: {"10.1.2.3"}}}, ""},
+		// Test case - 4.
+		// List with the prefix.
+		{Args{"reader", s3action.ListObjectsAction, "bucket", "",
+			map[string][]string{KeySourceIP: {"10.1.2.3"}, KeyPrefix: {"photos/2023/"}}}, Allow},
+		// Test case - 5.
+		// List without prefix.
+		{Args{"reader", s3action.ListObjectsAction, "bucket", "",
+			map[string][]string{KeySourceIP: {"10.1.2.3"}, KeyPrefix: {""}}}, ""},
+		// Test case - 6.
+		// Read from outside network is denied.
+		{Args{"reader", s3action.GetObjectAction, "bucket", "photos/a.png",
+			map[string][]string{KeySourceIP: {"192.168.1.1"}}}, Deny},
+		// Test case - 7.
+		// Single ip is treated as host network.
+		{Args{"reader", s3action.GetObjectAction, "bucket", "photos/a.png",
+			map[string][]string{KeySourceIP: {"127.0.0.1"}}}, Allow},
+		// Test case - 8.
+		// Other principal.
+		{Args{"writer", s3action.GetObjectAction, "bucket", "photos/a.png",
+			map[string][]string{KeySourceIP: {"10.1.2.3"}}}, ""},
+	}
+
+	for i, testCase := range testCases {
+		effect := p.Evaluate(&testCase.args)
+		if effect != testCase.effect {
+			t.Errorf("Test %d: Expected effect %q, got %q", i+1, testCase.effect, effect)
+		}
+	}
+}
@@ -13,16 +13,22 @@ const (
 var rwActionMap = map[s3action.Action]struct{}{
 	s3action.ListObjectsAction:             {},
 	s3action.ListObjectsV2Action:           {},
+	s3action.ListObjectVersionsAction:      {},
 	s3action.HeadObjectAction:              {},
 	s3action.PutObjectAction:               {},
 	s3action.GetObjectAction:               {},
 	s3action.CopyObjectAction:              {},
 	s3action.DeleteObjectAction:            {},
 	s3action.DeleteObjectsAction:           {},
+	s3action.PutObjectTaggingAction:        {},
+	s3action.GetObjectTaggingAction:        {},
+	s3action.DeleteObjectTaggingAction:     {},
 	s3action.CreateMultipartUploadAction:   {},
 	s3action.AbortMultipartUploadAction:    {},
 	s3action.CompleteMultipartUploadAction: {},
 	s3action.UploadPartAction:              {},
+	s3action.ListMultipartUploadsAction:    {},
+	s3action.ListPartsAction:               {},
 }
 
 // checkActionInPublicReadWrite - returns whether action is RW or not.
@@ -32,10 +38,12 @@ func checkActionInPublicReadWrite(action s3action.Action) bool {
 }
 
 var rdActionMap = map[s3action.Action]struct{}{
-	s3action.ListObjectsAction:   {},
-	s3action.ListObjectsV2Action: {},
-	s3action.HeadObjectAction:    {},
-	s3action.GetObjectAction:     {},
+	s3action.ListObjectsAction:        {},
+	s3action.ListObjectsV2Action:      {},
+	s3action.ListObjectVersionsAction: {},
+	s3action.HeadObjectAction:         {},
+	s3action.GetObjectAction:          {},
+	s3action.GetObjectTaggingAction:   {},
 }
 
 // checkActionInPublicRead - returns whether action is Read or not.
@@ -2,6 +2,7 @@ package s3
 
 import (
 	config "github.com/bittorrent/go-btfs-config"
+	"github.com/bittorrent/go-btfs/keystore"
 	"github.com/bittorrent/go-btfs/s3/api/handlers"
 	"github.com/bittorrent/go-btfs/s3/api/providers"
 	"github.com/bittorrent/go-btfs/s3/api/routers"
@@ -17,20 +18,27 @@ import (
 var (
 	ps   *providers.Providers
 	once sync.Once
+
+	// global multiple keys read write lock
+	lock = ctxmu.NewDefaultMultiCtxRWMutex()
 )
 
-func InitProviders(stateStore storage.StateStorer) (err error) {
+func InitProviders(stateStore storage.StateStorer, keyStore keystore.Keystore) (err error) {
 	once.Do(func() {
 		var (
 			sstore providers.StateStorer
-			fstore providers.FileStorer
+			bapi   *providers.BtfsAPI
 		)
 		sstore = providers.NewStorageStateStoreProxy(stateStore)
-		fstore, err = providers.NewBtfsAPI()
+		bapi, err = providers.NewBtfsAPI()
 		if err != nil {
 			return
 		}
-		ps = providers.NewProviders(sstore, fstore)
+		ps = providers.NewProviders(
+			sstore, bapi,
+			providers.WithKeyStore(providers.NewNodeKeyStore(keyStore)),
+			providers.WithStorageUploader(bapi),
+		)
 	})
 	return
 }
@@ -39,15 +47,20 @@ func GetProviders() *providers.Providers {
 	return ps
 }
 
-func NewServer(cfg config.S3CompatibleAPI) *server.Server {
-	// global multiple keys read write lock
-	lock := ctxmu.NewDefaultMultiCtxRWMutex()
+func GetLock() ctxmu.MultiCtxRWLocker {
+	return lock
+}
 
+func NewServer(cfg config.S3CompatibleAPI) *server.Server {
 	// services
 	sigsvc := sign.NewService()
 	acksvc := accesskey.NewService(ps, accesskey.WithLock(lock))
 	objsvc := object.NewService(ps, object.WithLock(lock))
 
+	// workers
+	sweeper := object.NewLifecycleSweeper(ps, object.WithLock(lock))
+	pusher := object.NewStoragePusher(ps, object.WithLock(lock))
+
 	// handlers
 	hs := handlers.NewHandlers(
 		acksvc, sigsvc, objsvc,
@@ -61,6 +74,7 @@ func NewServer(cfg config.S3CompatibleAPI) *server.Server {
 	svr := server.NewServer(
 		rs,
 		server.WithAddress(cfg.Address),
+		server.WithWorkers(sweeper, pusher),
 	)
 
 	return svr
@@ -0,0 +1,230 @@
+// Package sse implements the server side encryption of the s3 object body.
+//
+// Every object has its own random key which is sealed by the key encryption
+// key, the customer provided key of SSE-C or the bucket data key of SSE-S3.
+// The body is encrypted with AES-256-CTR so the ciphertext has the same size
+// as the plaintext and any range of it can be decrypted independently. The
+// body of a multipart object is the concatenation of segments, every segment
+// is one part encrypted with its own iv.
+package sse
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// AlgorithmAES256 is the only supported encryption algorithm
+	AlgorithmAES256 = "AES256"
+
+	// TypeSSEC is the encryption with customer provided key
+	TypeSSEC = "SSE-C"
+
+	// TypeSSES3 is the encryption with node managed key
+	TypeSSES3 = "SSE-S3"
+
+	// KeySize is the size of all the keys
+	KeySize = 32
+
+	// IVSize is the size of the segment iv
+	IVSize = aes.BlockSize
+)
+
+var (
+	ErrKeySizeInvalid = errors.New("sse: key size is invalid")
+	ErrSealedInvalid  = errors.New("sse: sealed key is invalid")
+	ErrSegmentInvalid = errors.New("sse: segment is invalid")
+)
+
+// Segment is a continuous range of the body encrypted with the same iv
+type Segment struct {
+	Size int64  `json:"size"`
+	IV   []byte `json:"iv"`
+}
+
+// GenerateKey generate a random key
+func GenerateKey() (key []byte, err error) {
+	key = make([]byte, KeySize)
+	_, err = io.ReadFull(rand.Reader, key)
+	return
+}
+
+// GenerateIV generate a random segment iv
+func GenerateIV() (iv []byte, err error) {
+	iv = make([]byte, IVSize)
+	_, err = io.ReadFull(rand.Reader, iv)
+	return
+}
+
+// KeyMD5 return the base64 encoded md5 of the key
+func KeyMD5(key []byte) (sum string) {
+	h := md5.Sum(key)
+	sum = base64.StdEncoding.EncodeToString(h[:])
+	return
+}
+
+// Seal encrypt the key with the key encryption key by AES-256-GCM,
+// the random nonce is prepended to the sealed key
+func Seal(kek, key []byte) (sealed []byte, err error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return
+	}
+	sealed = aead.Seal(nonce, nonce, key, nil)
+	return
+}
+
+// Unseal decrypt the sealed key with the key encryption key, an error
+// is returned if the key encryption key is wrong
+func Unseal(kek, sealed []byte) (key []byte, err error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return
+	}
+	if len(sealed) < aead.NonceSize() {
+		err = ErrSealedInvalid
+		return
+	}
+	nonce, data := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	key, err = aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		err = ErrSealedInvalid
+	}
+	return
+}
+
+func newGCM(kek []byte) (aead cipher.AEAD, err error) {
+	if len(kek) != KeySize {
+		err = ErrKeySizeInvalid
+		return
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return
+	}
+	aead, err = cipher.NewGCM(block)
+	return
+}
+
+// EncryptReader return a reader of the ciphertext of r as one segment
+func EncryptReader(key, iv []byte, r io.Reader) (er io.Reader, err error) {
+	stream, err := newStream(key, iv, 0)
+	if err != nil {
+		return
+	}
+	er = &cipher.StreamReader{S: stream, R: r}
+	return
+}
+
+// DecryptReader return a reader of the plaintext of r, which is the ciphertext
+// of the segments starting at offset
+func DecryptReader(key []byte, segments []*Segment, offset int64, r io.Reader) (dr io.Reader, err error) {
+	if len(key) != KeySize {
+		err = ErrKeySizeInvalid
+		return
+	}
+	for _, seg := range segments {
+		if seg == nil || seg.Size < 0 || len(seg.IV) != IVSize {
+			err = ErrSegmentInvalid
+			return
+		}
+	}
+	dr = &segmentsReader{
+		key:      key,
+		segments: segments,
+		offset:   offset,
+		r:        r,
+	}
+	return
+}
+
+// newStream create the CTR stream of the segment positioned at offset
+func newStream(key, iv []byte, offset int64) (stream cipher.Stream, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+	if len(iv) != IVSize {
+		err = ErrSegmentInvalid
+		return
+	}
+
+	// Counter of the block where offset located
+	ctr := make([]byte, IVSize)
+	copy(ctr, iv)
+	hi := binary.BigEndian.Uint64(ctr[:8])
+	lo := binary.BigEndian.Uint64(ctr[8:])
+	blocks := uint64(offset / IVSize)
+	if lo+blocks < lo {
+		hi++
+	}
+	lo += blocks
+	binary.BigEndian.PutUint64(ctr[:8], hi)
+	binary.BigEndian.PutUint64(ctr[8:], lo)
+	stream = cipher.NewCTR(block, ctr)
+
+	// Skip the key stream before offset in the block
+	skip := make([]byte, offset%IVSize)
+	stream.XORKeyStream(skip, skip)
+
+	return
+}
+
+// segmentsReader decrypt the segments continuously, the stream will be
+// switched to the next segment at the boundary
+type segmentsReader struct {
+	key      []byte
+	segments []*Segment
+	offset   int64
+	r        io.Reader
+	stream   cipher.Stream
+	remain   int64
+}
+
+func (sr *segmentsReader) Read(p []byte) (n int, err error) {
+	if sr.remain == 0 {
+		err = sr.next()
+		if err != nil {
+			return
+		}
+	}
+	if int64(len(p)) > sr.remain {
+		p = p[:sr.remain]
+	}
+	n, err = sr.r.Read(p)
+	sr.stream.XORKeyStream(p[:n], p[:n])
+	sr.remain -= int64(n)
+	return
+}
+
+// next locate the segment at current offset and create its stream
+func (sr *segmentsReader) next() (err error) {
+	for len(sr.segments) > 0 {
+		seg := sr.segments[0]
+		sr.segments = sr.segments[1:]
+		if sr.offset >= seg.Size {
+			sr.offset -= seg.Size
+			continue
+		}
+		sr.stream, err = newStream(sr.key, seg.IV, sr.offset)
+		if err != nil {
+			return
+		}
+		sr.remain = seg.Size - sr.offset
+		sr.offset = 0
+		return
+	}
+	err = io.EOF
+	return
+}
@@ -0,0 +1,94 @@
+package sse
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSealUnseal(t *testing.T) {
+	kek, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := Seal(kek, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsealed, err := Unseal(kek, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, unsealed) {
+		t.Fatal("Unsealed key doesn't match")
+	}
+
+	other, _ := GenerateKey()
+	if _, err = Unseal(other, sealed); err != ErrSealedInvalid {
+		t.Fatalf("Expected error %v, got %v", ErrSealedInvalid, err)
+	}
+}
+
+func TestDecryptSegments(t *testing.T) {
+	key, _ := GenerateKey()
+	plain := make([]byte, 1000)
+	for i := range plain {
+		plain[i] = byte(i)
+	}
+
+	// Encrypt the body as three segments
+	sizes := []int64{100, 517, 383}
+	var (
+		segments []*Segment
+		cipher   []byte
+		start    int64
+	)
+	for _, size := range sizes {
+		iv, _ := GenerateIV()
+		er, err := EncryptReader(key, iv, bytes.NewReader(plain[start:start+size]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(er)
+		cipher = append(cipher, data...)
+		segments = append(segments, &Segment{Size: size, IV: iv})
+		start += size
+	}
+
+	testCases := []struct {
+		offset int64
+		length int64
+	}{
+		// Test case - 1.
+		// The whole body.
+		{0, 1000},
+		// Test case - 2.
+		// Range not aligned to the block in the first segment.
+		{7, 50},
+		// Test case - 3.
+		// Range across all the segments.
+		{99, 802},
+		// Test case - 4.
+		// Range at the end of body.
+		{995, 5},
+	}
+
+	for i, testCase := range testCases {
+		end := testCase.offset + testCase.length
+		dr, err := DecryptReader(key, segments, testCase.offset, bytes.NewReader(cipher[testCase.offset:end]))
+		if err != nil {
+			t.Fatalf("Test %d: %v", i+1, err)
+		}
+		data, err := io.ReadAll(dr)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i+1, err)
+		}
+		if !bytes.Equal(data, plain[testCase.offset:end]) {
+			t.Errorf("Test %d: Decrypted data doesn't match", i+1)
+		}
+	}
+}
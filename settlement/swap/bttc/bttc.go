@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/ledger"
 	"github.com/bittorrent/go-btfs/settlement/swap/erc20"
 	"github.com/bittorrent/go-btfs/transaction"
 	"github.com/ethereum/go-ethereum/common"
@@ -59,6 +61,9 @@ func (svc *service) SwapBtt2Wbtt(ctx context.Context, amount *big.Int) (trx comm
 		return zeroHash, ErrInsufficientBTT
 	}
 	trx, err = svc.erc20Service.Deposit(ctx, amount)
+	if err == nil {
+		recordSwap(ledger.BTT, tokencfg.WBTT, amount, trx)
+	}
 	return
 }
 
@@ -76,6 +81,9 @@ func (svc *service) SwapWbtt2Btt(ctx context.Context, amount *big.Int) (trx comm
 		return zeroHash, ErrInsufficientWBTT
 	}
 	trx, err = svc.erc20Service.Withdraw(ctx, amount)
+	if err == nil {
+		recordSwap(tokencfg.WBTT, ledger.BTT, amount, trx)
+	}
 	return
 }
 
@@ -94,6 +102,9 @@ func (svc *service) SendWbttTo(ctx context.Context, to common.Address, amount *b
 		return zeroHash, ErrInsufficientWBTT
 	}
 	trx, err = svc.erc20Service.Transfer(ctx, to, amount)
+	if err == nil {
+		recordTransfer(tokencfg.WBTT, to, amount, trx)
+	}
 	return
 }
 
@@ -112,6 +123,9 @@ func (svc *service) SendTokenTo(ctx context.Context, to common.Address, amount *
 		return zeroHash, ErrInsufficientWBTT
 	}
 	trx, err = svc.mpErc20Service[tokenStr].Transfer(ctx, to, amount)
+	if err == nil {
+		recordTransfer(tokenStr, to, amount, trx)
+	}
 	return
 }
 
@@ -138,9 +152,45 @@ func (svc *service) SendBttTo(ctx context.Context, to common.Address, amount *bi
 		Description: fmt.Sprintf("send %d btt to %s", amount, to),
 	}
 	trx, err = svc.trxService.Send(ctx, req)
+	if err == nil {
+		recordTransfer(ledger.BTT, to, amount, trx)
+	}
 	return
 }
 
+// recordSwap records the swap of the amount in the wallet once it is mined,
+// both tokens go through the exchange account
+func recordSwap(from, to string, amount *big.Int, trx common.Hash) {
+	ledger.RecordPending(trx, &ledger.Entry{
+		Type:   ledger.TypeSwap,
+		Debit:  ledger.AccountExchange,
+		Credit: ledger.AccountWallet,
+		Token:  from,
+		Amount: amount,
+		TxHash: trx.String(),
+	}, &ledger.Entry{
+		Type:   ledger.TypeSwap,
+		Debit:  ledger.AccountWallet,
+		Credit: ledger.AccountExchange,
+		Token:  to,
+		Amount: amount,
+		TxHash: trx.String(),
+	})
+}
+
+// recordTransfer records the transfer from the wallet once it is mined
+func recordTransfer(token string, to common.Address, amount *big.Int, trx common.Hash) {
+	ledger.RecordPending(trx, &ledger.Entry{
+		Type:         ledger.TypeTransfer,
+		Debit:        ledger.AccountExternal,
+		Credit:       ledger.AccountWallet,
+		Token:        token,
+		Amount:       amount,
+		Counterparty: to.String(),
+		TxHash:       trx.String(),
+	})
+}
+
 func validateBeforeTransfer(ctx context.Context, from, to common.Address, amount *big.Int) error {
 	if amount.Cmp(zeroBig) <= 0 {
 		return errors.New("amount should bigger than zero")
@@ -9,6 +9,8 @@ import (
 	"math/big"
 	"sync"
 
+	"github.com/bittorrent/go-btfs/ledger"
+	storagemetrics "github.com/bittorrent/go-btfs/metrics"
 	"github.com/bittorrent/go-btfs/settlement"
 	"github.com/bittorrent/go-btfs/settlement/swap/swapprotocol"
 	"github.com/bittorrent/go-btfs/settlement/swap/vault"
@@ -123,6 +125,15 @@ func (s *Service) ReceiveCheque(ctx context.Context, peer string, cheque *vault.
 	tot, _ := big.NewFloat(0).SetInt(receivedAmount).Float64()
 	s.metrics.TotalReceived.Add(tot)
 	s.metrics.ChequesReceived.Inc()
+	storagemetrics.ChequeReceivedAmount.WithLabelValues(tokencfg.MpTokenStr[token]).Add(tot)
+	ledger.Record(&ledger.Entry{
+		Type:         ledger.TypeChequeReceived,
+		Debit:        ledger.AccountReceivable,
+		Credit:       ledger.AccountExternal,
+		Token:        ledger.TokenName(token),
+		Amount:       receivedAmount,
+		Counterparty: peer,
+	})
 
 	// total received count
 	totalReceivedCount, err := s.vault.TotalReceivedCount(token)
@@ -180,6 +191,16 @@ func (s *Service) Pay(ctx context.Context, peer string, amount *big.Int, contrac
 	amountFloat, _ := big.NewFloat(0).SetInt(amount).Float64()
 	s.metrics.TotalSent.Add(amountFloat)
 	s.metrics.ChequesSent.Inc()
+	storagemetrics.ChequeSentAmount.WithLabelValues(tokencfg.MpTokenStr[token]).Add(amountFloat)
+	ledger.Record(&ledger.Entry{
+		Type:         ledger.TypeChequeSent,
+		Debit:        ledger.AccountExternal,
+		Credit:       ledger.AccountVault,
+		Token:        ledger.TokenName(token),
+		Amount:       amount,
+		Counterparty: peer,
+		Reference:    contractId,
+	})
 }
 
 func (s *Service) SetAccounting(accounting settlement.Accounting) {
@@ -9,6 +9,8 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/bittorrent/go-btfs/ledger"
+	"github.com/bittorrent/go-btfs/metrics"
 	"github.com/bittorrent/go-btfs/statestore"
 	"github.com/bittorrent/go-btfs/transaction"
 	"github.com/bittorrent/go-btfs/transaction/storage"
@@ -209,6 +211,7 @@ func (s *cashoutService) CashCheque(ctx context.Context, vault, recipient common
 	//}
 
 	// 2.3.0 import
+	sentAt := time.Now()
 	txHash, err := _CashChequeMuti(ctx, vault, recipient, cheque, s.transactionService, token)
 	if err != nil {
 		return common.Hash{}, err
@@ -234,6 +237,8 @@ func (s *cashoutService) CashCheque(ctx context.Context, vault, recipient common
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// the gas of the cashout, its amount is recorded with the result below
+	ledger.RecordPending(txHash)
 
 	// WaitForReceipt takes long time
 	go func() {
@@ -242,7 +247,7 @@ func (s *cashoutService) CashCheque(ctx context.Context, vault, recipient common
 				log.Errorf("storeCashResult recovered:%+v", err)
 			}
 		}()
-		s.storeCashResult(context.Background(), vault, txHash, cheque, token)
+		s.storeCashResult(context.Background(), vault, txHash, cheque, token, sentAt)
 
 		// 2.delete cash out status
 		err = s.DeleteCashOutStatusStore(cashOutStateInfo)
@@ -254,7 +259,7 @@ func (s *cashoutService) CashCheque(ctx context.Context, vault, recipient common
 	return txHash, nil
 }
 
-func (s *cashoutService) storeCashResult(ctx context.Context, vault common.Address, txHash common.Hash, cheque *SignedCheque, token common.Address) error {
+func (s *cashoutService) storeCashResult(ctx context.Context, vault common.Address, txHash common.Hash, cheque *SignedCheque, token common.Address, sentAt time.Time) error {
 	cashResult := CashOutResult{
 		TxHash:   txHash,
 		Vault:    vault,
@@ -324,6 +329,24 @@ func (s *cashoutService) storeCashResult(ctx context.Context, vault common.Addre
 			}
 		}
 	}
+	result := metrics.ResultFailure
+	if cashResult.Status == "success" {
+		result = metrics.ResultSuccess
+	}
+	metrics.CashoutLatency.WithLabelValues(tokencfg.MpTokenStr[token], result).Observe(time.Since(sentAt).Seconds())
+	if cashResult.Status == "success" {
+		// the cheques are cashed into the vault of this node
+		ledger.Record(&ledger.Entry{
+			Type:         ledger.TypeCashout,
+			Debit:        ledger.AccountVault,
+			Credit:       ledger.AccountReceivable,
+			Token:        ledger.TokenName(token),
+			Amount:       cashResult.Amount,
+			Counterparty: vault.String(),
+			TxHash:       txHash.String(),
+		})
+	}
+
 	err = s.store.Put(statestore.CashoutResultKey(vault), &cashResult)
 	if err != nil {
 		log.Infof("CashOutStats:put cashoutResultKey err:%+v", err)
@@ -512,6 +535,20 @@ func (s *cashoutService) fixStoreCashResult(vault common.Address, shouldPaidOut
 		}
 	}
 
+	// the cashouts not recorded when their results were stored
+	entry := &ledger.Entry{
+		Type:         ledger.TypeCashout,
+		Debit:        ledger.AccountVault,
+		Credit:       ledger.AccountReceivable,
+		Token:        ledger.TokenName(token),
+		Amount:       shouldPaidOut,
+		Counterparty: vault.String(),
+	}
+	if txHash != (common.Hash{}) {
+		entry.TxHash = txHash.String()
+	}
+	ledger.Record(entry)
+
 	err = s.store.Put(statestore.CashoutResultKey(vault), &cashResult)
 	if err != nil {
 		log.Infof("fixStoreCashResult:put cashoutResultKey err:%+v", err)
@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	conabi "github.com/bittorrent/go-btfs/chain/abi"
+	"github.com/bittorrent/go-btfs/ledger"
 	"github.com/bittorrent/go-btfs/settlement/swap/erc20"
 	"github.com/bittorrent/go-btfs/statestore"
 	"github.com/bittorrent/go-btfs/transaction"
@@ -139,7 +140,18 @@ func (s *service) Deposit(ctx context.Context, amount *big.Int, token common.Add
 	//	return common.Hash{}, ErrInsufficientFunds
 	//}
 
-	return s.contract.Deposit(ctx, amount, token)
+	hash, err = s.contract.Deposit(ctx, amount, token)
+	if err == nil {
+		ledger.RecordPending(hash, &ledger.Entry{
+			Type:   ledger.TypeVaultDeposit,
+			Debit:  ledger.AccountVault,
+			Credit: ledger.AccountWallet,
+			Token:  ledger.TokenName(token),
+			Amount: amount,
+			TxHash: hash.String(),
+		})
+	}
+	return hash, err
 }
 
 // Deposit starts depositing erc20 token into the vault. This returns once the transactions has been broadcast.
@@ -551,7 +563,18 @@ func (s *service) Withdraw(ctx context.Context, amount *big.Int, token common.Ad
 		return common.Hash{}, ErrInsufficientFunds
 	}
 
-	return s.contract.Withdraw(ctx, amount, token)
+	hash, err = s.contract.Withdraw(ctx, amount, token)
+	if err == nil {
+		ledger.RecordPending(hash, &ledger.Entry{
+			Type:   ledger.TypeVaultWithdraw,
+			Debit:  ledger.AccountWallet,
+			Credit: ledger.AccountVault,
+			Token:  ledger.TokenName(token),
+			Amount: amount,
+			TxHash: hash.String(),
+		})
+	}
+	return hash, err
 }
 
 func (s *service) WBTTBalanceOf(ctx context.Context, addr common.Address) (*big.Int, error) {
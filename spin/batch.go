@@ -0,0 +1,45 @@
+package spin
+
+import (
+	"time"
+
+	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/upload"
+	"github.com/bittorrent/go-btfs/settlement/swap/swapprotocol"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/bittorrent/go-btfs/core"
+)
+
+const (
+	renterBatchUploadPeriod  = 30 * time.Second
+	renterBatchUploadTimeout = 5 * time.Minute
+)
+
+// BatchUploads loads the batch upload jobs of this renter and keeps running them
+func BatchUploads(n *core.IpfsNode, req *cmds.Request, env cmds.Environment) {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		log.Errorf("Failed to get configuration %s", err)
+		return
+	}
+	if !cfg.Experimental.StorageClientEnabled {
+		return
+	}
+	ctxParams, err := uh.ExtractContextParams(req, env)
+	if err != nil {
+		log.Errorf("Failed to extract context parameters %s", err)
+		return
+	}
+	q, err := upload.StartBatchQueue(ctxParams)
+	if err != nil {
+		log.Errorf("Failed to start batch upload queue %s", err)
+		return
+	}
+	// the sessions started by the queue pay the hosts without an upload request
+	if swapprotocol.Req == nil {
+		swapprotocol.Req = req
+		swapprotocol.Env = env
+	}
+	go periodicSync(renterBatchUploadPeriod, renterBatchUploadTimeout, "renter batch upload", q.Process)
+}
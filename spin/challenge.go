@@ -0,0 +1,41 @@
+package spin
+
+import (
+	"context"
+	"time"
+
+	"github.com/bittorrent/go-btfs/core/commands/storage/challenge"
+	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/bittorrent/go-btfs/core"
+)
+
+const (
+	renterChallengeAuditPeriod  = 6 * 60 * time.Minute
+	renterChallengeAuditTimeout = 60 * time.Minute
+)
+
+// ChallengeAudit periodically challenges the hosts storing the files uploaded
+// by this renter and records the results to the challenge history
+func ChallengeAudit(n *core.IpfsNode, req *cmds.Request, env cmds.Environment) {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		log.Errorf("Failed to get configuration %s", err)
+		return
+	}
+	if !cfg.Experimental.StorageClientEnabled {
+		return
+	}
+	ctxParams, err := uh.ExtractContextParams(req, env)
+	if err != nil {
+		log.Errorf("Failed to extract context parameters %s", err)
+		return
+	}
+	go periodicSync(renterChallengeAuditPeriod, renterChallengeAuditTimeout, "renter challenges",
+		func(ctx context.Context) error {
+			params := *ctxParams
+			params.Ctx = ctx
+			return challenge.AuditSessions(&params)
+		})
+}
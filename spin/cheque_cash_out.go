@@ -1,9 +1,40 @@
 package spin
 
 import (
+	"context"
+	"time"
+
 	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/core"
+	"github.com/bittorrent/go-btfs/core/commands/cheque"
+)
+
+const (
+	hostAutoCashPeriod  = 1 * time.Hour
+	hostAutoCashTimeout = 30 * time.Minute
 )
 
 func RestartFixChequeCashOut() {
 	chain.SettleObject.CashoutService.RestartFixChequeCashOut()
 }
+
+// AutoCashCheques cashes out the cheques received by the host by the auto
+// cashout config
+func AutoCashCheques(n *core.IpfsNode) {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		log.Errorf("Failed to get configuration %s", err)
+		return
+	}
+	if !cfg.Experimental.StorageHostEnabled {
+		return
+	}
+	go periodicSync(hostAutoCashPeriod, hostAutoCashTimeout, "host auto cashout",
+		func(ctx context.Context) error {
+			err := cheque.AutoCashCheques(ctx)
+			if err != nil {
+				log.Errorf("Failed to cash out cheques automatically: %v", err)
+			}
+			return err
+		})
+}
@@ -0,0 +1,114 @@
+package spin
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+	"github.com/bittorrent/go-btfs/chain/tokencfg"
+	"github.com/bittorrent/go-btfs/core"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
+	"github.com/bittorrent/go-btfs/metrics"
+	"github.com/bittorrent/go-btfs/protos/metadata"
+
+	nodepb "github.com/bittorrent/go-btfs-common/protos/node"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	storageMetricsPeriod  = 5 * time.Minute
+	storageMetricsTimeout = 2 * time.Minute
+)
+
+// StorageMetrics keeps the gauges of the contracts, the upload sessions and the
+// vault balances up to date
+func StorageMetrics(n *core.IpfsNode) {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		log.Errorf("Failed to get configuration %s", err)
+		return
+	}
+	if !cfg.Experimental.StorageHostEnabled && !cfg.Experimental.StorageClientEnabled {
+		return
+	}
+	go periodicSync(storageMetricsPeriod, storageMetricsTimeout, "storage metrics",
+		func(ctx context.Context) error {
+			err := refreshStorageMetrics(ctx, n)
+			if err != nil {
+				log.Errorf("Failed to refresh storage metrics: %v", err)
+			}
+			return err
+		})
+}
+
+type contractLabels struct {
+	role, token, state string
+}
+
+func refreshStorageMetrics(ctx context.Context, n *core.IpfsNode) error {
+	d := n.Repo.Datastore()
+	peerId := n.Identity.String()
+
+	now := time.Now().Unix()
+	contracts := make(map[contractLabels]int)
+	for _, role := range []string{nodepb.ContractStat_HOST.String(), nodepb.ContractStat_RENTER.String()} {
+		cs, err := sessions.ListShardsContracts(d, peerId, role)
+		if err != nil {
+			return err
+		}
+		for _, c := range cs {
+			// the contracts of the aborted sessions are never active
+			if c.Status == metadata.Contract_CLOSED {
+				continue
+			}
+			state := metrics.ContractActive
+			if now > int64(c.Meta.StorageEnd) {
+				state = metrics.ContractExpired
+			}
+			contracts[contractLabels{strings.ToLower(role), tokenName(c.Meta.Token), state}]++
+		}
+	}
+	metrics.Contracts.Reset()
+	for l, count := range contracts {
+		metrics.Contracts.WithLabelValues(l.role, l.token, l.state).Set(float64(count))
+	}
+
+	counts, err := sessions.CountRenterSessions(d, peerId)
+	if err != nil {
+		return err
+	}
+	metrics.Sessions.Reset()
+	for status, count := range counts {
+		metrics.Sessions.WithLabelValues(status).Set(float64(count))
+	}
+
+	if chain.SettleObject.VaultService == nil {
+		return nil
+	}
+	for name, token := range tokencfg.MpTokenAddr {
+		total, err := chain.SettleObject.VaultService.TotalBalance(ctx, token)
+		if err != nil {
+			return err
+		}
+		available, err := chain.SettleObject.VaultService.AvailableBalance(ctx, token)
+		if err != nil {
+			return err
+		}
+		metrics.VaultBalance.WithLabelValues(name, "total").Set(metrics.Float(total))
+		metrics.VaultBalance.WithLabelValues(name, "available").Set(metrics.Float(available))
+	}
+	return nil
+}
+
+// tokenName returns the name of the token of the contract, the contracts
+// without token are paid in WBTT
+func tokenName(token string) string {
+	if token == "" {
+		return tokencfg.WBTT
+	}
+	if name, ok := tokencfg.MpTokenStr[common.HexToAddress(token)]; ok {
+		return name
+	}
+	return token
+}
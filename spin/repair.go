@@ -0,0 +1,42 @@
+package spin
+
+import (
+	"context"
+	"time"
+
+	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/upload"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/bittorrent/go-btfs/core"
+)
+
+const (
+	renterShardRepairPeriod  = 12 * 60 * time.Minute
+	renterShardRepairTimeout = 60 * time.Minute
+)
+
+// ShardRepair periodically checks the shards of the files uploaded by this
+// renter and repairs the lost ones
+func ShardRepair(n *core.IpfsNode, req *cmds.Request, env cmds.Environment) {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		log.Errorf("Failed to get configuration %s", err)
+		return
+	}
+	if !cfg.Experimental.StorageClientEnabled {
+		return
+	}
+	ctxParams, err := uh.ExtractContextParams(req, env)
+	if err != nil {
+		log.Errorf("Failed to extract context parameters %s", err)
+		return
+	}
+	go periodicSync(renterShardRepairPeriod, renterShardRepairTimeout, "renter shard repair",
+		func(ctx context.Context) error {
+			params := *ctxParams
+			params.Ctx = ctx
+			// the repairs outlive the round, they stop with the daemon
+			return upload.RepairSessions(&params, ctxParams.Ctx)
+		})
+}
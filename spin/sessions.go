@@ -1,30 +1,60 @@
 package spin
 
 import (
+	"errors"
+
 	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/sessions"
 	"github.com/bittorrent/go-btfs/core/commands/storage/upload/upload"
+	"github.com/bittorrent/go-btfs/settlement/swap/swapprotocol"
 
 	cmds "github.com/bittorrent/go-btfs-cmds"
 
 	"go4.org/syncutil"
 )
 
+// max number of the resumed sessions running at a time
+const resumedSessionConcurrency = 10
+
+// unfinished statuses of the renter sessions resumed on startup
+var resumedSessionStatuses = []string{
+	sessions.RssInitStatus,
+	sessions.RssSubmitStatus,
+	sessions.RssContractStatus,
+	sessions.RssGuardFileMetaSignedStatus,
+	sessions.RssFileMetaAddedStatus,
+	sessions.RssWaitUploadStatus,
+	sessions.RssWaitUploadReqSignedStatus,
+	sessions.RssPayStatus,
+}
+
+// RenterSessions resumes the upload sessions interrupted by the daemon restart,
+// the sessions which cannot be resumed are aborted. The next session is not
+// resumed until one of the running ones completes or fails, if there are
+// resumedSessionConcurrency ones running.
 func RenterSessions(req *cmds.Request, env cmds.Environment) {
 	go func() {
 		params, err := uh.ExtractContextParams(req, env)
 		if err != nil {
 			return
 		}
+		if !params.Cfg.Experimental.StorageClientEnabled {
+			return
+		}
+		// the resumed sessions pay the hosts without an upload request
+		if swapprotocol.Req == nil {
+			swapprotocol.Req = req
+			swapprotocol.Env = env
+		}
 
 		cursor, err := sessions.GetRenterSessionsCursor(params)
 		if err != nil {
 			return
 		}
-		// Limit to 10 at a time to lower resource consumption
-		sem := syncutil.NewSem(10)
+		// Limit the sessions running at a time to lower resource consumption
+		sem := syncutil.NewSem(resumedSessionConcurrency)
 		for {
-			session, err := cursor.NextSession(sessions.RssWaitUploadReqSignedStatus)
+			session, err := cursor.NextSession(resumedSessionStatuses...)
 			if err != nil {
 				break
 			}
@@ -32,11 +62,19 @@ func RenterSessions(req *cmds.Request, env cmds.Environment) {
 				break
 			}
 			sem.Acquire(1)
+			err = upload.ResumeSession(params, session.SsId)
+			if errors.Is(err, upload.ErrSessionNotResumable) || errors.Is(err, upload.ErrRepairInterrupted) {
+				err = upload.AbortSession(params, session.SsId, err)
+			}
+			if err != nil {
+				log.Errorf("Failed to resume upload session %s: %v", session.SsId, err)
+				sem.Release(1)
+				continue
+			}
+			// the session is cancelled once it completes or fails
 			go func(session *sessions.RenterSession) {
 				defer sem.Release(1)
-				if e := upload.ResumeWaitUploadOnSigning(session); e != nil {
-					return
-				}
+				<-session.Ctx.Done()
 			}(session)
 		}
 	}()
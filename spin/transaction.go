@@ -0,0 +1,34 @@
+package spin
+
+import (
+	"context"
+	"time"
+
+	"github.com/bittorrent/go-btfs/chain"
+)
+
+const (
+	transactionBumpPeriod  = 1 * time.Minute
+	transactionBumpTimeout = 30 * time.Second
+)
+
+// TransactionBumper periodically resends the transactions pending for the
+// blocks of the gas strategy at a higher gas price
+func TransactionBumper() {
+	go periodicSync(transactionBumpPeriod, transactionBumpTimeout, "transaction bumper",
+		func(ctx context.Context) error {
+			txService := chain.ChainObject.TransactionService
+			if txService == nil {
+				return nil
+			}
+			bumped, err := txService.BumpStuckTransactions(ctx)
+			if err != nil {
+				log.Errorf("Failed to bump stuck transactions: %v", err)
+				return err
+			}
+			for old, replacement := range bumped {
+				log.Infof("Bumped stuck transaction %x, replaced by %x", old, replacement)
+			}
+			return nil
+		})
+}
@@ -0,0 +1,45 @@
+package spin
+
+import (
+	"context"
+	"time"
+
+	uh "github.com/bittorrent/go-btfs/core/commands/storage/upload/helper"
+	"github.com/bittorrent/go-btfs/core/commands/storage/upload/upload"
+
+	cmds "github.com/bittorrent/go-btfs-cmds"
+	"github.com/bittorrent/go-btfs/core"
+)
+
+const (
+	renterVaultGuardPeriod  = 10 * time.Minute
+	renterVaultGuardTimeout = 5 * time.Minute
+)
+
+// VaultGuard periodically checks the vault balance against the storage
+// commitments of this renter, warns and tops it up
+func VaultGuard(n *core.IpfsNode, req *cmds.Request, env cmds.Environment) {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		log.Errorf("Failed to get configuration %s", err)
+		return
+	}
+	if !cfg.Experimental.StorageClientEnabled {
+		return
+	}
+	ctxParams, err := uh.ExtractContextParams(req, env)
+	if err != nil {
+		log.Errorf("Failed to extract context parameters %s", err)
+		return
+	}
+	go periodicSync(renterVaultGuardPeriod, renterVaultGuardTimeout, "renter vault guard",
+		func(ctx context.Context) error {
+			params := *ctxParams
+			params.Ctx = ctx
+			err := upload.GuardVaultBalance(&params)
+			if err != nil {
+				log.Errorf("Failed to guard vault balance: %v", err)
+			}
+			return err
+		})
+}
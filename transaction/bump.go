@@ -0,0 +1,230 @@
+package transaction
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bittorrent/go-btfs/transaction/sctx"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"golang.org/x/net/context"
+)
+
+const replacedTransactionPrefix = "transaction_replaced_"
+
+var ErrTransactionNotPending = errors.New("transaction is not pending")
+
+func replacedTransactionKey(txHash common.Hash) string {
+	return replacedTransactionPrefix + txHash.Hex()
+}
+
+// replacement returns the transaction replacing the bumped one
+func (t *transactionService) replacement(txHash common.Hash) (common.Hash, bool, error) {
+	var replacement common.Hash
+	err := t.store.Get(replacedTransactionKey(txHash), &replacement)
+	if errors.Is(err, storage.ErrNotFound) {
+		return common.Hash{}, false, nil
+	}
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+	return replacement, true, nil
+}
+
+// blockNumber returns the current block number, or 0 if it is unknown
+func (t *transactionService) blockNumber(ctx context.Context) uint64 {
+	block, err := t.backend.BlockNumber(ctx)
+	if err != nil {
+		return 0
+	}
+	return block
+}
+
+func (t *transactionService) BumpTransaction(ctx context.Context, txHash common.Hash) (common.Hash, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	err := t.store.Get(pendingTransactionKey(txHash), &struct{}{})
+	if errors.Is(err, storage.ErrNotFound) {
+		return common.Hash{}, ErrTransactionNotPending
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+	storedTransaction, err := t.StoredTransaction(txHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	strategy, err := t.GasStrategy()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	minGasPrice, err := strategy.bumpedGasPrice(storedTransaction.GasPrice)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	gasPrice := sctx.GetGasPrice(ctx)
+	if gasPrice == nil {
+		gasPrice = minGasPrice
+	} else if gasPrice.Cmp(minGasPrice) < 0 {
+		return common.Hash{}, ErrGasPriceTooLow
+	}
+
+	var tx *types.Transaction
+	if storedTransaction.To != nil {
+		tx = types.NewTransaction(
+			storedTransaction.Nonce,
+			*storedTransaction.To,
+			storedTransaction.Value,
+			storedTransaction.GasLimit,
+			gasPrice,
+			storedTransaction.Data,
+		)
+	} else {
+		tx = types.NewContractCreation(
+			storedTransaction.Nonce,
+			storedTransaction.Value,
+			storedTransaction.GasLimit,
+			gasPrice,
+			storedTransaction.Data,
+		)
+	}
+	signedTx, err := t.signer.SignTx(tx, t.chainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	logTran.Infof("bumping transaction %x with nonce %d to gas price %d", txHash, storedTransaction.Nonce, gasPrice)
+
+	err = t.backend.SendTransaction(ctx, signedTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	newTxHash := signedTx.Hash()
+	err = t.store.Put(storedTransactionKey(newTxHash), StoredTransaction{
+		To:          signedTx.To(),
+		Data:        signedTx.Data(),
+		GasPrice:    signedTx.GasPrice(),
+		GasLimit:    signedTx.Gas(),
+		Value:       signedTx.Value(),
+		Nonce:       signedTx.Nonce(),
+		Created:     time.Now().Unix(),
+		Block:       t.blockNumber(ctx),
+		Description: storedTransaction.Description,
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	err = t.store.Put(replacedTransactionKey(txHash), newTxHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	err = t.store.Put(pendingTransactionKey(newTxHash), struct{}{})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// the replaced transaction is watched until it is cancelled by the
+	// replacement, but it is no longer listed as pending
+	err = t.store.Delete(pendingTransactionKey(txHash))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	t.waitForPendingTx(newTxHash)
+
+	return newTxHash, nil
+}
+
+// BumpStuckTransactions bumps the pending transactions sent at least the
+// blocks of the gas strategy ago, and returns the replacements of the bumped
+// ones
+func (t *transactionService) BumpStuckTransactions(ctx context.Context) (map[common.Hash]common.Hash, error) {
+	strategy, err := t.GasStrategy()
+	if err != nil {
+		return nil, err
+	}
+	if strategy.BumpAfterBlocks == 0 {
+		return nil, nil
+	}
+	block, err := t.backend.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the transactions of lower nonce are mined already or being replaced
+	nonce, err := t.backend.NonceAt(ctx, t.sender, nil)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := t.PendingTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	bumped := make(map[common.Hash]common.Hash)
+	for _, txHash := range pending {
+		storedTransaction, err := t.StoredTransaction(txHash)
+		if err != nil {
+			return nil, err
+		}
+		if storedTransaction.Nonce < nonce {
+			continue
+		}
+		sentAt := storedTransaction.Block
+		if sentAt == 0 {
+			sentAt = t.firstSeenAt(txHash, block)
+		}
+		if block < sentAt+strategy.BumpAfterBlocks {
+			continue
+		}
+		newTxHash, err := t.BumpTransaction(ctx, txHash)
+		if errors.Is(err, ErrGasPriceCapReached) {
+			logTran.Warnf("transaction %x pending since block %d cannot be bumped: %v", txHash, sentAt, err)
+			continue
+		}
+		if err != nil {
+			logTran.Errorf("failed to bump transaction %x: %v", txHash, err)
+			continue
+		}
+		bumped[txHash] = newTxHash
+	}
+	t.forgetSeen(pending)
+	return bumped, nil
+}
+
+// firstSeenAt returns the block the pending transaction of unknown block was
+// first seen at
+func (t *transactionService) firstSeenAt(txHash common.Hash, block uint64) uint64 {
+	t.seenLock.Lock()
+	defer t.seenLock.Unlock()
+	if seen, ok := t.seenAt[txHash]; ok {
+		return seen
+	}
+	t.seenAt[txHash] = block
+	return block
+}
+
+// forgetSeen drops the transactions no longer pending
+func (t *transactionService) forgetSeen(pending []common.Hash) {
+	t.seenLock.Lock()
+	defer t.seenLock.Unlock()
+	keep := make(map[common.Hash]struct{}, len(pending))
+	for _, txHash := range pending {
+		keep[txHash] = struct{}{}
+	}
+	for txHash := range t.seenAt {
+		if _, ok := keep[txHash]; !ok {
+			delete(t.seenAt, txHash)
+		}
+	}
+}
+
+// PendingBlocks returns the number of the blocks the transaction has been
+// pending for, 0 if it is unknown
+func PendingBlocks(stored *StoredTransaction, block uint64) uint64 {
+	if stored.Block == 0 || block < stored.Block {
+		return 0
+	}
+	return block - stored.Block
+}
@@ -3,7 +3,8 @@ package transaction
 import "time"
 
 var (
-	StoredTransactionKey = storedTransactionKey
+	StoredTransactionKey  = storedTransactionKey
+	PendingTransactionKey = pendingTransactionKey
 )
 
 func (s *Matcher) SetTimeNow(f func() time.Time) {
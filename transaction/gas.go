@@ -0,0 +1,127 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"golang.org/x/net/context"
+)
+
+const gasStrategyKey = "transaction_gas_strategy"
+
+// modes of the gas strategy
+const (
+	// GasModeFixed always uses the price of the strategy
+	GasModeFixed = "fixed"
+	// GasModeSuggested uses the suggested price times the multiplier
+	GasModeSuggested = "suggested"
+	// GasModeCapped uses the suggested price times the multiplier, but no
+	// more than the price of the strategy
+	GasModeCapped = "capped"
+)
+
+const (
+	defaultGasMultiplier = 100
+	// nodes accept a replacement of a pending transaction only if its gas
+	// price is at least 10% higher
+	minBumpPercent = 10
+)
+
+var (
+	ErrGasPriceCapReached = errors.New("gas price cap reached")
+)
+
+// GasStrategy decides the gas prices of the transactions sent without one, and
+// when and how much the stuck transactions are bumped
+type GasStrategy struct {
+	Mode string `json:"mode"`
+	// the fixed price, or the max price in capped mode
+	Price *big.Int `json:"price,omitempty"`
+	// percent of the suggested price
+	Multiplier uint64 `json:"multiplier"`
+	// transactions pending for the blocks are bumped, 0 disables the bump
+	BumpAfterBlocks uint64 `json:"bump_after_blocks"`
+	// percent the gas price of a bumped transaction is raised by
+	BumpPercent uint64 `json:"bump_percent"`
+}
+
+// DefaultGasStrategy uses the suggested gas price and never bumps
+func DefaultGasStrategy() *GasStrategy {
+	return &GasStrategy{
+		Mode:        GasModeSuggested,
+		Multiplier:  defaultGasMultiplier,
+		BumpPercent: minBumpPercent,
+	}
+}
+
+// Validate checks the strategy is usable
+func (s *GasStrategy) Validate() error {
+	switch s.Mode {
+	case GasModeFixed, GasModeCapped:
+		if s.Price == nil || s.Price.Sign() <= 0 {
+			return fmt.Errorf("gas price is required in %s mode", s.Mode)
+		}
+	case GasModeSuggested:
+	default:
+		return fmt.Errorf("invalid gas mode: %s", s.Mode)
+	}
+	if s.Mode != GasModeFixed && s.Multiplier == 0 {
+		return errors.New("gas multiplier must be positive")
+	}
+	if s.BumpPercent < minBumpPercent {
+		return fmt.Errorf("bump percent must be at least %d", minBumpPercent)
+	}
+	return nil
+}
+
+// gasPrice returns the price of a new transaction
+func (s *GasStrategy) gasPrice(ctx context.Context, backend Backend) (*big.Int, error) {
+	if s.Mode == GasModeFixed {
+		return new(big.Int).Set(s.Price), nil
+	}
+	suggested, err := backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	price := suggested
+	if s.Multiplier != defaultGasMultiplier {
+		price = new(big.Int).Mul(suggested, new(big.Int).SetUint64(s.Multiplier))
+		price.Div(price, big.NewInt(100))
+	}
+	if s.Mode == GasModeCapped && price.Cmp(s.Price) > 0 {
+		price = new(big.Int).Set(s.Price)
+	}
+	return price, nil
+}
+
+// bumpedGasPrice returns the price replacing a pending transaction of the
+// price, the cap of capped mode is never exceeded
+func (s *GasStrategy) bumpedGasPrice(price *big.Int) (*big.Int, error) {
+	bumped := new(big.Int).Mul(price, new(big.Int).SetUint64(100+s.BumpPercent))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(price) <= 0 {
+		bumped.Add(price, big.NewInt(1))
+	}
+	if s.Mode == GasModeCapped && bumped.Cmp(s.Price) > 0 {
+		return nil, ErrGasPriceCapReached
+	}
+	return bumped, nil
+}
+
+func (t *transactionService) GasStrategy() (*GasStrategy, error) {
+	s := DefaultGasStrategy()
+	err := t.store.Get(gasStrategyKey, s)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (t *transactionService) SetGasStrategy(s *GasStrategy) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	return t.store.Put(gasStrategyKey, s)
+}
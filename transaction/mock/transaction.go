@@ -25,6 +25,9 @@ type transactionServiceMock struct {
 	pendingTransactions  func() ([]common.Hash, error)
 	resendTransaction    func(ctx context.Context, txHash common.Hash) error
 	cancelTransaction    func(ctx context.Context, originalTxHash common.Hash) (common.Hash, error)
+	bumpTransaction      func(ctx context.Context, txHash common.Hash) (common.Hash, error)
+	gasStrategy          func() (*transaction.GasStrategy, error)
+	setGasStrategy       func(s *transaction.GasStrategy) error
 	bttBalanceAt         func(ctx context.Context, address common.Address, block *big.Int) (*big.Int, error)
 	myBttBalance         func(ctx context.Context) (*big.Int, error)
 }
@@ -106,6 +109,31 @@ func (m *transactionServiceMock) CancelTransaction(ctx context.Context, original
 	return common.Hash{}, errors.New("transactionServiceMock.cancelTransaction not implemented")
 }
 
+func (m *transactionServiceMock) BumpTransaction(ctx context.Context, txHash common.Hash) (common.Hash, error) {
+	if m.bumpTransaction != nil {
+		return m.bumpTransaction(ctx, txHash)
+	}
+	return common.Hash{}, errors.New("transactionServiceMock.bumpTransaction not implemented")
+}
+
+func (m *transactionServiceMock) BumpStuckTransactions(ctx context.Context) (map[common.Hash]common.Hash, error) {
+	return nil, nil
+}
+
+func (m *transactionServiceMock) GasStrategy() (*transaction.GasStrategy, error) {
+	if m.gasStrategy != nil {
+		return m.gasStrategy()
+	}
+	return transaction.DefaultGasStrategy(), nil
+}
+
+func (m *transactionServiceMock) SetGasStrategy(s *transaction.GasStrategy) error {
+	if m.setGasStrategy != nil {
+		return m.setGasStrategy(s)
+	}
+	return errors.New("transactionServiceMock.setGasStrategy not implemented")
+}
+
 func (m *transactionServiceMock) Close() error {
 	return nil
 }
@@ -193,6 +221,24 @@ func WithCancelTransactionFunc(f func(ctx context.Context, originalTxHash common
 	})
 }
 
+func WithBumpTransactionFunc(f func(ctx context.Context, txHash common.Hash) (common.Hash, error)) Option {
+	return optionFunc(func(s *transactionServiceMock) {
+		s.bumpTransaction = f
+	})
+}
+
+func WithGasStrategyFunc(f func() (*transaction.GasStrategy, error)) Option {
+	return optionFunc(func(s *transactionServiceMock) {
+		s.gasStrategy = f
+	})
+}
+
+func WithSetGasStrategyFunc(f func(s *transaction.GasStrategy) error) Option {
+	return optionFunc(func(s *transactionServiceMock) {
+		s.setGasStrategy = f
+	})
+}
+
 func WithBttBalanceAt(f func(ctx context.Context, address common.Address, block *big.Int) (*big.Int, error)) Option {
 	return optionFunc(func(s *transactionServiceMock) {
 		s.bttBalanceAt = f
@@ -0,0 +1,568 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// number of the latest requests the error rate of an endpoint is over
+	errorRateWindow = 20
+	// json-rpc code of the providers rejecting requests over their rate limit
+	limitExceededCode = -32005
+)
+
+var (
+	ErrNoEndpoint           = errors.New("no rpc endpoint")
+	ErrEndpointNotConnected = errors.New("rpc endpoint not connected")
+)
+
+// Endpoint is a backend and the url it is connected to. An endpoint without
+// backend is connected by Dial on the health checks, until it succeeds.
+type Endpoint struct {
+	URL     string
+	Backend Backend
+	Dial    func(ctx context.Context) (Backend, error)
+}
+
+// MultiBackendOptions are the thresholds of the health checks
+type MultiBackendOptions struct {
+	CheckInterval time.Duration
+	CheckTimeout  time.Duration
+	// blocks an endpoint may be behind the most advanced one
+	MaxBlockLag uint64
+	// ratio of the failed requests among the latest ones
+	MaxErrorRate float64
+	MaxLatency   time.Duration
+}
+
+func DefaultMultiBackendOptions() MultiBackendOptions {
+	return MultiBackendOptions{
+		CheckInterval: 30 * time.Second,
+		CheckTimeout:  10 * time.Second,
+		MaxBlockLag:   5,
+		MaxErrorRate:  0.5,
+		MaxLatency:    5 * time.Second,
+	}
+}
+
+// EndpointStatus is the health of an endpoint as of the last check
+type EndpointStatus struct {
+	URL       string  `json:"url"`
+	Active    bool    `json:"active"`
+	Healthy   bool    `json:"healthy"`
+	Block     uint64  `json:"block"`
+	BlockLag  uint64  `json:"block_lag"`
+	LatencyMs int64   `json:"latency_ms"`
+	ErrorRate float64 `json:"error_rate"`
+	Requests  uint64  `json:"requests"`
+	Errors    uint64  `json:"errors"`
+	LastError string  `json:"last_error,omitempty"`
+	CheckedAt int64   `json:"checked_at"`
+}
+
+type endpoint struct {
+	url  string
+	dial func(ctx context.Context) (Backend, error)
+
+	// the fields below are guarded by the lock of the MultiBackend
+	backend   Backend
+	healthy   bool
+	checked   bool
+	block     uint64
+	blockLag  uint64
+	latency   time.Duration
+	results   [errorRateWindow]bool
+	nResults  int
+	next      int
+	requests  uint64
+	errors    uint64
+	lastErr   error
+	checkedAt time.Time
+}
+
+// errorRate returns the ratio of the failed requests within the window
+func (e *endpoint) errorRate() float64 {
+	if e.nResults == 0 {
+		return 0
+	}
+	failed := 0
+	for i := 0; i < e.nResults; i++ {
+		if e.results[i] {
+			failed++
+		}
+	}
+	return float64(failed) / float64(e.nResults)
+}
+
+// MultiBackend is a Backend over several rpc endpoints. The endpoints are
+// checked periodically for their block lag, error rate and latency. The
+// transactions, nonces, receipts and block numbers are requested from the
+// active endpoint, so the view of the pending transactions stays consistent,
+// while the other reads are balanced across the healthy endpoints. A request
+// failing for the endpoint rather than for the call fails over to the next
+// endpoint.
+type MultiBackend struct {
+	opts      MultiBackendOptions
+	endpoints []*endpoint
+
+	lock   sync.Mutex
+	active int
+	next   int
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewMultiBackend(endpoints []Endpoint, opts MultiBackendOptions) (*MultiBackend, error) {
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoint
+	}
+	m := &MultiBackend{
+		opts: opts,
+		quit: make(chan struct{}),
+	}
+	for _, e := range endpoints {
+		// unchecked endpoints are tried in order
+		m.endpoints = append(m.endpoints, &endpoint{
+			url:     e.URL,
+			dial:    e.Dial,
+			backend: e.Backend,
+			healthy: e.Backend != nil,
+		})
+	}
+	return m, nil
+}
+
+// Start checks the endpoints periodically until closed
+func (m *MultiBackend) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+		ticker := time.NewTicker(m.opts.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.quit:
+				return
+			case <-ticker.C:
+				m.Check(ctx)
+			}
+		}
+	}()
+	go func() {
+		<-m.quit
+		cancel()
+	}()
+}
+
+func (m *MultiBackend) Close() error {
+	close(m.quit)
+	m.wg.Wait()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, e := range m.endpoints {
+		if c, ok := e.backend.(interface{ Close() }); ok {
+			c.Close()
+		}
+	}
+	return nil
+}
+
+// Check requests the latest block of every endpoint, updates their health and
+// switches the active endpoint if it is unhealthy. The endpoints not connected
+// are dialed first. It returns the number of the healthy endpoints.
+func (m *MultiBackend) Check(ctx context.Context) int {
+	type result struct {
+		block   uint64
+		latency time.Duration
+		err     error
+		dialed  Backend
+	}
+	m.lock.Lock()
+	backends := make([]Backend, len(m.endpoints))
+	for i, e := range m.endpoints {
+		backends[i] = e.backend
+	}
+	m.lock.Unlock()
+
+	results := make([]result, len(m.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range m.endpoints {
+		wg.Add(1)
+		go func(i int, e *endpoint, b Backend) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, m.opts.CheckTimeout)
+			defer cancel()
+			start := time.Now()
+			if b == nil {
+				if e.dial == nil {
+					results[i] = result{err: ErrEndpointNotConnected}
+					return
+				}
+				dialed, err := e.dial(cctx)
+				if err != nil {
+					results[i] = result{latency: time.Since(start), err: err}
+					return
+				}
+				b = dialed
+				results[i].dialed = dialed
+			}
+			block, err := b.BlockNumber(cctx)
+			results[i].block, results[i].latency, results[i].err = block, time.Since(start), err
+		}(i, e, backends[i])
+	}
+	wg.Wait()
+
+	var maxBlock uint64
+	for _, r := range results {
+		if r.err == nil && r.block > maxBlock {
+			maxBlock = r.block
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	healthy := 0
+	for i, e := range m.endpoints {
+		r := results[i]
+		if r.dialed != nil {
+			logTranBackend.Infof("rpc endpoint %s connected", e.url)
+			e.backend = r.dialed
+		}
+		m.record(e, r.err, r.latency)
+		e.checked = true
+		e.checkedAt = time.Now()
+		if r.err == nil {
+			e.block = r.block
+			e.blockLag = maxBlock - r.block
+		}
+		e.healthy = r.err == nil &&
+			e.blockLag <= m.opts.MaxBlockLag &&
+			e.errorRate() <= m.opts.MaxErrorRate &&
+			e.latency <= m.opts.MaxLatency
+		if e.healthy {
+			healthy++
+		}
+	}
+
+	if !m.endpoints[m.active].healthy {
+		for i, e := range m.endpoints {
+			if e.healthy {
+				logTranBackend.Warnf("rpc endpoint %s is unhealthy, switched to %s", m.endpoints[m.active].url, e.url)
+				m.active = i
+				break
+			}
+		}
+	}
+	return healthy
+}
+
+// WaitHealthy checks the endpoints until one of them is healthy
+func (m *MultiBackend) WaitHealthy(ctx context.Context, interval time.Duration) error {
+	for {
+		if m.Check(ctx) > 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ActiveEndpoint returns the url of the active endpoint
+func (m *MultiBackend) ActiveEndpoint() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.endpoints[m.active].url
+}
+
+// Status returns the health of the endpoints
+func (m *MultiBackend) Status() []EndpointStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	status := make([]EndpointStatus, 0, len(m.endpoints))
+	for i, e := range m.endpoints {
+		s := EndpointStatus{
+			URL:       e.url,
+			Active:    i == m.active,
+			Healthy:   e.healthy,
+			Block:     e.block,
+			BlockLag:  e.blockLag,
+			LatencyMs: e.latency.Milliseconds(),
+			ErrorRate: e.errorRate(),
+			Requests:  e.requests,
+			Errors:    e.errors,
+		}
+		if e.lastErr != nil {
+			s.LastError = e.lastErr.Error()
+		}
+		if e.checked {
+			s.CheckedAt = e.checkedAt.Unix()
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// record adds the outcome of a request to the stats of the endpoint, the lock
+// must be held
+func (m *MultiBackend) record(e *endpoint, err error, latency time.Duration) {
+	failed := isEndpointError(err)
+	e.requests++
+	if failed {
+		e.errors++
+		e.lastErr = err
+	}
+	e.results[e.next] = failed
+	e.next = (e.next + 1) % errorRateWindow
+	if e.nResults < errorRateWindow {
+		e.nResults++
+	}
+	// moving average of the latency
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = (4*e.latency + latency) / 5
+	}
+}
+
+// candidates returns the endpoints to try in order: the active one or, for a
+// balanced read, the next healthy one, then the other healthy ones and the
+// unhealthy ones last
+func (m *MultiBackend) candidates(balanced bool) []*endpoint {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	n := len(m.endpoints)
+	first := m.active
+	if balanced {
+		for i := 0; i < n; i++ {
+			j := (m.next + i) % n
+			if m.endpoints[j].healthy {
+				first = j
+				break
+			}
+		}
+		m.next = (first + 1) % n
+	}
+	out := make([]*endpoint, 0, n)
+	out = append(out, m.endpoints[first])
+	for _, healthy := range []bool{true, false} {
+		for i, e := range m.endpoints {
+			if i != first && e.healthy == healthy {
+				out = append(out, e)
+			}
+		}
+	}
+	return out
+}
+
+// do runs the request on the endpoints until one does not fail for itself, the
+// endpoints not connected are skipped
+func (m *MultiBackend) do(ctx context.Context, balanced bool, f func(Backend) error) error {
+	err := ErrEndpointNotConnected
+	for _, e := range m.candidates(balanced) {
+		m.lock.Lock()
+		b := e.backend
+		m.lock.Unlock()
+		if b == nil {
+			continue
+		}
+		start := time.Now()
+		err = f(b)
+		if ctx.Err() != nil {
+			// cancelled by the caller, not the fault of the endpoint
+			return err
+		}
+		m.lock.Lock()
+		m.record(e, err, time.Since(start))
+		m.lock.Unlock()
+		if !isEndpointError(err) {
+			return err
+		}
+		logTranBackend.Warnf("rpc endpoint %s failed: %v", e.url, err)
+	}
+	return err
+}
+
+// isEndpointError tells whether the error is caused by the endpoint, so the
+// request may succeed on another one, rather than by the request itself
+func isEndpointError(err error) bool {
+	if err == nil || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == limitExceededCode
+	}
+	return true
+}
+
+func (m *MultiBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) (code []byte, err error) {
+	err = m.do(ctx, true, func(b Backend) (err error) {
+		code, err = b.CodeAt(ctx, contract, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+func (m *MultiBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) (ret []byte, err error) {
+	err = m.do(ctx, true, func(b Backend) (err error) {
+		ret, err = b.CallContract(ctx, call, blockNumber)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MultiBackend) HeaderByNumber(ctx context.Context, number *big.Int) (header *types.Header, err error) {
+	err = m.do(ctx, true, func(b Backend) (err error) {
+		header, err = b.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+func (m *MultiBackend) PendingCodeAt(ctx context.Context, account common.Address) (code []byte, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		code, err = b.PendingCodeAt(ctx, account)
+		return err
+	})
+	return code, err
+}
+
+func (m *MultiBackend) PendingNonceAt(ctx context.Context, account common.Address) (nonce uint64, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		nonce, err = b.PendingNonceAt(ctx, account)
+		return err
+	})
+	return nonce, err
+}
+
+func (m *MultiBackend) SuggestGasPrice(ctx context.Context) (price *big.Int, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		price, err = b.SuggestGasPrice(ctx)
+		return err
+	})
+	return price, err
+}
+
+func (m *MultiBackend) SuggestGasTipCap(ctx context.Context) (tip *big.Int, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		tip, err = b.SuggestGasTipCap(ctx)
+		return err
+	})
+	return tip, err
+}
+
+func (m *MultiBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (gas uint64, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		gas, err = b.EstimateGas(ctx, call)
+		return err
+	})
+	return gas, err
+}
+
+// SendTransaction sends the transaction to the active endpoint. If it fails
+// over, the failed endpoint may have broadcast the transaction already, so the
+// next endpoint rejecting it as known or its nonce as used means it is sent.
+func (m *MultiBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	failedOver := false
+	return m.do(ctx, false, func(b Backend) error {
+		err := b.SendTransaction(ctx, tx)
+		if failedOver && isKnownTransactionError(err) {
+			logTranBackend.Infof("transaction %s has been sent before the failover: %v", tx.Hash(), err)
+			return nil
+		}
+		failedOver = true
+		return err
+	})
+}
+
+// isKnownTransactionError tells whether the node rejects the transaction as it
+// is in the pool already or its nonce has been used
+func isKnownTransactionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") ||
+		strings.Contains(msg, "known transaction") ||
+		strings.Contains(msg, "nonce too low")
+}
+
+func (m *MultiBackend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) (logs []types.Log, err error) {
+	err = m.do(ctx, true, func(b Backend) (err error) {
+		logs, err = b.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+func (m *MultiBackend) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (sub ethereum.Subscription, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		sub, err = b.SubscribeFilterLogs(ctx, query, ch)
+		return err
+	})
+	return sub, err
+}
+
+func (m *MultiBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		receipt, err = b.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
+
+func (m *MultiBackend) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		tx, isPending, err = b.TransactionByHash(ctx, hash)
+		return err
+	})
+	return tx, isPending, err
+}
+
+func (m *MultiBackend) BlockNumber(ctx context.Context) (block uint64, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		block, err = b.BlockNumber(ctx)
+		return err
+	})
+	return block, err
+}
+
+func (m *MultiBackend) BlockByNumber(ctx context.Context, number *big.Int) (block *types.Block, err error) {
+	err = m.do(ctx, true, func(b Backend) (err error) {
+		block, err = b.BlockByNumber(ctx, number)
+		return err
+	})
+	return block, err
+}
+
+func (m *MultiBackend) BalanceAt(ctx context.Context, address common.Address, block *big.Int) (balance *big.Int, err error) {
+	err = m.do(ctx, true, func(b Backend) (err error) {
+		balance, err = b.BalanceAt(ctx, address, block)
+		return err
+	})
+	return balance, err
+}
+
+func (m *MultiBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (nonce uint64, err error) {
+	err = m.do(ctx, false, func(b Backend) (err error) {
+		nonce, err = b.NonceAt(ctx, account, blockNumber)
+		return err
+	})
+	return nonce, err
+}
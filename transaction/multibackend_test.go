@@ -0,0 +1,200 @@
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/bittorrent/go-btfs/transaction/backendmock"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type rpcError struct{ code int }
+
+func (e rpcError) Error() string  { return "rpc error" }
+func (e rpcError) ErrorCode() int { return e.code }
+
+func blockNumberBackend(block uint64, err error) transaction.Backend {
+	return backendmock.New(
+		backendmock.WithBlockNumberFunc(func(ctx context.Context) (uint64, error) {
+			return block, err
+		}),
+	)
+}
+
+func TestMultiBackendFailover(t *testing.T) {
+	ctx := context.Background()
+	nonce := uint64(10)
+
+	var calls []string
+	nonceBackend := func(name string, err error) transaction.Backend {
+		return backendmock.New(
+			backendmock.WithPendingNonceAtFunc(func(ctx context.Context, account common.Address) (uint64, error) {
+				calls = append(calls, name)
+				return nonce, err
+			}),
+		)
+	}
+
+	t.Run("endpoint error", func(t *testing.T) {
+		calls = nil
+		m, err := transaction.NewMultiBackend([]transaction.Endpoint{
+			{URL: "a", Backend: nonceBackend("a", errors.New("connection refused"))},
+			{URL: "b", Backend: nonceBackend("b", nil)},
+		}, transaction.DefaultMultiBackendOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.PendingNonceAt(ctx, common.Address{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != nonce {
+			t.Fatalf("got nonce %d, wanted %d", got, nonce)
+		}
+		if len(calls) != 2 {
+			t.Fatalf("got calls %v, wanted a then b", calls)
+		}
+	})
+
+	t.Run("call error", func(t *testing.T) {
+		calls = nil
+		callErr := rpcError{code: 3}
+		m, err := transaction.NewMultiBackend([]transaction.Endpoint{
+			{URL: "a", Backend: nonceBackend("a", callErr)},
+			{URL: "b", Backend: nonceBackend("b", nil)},
+		}, transaction.DefaultMultiBackendOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = m.PendingNonceAt(ctx, common.Address{})
+		if !errors.Is(err, callErr) {
+			t.Fatalf("got error %v, wanted %v", err, callErr)
+		}
+		if len(calls) != 1 {
+			t.Fatalf("got calls %v, wanted a only", calls)
+		}
+	})
+}
+
+func TestMultiBackendCheck(t *testing.T) {
+	ctx := context.Background()
+	opts := transaction.DefaultMultiBackendOptions()
+
+	m, err := transaction.NewMultiBackend([]transaction.Endpoint{
+		{URL: "lagging", Backend: blockNumberBackend(100, nil)},
+		{URL: "failing", Backend: blockNumberBackend(0, errors.New("connection refused"))},
+		{URL: "synced", Backend: blockNumberBackend(100+opts.MaxBlockLag+1, nil)},
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	healthy := m.Check(ctx)
+	if healthy != 1 {
+		t.Fatalf("got %d healthy endpoints, wanted 1", healthy)
+	}
+	if active := m.ActiveEndpoint(); active != "synced" {
+		t.Fatalf("got active endpoint %s, wanted synced", active)
+	}
+
+	status := m.Status()
+	if status[0].BlockLag != opts.MaxBlockLag+1 || status[0].Healthy {
+		t.Fatalf("lagging endpoint reported as %+v", status[0])
+	}
+	if status[1].LastError == "" || status[1].Healthy {
+		t.Fatalf("failing endpoint reported as %+v", status[1])
+	}
+	if !status[2].Active || !status[2].Healthy {
+		t.Fatalf("synced endpoint reported as %+v", status[2])
+	}
+}
+
+func TestMultiBackendSendTransaction(t *testing.T) {
+	ctx := context.Background()
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+
+	sendBackend := func(err error) transaction.Backend {
+		return backendmock.New(
+			backendmock.WithSendTransactionFunc(func(ctx context.Context, tx *types.Transaction) error {
+				return err
+			}),
+		)
+	}
+	timeout := errors.New("i/o timeout")
+
+	for _, tc := range []struct {
+		name      string
+		endpoints []error
+		wantErr   bool
+	}{
+		{"known after failover", []error{timeout, errors.New("already known")}, false},
+		{"nonce used after failover", []error{timeout, errors.New("nonce too low: next nonce 1, tx nonce 0")}, false},
+		{"known without failover", []error{errors.New("already known")}, true},
+		{"nonce used without failover", []error{errors.New("nonce too low")}, true},
+		{"all failed", []error{timeout, timeout}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoints := make([]transaction.Endpoint, 0, len(tc.endpoints))
+			for i, err := range tc.endpoints {
+				endpoints = append(endpoints, transaction.Endpoint{URL: string(rune('a' + i)), Backend: sendBackend(err)})
+			}
+			m, err := transaction.NewMultiBackend(endpoints, transaction.DefaultMultiBackendOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = m.SendTransaction(ctx, tx)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, wanted error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMultiBackendRedial(t *testing.T) {
+	ctx := context.Background()
+	dialErr := errors.New("connection refused")
+	dials := 0
+	m, err := transaction.NewMultiBackend([]transaction.Endpoint{
+		{
+			URL: "redialed",
+			Dial: func(ctx context.Context) (transaction.Backend, error) {
+				dials++
+				if dials == 1 {
+					return nil, dialErr
+				}
+				return blockNumberBackend(100, nil), nil
+			},
+		},
+		{URL: "failing", Backend: blockNumberBackend(0, errors.New("connection refused"))},
+	}, transaction.DefaultMultiBackendOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.BlockNumber(ctx); err == nil {
+		t.Fatal("expected error without connected endpoint")
+	}
+	if healthy := m.Check(ctx); healthy != 0 {
+		t.Fatalf("got %d healthy endpoints, wanted 0", healthy)
+	}
+	if status := m.Status(); status[0].LastError != dialErr.Error() {
+		t.Fatalf("redialed endpoint reported as %+v", status[0])
+	}
+
+	if healthy := m.Check(ctx); healthy != 1 {
+		t.Fatalf("got %d healthy endpoints, wanted 1", healthy)
+	}
+	if active := m.ActiveEndpoint(); active != "redialed" {
+		t.Fatalf("got active endpoint %s, wanted redialed", active)
+	}
+	block, err := m.BlockNumber(ctx)
+	if err != nil || block != 100 {
+		t.Fatalf("got block %d error %v, wanted 100", block, err)
+	}
+}
@@ -59,6 +59,7 @@ type StoredTransaction struct {
 	Value       *big.Int        // amount of wei to send
 	Nonce       uint64          // used nonce
 	Created     int64           // creation timestamp
+	Block       uint64          // block number when sent, 0 if unknown
 	Description string          // description
 }
 
@@ -89,6 +90,14 @@ type Service interface {
 	ResendTransaction(ctx context.Context, txHash common.Hash) error
 	// CancelTransaction cancels a previously sent transaction by double-spending its nonce with zero-transfer one
 	CancelTransaction(ctx context.Context, originalTxHash common.Hash) (common.Hash, error)
+	// BumpTransaction replaces a pending transaction with the same one of a higher gas price
+	BumpTransaction(ctx context.Context, txHash common.Hash) (common.Hash, error)
+	// BumpStuckTransactions bumps the transactions pending for more blocks than the gas strategy allows
+	BumpStuckTransactions(ctx context.Context) (map[common.Hash]common.Hash, error)
+	// GasStrategy returns the gas strategy of the transactions
+	GasStrategy() (*GasStrategy, error)
+	// SetGasStrategy sets the gas strategy of the transactions
+	SetGasStrategy(s *GasStrategy) error
 	// BalanceAt get btt balance from backend
 	BttBalanceAt(ctx context.Context, address common.Address, block *big.Int) (*big.Int, error)
 	// MyBttBalance get btt balance of current BTTC address
@@ -107,6 +116,10 @@ type transactionService struct {
 	store   storage.StateStorer
 	chainID *big.Int
 	monitor Monitor
+
+	// first block the pending transactions of unknown block were seen at
+	seenLock sync.Mutex
+	seenAt   map[common.Hash]uint64
 }
 
 // NewService creates a new transaction service.
@@ -127,6 +140,7 @@ func NewService(backend Backend, signer crypto.Signer, store storage.StateStorer
 		store:   store,
 		chainID: chainID,
 		monitor: monitor,
+		seenAt:  make(map[common.Hash]uint64),
 	}
 
 	pendingTxs, err := t.PendingTransactions()
@@ -162,7 +176,12 @@ func (t *transactionService) Send(ctx context.Context, request *TxRequest) (txHa
 		return common.Hash{}, err
 	}
 
-	tx, err := prepareTransaction(ctx, request, t.sender, t.backend, nonce)
+	strategy, err := t.GasStrategy()
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	tx, err := prepareTransaction(ctx, request, t.sender, t.backend, nonce, strategy)
 	if err != nil {
 		return common.Hash{}, err
 	}
@@ -197,6 +216,7 @@ func (t *transactionService) Send(ctx context.Context, request *TxRequest) (txHa
 		Value:       signedTx.Value(),
 		Nonce:       signedTx.Nonce(),
 		Created:     time.Now().Unix(),
+		Block:       t.blockNumber(ctx),
 		Description: request.Description,
 	})
 	if err != nil {
@@ -241,7 +261,7 @@ func (t *transactionService) waitForPendingTx(txHash common.Hash) {
 	t.wg.Add(1)
 	go func() {
 		defer t.wg.Done()
-		_, err := t.WaitForReceipt(t.ctx, txHash)
+		_, err := t.waitForReceipt(t.ctx, txHash)
 		if err != nil {
 			if !errors.Is(err, ErrTransactionCancelled) {
 				logTran.Errorf("error while waiting for pending transaction %x: %v", txHash, err)
@@ -290,7 +310,8 @@ func (t *transactionService) StoredTransaction(txHash common.Hash) (*StoredTrans
 }
 
 // prepareTransaction creates a signable transaction based on a request.
-func prepareTransaction(ctx context.Context, request *TxRequest, from common.Address, backend Backend, nonce uint64) (tx *types.Transaction, err error) {
+func prepareTransaction(ctx context.Context, request *TxRequest, from common.Address, backend Backend, nonce uint64,
+	strategy *GasStrategy) (tx *types.Transaction, err error) {
 	var gasLimit uint64
 	if request.GasLimit == 0 {
 		gasLimit, err = backend.EstimateGas(ctx, ethereum.CallMsg{
@@ -309,7 +330,7 @@ func prepareTransaction(ctx context.Context, request *TxRequest, from common.Add
 
 	var gasPrice *big.Int
 	if request.GasPrice == nil {
-		gasPrice, err = backend.SuggestGasPrice(ctx)
+		gasPrice, err = strategy.gasPrice(ctx, backend)
 		if err != nil {
 			return nil, err
 		}
@@ -382,8 +403,23 @@ func (t *transactionService) putNonce(nonce uint64) error {
 }
 
 // WaitForReceipt waits until either the transaction with the given hash has
-// been mined or the context is cancelled.
+// been mined or the context is cancelled. The transactions replaced by bumped
+// ones are followed to their replacements.
 func (t *transactionService) WaitForReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+	for {
+		receipt, err = t.waitForReceipt(ctx, txHash)
+		if !errors.Is(err, ErrTransactionCancelled) {
+			return receipt, err
+		}
+		replacement, ok, rerr := t.replacement(txHash)
+		if rerr != nil || !ok {
+			return receipt, err
+		}
+		txHash = replacement
+	}
+}
+
+func (t *transactionService) waitForReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
 	receiptC, errC, err := t.WatchSentTransaction(txHash)
 	if err != nil {
 		return nil, err
@@ -478,7 +514,14 @@ func (t *transactionService) CancelTransaction(ctx context.Context, originalTxHa
 
 	gasPrice := sctx.GetGasPrice(ctx)
 	if gasPrice == nil {
-		gasPrice = new(big.Int).Add(storedTransaction.GasPrice, big.NewInt(1))
+		strategy, err := t.GasStrategy()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		gasPrice, err = strategy.bumpedGasPrice(storedTransaction.GasPrice)
+		if err != nil {
+			return common.Hash{}, err
+		}
 	} else if gasPrice.Cmp(storedTransaction.GasPrice) <= 0 {
 		return common.Hash{}, ErrGasPriceTooLow
 	}
@@ -509,6 +552,7 @@ func (t *transactionService) CancelTransaction(ctx context.Context, originalTxHa
 		Value:       signedTx.Value(),
 		Nonce:       signedTx.Nonce(),
 		Created:     time.Now().Unix(),
+		Block:       t.blockNumber(ctx),
 		Description: fmt.Sprintf("%s (cancellation)", storedTransaction.Description),
 	})
 	if err != nil {
@@ -626,3 +626,103 @@ func TestTransactionCancel(t *testing.T) {
 		}
 	})
 }
+
+func TestTransactionBump(t *testing.T) {
+	recipient := common.HexToAddress("0xbbbddd")
+	chainID := big.NewInt(5)
+	nonce := uint64(10)
+	data := []byte{1, 2, 3, 4}
+	gasPrice := big.NewInt(100)
+	gasLimit := uint64(100000)
+	value := big.NewInt(0)
+
+	store := storemock.NewStateStore()
+	defer store.Close()
+
+	signedTx := types.NewTransaction(nonce, recipient, value, gasLimit, gasPrice, data)
+	err := store.Put(transaction.StoredTransactionKey(signedTx.Hash()), transaction.StoredTransaction{
+		Nonce:    nonce,
+		To:       &recipient,
+		Data:     data,
+		GasPrice: gasPrice,
+		GasLimit: gasLimit,
+		Value:    value,
+		Block:    5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Put(transaction.PendingTransactionKey(signedTx.Hash()), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bumpedTx := types.NewTransaction(nonce, recipient, value, gasLimit, big.NewInt(110), data)
+
+	transactionService, err := transaction.NewService(
+		backendmock.New(
+			backendmock.WithSendTransactionFunc(func(ctx context.Context, tx *types.Transaction) error {
+				if tx != bumpedTx {
+					t.Fatal("not sending signed transaction")
+				}
+				return nil
+			}),
+			backendmock.WithBlockNumberFunc(func(ctx context.Context) (uint64, error) {
+				return 20, nil
+			}),
+			backendmock.WithNonceAtFunc(func(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+				return nonce, nil
+			}),
+		),
+		signerMockForTransaction(bumpedTx, recipient, chainID, t),
+		store,
+		chainID,
+		monitormock.New(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transactionService.Close()
+
+	t.Run("disabled", func(t *testing.T) {
+		bumped, err := transactionService.BumpStuckTransactions(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bumped) != 0 {
+			t.Fatalf("bumped %d transactions with bump disabled", len(bumped))
+		}
+	})
+
+	t.Run("stuck", func(t *testing.T) {
+		strategy := transaction.DefaultGasStrategy()
+		strategy.BumpAfterBlocks = 10
+		err := transactionService.SetGasStrategy(strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bumped, err := transactionService.BumpStuckTransactions(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bumped[signedTx.Hash()] != bumpedTx.Hash() {
+			t.Fatalf("got replacements %v, wanted %x", bumped, bumpedTx.Hash())
+		}
+
+		pending, err := transactionService.PendingTransactions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0] != bumpedTx.Hash() {
+			t.Fatalf("got pending transactions %v, wanted %x", pending, bumpedTx.Hash())
+		}
+	})
+
+	t.Run("not pending", func(t *testing.T) {
+		_, err := transactionService.BumpTransaction(context.Background(), signedTx.Hash())
+		if !errors.Is(err, transaction.ErrTransactionNotPending) {
+			t.Fatalf("returned wrong error. wanted %v, got %v", transaction.ErrTransactionNotPending, err)
+		}
+	})
+}